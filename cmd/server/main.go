@@ -4,19 +4,27 @@ import (
 	"context"
 	"database/sql"
 	_ "encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
-	
+
 	"inventory-system/internal/app"
 	"inventory-system/internal/config"
 	"inventory-system/internal/database"
+	"inventory-system/internal/events"
 	"inventory-system/internal/handlers"
+	"inventory-system/internal/ldap"
+	"inventory-system/internal/mail"
+	"inventory-system/internal/oidc"
+	"inventory-system/internal/ratelimit"
 	"inventory-system/internal/repository"
 	"inventory-system/internal/services"
+	"inventory-system/internal/signing"
+	"inventory-system/internal/tenant"
 )
 
 func main() {
@@ -38,25 +46,78 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Pass the organization of each request to row-level security policies.
+	// Without it the policies hide every row, so refuse to start instead of
+	// failing every login
+	if cfg.Organizations.RowLevelSecurity {
+		repository.EnableRowLevelSecurity()
+	} else {
+		enforced, err := repository.RowLevelSecurityEnforced(context.Background(), db)
+		if err != nil {
+			log.Fatalf("Failed to check row-level security: %v", err)
+		}
+		if enforced {
+			log.Fatal("Row-level security policies are enabled in the database: set DB_ROW_LEVEL_SECURITY=true")
+		}
+	}
+
 	// Initialize repositories
 	repos := initializeRepositories(db)
 
+	// Change notifications for live updates
+	eventBus := events.NewBus(cfg.Events.HistorySize)
+
+	// Email notifications
+	mailer, err := newMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+	mailTemplates, err := mail.LoadTemplates()
+	if err != nil {
+		log.Fatalf("Failed to load mail templates: %v", err)
+	}
+
 	// Initialize services
-	services := initializeServices(repos, cfg)
+	services, err := initializeServices(repos, cfg, eventBus, mailer, mailTemplates)
+	if err != nil {
+		log.Fatalf("Failed to initialize services: %v", err)
+	}
 
 	// Initialize handlers
-	handler := handlers.NewHandler(
-		services.DepartmentService,
-		services.EmployeeService,
-		services.AssetService,
-		services.LocationService,
-		services.TransferService,
-		services.AuthService,
-		services.ReportService,
-	)
+	handler := initializeHandler(services, eventBus)
+
+	// Background jobs serve all organizations at once
+	background := tenant.WithAllOrganizations(context.Background())
+
+	// Load access token signing keys, creating the first one if needed
+	if err := services.SigningKeyService.Rotate(background); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	keysCtx, stopKeys := context.WithCancel(background)
+	defer stopKeys()
+	go services.SigningKeyService.Run(keysCtx)
+
+	// Purge records soft-deleted longer than the retention period
+	retentionCtx, stopRetention := context.WithCancel(background)
+	defer stopRetention()
+	go services.RetentionService.Run(retentionCtx)
+
+	// Deliver outbox events to webhook subscribers
+	webhookCtx, stopWebhooks := context.WithCancel(background)
+	defer stopWebhooks()
+	go services.WebhookDispatcher.Run(webhookCtx)
+
+	// Send queued emails and warranty reminders
+	mailCtx, stopMail := context.WithCancel(background)
+	defer stopMail()
+	go services.MailDispatcher.Run(mailCtx)
+	go services.NotificationService.Run(mailCtx)
 
 	// Setup router
-	router := app.NewRouter(handler)
+	router := app.NewRouter(handler, app.Deprecation{
+		At:     cfg.API.LegacyDeprecatedAt,
+		Sunset: cfg.API.LegacySunset,
+	})
 
 	// Serve static files
 	fs := http.FileServer(http.Dir("./frontend/static"))
@@ -72,6 +133,12 @@ func main() {
 	router.HandleFunc("/register", serveRegister)
 	router.HandleFunc("/register.html", serveRegister)
 
+	router.HandleFunc("/reset-password", serveResetPassword)
+	router.HandleFunc("/reset-password.html", serveResetPassword)
+
+	router.HandleFunc("/two-factor", serveTwoFactor)
+	router.HandleFunc("/two-factor.html", serveTwoFactor)
+
 	router.HandleFunc("/assets", serveAssets)
 	router.HandleFunc("/assets.html", serveAssets)
 
@@ -92,6 +159,8 @@ func main() {
 		Addr:    cfg.Server.Address,
 		Handler: router,
 	}
+	// Open event streams would otherwise hold Shutdown until its timeout
+	server.RegisterOnShutdown(eventBus.Close)
 
 	// Start server in a goroutine
 	go func() {
@@ -107,6 +176,9 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopRetention()
+	stopWebhooks()
+	stopMail()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -130,61 +202,359 @@ func runMigrations(db *sql.DB) error {
 }
 
 type Repositories struct {
-	DepartmentRepo *repository.DepartmentRepository
-	EmployeeRepo   *repository.EmployeeRepository
-	AssetRepo      *repository.AssetRepository
-	StatusRepo     *repository.StatusRepository
-	LocationRepo   *repository.LocationRepository
-	TransferRepo   *repository.TransferRepository
+	DepartmentRepo    *repository.DepartmentRepository
+	EmployeeRepo      *repository.EmployeeRepository
+	AssetRepo         *repository.AssetRepository
+	StatusRepo        *repository.StatusRepository
+	LocationRepo      *repository.LocationRepository
+	TransferRepo      *repository.TransferRepository
+	CategoryRepo      *repository.CategoryRepository
+	ReservationRepo   *repository.ReservationRepository
+	OutboxRepo        *repository.OutboxRepository
+	WebhookRepo       *repository.WebhookRepository
+	NotificationRepo  *repository.NotificationRepository
+	MailQueueRepo     *repository.MailQueueRepository
+	PasswordResetRepo *repository.PasswordResetRepository
+	LoginAuditRepo    *repository.LoginAuditRepository
+	TwoFactorRepo     *repository.TwoFactorRepository
+	APITokenRepo      *repository.APITokenRepository
+	SigningKeyRepo    *repository.SigningKeyRepository
+	InvitationRepo    *repository.InvitationRepository
+	OrganizationRepo  *repository.OrganizationRepository
+	Transactor        *repository.Transactor
 }
 
 func initializeRepositories(db *sql.DB) *Repositories {
 	return &Repositories{
-		DepartmentRepo: repository.NewDepartmentRepository(db),
-		EmployeeRepo:   repository.NewEmployeeRepository(db),
-		AssetRepo:      repository.NewAssetRepository(db),
-		StatusRepo:     repository.NewStatusRepository(db),
-		LocationRepo:   repository.NewLocationRepository(db),
-		TransferRepo:   repository.NewTransferRepository(db),
+		DepartmentRepo:    repository.NewDepartmentRepository(db),
+		EmployeeRepo:      repository.NewEmployeeRepository(db),
+		AssetRepo:         repository.NewAssetRepository(db),
+		StatusRepo:        repository.NewStatusRepository(db),
+		LocationRepo:      repository.NewLocationRepository(db),
+		TransferRepo:      repository.NewTransferRepository(db),
+		CategoryRepo:      repository.NewCategoryRepository(db),
+		ReservationRepo:   repository.NewReservationRepository(db),
+		OutboxRepo:        repository.NewOutboxRepository(db),
+		WebhookRepo:       repository.NewWebhookRepository(db),
+		NotificationRepo:  repository.NewNotificationRepository(db),
+		MailQueueRepo:     repository.NewMailQueueRepository(db),
+		PasswordResetRepo: repository.NewPasswordResetRepository(db),
+		LoginAuditRepo:    repository.NewLoginAuditRepository(db),
+		TwoFactorRepo:     repository.NewTwoFactorRepository(db),
+		APITokenRepo:      repository.NewAPITokenRepository(db),
+		SigningKeyRepo:    repository.NewSigningKeyRepository(db),
+		InvitationRepo:    repository.NewInvitationRepository(db),
+		OrganizationRepo:  repository.NewOrganizationRepository(db),
+		Transactor:        repository.NewTransactor(db),
 	}
 }
 
 type Services struct {
-	DepartmentService *services.DepartmentService
-	EmployeeService   *services.EmployeeService
-	AssetService      *services.AssetService
-	LocationService   *services.LocationService
-	TransferService   *services.TransferService
-	AuthService       *services.AuthService
-	ReportService     *services.ReportService
+	DepartmentService    *services.DepartmentService
+	EmployeeService      *services.EmployeeService
+	AssetService         *services.AssetService
+	LocationService      *services.LocationService
+	TransferService      *services.TransferService
+	AuthService          *services.AuthService
+	ReportService        *services.ReportService
+	CategoryService      *services.CategoryService
+	ReservationService   *services.ReservationService
+	RetentionService     *services.RetentionService
+	WebhookService       *services.WebhookService
+	WebhookDispatcher    *services.WebhookDispatcher
+	NotificationService  *services.NotificationService
+	MailDispatcher       *services.MailDispatcher
+	PasswordResetService *services.PasswordResetService
+	TwoFactorService     *services.TwoFactorService
+	OIDCService          *services.OIDCService
+	APITokenService      *services.APITokenService
+	SigningKeyService    *services.SigningKeyService
+	RegistrationService  *services.RegistrationService
+	OrganizationService  *services.OrganizationService
 }
 
-func initializeServices(repos *Repositories, cfg *config.Config) *Services {
+func initializeServices(
+	repos *Repositories,
+	cfg *config.Config,
+	bus *events.Bus,
+	mailer mail.Mailer,
+	mailTemplates *mail.Templates,
+) (*Services, error) {
+	notificationService := services.NewNotificationService(
+		repos.NotificationRepo,
+		repos.MailQueueRepo,
+		repos.EmployeeRepo,
+		repos.AssetRepo,
+		repos.TransferRepo,
+		repos.Transactor,
+		mailTemplates,
+		cfg.Notifications.BaseURL,
+		cfg.Notifications.WarrantyDays,
+		cfg.Notifications.WarrantyInterval,
+	)
+	organizationService := services.NewOrganizationService(repos.OrganizationRepo, cfg.Organizations.DefaultCode)
+	employeeService := services.NewEmployeeService(
+		repos.EmployeeRepo,
+		repos.DepartmentRepo,
+		repos.APITokenRepo,
+		organizationService,
+		repos.Transactor,
+		bus,
+	)
+	twoFactorService := services.NewTwoFactorService(
+		repos.TwoFactorRepo,
+		repos.EmployeeRepo,
+		repos.Transactor,
+		cfg.Auth.TwoFactorIssuer,
+		cfg.Auth.TwoFactorRoles,
+		ratelimit.SystemClock,
+	)
+	authProviders, err := newAuthProviders(cfg, repos, employeeService)
+	if err != nil {
+		return nil, err
+	}
+	signingKeys := signing.NewKeyRing()
+
 	return &Services{
-		DepartmentService: services.NewDepartmentService(repos.DepartmentRepo, repos.EmployeeRepo),
-		EmployeeService:   services.NewEmployeeService(repos.EmployeeRepo, repos.DepartmentRepo),
+		DepartmentService: services.NewDepartmentService(
+			repos.DepartmentRepo,
+			repos.EmployeeRepo,
+			repos.Transactor,
+			notificationService,
+			bus,
+		),
+		EmployeeService: employeeService,
 		AssetService: services.NewAssetService(
 			repos.AssetRepo,
 			repos.StatusRepo,
 			repos.LocationRepo,
 			repos.DepartmentRepo,
+			repos.CategoryRepo,
+			repos.OutboxRepo,
+			repos.Transactor,
+			notificationService,
+			bus,
 		),
-		LocationService: services.NewLocationService(repos.LocationRepo),
+		LocationService: services.NewLocationService(repos.LocationRepo, bus),
 		TransferService: services.NewTransferService(
 			repos.TransferRepo,
 			repos.AssetRepo,
 			repos.EmployeeRepo,
 			repos.LocationRepo,
+			repos.OutboxRepo,
+			repos.Transactor,
+			notificationService,
+			bus,
 		),
 		AuthService: services.NewAuthService(
 			repos.EmployeeRepo,
+			repos.LoginAuditRepo,
+			twoFactorService,
+			authProviders,
+			ratelimit.NewMemoryStore(),
+			services.LoginPolicy{
+				IPLimit:            ratelimit.Limit{Burst: cfg.Login.IPBurst, Interval: cfg.Login.IPInterval},
+				AccountLimit:       ratelimit.Limit{Burst: cfg.Login.AccountBurst, Interval: cfg.Login.AccountInterval},
+				LockoutThreshold:   cfg.Login.LockoutThreshold,
+				LockoutDuration:    cfg.Login.LockoutDuration,
+				MaxLockoutDuration: cfg.Login.MaxLockoutDuration,
+			},
+			ratelimit.SystemClock,
+			signingKeys,
 			cfg.Auth.SecretKey,
 			cfg.Auth.TokenExpiry,
 		),
 		ReportService: services.NewReportService(
-			services.NewAssetService(repos.AssetRepo, repos.StatusRepo, repos.LocationRepo, repos.DepartmentRepo),
-			services.NewTransferService(repos.TransferRepo, repos.AssetRepo, repos.EmployeeRepo, repos.LocationRepo),
+			services.NewAssetService(repos.AssetRepo, repos.StatusRepo, repos.LocationRepo, repos.DepartmentRepo, repos.CategoryRepo, repos.OutboxRepo, repos.Transactor, notificationService, bus),
+			services.NewTransferService(repos.TransferRepo, repos.AssetRepo, repos.EmployeeRepo, repos.LocationRepo, repos.OutboxRepo, repos.Transactor, notificationService, bus),
+			services.NewCategoryService(repos.CategoryRepo, bus),
+			organizationService,
+		),
+		CategoryService: services.NewCategoryService(repos.CategoryRepo, bus),
+		ReservationService: services.NewReservationService(
+			repos.ReservationRepo,
+			repos.AssetRepo,
+			repos.EmployeeRepo,
+			repos.CategoryRepo,
+			repos.LocationRepo,
+			repos.Transactor,
+			notificationService,
+			bus,
 		),
+		RetentionService: services.NewRetentionService(
+			repos.AssetRepo,
+			repos.EmployeeRepo,
+			repos.DepartmentRepo,
+			repos.LocationRepo,
+			cfg.Retention.Period,
+			cfg.Retention.Interval,
+		),
+		WebhookService: services.NewWebhookService(repos.WebhookRepo),
+		WebhookDispatcher: services.NewWebhookDispatcher(
+			repos.OutboxRepo,
+			repos.WebhookRepo,
+			repos.Transactor,
+			services.NewWebhookClient(cfg.Webhooks.Timeout),
+			cfg.Webhooks.Interval,
+			cfg.Webhooks.MaxAttempts,
+			cfg.Webhooks.RetryDelay,
+		),
+		NotificationService: notificationService,
+		MailDispatcher: services.NewMailDispatcher(
+			repos.MailQueueRepo,
+			mailer,
+			cfg.Mail.Interval,
+			cfg.Mail.MaxAttempts,
+			cfg.Mail.RetryDelay,
+		),
+		PasswordResetService: services.NewPasswordResetService(
+			repos.PasswordResetRepo,
+			repos.EmployeeRepo,
+			employeeService,
+			repos.Transactor,
+			notificationService,
+			cfg.Auth.ResetTokenTTL,
+		),
+		TwoFactorService: twoFactorService,
+		OIDCService:      newOIDCService(cfg, repos),
+		APITokenService: services.NewAPITokenService(
+			repos.APITokenRepo,
+			repos.EmployeeRepo,
+			ratelimit.SystemClock,
+			cfg.APITokens.DefaultTTL,
+			cfg.APITokens.MaxTTL,
+		),
+		SigningKeyService: services.NewSigningKeyService(
+			repos.SigningKeyRepo,
+			repos.Transactor,
+			signingKeys,
+			services.KeyRotationPolicy{
+				Algorithm:        cfg.Auth.SigningAlgorithm,
+				RotationInterval: cfg.Auth.KeyRotationInterval,
+				Prepublish:       cfg.Auth.KeyPrepublish,
+				TokenLifetime:    cfg.Auth.TokenExpiry,
+			},
+			ratelimit.SystemClock,
+			cfg.Auth.KeyRefreshInterval,
+		),
+		RegistrationService: services.NewRegistrationService(
+			repos.InvitationRepo,
+			repos.EmployeeRepo,
+			repos.DepartmentRepo,
+			employeeService,
+			organizationService,
+			repos.Transactor,
+			notificationService,
+			ratelimit.SystemClock,
+			cfg.Registration.Mode,
+			cfg.Registration.Domains,
+			cfg.Registration.InvitationTTL,
+		),
+		OrganizationService: organizationService,
+	}, nil
+}
+
+func initializeHandler(services *Services, bus *events.Bus) *handlers.Handler {
+	return handlers.NewHandler(
+		services.DepartmentService,
+		services.EmployeeService,
+		services.AssetService,
+		services.LocationService,
+		services.TransferService,
+		services.AuthService,
+		services.ReportService,
+		services.CategoryService,
+		services.ReservationService,
+		services.WebhookService,
+		services.NotificationService,
+		services.PasswordResetService,
+		services.TwoFactorService,
+		services.OIDCService,
+		services.APITokenService,
+		services.RegistrationService,
+		services.OrganizationService,
+		bus,
+	)
+}
+
+// newOIDCService создает сервис единого входа или возвращает nil, если
+// провайдер не настроен
+func newOIDCService(cfg *config.Config, repos *Repositories) *services.OIDCService {
+	if cfg.OIDC.Issuer == "" {
+		return nil
+	}
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.OIDC.Issuer,
+		ClientID:     cfg.OIDC.ClientID,
+		ClientSecret: cfg.OIDC.ClientSecret,
+		RedirectURL:  cfg.OIDC.RedirectURL,
+		Scopes:       cfg.OIDC.Scopes,
+	})
+	return services.NewOIDCService(provider, repos.EmployeeRepo, cfg.Auth.SecretKey, ratelimit.SystemClock)
+}
+
+// newAuthProviders создает провайдеры проверки пароля в порядке из
+// AUTH_PROVIDERS
+func newAuthProviders(cfg *config.Config, repos *Repositories, employeeService *services.EmployeeService) ([]services.AuthProvider, error) {
+	var providers []services.AuthProvider
+	for _, name := range cfg.Auth.Providers {
+		switch name {
+		case "password":
+			providers = append(providers, services.NewPasswordProvider(repos.EmployeeRepo))
+		case services.AuthSourceLDAP:
+			providers = append(providers, services.NewLDAPProvider(
+				services.LDAPConfig{
+					Conn: ldap.Config{
+						URL:      cfg.LDAP.URL,
+						StartTLS: cfg.LDAP.StartTLS,
+						Timeout:  cfg.LDAP.Timeout,
+					},
+					BindDN:       cfg.LDAP.BindDN,
+					BindPassword: cfg.LDAP.BindPassword,
+					BaseDN:       cfg.LDAP.BaseDN,
+					UserFilter:   cfg.LDAP.UserFilter,
+					Attributes: services.LDAPAttributes{
+						ID:         cfg.LDAP.AttrID,
+						Email:      cfg.LDAP.AttrEmail,
+						FullName:   cfg.LDAP.AttrFullName,
+						Position:   cfg.LDAP.AttrPosition,
+						Department: cfg.LDAP.AttrDepartment,
+						Groups:     cfg.LDAP.AttrGroups,
+					},
+					RoleGroups:    cfg.LDAP.RoleGroups,
+					DepartmentMap: cfg.LDAP.DepartmentMap,
+				},
+				employeeService,
+				repos.DepartmentRepo,
+			))
+		default:
+			return nil, fmt.Errorf("unknown auth provider %q", name)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no auth providers configured")
+	}
+	return providers, nil
+}
+
+// newMailer создает способ отправки писем по MAIL_DRIVER
+func newMailer(cfg *config.Config) (mail.Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return mail.NewSMTPMailer(
+			cfg.Mail.SMTPHost,
+			cfg.Mail.SMTPPort,
+			cfg.Mail.SMTPUsername,
+			cfg.Mail.SMTPPassword,
+			cfg.Mail.From,
+		), nil
+	case "file":
+		return mail.NewFileMailer(cfg.Mail.Dir, cfg.Mail.From)
+	case "log":
+		return mail.NewLogMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
 	}
 }
 
@@ -212,6 +582,24 @@ func serveRegister(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
+func serveResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Обрабатываем как /reset-password, так и /reset-password.html
+	if r.URL.Path == "/reset-password" || r.URL.Path == "/reset-password.html" {
+		http.ServeFile(w, r, "./frontend/static/reset-password.html")
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func serveTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Обрабатываем как /two-factor, так и /two-factor.html
+	if r.URL.Path == "/two-factor" || r.URL.Path == "/two-factor.html" {
+		http.ServeFile(w, r, "./frontend/static/two-factor.html")
+		return
+	}
+	http.NotFound(w, r)
+}
+
 func serveAssets(w http.ResponseWriter, r *http.Request) {
 	// Обрабатываем как /assets, так и /assets.html
 	if r.URL.Path == "/assets" || r.URL.Path == "/assets.html" {
@@ -1,8 +1,10 @@
 const API_BASE_URL = window.location.origin;
+const API_PREFIX = '/api/v1';
 
-async function makeRequest(url, method = 'GET', body = null) {
+async function makeRequest(url, method = 'GET', body = null, extraHeaders = {}) {
     const headers = {
         'Content-Type': 'application/json',
+        ...extraHeaders,
     };
 
     // Получаем токен из localStorage
@@ -20,11 +22,27 @@ async function makeRequest(url, method = 'GET', body = null) {
         options.body = JSON.stringify(body);
     }
 
-    const response = await fetch(`${API_BASE_URL}/api${url}`, options); // Добавлен /api
+    const response = await fetch(`${API_BASE_URL}${API_PREFIX}${url}`, options);
 
+    // Запись успели изменить: сервер возвращает ее актуальное состояние
+    if (response.status === 412) {
+        const error = new Error('Запись была изменена другим пользователем');
+        error.current = await response.json();
+        throw error;
+    }
+
+    // Ошибки приходят в формате application/problem+json (RFC 7807)
     if (!response.ok) {
-        const errorData = await response.json();
-        throw new Error(errorData.error || 'Ошибка запроса');
+        const problem = await response.json();
+        const details = Object.entries(problem.errors || {}).map(([field, message]) => `${field}: ${message}`);
+        const error = new Error([problem.detail || 'Ошибка запроса', ...details].join('\n'));
+        error.status = problem.status;
+        error.fields = problem.errors || {};
+        throw error;
+    }
+
+    if (response.status === 204) {
+        return null;
     }
 
     return response.json();
@@ -32,11 +50,11 @@ async function makeRequest(url, method = 'GET', body = null) {
 
 // Auth
 async function login(email, password) {
-    return makeRequest('/api/auth/login', 'POST', { email, password });
+    return makeRequest('/auth/login', 'POST', { email, password });
 }
 
 async function register(employee) {
-    return makeRequest('/api/auth/register', 'POST', employee);
+    return makeRequest('/auth/register', 'POST', employee);
 }
 
 
@@ -53,8 +71,9 @@ async function createAsset(asset) {
     return makeRequest('/assets', 'POST', asset);
 }
 
-async function updateAsset(id, asset) {
-    return makeRequest(`/assets/${id}`, 'PUT', asset);
+// version - значение поля version, полученное вместе с записью
+async function updateAsset(id, asset, version) {
+    return makeRequest(`/assets/${id}`, 'PUT', asset, { 'If-Match': `"${version}"` });
 }
 
 async function deleteAsset(id) {
@@ -78,8 +97,8 @@ async function createEmployee(employee) {
     return makeRequest('/employees', 'POST', employee);
 }
 
-async function updateEmployee(id, employee) {
-    return makeRequest(`/employees/${id}`, 'PUT', employee);
+async function updateEmployee(id, employee, version) {
+    return makeRequest(`/employees/${id}`, 'PUT', employee, { 'If-Match': `"${version}"` });
 }
 
 async function deleteEmployee(id) {
@@ -99,8 +118,8 @@ async function createDepartment(department) {
     return makeRequest('/departments', 'POST', department);
 }
 
-async function updateDepartment(id, department) {
-    return makeRequest(`/departments/${id}`, 'PUT', department);
+async function updateDepartment(id, department, version) {
+    return makeRequest(`/departments/${id}`, 'PUT', department, { 'If-Match': `"${version}"` });
 }
 
 async function deleteDepartment(id) {
@@ -149,4 +168,117 @@ async function getAllStatuses() {
 
 async function getAllLocations() {
     return makeRequest('/locations');
-}
\ No newline at end of file
+}
+
+// Входящие уведомления
+async function getNotifications(limit = 10, offset = 0, unreadOnly = false) {
+    const unread = unreadOnly ? '&unread=true' : '';
+    return makeRequest(`/me/notifications?limit=${limit}&offset=${offset}${unread}`);
+}
+
+async function markNotificationRead(id) {
+    return makeRequest(`/me/notifications/${id}/read`, 'POST');
+}
+
+async function markAllNotificationsRead() {
+    return makeRequest('/me/notifications/read-all', 'POST');
+}
+// Поток изменений (Server-Sent Events). EventSource не умеет передавать
+// заголовок Authorization, поэтому поток читается через fetch.
+// onEvent получает имя события (например, transfer.created) и его данные;
+// событие reset означает, что часть изменений пропущена и данные нужно
+// перечитать целиком. Возвращает функцию, закрывающую поток
+function subscribeToEvents(entities, onEvent) {
+    const controller = new AbortController();
+    let lastEventId = null;
+    let retryDelay = 3000;
+
+    async function connect() {
+        const headers = {};
+        const token = localStorage.getItem('token');
+        if (token) {
+            headers['Authorization'] = `Bearer ${token}`;
+        }
+        if (lastEventId) {
+            headers['Last-Event-ID'] = lastEventId;
+        }
+
+        const query = entities.length ? `?entity=${entities.join(',')}` : '';
+        const response = await fetch(`${API_BASE_URL}${API_PREFIX}/events${query}`, {
+            headers,
+            signal: controller.signal,
+        });
+        if (!response.ok) {
+            throw new Error(`Поток событий недоступен: ${response.status}`);
+        }
+
+        const reader = response.body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = '';
+
+        while (true) {
+            const { value, done } = await reader.read();
+            if (done) {
+                return;
+            }
+            buffer += decoder.decode(value, { stream: true });
+
+            // События разделяются пустой строкой
+            let boundary;
+            while ((boundary = buffer.indexOf('\n\n')) !== -1) {
+                const block = buffer.slice(0, boundary);
+                buffer = buffer.slice(boundary + 2);
+
+                let name = 'message';
+                let data = '';
+                for (const line of block.split('\n')) {
+                    if (line.startsWith(':')) {
+                        continue;
+                    }
+                    const colon = line.indexOf(':');
+                    const field = colon === -1 ? line : line.slice(0, colon);
+                    const fieldValue = colon === -1 ? '' : line.slice(colon + 1).replace(/^ /, '');
+                    if (field === 'id') {
+                        lastEventId = fieldValue;
+                    } else if (field === 'event') {
+                        name = fieldValue;
+                    } else if (field === 'data') {
+                        data += fieldValue;
+                    } else if (field === 'retry') {
+                        retryDelay = parseInt(fieldValue, 10) || retryDelay;
+                    }
+                }
+
+                if (data && name !== 'ready') {
+                    onEvent(name, JSON.parse(data));
+                }
+            }
+        }
+    }
+
+    (async function run() {
+        while (!controller.signal.aborted) {
+            try {
+                await connect();
+            } catch (error) {
+                if (controller.signal.aborted) {
+                    return;
+                }
+                console.warn('Поток событий прерван:', error);
+            }
+            await new Promise(resolve => setTimeout(resolve, retryDelay));
+        }
+    })();
+
+    return () => controller.abort();
+}
+
+// liveReload вызывает reload при изменении любой из сущностей entities.
+// Серия событий (например, перемещение комплекта) приводит к одному вызову
+function liveReload(entities, reload) {
+    let timer = null;
+    return subscribeToEvents(entities, () => {
+        clearTimeout(timer);
+        timer = setTimeout(reload, 300);
+    });
+}
@@ -9,6 +9,7 @@ document.addEventListener('DOMContentLoaded', function() {
 
     checkAuth();
     initNavigation();
+    initNotificationBell();
     // Удаляем дублирующиеся обработчики
     document.querySelectorAll('script[src*="auth.js"]').forEach(script => {
         if (script.getAttribute('data-processed')) {
@@ -71,4 +72,86 @@ function checkAuth() {
 function logout() {
     localStorage.removeItem('token');
     window.location.href = '/login';
-}
\ No newline at end of file
+}
+
+// Колокольчик с входящими уведомлениями. Число непрочитанных
+// обновляется раз в минуту и при возвращении на вкладку
+function initNotificationBell() {
+    const logoutBtn = document.getElementById('logout');
+    if (!logoutBtn || !localStorage.getItem('token') || typeof getNotifications !== 'function') {
+        return;
+    }
+
+    const bell = document.createElement('div');
+    bell.className = 'notification-bell';
+    bell.innerHTML = `
+        <button type="button" class="bell-button" title="Уведомления">&#128276;<span class="bell-count" hidden></span></button>
+        <div class="bell-panel" hidden>
+            <div class="bell-header">
+                <strong>Уведомления</strong>
+                <a href="#" class="bell-read-all">Прочитать все</a>
+            </div>
+            <ul class="bell-list"></ul>
+        </div>`;
+    logoutBtn.parentNode.insertBefore(bell, logoutBtn);
+
+    const button = bell.querySelector('.bell-button');
+    const count = bell.querySelector('.bell-count');
+    const panel = bell.querySelector('.bell-panel');
+    const list = bell.querySelector('.bell-list');
+
+    async function refresh() {
+        try {
+            const page = await getNotifications(10);
+            count.textContent = page.unread > 99 ? '99+' : page.unread;
+            count.hidden = page.unread === 0;
+
+            list.innerHTML = '';
+            if (page.items.length === 0) {
+                list.innerHTML = '<li class="bell-empty">Уведомлений нет</li>';
+            }
+            page.items.forEach(item => {
+                const li = document.createElement('li');
+                li.className = item.read_at ? 'bell-item' : 'bell-item unread';
+                li.innerHTML = '<div class="bell-title"></div><div class="bell-time"></div>';
+                li.querySelector('.bell-title').textContent = item.title;
+                li.querySelector('.bell-time').textContent = new Date(item.created_at).toLocaleString('ru-RU');
+                li.addEventListener('click', async () => {
+                    if (!item.read_at) {
+                        await markNotificationRead(item.id);
+                    }
+                    if (item.link) {
+                        window.location.href = item.link;
+                    } else {
+                        refresh();
+                    }
+                });
+                list.appendChild(li);
+            });
+        } catch (error) {
+            console.error('Ошибка загрузки уведомлений:', error);
+        }
+    }
+
+    button.addEventListener('click', () => {
+        panel.hidden = !panel.hidden;
+    });
+    document.addEventListener('click', e => {
+        if (!bell.contains(e.target)) {
+            panel.hidden = true;
+        }
+    });
+    bell.querySelector('.bell-read-all').addEventListener('click', async e => {
+        e.preventDefault();
+        await markAllNotificationsRead();
+        refresh();
+    });
+    document.addEventListener('visibilitychange', () => {
+        if (!document.hidden) {
+            refresh();
+        }
+    });
+
+    refresh();
+    setInterval(refresh, 60000);
+}
@@ -2,6 +2,9 @@ document.addEventListener('DOMContentLoaded', async function() {
     // Загрузка данных при открытии страницы
     await loadAssets();
 
+    // Обновление таблицы при изменениях, сделанных другими пользователями
+    liveReload(['asset', 'location', 'department', 'category'], loadAssets);
+
     // Инициализация модального окна
     initAssetModal();
 
@@ -103,10 +106,12 @@ async function openAssetModal(assetId = null) {
         document.getElementById('asset-id').value = assetId;
 
         const asset = await getAssetById(assetId);
+        form.dataset.version = asset.version;
         document.getElementById('asset-name').value = asset.name;
         document.getElementById('asset-category').value = asset.category;
         document.getElementById('asset-date').value = asset.acquisition_date;
         document.getElementById('asset-cost').value = asset.cost;
+        document.getElementById('asset-warranty').value = asset.warranty_until || '';
         document.getElementById('asset-status').value = asset.status_id;
         document.getElementById('asset-location').value = asset.current_location_id;
         document.getElementById('asset-department').value = asset.department_id || '';
@@ -171,15 +176,16 @@ async function saveAsset() {
         category: document.getElementById('asset-category').value,
         acquisition_date: document.getElementById('asset-date').value,
         cost: parseFloat(document.getElementById('asset-cost').value),
+        warranty_until: document.getElementById('asset-warranty').value || null,
         status_id: parseInt(document.getElementById('asset-status').value),
         current_location_id: parseInt(document.getElementById('asset-location').value),
-        department_id: document.getElementById('asset-department').value || null
+        department_id: parseInt(document.getElementById('asset-department').value) || null
     };
 
     try {
         if (assetId) {
             // Обновление существующего актива
-            await updateAsset(assetId, asset);
+            await updateAsset(assetId, asset, document.getElementById('asset-form').dataset.version);
         } else {
             // Создание нового актива
             await createAsset(asset);
@@ -190,6 +196,12 @@ async function saveAsset() {
     } catch (error) {
         console.error('Ошибка сохранения актива:', error);
 
+        if (error.current) {
+            alert(`${error.message}. Форма обновлена актуальными данными.`);
+            await openAssetModal(assetId);
+            return;
+        }
+
         alert('Не удалось сохранить актив');
     }
 }
@@ -7,7 +7,7 @@ class Auth {
 
     async login(email, password) {
         try {
-            const response = await fetch('/api/auth/login', {
+            const response = await fetch('/api/v1/auth/login', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -18,22 +18,76 @@ class Auth {
             const data = await response.json();
 
             if (!response.ok) {
-                throw new Error(data.error || 'Login failed');
+                throw new Error(data.detail || 'Login failed');
             }
 
-            if (data.token) {
-                this.token = data.token;
-                this.user = data.employee;
-                localStorage.setItem('token', data.token);
-                return true;
+            // Нужен код двухфакторной аутентификации: токен второго шага
+            // передается в verifyTwoFactor
+            if (data.two_factor_required) {
+                this.challengeToken = data.challenge_token;
+                return 'two_factor';
             }
-            return false;
+
+            return this.acceptToken(data);
         } catch (error) {
             console.error('Login error:', error);
             throw error;
         }
     }
 
+    // completeSSO обменивает билет единого входа, полученный после возврата
+    // с провайдера, на токен доступа
+    async completeSSO(ticket) {
+        const response = await fetch('/api/v1/auth/sso/complete', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ ticket }),
+        });
+
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.detail || 'Single sign-on failed');
+        }
+
+        if (data.two_factor_required) {
+            this.challengeToken = data.challenge_token;
+            return 'two_factor';
+        }
+
+        return this.acceptToken(data);
+    }
+
+    async verifyTwoFactor(code) {
+        const response = await fetch('/api/v1/auth/login/two-factor', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ challenge_token: this.challengeToken, code }),
+        });
+
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.detail || 'Invalid code');
+        }
+
+        this.challengeToken = null;
+        return this.acceptToken(data);
+    }
+
+    acceptToken(data) {
+        if (!data.token) {
+            return false;
+        }
+        this.token = data.token;
+        this.user = data.employee;
+        this.twoFactorSetupRequired = data.two_factor_setup_required;
+        localStorage.setItem('token', data.token);
+        return true;
+    }
+
     logout() {
         this.token = null;
         this.user = null;
@@ -0,0 +1,33 @@
+document.addEventListener('DOMContentLoaded', async function() {
+    await loadDashboard();
+
+    // Сводка обновляется при изменениях, сделанных другими пользователями
+    liveReload(['asset', 'employee', 'transfer'], loadDashboard);
+});
+
+// Загрузка сводки для главной страницы
+async function loadDashboard() {
+    try {
+        const [assets, employees, transfers] = await Promise.all([
+            getAllAssets(),
+            getAllEmployees(),
+            getRecentTransfers()
+        ]);
+
+        document.getElementById('total-assets').textContent = assets.length;
+        document.getElementById('total-employees').textContent = employees.length;
+
+        const list = document.getElementById('recent-transfers');
+        list.innerHTML = '';
+        transfers.slice(0, 5).forEach(transfer => {
+            const item = document.createElement('li');
+            item.textContent = `${transfer.asset_name}: ${transfer.from_location} → ${transfer.to_location}`;
+            list.appendChild(item);
+        });
+        if (transfers.length === 0) {
+            list.innerHTML = '<li>Перемещений пока нет</li>';
+        }
+    } catch (error) {
+        console.error('Ошибка загрузки сводки:', error);
+    }
+}
@@ -1,5 +1,6 @@
 document.addEventListener('DOMContentLoaded', async function() {
     await loadDepartments();
+    liveReload(['department', 'employee'], loadDepartments);
     initDepartmentModal();
 
     document.getElementById('add-department-btn').addEventListener('click', function() {
@@ -100,6 +101,7 @@ async function openDepartmentModal(departmentId = null) {
         document.getElementById('department-id').value = departmentId;
 
         const department = await getDepartmentById(departmentId);
+        form.dataset.version = department.version;
         document.getElementById('department-name').value = department.name;
         document.getElementById('department-location').value = department.location;
         document.getElementById('department-head').value = department.head_id || '';
@@ -145,12 +147,12 @@ async function saveDepartment() {
     const department = {
         name: document.getElementById('department-name').value,
         location: document.getElementById('department-location').value,
-        head_id: document.getElementById('department-head').value || null
+        head_id: parseInt(document.getElementById('department-head').value) || null
     };
 
     try {
         if (departmentId) {
-            await updateDepartment(departmentId, department);
+            await updateDepartment(departmentId, department, document.getElementById('department-form').dataset.version);
         } else {
             await createDepartment(department);
         }
@@ -159,6 +161,11 @@ async function saveDepartment() {
         await loadDepartments();
     } catch (error) {
         console.error('Ошибка сохранения отдела:', error);
+        if (error.current) {
+            alert(`${error.message}. Форма обновлена актуальными данными.`);
+            await openDepartmentModal(departmentId);
+            return;
+        }
         alert('Не удалось сохранить отдел');
     }
 }
\ No newline at end of file
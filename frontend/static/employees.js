@@ -1,5 +1,6 @@
 document.addEventListener('DOMContentLoaded', async function() {
     await loadEmployees();
+    liveReload(['employee', 'department'], loadEmployees);
     initEmployeeModal();
 
     document.getElementById('add-employee-btn').addEventListener('click', function() {
@@ -91,6 +92,7 @@ async function openEmployeeModal(employeeId = null) {
         document.getElementById('employee-id').value = employeeId;
 
         const employee = await getEmployeeById(employeeId);
+        form.dataset.version = employee.version;
         document.getElementById('employee-fullname').value = employee.full_name;
         document.getElementById('employee-position').value = employee.position;
         document.getElementById('employee-email').value = employee.email;
@@ -141,16 +143,16 @@ async function saveEmployee() {
         position: document.getElementById('employee-position').value,
         email: document.getElementById('employee-email').value,
         role: document.getElementById('employee-role').value,
-        department_id: document.getElementById('employee-department').value || null
+        department_id: parseInt(document.getElementById('employee-department').value) || null
     };
 
-    if (password) {
-        employee.password_hash = password;
+    if (password && !employeeId) {
+        employee.password = password;
     }
 
     try {
         if (employeeId) {
-            await updateEmployee(employeeId, employee);
+            await updateEmployee(employeeId, employee, document.getElementById('employee-form').dataset.version);
         } else {
             if (!password) {
                 throw new Error('Для нового сотрудника необходимо указать пароль');
@@ -162,6 +164,11 @@ async function saveEmployee() {
         await loadEmployees();
     } catch (error) {
         console.error('Ошибка сохранения сотрудника:', error);
+        if (error.current) {
+            alert(`${error.message}. Форма обновлена актуальными данными.`);
+            await openEmployeeModal(employeeId);
+            return;
+        }
         alert(error.message || 'Не удалось сохранить сотрудника');
     }
 }
\ No newline at end of file
@@ -1,5 +1,6 @@
 document.addEventListener('DOMContentLoaded', async function() {
     await loadTransfers();
+    liveReload(['transfer'], loadTransfers);
     initTransferModal();
     await loadFilters();
 
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/handlers"
+	"inventory-system/internal/mail"
+	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/repository/repotest"
+	"inventory-system/internal/services"
+	"inventory-system/internal/signing"
+)
+
+// testAPI — маршруты с настоящими обработчиками, сервисами и
+// репозиториями поверх базы repotest. Сотрудника, которому выдан токен
+// запросов, testAPI находит сам, остальные запросы к базе получает
+// обработчик теста
+type testAPI struct {
+	t        *testing.T
+	router   http.Handler
+	auth     *services.AuthService
+	employee models.Employee
+	token    string
+}
+
+// testEmployee — администратор организации 1, от имени которого
+// выполняются запросы testAPI
+var testEmployee = models.Employee{
+	ID:             1,
+	FullName:       "Иван Петров",
+	Position:       "Администратор",
+	Email:          "admin@example.com",
+	Role:           "admin",
+	Version:        1,
+	OrganizationID: 1,
+}
+
+// newTestKeyRing возвращает набор с одним действующим ключом подписи
+func newTestKeyRing(t *testing.T) *signing.KeyRing {
+	t.Helper()
+	key, err := signing.Generate(signing.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := signing.NewKeyRing()
+	keys.Replace([]*signing.Key{key})
+	return keys
+}
+
+func newTestAPI(t *testing.T, handle repotest.Handler) *testAPI {
+	t.Helper()
+
+	a := &testAPI{t: t, employee: testEmployee}
+	db := repotest.Open(t, func(query string, args []driver.Value) (repotest.Result, error) {
+		if strings.Contains(query, "FROM employees e") && strings.Contains(query, "WHERE e.id = $1") &&
+			args[0] == int64(a.employee.ID) {
+			return a.employeeRow(), nil
+		}
+		if handle == nil {
+			t.Errorf("unexpected query %q", query)
+			return repotest.Result{}, nil
+		}
+		return handle(query, args)
+	})
+
+	templates, err := mail.LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := ratelimit.SystemClock
+
+	employeeRepo := repository.NewEmployeeRepository(db)
+	departmentRepo := repository.NewDepartmentRepository(db)
+	assetRepo := repository.NewAssetRepository(db)
+	statusRepo := repository.NewStatusRepository(db)
+	locationRepo := repository.NewLocationRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	transactor := repository.NewTransactor(db)
+
+	notifications := services.NewNotificationService(
+		repository.NewNotificationRepository(db),
+		repository.NewMailQueueRepository(db),
+		employeeRepo,
+		assetRepo,
+		transferRepo,
+		transactor,
+		templates,
+		"http://localhost:8080",
+		30,
+		time.Hour,
+	)
+	organizations := services.NewOrganizationService(repository.NewOrganizationRepository(db), "default")
+	employees := services.NewEmployeeService(employeeRepo, departmentRepo, apiTokenRepo, organizations, transactor, nil)
+	twoFactor := services.NewTwoFactorService(repository.NewTwoFactorRepository(db), employeeRepo, transactor, "test", nil, clock)
+	a.auth = services.NewAuthService(
+		employeeRepo,
+		repository.NewLoginAuditRepository(db),
+		twoFactor,
+		[]services.AuthProvider{services.NewPasswordProvider(employeeRepo)},
+		ratelimit.NewMemoryStore(),
+		services.LoginPolicy{
+			IPLimit:      ratelimit.Limit{Burst: 10, Interval: time.Minute},
+			AccountLimit: ratelimit.Limit{Burst: 10, Interval: time.Minute},
+		},
+		clock,
+		newTestKeyRing(t),
+		"test-secret",
+		time.Hour,
+	)
+	assets := services.NewAssetService(assetRepo, statusRepo, locationRepo, departmentRepo, categoryRepo, outboxRepo, transactor, notifications, nil)
+	transfers := services.NewTransferService(transferRepo, assetRepo, employeeRepo, locationRepo, outboxRepo, transactor, notifications, nil)
+	categories := services.NewCategoryService(categoryRepo, nil)
+
+	h := handlers.NewHandler(
+		services.NewDepartmentService(departmentRepo, employeeRepo, transactor, notifications, nil),
+		employees,
+		assets,
+		services.NewLocationService(locationRepo, nil),
+		transfers,
+		a.auth,
+		services.NewReportService(assets, transfers, categories, organizations),
+		categories,
+		services.NewReservationService(repository.NewReservationRepository(db), assetRepo, employeeRepo, categoryRepo, locationRepo, transactor, notifications, nil),
+		services.NewWebhookService(repository.NewWebhookRepository(db)),
+		notifications,
+		services.NewPasswordResetService(repository.NewPasswordResetRepository(db), employeeRepo, employees, transactor, notifications, time.Hour),
+		twoFactor,
+		nil,
+		services.NewAPITokenService(apiTokenRepo, employeeRepo, clock, 30*24*time.Hour, 365*24*time.Hour),
+		services.NewRegistrationService(
+			repository.NewInvitationRepository(db),
+			employeeRepo,
+			departmentRepo,
+			employees,
+			organizations,
+			transactor,
+			notifications,
+			clock,
+			models.RegistrationInvite,
+			nil,
+			7*24*time.Hour,
+		),
+		organizations,
+		nil,
+	)
+	a.router = NewRouter(h, Deprecation{})
+
+	token, _, err := a.auth.GenerateToken(&a.employee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.token = token
+	return a
+}
+
+// employeeRow — строка EmployeeRepository.GetByID для сотрудника запросов
+func (a *testAPI) employeeRow() repotest.Result {
+	e := a.employee
+	return repotest.Result{
+		Columns: make([]string, 14),
+		Rows: [][]driver.Value{{
+			int64(e.ID), e.FullName, e.Position, e.Email, e.Role,
+			nil, nil, int64(e.Version),
+			nil, nil, e.TwoFactorEnabled,
+			e.AuthSource, e.ExternalID, int64(e.OrganizationID),
+		}},
+	}
+}
+
+// do выполняет запрос с токеном сотрудника. Тело отправляется как JSON,
+// если header не задает другой Content-Type
+func (a *testAPI) do(method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	req := httptest.NewRequest(method, currentPrefix+target, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/repository/repotest"
+	"inventory-system/internal/services"
+)
+
+// readLocationsToken — персональный токен с единственной областью
+// locations:read
+const readLocationsToken = services.APITokenPrefix + "cmVhZC1sb2NhdGlvbnMtdGVzdC10b2tlbi0wMDAwMDA"
+
+// patTable отвечает на поиск персонального токена: в базе есть только
+// readLocationsToken. Остальные запросы получает locationTable
+func patTable(query string, args []driver.Value) (repotest.Result, error) {
+	if !strings.Contains(query, "FROM api_tokens WHERE token_hash") {
+		return locationTable(query, args)
+	}
+	result := repotest.Result{Columns: make([]string, 10)}
+	sum := sha256.Sum256([]byte(readLocationsToken))
+	if args[0] != hex.EncodeToString(sum[:]) {
+		return result, nil
+	}
+	// Токен использован только что, поэтому время использования не обновляется
+	now := time.Now().UTC()
+	result.Rows = [][]driver.Value{{
+		int64(7), int64(testEmployee.ID), "Инвентаризация", readLocationsToken[:16], []byte("{locations:read}"),
+		now.Add(time.Hour), now, nil, nil, now.Add(-time.Hour),
+	}}
+	return result, nil
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	api := newTestAPI(t, patTable)
+	bearer := http.Header{"Authorization": {"Bearer " + readLocationsToken}}
+
+	tests := []struct {
+		name           string
+		method, target string
+		body           string
+		header         http.Header
+		status         int
+	}{
+		{"read within scope", http.MethodGet, "/locations/1", "", bearer, http.StatusOK},
+		{"write to a read-only resource", http.MethodPost, "/locations", `{"address":"Склад","type":"Склад"}`, bearer, http.StatusForbidden},
+		{"delete from a read-only resource", http.MethodDelete, "/locations/1", "", bearer, http.StatusForbidden},
+		{"read of another resource", http.MethodGet, "/assets", "", bearer, http.StatusForbidden},
+		{"token management", http.MethodGet, "/me/api-tokens", "", bearer, http.StatusForbidden},
+		{"unknown token", http.MethodGet, "/locations/1", "",
+			http.Header{"Authorization": {"Bearer " + services.APITokenPrefix + "unknown"}}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		rec := api.do(tt.method, tt.target, tt.body, tt.header)
+		if rec.Code != tt.status {
+			t.Errorf("%s: %s %s = %d, want %d\n%s", tt.name, tt.method, tt.target, rec.Code, tt.status, rec.Body.String())
+			continue
+		}
+		if tt.status != http.StatusForbidden {
+			continue
+		}
+		var problem struct {
+			Detail string `json:"detail"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if problem.Detail != services.ErrInsufficientScope.Error() {
+			t.Errorf("%s: detail = %q, want %q", tt.name, problem.Detail, services.ErrInsufficientScope.Error())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/handlers"
+	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/repository/repotest"
+	"inventory-system/internal/services"
+)
+
+// directoryProvider — провайдер входа с одной учетной записью каталога,
+// логин которой не является адресом
+type directoryProvider struct {
+	login, password string
+	employee        models.Employee
+	logins          []string
+}
+
+func (p *directoryProvider) Name() string { return services.AuthSourceLDAP }
+
+func (p *directoryProvider) Verify(ctx context.Context, login, password string) (*models.Employee, error) {
+	p.logins = append(p.logins, login)
+	if login != p.login || password != p.password {
+		return nil, services.ErrInvalidCredentials
+	}
+	employee := p.employee
+	return &employee, nil
+}
+
+func TestLoginAcceptsDirectoryLogin(t *testing.T) {
+	db := repotest.Open(t, func(query string, args []driver.Value) (repotest.Result, error) {
+		switch {
+		case strings.Contains(query, "WHERE email = $1"):
+			// В базе нет сотрудника с таким адресом
+			return repotest.Result{Columns: []string{"id"}}, nil
+		case strings.Contains(query, "SET failed_logins = 0"):
+			return repotest.Result{Affected: 1}, nil
+		}
+		t.Errorf("unexpected query %q", query)
+		return repotest.Result{}, nil
+	})
+
+	provider := &directoryProvider{
+		login:    "alice",
+		password: "directory-secret",
+		employee: models.Employee{ID: 7, FullName: "Alice", Email: "alice@example.com", Role: "employee", OrganizationID: 1},
+	}
+	clock := ratelimit.SystemClock
+	auth := services.NewAuthService(
+		repository.NewEmployeeRepository(db),
+		repository.NewLoginAuditRepository(db),
+		nil,
+		[]services.AuthProvider{provider},
+		ratelimit.NewMemoryStore(),
+		services.LoginPolicy{IPLimit: ratelimit.Limit{Burst: 10, Interval: time.Minute}, AccountLimit: ratelimit.Limit{Burst: 10, Interval: time.Minute}},
+		clock,
+		newTestKeyRing(t),
+		"test-secret",
+		time.Hour,
+	)
+	twoFactor := services.NewTwoFactorService(nil, nil, nil, "test", nil, clock)
+	h := handlers.NewHandler(nil, nil, nil, nil, nil, auth, nil, nil, nil, nil, nil, nil, twoFactor, nil, nil, nil, nil, nil)
+	r := NewRouter(h, Deprecation{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"alice","password":"directory-secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /auth/login = %d, want %d\n%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var response struct {
+		Token    string          `json:"token"`
+		Employee models.Employee `json:"employee"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Token == "" || response.Employee.ID != 7 {
+		t.Errorf("response = %s", rec.Body.String())
+	}
+	if len(provider.logins) != 1 || provider.logins[0] != "alice" {
+		t.Errorf("provider got logins %q, want [alice]", provider.logins)
+	}
+}
@@ -0,0 +1,522 @@
+package app
+
+import (
+	"inventory-system/internal/mergepatch"
+	"inventory-system/internal/models"
+	"inventory-system/internal/openapi"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// createdResponse — ответ на создание записи
+type createdResponse struct {
+	ID int `json:"id"`
+}
+
+// tokenResponse — ответ на успешный вход
+type tokenResponse struct {
+	Token     string          `json:"token"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Employee  models.Employee `json:"employee"`
+	// TwoFactorSetupRequired — роль требует двухфакторной аутентификации,
+	// а она не настроена: до настройки доступны только /me/two-factor
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required"`
+}
+
+// challengeResponse — ответ на вход с паролем, когда нужен еще код
+type challengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// renewedTokenResponse — новый токен взамен отозванных сменой пароля
+type renewedTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ssoStatusResponse — настроен ли единый вход
+type ssoStatusResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// messageResponse — ответ с текстовым сообщением
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// NewSpec описывает маршруты API версии 1 в формате OpenAPI 3. Каждый
+// маршрут, зарегистрированный в apiRoutes, должен быть описан здесь:
+// расхождения выводятся в лог при запуске
+func NewSpec() *openapi.Document {
+	d := openapi.New("Inventory System API", "1.0.0")
+	d.Info.Description = "API учета активов: активы, сотрудники, отделы, местоположения, перемещения и бронирования. " +
+		"Каждый сотрудник видит только данные своей организации; записи других организаций отвечают 404. " +
+		"Ошибки возвращаются в формате application/problem+json (RFC 7807). " +
+		"Пути без версии (/api/...) устарели и обслуживаются как синонимы /api/v1."
+	d.Servers = []openapi.Server{{URL: currentPrefix, Description: "Версия 1"}}
+	d.Components.SecuritySchemes["bearerAuth"] = openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description: "Токен входа (JWT) или персональный токен inv_pat_... из /me/api-tokens. " +
+			"Персональному токену доступны только ресурсы из его областей: read — чтение всего, " +
+			"<ресурс>:read — чтение ресурса, <ресурс>:write — чтение и изменение; иначе ответ 403.",
+	}
+	d.Tags = []openapi.Tag{
+		{Name: "auth", Description: "Вход и регистрация"},
+		{Name: "assets", Description: "Активы и комплекты"},
+		{Name: "categories", Description: "Категории и дополнительные поля"},
+		{Name: "employees", Description: "Сотрудники"},
+		{Name: "departments", Description: "Отделы"},
+		{Name: "locations", Description: "Местоположения"},
+		{Name: "transfers", Description: "Перемещения активов"},
+		{Name: "reservations", Description: "Бронирования"},
+		{Name: "reports", Description: "Отчеты"},
+		{Name: "events", Description: "Поток изменений в реальном времени"},
+		{Name: "invitations", Description: "Приглашения к регистрации (только для администраторов)"},
+		{Name: "webhooks", Description: "Подписки внешних систем на события (только для администраторов)"},
+		{Name: "organizations", Description: "Организации группы и сводные отчеты (только для суперадминистраторов)"},
+		{Name: "me", Description: "Профиль текущего сотрудника"},
+		{Name: "meta", Description: "Описание API"},
+	}
+
+	// Auth
+	d.Op("POST", "/auth/login", "auth", "Вход по email и паролю").Public().
+		Describe("Поле email принимает и логин внешнего каталога (LDAP), если он не совпадает с адресом. "+
+			"Частота попыток ограничена для IP-адреса и для email. После нескольких неудачных попыток "+
+			"подряд вход в учетную запись блокируется; каждая следующая неудача удваивает срок блокировки. "+
+			"Снять блокировку может администратор или сам сотрудник, сбросив пароль. "+
+			"Если у сотрудника включена двухфакторная аутентификация, вместо токена доступа возвращается "+
+			"токен второго шага для /auth/login/two-factor.").
+		Body(models.LoginRequest{}).
+		Returns(http.StatusOK, "Токен доступа", tokenResponse{}).
+		Returns(http.StatusAccepted, "Нужен код двухфакторной аутентификации", challengeResponse{}).
+		ReturnsAs(http.StatusTooManyRequests, "Слишком много попыток входа", "application/problem+json", openapi.Ref(openapi.ProblemSchema)).
+		ResponseHeader(http.StatusTooManyRequests, "Retry-After", "Через сколько секунд можно повторить попытку")
+	d.Op("POST", "/auth/login/two-factor", "auth", "Второй шаг входа").Public().
+		Describe("Принимает токен второго шага (действует 5 минут) и код из приложения-аутентификатора "+
+			"или резервный код. Каждый код принимается один раз; неверные коды учитываются как неудачные попытки входа.").
+		Body(models.TwoFactorLoginRequest{}).
+		Returns(http.StatusOK, "Токен доступа", tokenResponse{}).
+		ReturnsAs(http.StatusTooManyRequests, "Слишком много попыток входа", "application/problem+json", openapi.Ref(openapi.ProblemSchema)).
+		ResponseHeader(http.StatusTooManyRequests, "Retry-After", "Через сколько секунд можно повторить попытку")
+	d.Op("GET", "/auth/sso", "auth", "Доступность единого входа").Public().
+		Returns(http.StatusOK, "Настроен ли вход через провайдера OpenID Connect", ssoStatusResponse{})
+	d.Op("GET", "/auth/sso/login", "auth", "Начало единого входа").Public().
+		Describe("Перенаправляет браузер на страницу входа провайдера OpenID Connect (authorization code + PKCE). "+
+			"Состояние входа сохраняется в cookie oidc_flow на 10 минут.").
+		Returns(http.StatusFound, "Переход к провайдеру", nil).
+		ReturnsAs(http.StatusNotFound, "Единый вход не настроен", "application/problem+json", openapi.Ref(openapi.ProblemSchema))
+	d.Op("GET", "/auth/sso/callback", "auth", "Возврат с провайдера единого входа").Public().
+		Describe("Проверяет state, обменивает код на ID-токен, проверяет его подпись по ключам JWKS провайдера "+
+			"и находит сотрудника по email из токена. Перенаправляет на /login с билетом входа "+
+			"(#sso_ticket=...) или кодом ошибки (#sso_error=expired|cancelled|no_account|email_not_verified|failed).").
+		Query("code", "string", "Код авторизации", false).
+		Query("state", "string", "Значение state из запроса авторизации", false).
+		Query("error", "string", "Код ошибки провайдера", false).
+		Returns(http.StatusFound, "Переход на страницу входа", nil)
+	d.Op("POST", "/auth/sso/complete", "auth", "Завершение единого входа").Public().
+		Describe("Обменивает билет входа (действует 2 минуты) на токен доступа. Если у сотрудника включена "+
+			"двухфакторная аутентификация, возвращается токен второго шага для /auth/login/two-factor.").
+		Body(models.SSOCompleteRequest{}).
+		Returns(http.StatusOK, "Токен доступа", tokenResponse{}).
+		Returns(http.StatusAccepted, "Нужен код двухфакторной аутентификации", challengeResponse{}).
+		ReturnsAs(http.StatusTooManyRequests, "Слишком много попыток входа", "application/problem+json", openapi.Ref(openapi.ProblemSchema)).
+		ResponseHeader(http.StatusTooManyRequests, "Retry-After", "Через сколько секунд можно повторить попытку")
+	d.Op("GET", "/auth/registration", "auth", "Режим регистрации").Public().
+		Describe("disabled — регистрации нет; invite — только по приглашениям администраторов; "+
+			"domain — также по ссылке, которую можно запросить на адрес из разрешенного домена (domains).").
+		Returns(http.StatusOK, "Режим регистрации", models.RegistrationSettings{})
+	d.Op("POST", "/auth/register/email", "auth", "Запрос ссылки регистрации").Public().
+		Describe("Только в режиме domain. Если адрес из разрешенного домена еще не зарегистрирован, на него "+
+			"отправляется ссылка регистрации с ролью employee. Ответ одинаков для зарегистрированных и новых адресов.").
+		Body(models.RegistrationEmailRequest{}).
+		Returns(http.StatusAccepted, "Запрос принят", messageResponse{})
+	d.Op("POST", "/auth/register/invitation", "auth", "Данные приглашения").Public().
+		Describe("Возвращает адрес, роль и отдел действующего приглашения по токену из ссылки.").
+		Body(models.InvitationTokenRequest{}).
+		Returns(http.StatusOK, "Приглашение", models.InvitationPreview{})
+	d.Op("POST", "/auth/register", "auth", "Регистрация по приглашению").Public().
+		Describe("Email, роль и отдел берутся из приглашения. Приглашение используется один раз.").
+		Body(models.RegisterRequest{}).
+		Returns(http.StatusCreated, "Сотрудник создан", createdResponse{})
+	d.Op("POST", "/auth/password-reset", "auth", "Запрос ссылки для сброса пароля").Public().
+		Describe("Если адрес принадлежит сотруднику, на него отправляется одноразовая ссылка сброса пароля; "+
+			"ранее выданные ссылки отменяются. Ответ одинаков для зарегистрированных и неизвестных адресов.").
+		Body(models.PasswordResetRequest{}).
+		Returns(http.StatusAccepted, "Запрос принят", messageResponse{})
+	d.Op("POST", "/auth/password-reset/confirm", "auth", "Установка нового пароля по ссылке").Public().
+		Describe("Токен из ссылки действует ограниченное время и используется один раз. Новый пароль "+
+			"проверяется так же, как при смене пароля. После сброса все выданные токены входа недействительны, "+
+			"персональные токены API отозваны.").
+		Body(models.PasswordResetConfirmRequest{}).
+		Returns(http.StatusNoContent, "Пароль изменен", nil)
+
+	// Assets
+	d.Op("GET", "/assets", "assets", "Список активов").
+		Query("category_id", "integer", "Категория вместе с подкатегориями", false).
+		Query("q", "string", "Поиск по названию и дополнительным полям", false).
+		Query("cf.{key}", "string", "Отбор по значению дополнительного поля key", false).
+		Query("include_deleted", "boolean", "Включить удаленные записи (только для администраторов)", false).
+		Returns(http.StatusOK, "Активы", []models.Asset{})
+	d.Op("POST", "/assets", "assets", "Создание актива").
+		Body(models.AssetRequest{}).
+		Returns(http.StatusCreated, "Актив создан", createdResponse{})
+	d.Op("GET", "/assets/{id}", "assets", "Актив с компонентами").
+		Returns(http.StatusOK, "Актив", models.Asset{}).
+		ResponseHeader(http.StatusOK, "ETag", "Версия записи")
+	replace(d, "/assets/{id}", "assets", "Замена актива", models.AssetRequest{}, models.Asset{})
+	patch(d, "/assets/{id}", "assets", "Частичное изменение актива", models.Asset{})
+	remove(d, "/assets/{id}", "assets", "Удаление актива")
+	restore(d, "/assets/{id}/restore", "assets", "Восстановление актива")
+	d.Op("GET", "/assets/{id}/transfers", "assets", "История перемещений актива").
+		Returns(http.StatusOK, "Перемещения", []models.AssetTransfer{})
+	d.Op("POST", "/assets/{id}/components", "assets", "Добавление компонента в комплект").
+		Body(models.AttachComponentRequest{}).
+		Returns(http.StatusNoContent, "Компонент добавлен", nil)
+	d.Op("DELETE", "/assets/{id}/components/{componentID}", "assets", "Исключение компонента из комплекта").
+		Returns(http.StatusNoContent, "Компонент исключен", nil)
+	calendar(d, "/assets/{id}/reservations.ics", "assets", "Календарь бронирований актива")
+
+	// Categories
+	d.Op("GET", "/categories", "categories", "Список категорий").
+		Returns(http.StatusOK, "Категории", []models.Category{})
+	d.Op("POST", "/categories", "categories", "Создание категории").
+		Body(models.CategoryRequest{}).
+		Returns(http.StatusCreated, "Категория создана", createdResponse{})
+	d.Op("GET", "/categories/tree", "categories", "Дерево категорий").
+		Returns(http.StatusOK, "Корневые категории с подкатегориями", []models.Category{})
+	d.Op("GET", "/categories/duplicates", "categories", "Группы категорий с совпадающими названиями").
+		Returns(http.StatusOK, "Группы дубликатов", [][]models.Category{})
+	d.Op("GET", "/categories/{id}", "categories", "Категория").
+		Returns(http.StatusOK, "Категория", models.Category{})
+	d.Op("PUT", "/categories/{id}", "categories", "Замена категории").
+		Body(models.CategoryRequest{}).
+		Returns(http.StatusNoContent, "Категория сохранена", nil)
+	d.Op("DELETE", "/categories/{id}", "categories", "Удаление категории").
+		Returns(http.StatusNoContent, "Категория удалена", nil)
+	d.Op("GET", "/categories/{id}/fields", "categories", "Дополнительные поля с учетом родительских категорий").
+		Returns(http.StatusOK, "Поля", []models.CustomField{})
+	d.Op("POST", "/categories/{id}/merge", "categories", "Слияние категории с другой").
+		Body(models.MergeCategoryRequest{}).
+		Returns(http.StatusNoContent, "Категории объединены", nil)
+
+	// Employees
+	d.Op("GET", "/employees", "employees", "Список сотрудников").
+		Query("include_deleted", "boolean", "Включить удаленные записи (только для администраторов)", false).
+		Returns(http.StatusOK, "Сотрудники", []models.Employee{})
+	d.Op("POST", "/employees", "employees", "Создание сотрудника").
+		Describe("Только для администраторов.").
+		Body(models.CreateEmployeeRequest{}).
+		Returns(http.StatusCreated, "Сотрудник создан", createdResponse{})
+	d.Op("GET", "/employees/{id}", "employees", "Сотрудник").
+		Returns(http.StatusOK, "Сотрудник", models.Employee{}).
+		ResponseHeader(http.StatusOK, "ETag", "Версия записи")
+	replace(d, "/employees/{id}", "employees", "Замена сотрудника", models.EmployeeRequest{}, models.Employee{}).
+		Describe("Только для администраторов. Свой профиль сотрудник меняет через /me.")
+	employeePatch := patch(d, "/employees/{id}", "employees", "Частичное изменение сотрудника", models.Employee{})
+	employeePatch.Describe(employeePatch.Description + " Только для администраторов.")
+	remove(d, "/employees/{id}", "employees", "Удаление сотрудника")
+	restore(d, "/employees/{id}/restore", "employees", "Восстановление сотрудника")
+	d.Op("POST", "/employees/{id}/unlock", "employees", "Снятие блокировки входа").
+		Describe("Обнуляет счетчик неудачных попыток входа сотрудника. Только для администраторов.").
+		Returns(http.StatusNoContent, "Блокировка снята", nil)
+	d.Op("POST", "/employees/{id}/two-factor/reset", "employees", "Сброс двухфакторной аутентификации").
+		Describe("Отключает двухфакторную аутентификацию сотрудника, потерявшего устройство и резервные коды. "+
+			"Только для администраторов.").
+		Returns(http.StatusNoContent, "Двухфакторная аутентификация отключена", nil)
+	d.Op("GET", "/employees/{id}/api-tokens", "employees", "Персональные токены сотрудника").
+		Describe("Только для администраторов.").
+		Returns(http.StatusOK, "Токены, включая отозванные и истекшие", []models.APIToken{})
+	d.Op("DELETE", "/employees/{id}/api-tokens/{tokenID}", "employees", "Отзыв персонального токена сотрудника").
+		Describe("Только для администраторов.").
+		Returns(http.StatusNoContent, "Токен отозван", nil)
+	calendar(d, "/employees/{id}/reservations.ics", "employees", "Календарь бронирований сотрудника")
+
+	// Departments
+	d.Op("GET", "/departments", "departments", "Список отделов").
+		Query("include_deleted", "boolean", "Включить удаленные записи (только для администраторов)", false).
+		Returns(http.StatusOK, "Отделы", []models.Department{})
+	d.Op("POST", "/departments", "departments", "Создание отдела").
+		Body(models.DepartmentRequest{}).
+		Returns(http.StatusCreated, "Отдел создан", createdResponse{})
+	d.Op("GET", "/departments/{id}", "departments", "Отдел").
+		Returns(http.StatusOK, "Отдел", models.Department{}).
+		ResponseHeader(http.StatusOK, "ETag", "Версия записи")
+	replace(d, "/departments/{id}", "departments", "Замена отдела", models.DepartmentRequest{}, models.Department{})
+	patch(d, "/departments/{id}", "departments", "Частичное изменение отдела", models.Department{})
+	remove(d, "/departments/{id}", "departments", "Удаление отдела")
+	restore(d, "/departments/{id}/restore", "departments", "Восстановление отдела")
+	d.Op("GET", "/departments/{id}/employees", "departments", "Сотрудники отдела").
+		Returns(http.StatusOK, "Сотрудники", []models.Employee{})
+
+	// Locations
+	d.Op("GET", "/locations", "locations", "Список местоположений").
+		Query("include_deleted", "boolean", "Включить удаленные записи (только для администраторов)", false).
+		Returns(http.StatusOK, "Местоположения", []models.Location{})
+	d.Op("POST", "/locations", "locations", "Создание местоположения").
+		Body(models.LocationRequest{}).
+		Returns(http.StatusCreated, "Местоположение создано", createdResponse{})
+	d.Op("GET", "/locations/{id}", "locations", "Местоположение").
+		Returns(http.StatusOK, "Местоположение", models.Location{}).
+		ResponseHeader(http.StatusOK, "ETag", "Версия записи")
+	replace(d, "/locations/{id}", "locations", "Замена местоположения", models.LocationRequest{}, models.Location{})
+	patch(d, "/locations/{id}", "locations", "Частичное изменение местоположения", models.Location{})
+	remove(d, "/locations/{id}", "locations", "Удаление местоположения")
+	restore(d, "/locations/{id}/restore", "locations", "Восстановление местоположения")
+
+	// Reports
+	d.Op("GET", "/reports/assets-by-status", "reports", "Активы по статусам").
+		Returns(http.StatusOK, "Отчет", []models.AssetsByStatusReport{})
+	d.Op("GET", "/reports/transfers", "reports", "Перемещения за период").
+		Query("from", "date", "Начало периода, по умолчанию месяц назад", false).
+		Query("to", "date", "Конец периода, по умолчанию сегодня", false).
+		Returns(http.StatusOK, "Перемещения", []models.AssetTransfer{})
+	d.Op("GET", "/reports/department-costs", "reports", "Стоимость активов по отделам").
+		Returns(http.StatusOK, "Отчет", []models.AssetsCostByDepartmentReport{})
+	d.Op("GET", "/reports/inventory", "reports", "Сводка по инвентарю").
+		Returns(http.StatusOK, "Отчет", models.InventoryReport{})
+	d.Op("GET", "/reports/categories", "reports", "Активы по категориям с итогами по поддеревьям").
+		Returns(http.StatusOK, "Отчет", []models.CategoryReport{})
+
+	// Transfers
+	d.Op("GET", "/transfers", "transfers", "Список перемещений").
+		Returns(http.StatusOK, "Перемещения", []models.AssetTransfer{})
+	d.Op("POST", "/transfers", "transfers", "Перемещение актива").
+		Body(models.TransferRequest{}).
+		Returns(http.StatusCreated, "Перемещение зарегистрировано", createdResponse{})
+	d.Op("GET", "/transfers/{id}", "transfers", "Перемещение").
+		Returns(http.StatusOK, "Перемещение", models.AssetTransfer{})
+
+	// Reservations
+	d.Op("GET", "/reservations", "reservations", "Список бронирований").
+		Query("asset_id", "integer", "Актив", false).
+		Query("employee_id", "integer", "Сотрудник", false).
+		Query("status", "string", "Статус: pending, approved, rejected, cancelled", false).
+		Query("from", "date-time", "Бронирования, заканчивающиеся после момента", false).
+		Query("to", "date-time", "Бронирования, начинающиеся до момента", false).
+		Returns(http.StatusOK, "Бронирования", []models.Reservation{})
+	d.Op("POST", "/reservations", "reservations", "Создание бронирования").
+		Body(models.ReservationRequest{}).
+		Returns(http.StatusCreated, "Бронирование создано", createdResponse{})
+	d.Op("GET", "/reservations/availability", "reservations", "Свободные на интервал активы").
+		Query("from", "date-time", "Начало интервала", true).
+		Query("to", "date-time", "Конец интервала", true).
+		Query("category_id", "integer", "Категория", false).
+		Query("location_id", "integer", "Местоположение", false).
+		Returns(http.StatusOK, "Активы", []models.Asset{})
+	d.Op("GET", "/reservations/{id}", "reservations", "Бронирование").
+		Returns(http.StatusOK, "Бронирование", models.Reservation{})
+	for _, action := range []struct{ name, summary string }{
+		{"approve", "Одобрение бронирования"},
+		{"reject", "Отклонение бронирования"},
+		{"cancel", "Отмена бронирования"},
+	} {
+		d.Op("POST", "/reservations/{id}/"+action.name, "reservations", action.summary).
+			Returns(http.StatusNoContent, "Статус изменен", nil)
+	}
+
+	// Events
+	d.Op("GET", "/events", "events", "Поток изменений (Server-Sent Events)").
+		Describe("Событие SSE называется <сущность>.<действие>, например transfer.created; "+
+			"данные — JSON с полями entity, action, id и occurred_at. "+
+			"При подключении приходит событие ready, каждые 25 секунд — комментарий-пинг. "+
+			"При переподключении с заголовком Last-Event-ID сервер досылает пропущенные события, "+
+			"а если они уже недоступны — событие reset, после которого данные нужно перечитать.").
+		Query("entity", "string", "Сущности через запятую: asset, transfer, employee, department, location, category, reservation", false).
+		Query("entity_id", "integer", "Только события одной записи", false).
+		Header("Last-Event-ID", "Идентификатор последнего полученного события", false).
+		ReturnsAs(http.StatusOK, "Поток событий", "text/event-stream", &openapi.Schema{Type: "string"})
+
+	// Invitations
+	d.Op("GET", "/invitations", "invitations", "Список приглашений").
+		Query("status", "string", "Состояние: pending, accepted, expired или revoked", false).
+		Returns(http.StatusOK, "Приглашения, начиная с новых", []models.Invitation{})
+	d.Op("POST", "/invitations", "invitations", "Создание приглашения").
+		Describe("Отправляет на адрес ссылку регистрации с заданными ролью и отделом; прежние приглашения "+
+			"на этот адрес отзываются. Ссылка возвращается и в ответе. Недоступно в режиме регистрации disabled.").
+		Body(models.InvitationRequest{}).
+		Returns(http.StatusCreated, "Приглашение создано", models.CreatedInvitation{})
+	d.Op("GET", "/invitations/{id}", "invitations", "Приглашение").
+		Returns(http.StatusOK, "Приглашение", models.Invitation{})
+	d.Op("DELETE", "/invitations/{id}", "invitations", "Отзыв приглашения").
+		Returns(http.StatusNoContent, "Приглашение отозвано", nil)
+	d.Op("POST", "/invitations/{id}/resend", "invitations", "Повторная отправка приглашения").
+		Describe("Выдает новую ссылку с новым сроком; прежняя ссылка перестает действовать.").
+		Returns(http.StatusOK, "Приглашение отправлено", models.CreatedInvitation{})
+
+	// Webhooks
+	d.Op("GET", "/webhooks", "webhooks", "Список подписок").
+		Returns(http.StatusOK, "Подписки без секретов", []models.WebhookSubscription{})
+	d.Op("POST", "/webhooks", "webhooks", "Создание подписки").
+		Describe("Запрос к url подписки — POST с событием в теле. Заголовок X-Webhook-Signature имеет вид "+
+			"t=<unix-время>,v1=<hex>, где v1 — HMAC-SHA256 секрета от строки \"<t>.<тело запроса>\". "+
+			"Доставка успешна при ответе 2xx, иначе повторяется с растущей паузой. "+
+			"Секрет возвращается только в ответе на создание; без него он генерируется.").
+		Body(models.WebhookRequest{}).
+		Returns(http.StatusCreated, "Подписка создана, с секретом", models.WebhookSubscription{})
+	d.Op("GET", "/webhooks/{id}", "webhooks", "Подписка").
+		Returns(http.StatusOK, "Подписка без секрета", models.WebhookSubscription{})
+	d.Op("PUT", "/webhooks/{id}", "webhooks", "Замена подписки").
+		Describe("Без secret сохраняется прежний секрет.").
+		Body(models.WebhookRequest{}).
+		Returns(http.StatusNoContent, "Подписка сохранена", nil)
+	remove(d, "/webhooks/{id}", "webhooks", "Удаление подписки")
+	d.Op("GET", "/webhooks/{id}/deliveries", "webhooks", "Журнал доставок").
+		Returns(http.StatusOK, "Последние доставки", []models.WebhookDelivery{})
+	d.Op("POST", "/webhooks/{id}/deliveries/{deliveryID}/retry", "webhooks", "Повторная отправка").
+		Describe("Возвращает в очередь доставку, исчерпавшую попытки (статус dead).").
+		Returns(http.StatusAccepted, "Доставка поставлена в очередь", nil)
+
+	// Organizations
+	d.Op("GET", "/organizations", "organizations", "Список организаций").
+		Returns(http.StatusOK, "Организации", []models.Organization{})
+	d.Op("POST", "/organizations", "organizations", "Создание организации").
+		Describe("Сотрудники с адресами из email_domains входят через внешний каталог и регистрируются "+
+			"по ссылке в этой организации. Домен может принадлежать только одной организации.").
+		Body(models.OrganizationRequest{}).
+		Returns(http.StatusCreated, "Организация создана", createdResponse{})
+	d.Op("GET", "/organizations/{id}", "organizations", "Организация").
+		Returns(http.StatusOK, "Организация", models.Organization{})
+	d.Op("PUT", "/organizations/{id}", "organizations", "Замена организации").
+		Body(models.OrganizationRequest{}).
+		Returns(http.StatusNoContent, "Организация сохранена", nil)
+	d.Op("POST", "/organizations/{id}/invitations", "organizations", "Приглашение в организацию").
+		Describe("Приглашает сотрудника, обычно первого администратора, в организацию id. "+
+			"Отдел из запроса должен принадлежать этой организации.").
+		Body(models.InvitationRequest{}).
+		Returns(http.StatusCreated, "Приглашение создано", models.CreatedInvitation{})
+	d.Op("GET", "/group/reports/inventory", "organizations", "Сводный отчет по активам группы").
+		Returns(http.StatusOK, "Итоги по группе и по организациям", models.GroupInventoryReport{})
+	d.Op("GET", "/group/reports/assets-by-status", "organizations", "Активы группы по статусам").
+		Returns(http.StatusOK, "Итоги по группе и по организациям", models.GroupAssetsByStatusReport{})
+
+	// Current employee
+	// Failed login audit
+	d.Op("GET", "/login-failures", "auth", "Журнал неудачных попыток входа").
+		Describe("Только для администраторов. Новые записи идут первыми.").
+		Query("email", "string", "Только попытки входа с этим email", false).
+		Query("limit", "integer", "Размер страницы, по умолчанию 50, не более 500", false).
+		Query("offset", "integer", "Сколько записей пропустить", false).
+		Returns(http.StatusOK, "Неудачные попытки входа", []models.LoginFailure{})
+
+	d.Op("GET", "/me/two-factor", "me", "Состояние двухфакторной аутентификации").
+		Describe("Для ролей, где она обязательна, до ее включения доступны только маршруты /me/two-factor.").
+		Returns(http.StatusOK, "Состояние", models.TwoFactorStatus{})
+	d.Op("POST", "/me/two-factor/setup", "me", "Выпуск секрета TOTP").
+		Describe("provisioning_uri (otpauth://) кодируется в QR-код для приложения-аутентификатора. "+
+			"Повторный вызов до включения заменяет секрет.").
+		Returns(http.StatusOK, "Секрет и адрес для QR-кода", models.TwoFactorSetup{})
+	d.Op("POST", "/me/two-factor/enable", "me", "Включение двухфакторной аутентификации").
+		Describe("Код из приложения подтверждает, что секрет добавлен. Резервные коды показываются только в этом ответе.").
+		Body(models.TwoFactorCodeRequest{}).
+		Returns(http.StatusOK, "Резервные коды", models.RecoveryCodes{})
+	d.Op("POST", "/me/two-factor/disable", "me", "Отключение двухфакторной аутентификации").
+		Describe("Нужны пароль и код. Недоступно для ролей, где двухфакторная аутентификация обязательна.").
+		Body(models.DisableTwoFactorRequest{}).
+		Returns(http.StatusNoContent, "Отключена", nil)
+	d.Op("POST", "/me/two-factor/recovery-codes", "me", "Новые резервные коды").
+		Describe("Прежние резервные коды перестают действовать.").
+		Body(models.TwoFactorCodeRequest{}).
+		Returns(http.StatusOK, "Резервные коды", models.RecoveryCodes{})
+	d.Op("GET", "/me", "me", "Профиль текущего сотрудника").
+		Returns(http.StatusOK, "Сотрудник", models.Employee{}).
+		ResponseHeader(http.StatusOK, "ETag", "Версия записи")
+	patch(d, "/me", "me", "Изменение своего профиля", models.Employee{})
+	d.Op("POST", "/me/password", "me", "Смена пароля").
+		Describe("Новый пароль: от 8 до 72 байт, буквы и цифры, не из списка распространенных "+
+			"и без имени или email сотрудника. Неверный текущий пароль — ошибка проверки поля current_password. "+
+			"Смена пароля отзывает все выданные токены входа и персональные токены API; в ответе — новый токен для текущего сеанса.").
+		Body(models.ChangePasswordRequest{}).
+		Returns(http.StatusOK, "Пароль изменен", renewedTokenResponse{})
+	d.Op("GET", "/me/assets", "me", "Активы своего отдела").
+		Returns(http.StatusOK, "Активы", []models.Asset{})
+	d.Op("GET", "/me/transfers", "me", "Оформленные мной перемещения").
+		Returns(http.StatusOK, "Перемещения", []models.AssetTransfer{})
+	d.Op("GET", "/me/notification-preferences", "me", "Настройки уведомлений").
+		Describe("Письма приходят о перемещении активов отдела, решении по брони и окончании гарантии. "+
+			"Пока настройки не менялись, возвращаются значения по умолчанию: русский язык, все уведомления включены.").
+		Returns(http.StatusOK, "Настройки уведомлений", models.NotificationPreferences{})
+	d.Op("PUT", "/me/notification-preferences", "me", "Изменение настроек уведомлений").
+		Body(models.NotificationPreferencesRequest{}).
+		Returns(http.StatusOK, "Настройки сохранены", models.NotificationPreferences{})
+	d.Op("GET", "/me/notifications", "me", "Входящие уведомления").
+		Describe("Уведомления о перемещениях активов отдела, закреплении активов и назначениях, "+
+			"запросах на подтверждение брони и решениях по ним. Новые идут первыми.").
+		Query("unread", "boolean", "Только непрочитанные", false).
+		Query("limit", "integer", "Размер страницы, по умолчанию 20, не более 100", false).
+		Query("offset", "integer", "Сколько уведомлений пропустить", false).
+		Returns(http.StatusOK, "Страница входящих с числом всех и непрочитанных", models.NotificationPage{})
+	d.Op("POST", "/me/notifications/read-all", "me", "Прочитать все уведомления").
+		Returns(http.StatusOK, "Число отмеченных уведомлений", map[string]int64{})
+	d.Op("POST", "/me/notifications/{id}/read", "me", "Прочитать уведомление").
+		Returns(http.StatusNoContent, "Уведомление отмечено прочитанным", nil)
+	d.Op("GET", "/me/api-tokens", "me", "Мои персональные токены").
+		Returns(http.StatusOK, "Токены, включая отозванные и истекшие", []models.APIToken{})
+	d.Op("POST", "/me/api-tokens", "me", "Создание персонального токена").
+		Describe("Токен для скриптов и интеграций действует от имени сотрудника в пределах областей scopes: "+
+			strings.Join(models.APITokenScopes, ", ")+". "+
+			"Без expires_at действует срок по умолчанию (API_TOKEN_DEFAULT_TTL, 90 дней). Значение токена возвращается только в этом ответе, "+
+			"сохраняется лишь его хеш. Создавать и отзывать токены персональным токеном нельзя.").
+		Body(models.CreateAPITokenRequest{}).
+		Returns(http.StatusCreated, "Токен создан", models.CreatedAPIToken{})
+	d.Op("DELETE", "/me/api-tokens/{id}", "me", "Отзыв персонального токена").
+		Returns(http.StatusNoContent, "Токен отозван", nil)
+
+	// Meta
+	d.Op("GET", "/openapi.json", "meta", "Этот документ").Public().
+		ReturnsAs(http.StatusOK, "Документ OpenAPI", "application/json", &openapi.Schema{Type: "object"})
+
+	return d
+}
+
+func replace(d *openapi.Document, path, tag, summary string, request, model interface{}) *openapi.Operation {
+	return d.Op("PUT", path, tag, summary).
+		Header("If-Match", "ETag, полученный при чтении записи", true).
+		Body(request).
+		Returns(http.StatusNoContent, "Запись сохранена", nil).
+		ResponseHeader(http.StatusNoContent, "ETag", "Новая версия записи").
+		Returns(http.StatusPreconditionFailed, "Запись изменена другим пользователем: актуальное состояние", model).
+		ResponseHeader(http.StatusPreconditionFailed, "ETag", "Текущая версия записи")
+}
+
+func patch(d *openapi.Document, path, tag, summary string, model interface{}) *openapi.Operation {
+	return d.Op("PATCH", path, tag, summary).
+		Describe("Тело — JSON Merge Patch (RFC 7396): переданные поля заменяются, null очищает необязательное поле.").
+		Header("If-Match", "ETag, полученный при чтении записи", true).
+		BodyAs(mergepatch.ContentType, &openapi.Schema{Type: "object", AdditionalProperties: true}).
+		Returns(http.StatusOK, "Измененная запись", model).
+		ResponseHeader(http.StatusOK, "ETag", "Новая версия записи").
+		Returns(http.StatusPreconditionFailed, "Запись изменена другим пользователем: актуальное состояние", model).
+		ResponseHeader(http.StatusPreconditionFailed, "ETag", "Текущая версия записи")
+}
+
+func remove(d *openapi.Document, path, tag, summary string) {
+	d.Op("DELETE", path, tag, summary).
+		Returns(http.StatusNoContent, "Запись удалена", nil)
+}
+
+func restore(d *openapi.Document, path, tag, summary string) {
+	d.Op("POST", path, tag, summary).
+		Describe("Только для администраторов.").
+		Returns(http.StatusNoContent, "Запись восстановлена", nil)
+}
+
+func calendar(d *openapi.Document, path, tag, summary string) {
+	d.Op("GET", path, tag, summary).
+		Describe("Программы-календари не передают заголовок Authorization, поэтому для подписки "+
+			"персональный токен с правом чтения ресурса можно передать в параметре token.").
+		Query("token", "string", "Персональный токен inv_pat_... вместо заголовка Authorization", false).
+		ReturnsAs(http.StatusOK, "Календарь iCalendar", "text/calendar", openapi.Binary())
+}
+
+// specPath переводит шаблон маршрута chi в путь документа
+func specPath(route string) string {
+	if route != "/" {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return route
+}
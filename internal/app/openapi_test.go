@@ -0,0 +1,193 @@
+package app
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/handlers"
+	"inventory-system/internal/models"
+	"inventory-system/internal/openapi"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository/repotest"
+	"inventory-system/internal/services"
+)
+
+func TestRoutesMatchSpec(t *testing.T) {
+	undocumented, unrouted, err := specDiff(newTestRouter(Deprecation{}), NewSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range undocumented {
+		t.Errorf("route %s is not documented", op)
+	}
+	for _, op := range unrouted {
+		t.Errorf("documented operation %s has no route", op)
+	}
+}
+
+// newSpecTestRouter собирает маршруты с сервисами, которым для ответа не
+// нужна база данных
+func newSpecTestRouter() http.Handler {
+	registration := services.NewRegistrationService(
+		nil, nil, nil, nil, nil, nil, nil,
+		ratelimit.SystemClock,
+		models.RegistrationDomain,
+		[]string{"example.com"},
+		time.Hour,
+	)
+	h := handlers.NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, registration, nil, nil)
+	return NewRouter(h, Deprecation{})
+}
+
+// checkResponse выполняет запрос и сверяет ответ с описанием операции
+// method path в документе
+func checkResponse(t *testing.T, r http.Handler, spec *openapi.Document, method, path, target, body string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, currentPrefix+target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if err := spec.ValidateResponse(method, path, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes()); err != nil {
+		t.Errorf("%v\n%s", err, rec.Body.String())
+	}
+	return rec.Code
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// samplePath подставляет в параметры пути допустимые по форме значения
+func samplePath(path string) string {
+	return pathParam.ReplaceAllString(path, "1")
+}
+
+func TestPublicResponsesMatchSpec(t *testing.T) {
+	r := newSpecTestRouter()
+	spec := NewSpec()
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodGet, "/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/auth/registration", "", http.StatusOK},
+		{http.MethodGet, "/auth/sso", "", http.StatusOK},
+		{http.MethodGet, "/auth/sso/login", "", http.StatusNotFound},
+		{http.MethodPost, "/auth/login", `{"email":`, http.StatusBadRequest},
+		{http.MethodPost, "/auth/login", `{"email":"a@example.com","password":"x","extra":1}`, http.StatusBadRequest},
+		{http.MethodPost, "/auth/login", `{"email":"","password":"x"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		if got := checkResponse(t, r, spec, tt.method, tt.path, tt.path, tt.body); got != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.status)
+		}
+	}
+}
+
+func TestProtectedResponsesMatchSpec(t *testing.T) {
+	r := newSpecTestRouter()
+	spec := NewSpec()
+
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			if op.Security != nil && len(*op.Security) == 0 {
+				continue
+			}
+			method = strings.ToUpper(method)
+			if got := checkResponse(t, r, spec, method, path, samplePath(path), ""); got != http.StatusUnauthorized {
+				t.Errorf("%s %s without a token = %d, want %d", method, path, got, http.StatusUnauthorized)
+			}
+		}
+	}
+}
+
+// inventoryTables дополняет locationTable списками местоположений, отделов,
+// категорий и активов. Изменение местоположения с версией 3 сохраняется
+func inventoryTables(query string, args []driver.Value) (repotest.Result, error) {
+	acquired := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	deleted := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	switch {
+	case strings.Contains(query, "SELECT id, address, type, deleted_at, deleted_by, version"):
+		return repotest.Result{
+			Columns: make([]string, 6),
+			Rows: [][]driver.Value{
+				{int64(1), "Главный офис", "Офис", nil, nil, int64(3)},
+				{int64(2), "Старый склад", "Склад", deleted, int64(1), int64(5)},
+			},
+		}, nil
+	case strings.Contains(query, "INSERT INTO locations"):
+		return repotest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(2)}}}, nil
+	case strings.HasPrefix(query, "UPDATE locations SET") && strings.Contains(query, "RETURNING version"):
+		version := args[len(args)-2]
+		if version != int64(3) {
+			return repotest.Result{Columns: []string{"version"}}, nil
+		}
+		return repotest.Result{Columns: []string{"version"}, Rows: [][]driver.Value{{int64(4)}}}, nil
+	case strings.Contains(query, "FROM departments d"):
+		return repotest.Result{
+			Columns: make([]string, 8),
+			Rows:    [][]driver.Value{{int64(1), "Бухгалтерия", "Главный офис", int64(1), testEmployee.FullName, nil, nil, int64(2)}},
+		}, nil
+	case strings.Contains(query, "FROM categories"):
+		return repotest.Result{
+			Columns: make([]string, 5),
+			Rows: [][]driver.Value{
+				{int64(1), "Техника", nil, false, nil},
+				{int64(2), "Ноутбуки", int64(1), true, []byte(`[{"name":"serial","type":"string","required":true}]`)},
+			},
+		}, nil
+	case strings.Contains(query, "FROM assets a"):
+		return repotest.Result{
+			Columns: make([]string, 19),
+			Rows: [][]driver.Value{{
+				int64(10), "Ноутбук", "Ноутбуки", int64(2), []byte(`{"serial":"SN-1"}`),
+				acquired, float64(125000), acquired.AddDate(3, 0, 0),
+				int64(1), "В эксплуатации", int64(1), "Главный офис",
+				int64(1), "Бухгалтерия", nil, nil, nil, int64(2), int64(1),
+			}},
+		}, nil
+	}
+	return locationTable(query, args)
+}
+
+func TestAuthenticatedResponsesMatchSpec(t *testing.T) {
+	api := newTestAPI(t, inventoryTables)
+	spec := NewSpec()
+
+	tests := []struct {
+		method, path, target, body string
+		header                     http.Header
+		status                     int
+	}{
+		{http.MethodGet, "/me", "/me", "", nil, http.StatusOK},
+		{http.MethodGet, "/locations", "/locations?include_deleted=true", "", nil, http.StatusOK},
+		{http.MethodGet, "/locations/{id}", "/locations/1", "", nil, http.StatusOK},
+		{http.MethodPost, "/locations", "/locations", `{"address":"Склад","type":"Склад"}`, nil, http.StatusCreated},
+		{http.MethodPatch, "/locations/{id}", "/locations/1", `{"type":"Склад"}`,
+			http.Header{"If-Match": {`"3"`}, "Content-Type": {"application/merge-patch+json"}}, http.StatusOK},
+		{http.MethodGet, "/departments", "/departments", "", nil, http.StatusOK},
+		{http.MethodGet, "/categories", "/categories", "", nil, http.StatusOK},
+		{http.MethodGet, "/assets", "/assets", "", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rec := api.do(tt.method, tt.target, tt.body, tt.header)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s = %d, want %d\n%s", tt.method, tt.target, rec.Code, tt.status, rec.Body.String())
+			continue
+		}
+		if err := spec.ValidateResponse(tt.method, tt.path, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes()); err != nil {
+			t.Errorf("%s %s: %v\n%s", tt.method, tt.target, err, rec.Body.String())
+		}
+	}
+}
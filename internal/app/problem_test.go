@@ -0,0 +1,117 @@
+package app
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"inventory-system/internal/repository/repotest"
+)
+
+// locationTable — таблица locations с одним местоположением версии 3, к
+// которому привязаны активы
+func locationTable(query string, args []driver.Value) (repotest.Result, error) {
+	found := args[0] == int64(1)
+	switch {
+	case strings.Contains(query, "SELECT id, address, type, version"):
+		if !found {
+			return repotest.Result{Columns: []string{"id"}}, nil
+		}
+		return repotest.Result{
+			Columns: []string{"id", "address", "type", "version"},
+			Rows:    [][]driver.Value{{int64(1), "Главный офис", "Офис", int64(3)}},
+		}, nil
+	case strings.Contains(query, "EXISTS(SELECT 1 FROM locations"),
+		strings.Contains(query, "FROM assets WHERE current_location_id"):
+		return repotest.Result{Columns: []string{"exists"}, Rows: [][]driver.Value{{found}}}, nil
+	case strings.Contains(query, "UPDATE locations"):
+		// Версия совпала бы только с 3, а тесты передают устаревшую
+		return repotest.Result{Columns: []string{"version"}}, nil
+	}
+	return repotest.Result{}, nil
+}
+
+func TestProblemResponses(t *testing.T) {
+	api := newTestAPI(t, locationTable)
+	spec := NewSpec()
+
+	location := `{"address":"Склад","type":"Склад"}`
+	tests := []struct {
+		name         string
+		method, path string
+		target, body string
+		header       http.Header
+		status       int
+		field        string
+	}{
+		{"invalid body", http.MethodPost, "/locations", "/locations", `{"address":"","type":"Склад"}`, nil, http.StatusBadRequest, "address"},
+		{"invalid If-Match", http.MethodPut, "/locations/{id}", "/locations/1", location, http.Header{"If-Match": {"3"}}, http.StatusBadRequest, ""},
+		{"unknown location", http.MethodGet, "/locations/{id}", "/locations/99", "", nil, http.StatusNotFound, ""},
+		{"location with assets", http.MethodDelete, "/locations/{id}", "/locations/1", "", nil, http.StatusConflict, ""},
+		{"missing If-Match", http.MethodPut, "/locations/{id}", "/locations/1", location, nil, http.StatusPreconditionRequired, ""},
+		{"merge patch content type", http.MethodPatch, "/locations/{id}", "/locations/1", `{"type":"Склад"}`,
+			http.Header{"If-Match": {`"3"`}, "Content-Type": {"text/plain"}}, http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tt := range tests {
+		rec := api.do(tt.method, tt.target, tt.body, tt.header)
+		if rec.Code != tt.status {
+			t.Errorf("%s: %s %s = %d, want %d\n%s", tt.name, tt.method, tt.target, rec.Code, tt.status, rec.Body.String())
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Errorf("%s: Content-Type = %q", tt.name, got)
+		}
+		if err := spec.ValidateResponse(tt.method, tt.path, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes()); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+
+		var problem struct {
+			Status   int               `json:"status"`
+			Instance string            `json:"instance"`
+			Errors   map[string]string `json:"errors"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if problem.Status != tt.status || problem.Instance != currentPrefix+tt.target {
+			t.Errorf("%s: problem = %s", tt.name, rec.Body.String())
+		}
+		if tt.field != "" && problem.Errors[tt.field] == "" {
+			t.Errorf("%s: no error for field %q: %s", tt.name, tt.field, rec.Body.String())
+		}
+	}
+}
+
+// TestStaleVersionReturnsCurrentRecord проверяет ответ 412: вместо
+// problem+json клиент получает актуальное состояние записи и ее ETag,
+// чтобы повторить изменение без отдельного запроса
+func TestStaleVersionReturnsCurrentRecord(t *testing.T) {
+	api := newTestAPI(t, locationTable)
+	spec := NewSpec()
+
+	rec := api.do(http.MethodPut, "/locations/1", `{"address":"Склад","type":"Склад"}`, http.Header{"If-Match": {`"2"`}})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT /locations/1 = %d, want %d\n%s", rec.Code, http.StatusPreconditionFailed, rec.Body.String())
+	}
+	if got := rec.Header().Get("ETag"); got != `"3"` {
+		t.Errorf("ETag = %q, want %q", got, `"3"`)
+	}
+	if err := spec.ValidateResponse(http.MethodPut, "/locations/{id}", rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes()); err != nil {
+		t.Error(err)
+	}
+
+	var current struct {
+		Address string `json:"address"`
+		Version int    `json:"version"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &current); err != nil {
+		t.Fatal(err)
+	}
+	if current.Address != "Главный офис" || current.Version != 3 {
+		t.Errorf("body = %s", rec.Body.String())
+	}
+}
@@ -1,34 +1,65 @@
 package app
 
 import (
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"inventory-system/internal/handlers"
+	"inventory-system/internal/openapi"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 )
 
-func NewRouter(h *handlers.Handler) *chi.Mux {
+// NewRouter собирает маршруты приложения. API доступно под /api/v1;
+// прежние пути /api/... остаются синонимами v1 с заголовками об устаревании
+func NewRouter(h *handlers.Handler, legacy Deprecation) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(redactQueryToken)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match", "Last-Event-ID"},
+		ExposedHeaders:   []string{"ETag", "Deprecation", "Sunset", "Link", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	// Auth routes
-	r.Route("/api/auth", func(r chi.Router) {
-		r.Post("/login", h.Login)
-		r.Post("/register", h.Register)
+	spec := NewSpec()
+
+	// Versioned API. A new version lists only the handlers that changed
+	versions := []apiVersion{
+		{prefix: currentPrefix},
+	}
+	for _, version := range versions {
+		r.Route(version.prefix, func(r chi.Router) {
+			vr := newVersionedRouter(r, version)
+			apiRoutes(vr, h, spec)
+			if unused := vr.unused(); len(unused) > 0 {
+				panic(fmt.Sprintf("%s: handlers without routes: %s", version.prefix, strings.Join(unused, ", ")))
+			}
+		})
+	}
+
+	// Unversioned API, kept as an alias of v1
+	r.Route(legacyPrefix, func(r chi.Router) {
+		r.Use(deprecated(legacy))
+		apiRoutes(r, h, spec)
+
+		r.With(h.AuthMiddleware).Get("/", h.ServeIndex)
+		r.With(h.AuthMiddleware).Get("/index", h.ServeIndex)
+		r.With(h.AuthMiddleware).Get("/index.html", h.ServeIndex)
 	})
 
+	// Public keys for verifying access tokens in other services
+	r.Get("/.well-known/jwks.json", h.GetJWKS)
+
 	// Serve static files
 	fs := http.FileServer(http.Dir("./frontend/static"))
 	r.Handle("/static/*", http.StripPrefix("/static/", fs))
@@ -37,15 +68,133 @@ func NewRouter(h *handlers.Handler) *chi.Mux {
 	r.Get("/", h.ServeIndex)
 	r.Get("/login", h.ServeLogin)
 	r.Get("/register", h.ServeRegister)
+	r.Get("/reset-password", h.ServeResetPassword)
+	r.Get("/two-factor", h.ServeTwoFactor)
 	r.Get("/assets", h.ServeAssets)
 	r.Get("/employees", h.ServeEmployees)
 	r.Get("/departments", h.ServeDepartments)
 	r.Get("/transfers", h.ServeTransfers)
 	r.Get("/reports", h.ServeReports)
+	r.Get("/docs", h.ServeDocs)
+
+	// Логирование роутов и сверка с документом OpenAPI
+	logRoute := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		log.Printf("%s %s\n", method, route)
+		return nil
+	}
+	if err := chi.Walk(r, logRoute); err != nil {
+		log.Printf("Logging err: %s\n", err.Error())
+	}
+
+	undocumented, unrouted, err := specDiff(r, spec)
+	if err != nil {
+		log.Printf("OpenAPI: %v", err)
+	}
+	for _, op := range undocumented {
+		log.Printf("OpenAPI: route %s is not documented", op)
+	}
+	for _, op := range unrouted {
+		log.Printf("OpenAPI: documented operation %s has no route", op)
+	}
+	return r
+}
+
+// specDiff сверяет маршруты /api/v1 с документом OpenAPI. Возвращает
+// маршруты без описания и описанные операции без маршрута в виде
+// «метод путь»
+func specDiff(r chi.Routes, spec *openapi.Document) (undocumented, unrouted []string, err error) {
+	routed := make(map[string]bool)
+	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, currentPrefix+"/") {
+			return nil
+		}
+		path := specPath(strings.TrimPrefix(route, currentPrefix))
+		if routed[method+" "+path] {
+			return nil
+		}
+		routed[method+" "+path] = true
+		if !spec.Has(method, path) {
+			undocumented = append(undocumented, method+" "+path)
+		}
+		return nil
+	}
+
+	if err := chi.Walk(r, walkFunc); err != nil {
+		return nil, nil, err
+	}
+	for _, op := range spec.Operations() {
+		if !routed[op] {
+			unrouted = append(unrouted, op)
+		}
+	}
+	sort.Strings(undocumented)
+	return undocumented, unrouted, nil
+}
+
+// redactQueryToken скрывает в журнале запросов персональный токен из
+// параметра token, с которым программы-календари запрашивают календари
+// бронирований. Меняется только RequestURI, который пишет журнал:
+// обработчики читают параметры из URL
+func redactQueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !query.Has("token") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		query.Set("token", "REDACTED")
+		redacted := *r
+		redacted.RequestURI = r.URL.Path + "?" + query.Encode()
+		next.ServeHTTP(w, &redacted)
+	})
+}
+
+// apiRoutes описывает маршруты API относительно префикса версии
+func apiRoutes(r chi.Router, h *handlers.Handler, spec http.Handler) {
+	// API description
+	r.Method(http.MethodGet, "/openapi.json", spec)
+
+	// Auth routes
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/login", h.Login)
+		r.Post("/register", h.Register)
+		r.Get("/registration", h.GetRegistrationSettings)
+		r.Post("/register/email", h.RequestRegistrationEmail)
+		r.Post("/register/invitation", h.PreviewInvitation)
+		r.Post("/password-reset", h.RequestPasswordReset)
+		r.Post("/password-reset/confirm", h.ConfirmPasswordReset)
+		r.Post("/login/two-factor", h.LoginTwoFactor)
+		r.Get("/sso", h.GetSSOStatus)
+		r.Get("/sso/login", h.StartSSOLogin)
+		r.Get("/sso/callback", h.SSOCallback)
+		r.Post("/sso/complete", h.CompleteSSOLogin)
+	})
+
+	// Two-factor setup, available before it becomes mandatory for the role
+	r.Group(func(r chi.Router) {
+		r.Use(h.AuthMiddleware)
+		r.Route("/me/two-factor", func(r chi.Router) {
+			r.Get("/", h.GetTwoFactorStatus)
+			r.Post("/setup", h.SetupTwoFactor)
+			r.Post("/enable", h.EnableTwoFactor)
+			r.Post("/disable", h.DisableTwoFactor)
+			r.Post("/recovery-codes", h.RegenerateRecoveryCodes)
+		})
+	})
+
+	// Reservation calendars: calendar apps cannot send the Authorization
+	// header, so a personal token may be passed in the query string
+	r.Group(func(r chi.Router) {
+		r.Use(h.CalendarAuthMiddleware)
+		r.Use(h.RequireTwoFactor)
+		r.Get("/assets/{id}/reservations.ics", h.GetAssetCalendar)
+		r.Get("/employees/{id}/reservations.ics", h.GetEmployeeCalendar)
+	})
 
 	// Protected API routes
-	r.Route("/api", func(r chi.Router) {
+	r.Group(func(r chi.Router) {
 		r.Use(h.AuthMiddleware)
+		r.Use(h.RequireTwoFactor)
 		r.Use(func(next http.Handler) http.Handler {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				if r.Method == "OPTIONS" {
@@ -55,23 +204,49 @@ func NewRouter(h *handlers.Handler) *chi.Mux {
 				next.ServeHTTP(w, r)
 			})
 		})
+		// Live updates
+		r.Get("/events", h.StreamEvents)
+
 		// Assets
 		r.Route("/assets", func(r chi.Router) {
 			r.Get("/", h.GetAllAssets)
 			r.Post("/", h.CreateAsset)
 			r.Get("/{id}", h.GetAsset)
 			r.Put("/{id}", h.UpdateAsset)
+			r.Patch("/{id}", h.PatchAsset)
 			r.Delete("/{id}", h.DeleteAsset)
+			r.Post("/{id}/restore", h.RestoreAsset)
 			r.Get("/{id}/transfers", h.GetAssetTransfers)
+			r.Post("/{id}/components", h.AttachAssetComponent)
+			r.Delete("/{id}/components/{componentID}", h.DetachAssetComponent)
+		})
+
+		// Categories
+		r.Route("/categories", func(r chi.Router) {
+			r.Get("/", h.GetAllCategories)
+			r.Post("/", h.CreateCategory)
+			r.Get("/tree", h.GetCategoryTree)
+			r.Get("/duplicates", h.GetDuplicateCategories)
+			r.Get("/{id}", h.GetCategory)
+			r.Put("/{id}", h.UpdateCategory)
+			r.Delete("/{id}", h.DeleteCategory)
+			r.Get("/{id}/fields", h.GetCategoryFields)
+			r.Post("/{id}/merge", h.MergeCategory)
 		})
 
 		// Employees
 		r.Route("/employees", func(r chi.Router) {
 			r.Get("/", h.GetAllEmployees)
-			r.Post("/", h.CreateEmployee)
+			r.With(h.RequireAdmin).Post("/", h.CreateEmployee)
 			r.Get("/{id}", h.GetEmployee)
-			r.Put("/{id}", h.UpdateEmployee)
+			r.With(h.RequireAdmin).Put("/{id}", h.UpdateEmployee)
+			r.With(h.RequireAdmin).Patch("/{id}", h.PatchEmployee)
 			r.Delete("/{id}", h.DeleteEmployee)
+			r.Post("/{id}/restore", h.RestoreEmployee)
+			r.With(h.RequireAdmin).Post("/{id}/unlock", h.UnlockEmployeeLogin)
+			r.With(h.RequireAdmin).Post("/{id}/two-factor/reset", h.ResetEmployeeTwoFactor)
+			r.With(h.RequireAdmin).Get("/{id}/api-tokens", h.GetEmployeeAPITokens)
+			r.With(h.RequireAdmin).Delete("/{id}/api-tokens/{tokenID}", h.RevokeEmployeeAPIToken)
 		})
 
 		// Departments
@@ -80,7 +255,9 @@ func NewRouter(h *handlers.Handler) *chi.Mux {
 			r.Post("/", h.CreateDepartment)
 			r.Get("/{id}", h.GetDepartment)
 			r.Put("/{id}", h.UpdateDepartment)
+			r.Patch("/{id}", h.PatchDepartment)
 			r.Delete("/{id}", h.DeleteDepartment)
+			r.Post("/{id}/restore", h.RestoreDepartment)
 			r.Get("/{id}/employees", h.GetDepartmentEmployees)
 		})
 
@@ -90,7 +267,9 @@ func NewRouter(h *handlers.Handler) *chi.Mux {
 			r.Post("/", h.CreateLocation)
 			r.Get("/{id}", h.GetLocation)
 			r.Put("/{id}", h.UpdateLocation)
+			r.Patch("/{id}", h.PatchLocation)
 			r.Delete("/{id}", h.DeleteLocation)
+			r.Post("/{id}/restore", h.RestoreLocation)
 		})
 
 		// Reports
@@ -99,6 +278,7 @@ func NewRouter(h *handlers.Handler) *chi.Mux {
 			r.Get("/transfers", h.GetTransfersReport)
 			r.Get("/department-costs", h.GetDepartmentCostsReport)
 			r.Get("/inventory", h.GetInventoryReport)
+			r.Get("/categories", h.GetCategoryReport)
 		})
 
 		// Transfers
@@ -108,18 +288,72 @@ func NewRouter(h *handlers.Handler) *chi.Mux {
 			r.Get("/{id}", h.GetTransfer)
 		})
 
-		r.Get("/", h.ServeIndex)
-		r.Get("/index", h.ServeIndex)
-		r.Get("/index.html", h.ServeIndex)
-	})
-	// Логирование роутов
-	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
-		log.Printf("%s %s\n", method, route)
-		return nil
-	}
+		// Reservations
+		r.Route("/reservations", func(r chi.Router) {
+			r.Get("/", h.GetAllReservations)
+			r.Post("/", h.CreateReservation)
+			r.Get("/availability", h.GetAvailableAssets)
+			r.Get("/{id}", h.GetReservation)
+			r.Post("/{id}/approve", h.ApproveReservation)
+			r.Post("/{id}/reject", h.RejectReservation)
+			r.Post("/{id}/cancel", h.CancelReservation)
+		})
 
-	if err := chi.Walk(r, walkFunc); err != nil {
-		log.Printf("Logging err: %s\n", err.Error())
-	}
-	return r
+		// Invitations
+		r.Route("/invitations", func(r chi.Router) {
+			r.Use(h.RequireAdmin)
+			r.Get("/", h.GetAllInvitations)
+			r.Post("/", h.CreateInvitation)
+			r.Get("/{id}", h.GetInvitation)
+			r.Delete("/{id}", h.RevokeInvitation)
+			r.Post("/{id}/resend", h.ResendInvitation)
+		})
+
+		// Webhooks
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(h.RequireAdmin)
+			r.Get("/", h.GetAllWebhooks)
+			r.Post("/", h.CreateWebhook)
+			r.Get("/{id}", h.GetWebhook)
+			r.Put("/{id}", h.UpdateWebhook)
+			r.Delete("/{id}", h.DeleteWebhook)
+			r.Get("/{id}/deliveries", h.GetWebhookDeliveries)
+			r.Post("/{id}/deliveries/{deliveryID}/retry", h.RetryWebhookDelivery)
+		})
+
+		// Organizations of the group and consolidated reports (super admins)
+		r.Route("/organizations", func(r chi.Router) {
+			r.Use(h.RequireSuperAdmin)
+			r.Get("/", h.GetAllOrganizations)
+			r.Post("/", h.CreateOrganization)
+			r.Get("/{id}", h.GetOrganization)
+			r.Put("/{id}", h.UpdateOrganization)
+			r.Post("/{id}/invitations", h.CreateOrganizationInvitation)
+		})
+		r.Route("/group/reports", func(r chi.Router) {
+			r.Use(h.RequireSuperAdmin)
+			r.Get("/inventory", h.GetGroupInventoryReport)
+			r.Get("/assets-by-status", h.GetGroupAssetsByStatusReport)
+		})
+
+		// Failed login audit
+		r.With(h.RequireAdmin).Get("/login-failures", h.GetLoginFailures)
+
+		// Current employee
+		r.Route("/me", func(r chi.Router) {
+			r.Get("/", h.GetMe)
+			r.Patch("/", h.PatchMe)
+			r.Post("/password", h.ChangeMyPassword)
+			r.Get("/assets", h.GetMyAssets)
+			r.Get("/transfers", h.GetMyTransfers)
+			r.Get("/notification-preferences", h.GetNotificationPreferences)
+			r.Put("/notification-preferences", h.UpdateNotificationPreferences)
+			r.Get("/notifications", h.GetNotifications)
+			r.Post("/notifications/read-all", h.MarkAllNotificationsRead)
+			r.Post("/notifications/{id}/read", h.MarkNotificationRead)
+			r.Get("/api-tokens", h.GetMyAPITokens)
+			r.Post("/api-tokens", h.CreateMyAPIToken)
+			r.Delete("/api-tokens/{id}", h.RevokeMyAPIToken)
+		})
+	})
 }
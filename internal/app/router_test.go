@@ -0,0 +1,88 @@
+package app
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/handlers"
+)
+
+func TestMain(m *testing.M) {
+	// NewRouter logs every route
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestRouter собирает маршруты с обработчиками без сервисов: запросы,
+// которые доходят до сервисов, в этих тестах не выполняются
+func newTestRouter(legacy Deprecation) *chi.Mux {
+	h := handlers.NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	return NewRouter(h, legacy)
+}
+
+func TestRouterMountsVersionAndLegacyAlias(t *testing.T) {
+	legacy := Deprecation{
+		At:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	r := newTestRouter(legacy)
+
+	for _, target := range []string{"/api/v1/assets", "/api/assets", "/api/v1/employees/5", "/api/employees/5"} {
+		rec := serve(t, r, http.MethodGet, target)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s = %d, want %d", target, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	for _, target := range []string{"/api/v1/openapi.json", "/api/openapi.json"} {
+		rec := serve(t, r, http.MethodGet, target)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want %d", target, rec.Code, http.StatusOK)
+		}
+	}
+
+	if rec := serve(t, r, http.MethodGet, "/api/v1/no-such-route"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown route = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestRouterLegacyAliasIsDeprecated(t *testing.T) {
+	legacy := Deprecation{
+		At:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	r := newTestRouter(legacy)
+
+	rec := serve(t, r, http.MethodGet, "/api/assets/5")
+	if got := rec.Header().Get("Deprecation"); got != "@1767225600" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != "Thu, 31 Dec 2026 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</api/v1/assets/5>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+
+	rec = serve(t, r, http.MethodGet, "/api/v1/assets/5")
+	for _, header := range []string{"Deprecation", "Sunset", "Link"} {
+		if got := rec.Header().Get(header); got != "" {
+			t.Errorf("/api/v1 response has %s: %q", header, got)
+		}
+	}
+}
+
+func TestRouterLegacyAliasWithoutDates(t *testing.T) {
+	rec := serve(t, newTestRouter(Deprecation{}), http.MethodGet, "/api/assets")
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation = %q, want true", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != "" {
+		t.Errorf("Sunset = %q, want none", got)
+	}
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// apiVersion описывает версию API, смонтированную под собственным префиксом.
+// Версия получает все маршруты API, а handlers заменяют обработчики только
+// изменившихся ресурсов. Ключ — метод и шаблон пути относительно префикса:
+//
+//	apiVersion{prefix: "/api/v2", handlers: map[string]http.HandlerFunc{
+//		"GET /assets/{id}": h.GetAssetV2,
+//	}}
+type apiVersion struct {
+	prefix   string
+	handlers map[string]http.HandlerFunc
+}
+
+// Deprecation задает сроки вывода из эксплуатации маршрутов без версии
+// (/api/...). Ответы на них получают заголовки Deprecation (RFC 9745),
+// Sunset (RFC 8594) и ссылку на ту же операцию в /api/v1
+type Deprecation struct {
+	At     time.Time
+	Sunset time.Time
+}
+
+// legacyPrefix — префикс маршрутов API без версии
+const legacyPrefix = "/api"
+
+// currentPrefix — префикс версии, которой соответствуют маршруты без версии
+const currentPrefix = "/api/v1"
+
+// deprecated добавляет к ответам заголовки об устаревании маршрутов
+func deprecated(d Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !d.At.IsZero() {
+				w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.At.Unix(), 10))
+			} else {
+				w.Header().Set("Deprecation", "true")
+			}
+			if !d.Sunset.IsZero() {
+				w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			successor := currentPrefix + strings.TrimPrefix(r.URL.Path, legacyPrefix)
+			w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// versionedRouter подменяет обработчики маршрутов обработчиками версии.
+// Маршруты описываются как обычно, через методы chi.Router
+type versionedRouter struct {
+	chi.Router
+	base     string
+	handlers map[string]http.HandlerFunc
+	used     map[string]bool
+}
+
+func newVersionedRouter(r chi.Router, version apiVersion) *versionedRouter {
+	return &versionedRouter{
+		Router:   r,
+		handlers: version.handlers,
+		used:     make(map[string]bool),
+	}
+}
+
+func (v *versionedRouter) sub(r chi.Router, pattern string) *versionedRouter {
+	return &versionedRouter{
+		Router:   r,
+		base:     v.base + pattern,
+		handlers: v.handlers,
+		used:     v.used,
+	}
+}
+
+// resolve возвращает обработчик версии для маршрута, если он задан
+func (v *versionedRouter) resolve(method, pattern string, handler http.Handler) http.Handler {
+	key := strings.ToUpper(method) + " " + specPath(v.base+pattern)
+	if override, ok := v.handlers[key]; ok {
+		v.used[key] = true
+		return override
+	}
+	return handler
+}
+
+// unused перечисляет обработчики версии, для которых не нашлось маршрута
+func (v *versionedRouter) unused() []string {
+	var keys []string
+	for key := range v.handlers {
+		if !v.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *versionedRouter) With(middlewares ...func(http.Handler) http.Handler) chi.Router {
+	return v.sub(v.Router.With(middlewares...), "")
+}
+
+func (v *versionedRouter) Route(pattern string, fn func(r chi.Router)) chi.Router {
+	return v.Router.Route(pattern, func(r chi.Router) {
+		fn(v.sub(r, pattern))
+	})
+}
+
+func (v *versionedRouter) Group(fn func(r chi.Router)) chi.Router {
+	return v.Router.Group(func(r chi.Router) {
+		fn(v.sub(r, ""))
+	})
+}
+
+// Mount подключает handler целиком. Подменить обработчики внутри него
+// нельзя, поэтому обработчик версии под pattern считается ошибкой описания
+// версии
+func (v *versionedRouter) Mount(pattern string, handler http.Handler) {
+	prefix := specPath(v.base + pattern)
+	for key := range v.handlers {
+		_, path, _ := strings.Cut(key, " ")
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			panic(fmt.Sprintf("versioned handler %s is under mounted %s", key, prefix))
+		}
+	}
+	v.Router.Mount(pattern, handler)
+}
+
+// Handle регистрирует handler для всех методов. Методы, для которых у
+// версии есть свой обработчик, получают его. Шаблон вида "GET /path"
+// регистрирует только указанный метод, как и в chi
+func (v *versionedRouter) Handle(pattern string, handler http.Handler) {
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		v.Method(method, strings.TrimSpace(path), handler)
+		return
+	}
+
+	v.Router.Handle(pattern, handler)
+	path := specPath(v.base + pattern)
+	for key, override := range v.handlers {
+		method, overridden, _ := strings.Cut(key, " ")
+		if overridden == path {
+			v.used[key] = true
+			v.Router.Method(method, pattern, override)
+		}
+	}
+}
+
+func (v *versionedRouter) HandleFunc(pattern string, handler http.HandlerFunc) {
+	v.Handle(pattern, handler)
+}
+
+func (v *versionedRouter) Method(method, pattern string, handler http.Handler) {
+	v.Router.Method(method, pattern, v.resolve(method, pattern, handler))
+}
+
+func (v *versionedRouter) MethodFunc(method, pattern string, handler http.HandlerFunc) {
+	v.Method(method, pattern, handler)
+}
+
+func (v *versionedRouter) Get(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodGet, pattern, handler)
+}
+
+func (v *versionedRouter) Post(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodPost, pattern, handler)
+}
+
+func (v *versionedRouter) Put(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodPut, pattern, handler)
+}
+
+func (v *versionedRouter) Patch(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodPatch, pattern, handler)
+}
+
+func (v *versionedRouter) Delete(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodDelete, pattern, handler)
+}
+
+func (v *versionedRouter) Head(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodHead, pattern, handler)
+}
+
+func (v *versionedRouter) Options(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodOptions, pattern, handler)
+}
+
+func (v *versionedRouter) Connect(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodConnect, pattern, handler)
+}
+
+func (v *versionedRouter) Trace(pattern string, handler http.HandlerFunc) {
+	v.Method(http.MethodTrace, pattern, handler)
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func text(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}
+}
+
+func serve(t *testing.T, h http.Handler, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestVersionedRouterReplacesHandlers(t *testing.T) {
+	version := apiVersion{prefix: "/api/v2", handlers: map[string]http.HandlerFunc{
+		"GET /things/{id}":   text("v2 get"),
+		"POST /things":       text("v2 create"),
+		"PUT /things/{id}":   text("v2 replace"),
+		"DELETE /all":        text("v2 delete all"),
+		"PATCH /direct/{id}": text("v2 patch"),
+	}}
+
+	var marked bool
+	mark := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			marked = true
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	r := chi.NewRouter()
+	vr := newVersionedRouter(r, version)
+	vr.Route("/things", func(r chi.Router) {
+		r.Get("/", text("v1 list"))
+		r.With(mark).Get("/{id}", text("v1 get"))
+		r.With(mark).Post("/", text("v1 create"))
+		r.Group(func(r chi.Router) {
+			r.With(mark).With(mark).Put("/{id}", text("v1 replace"))
+		})
+	})
+	vr.Handle("/all", text("v1 all"))
+	vr.HandleFunc("PATCH /direct/{id}", text("v1 patch"))
+
+	if unused := vr.unused(); len(unused) > 0 {
+		t.Fatalf("unused handlers: %v", unused)
+	}
+
+	tests := []struct {
+		method, target, want string
+		middleware           bool
+	}{
+		{http.MethodGet, "/things", "v1 list", false},
+		{http.MethodGet, "/things/1", "v2 get", true},
+		{http.MethodPost, "/things", "v2 create", true},
+		{http.MethodPut, "/things/1", "v2 replace", true},
+		{http.MethodGet, "/all", "v1 all", false},
+		{http.MethodDelete, "/all", "v2 delete all", false},
+		{http.MethodPatch, "/direct/1", "v2 patch", false},
+	}
+	for _, tt := range tests {
+		marked = false
+		rec := serve(t, r, tt.method, tt.target)
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+		if marked != tt.middleware {
+			t.Errorf("%s %s: middleware ran = %v, want %v", tt.method, tt.target, marked, tt.middleware)
+		}
+	}
+}
+
+func TestVersionedRouterReportsUnusedHandlers(t *testing.T) {
+	version := apiVersion{prefix: "/api/v2", handlers: map[string]http.HandlerFunc{
+		"GET /missing": text("v2"),
+	}}
+
+	vr := newVersionedRouter(chi.NewRouter(), version)
+	vr.Get("/present", text("v1"))
+
+	if unused := vr.unused(); len(unused) != 1 || unused[0] != "GET /missing" {
+		t.Errorf("unused = %v", unused)
+	}
+}
+
+func TestVersionedRouterMountRejectsHandlersInside(t *testing.T) {
+	version := apiVersion{prefix: "/api/v2", handlers: map[string]http.HandlerFunc{
+		"GET /files/{name}": text("v2"),
+	}}
+
+	vr := newVersionedRouter(chi.NewRouter(), version)
+	vr.Mount("/other", http.NotFoundHandler())
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Mount over a versioned handler did not panic")
+		}
+	}()
+	vr.Mount("/files", http.NotFoundHandler())
+}
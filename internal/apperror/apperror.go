@@ -0,0 +1,112 @@
+// Package apperror описывает ошибки предметной области. Сервисы и
+// репозитории сообщают через них вид ошибки, а обработчики по виду
+// выбирают HTTP-статус ответа
+package apperror
+
+import (
+	"errors"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindInternal           Kind = "internal"
+	KindNotFound           Kind = "not_found"
+	KindValidation         Kind = "validation"
+	KindConflict           Kind = "conflict"
+	KindForbidden          Kind = "forbidden"
+	KindUnauthorized       Kind = "unauthorized"
+	KindPreconditionFailed Kind = "precondition_failed"
+	KindTooManyRequests    Kind = "too_many_requests"
+)
+
+// Error — ошибка с видом, сообщением для клиента и, для ошибок проверки,
+// описанием ошибок по отдельным полям
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  map[string]string
+	Err     error
+	// RetryAfter — для KindTooManyRequests: через сколько можно повторить
+	// запрос
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors возвращает ошибки по полям запроса
+func (e *Error) FieldErrors() map[string]string {
+	return e.Fields
+}
+
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Validation создает ошибку проверки входных данных. fields может быть nil
+func Validation(message string, fields map[string]string) *Error {
+	return &Error{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: message}
+}
+
+// TooManyRequests создает ошибку превышения частоты запросов. Повторить
+// запрос можно через retryAfter
+func TooManyRequests(message string, retryAfter time.Duration) *Error {
+	return &Error{Kind: KindTooManyRequests, Message: message, RetryAfter: retryAfter}
+}
+
+// Wrap создает ошибку вида kind, сохраняя err в цепочке для errors.Is и
+// errors.As
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// KindOf возвращает вид первой ошибки Error в цепочке err. Ошибки, не
+// описанные через этот пакет, считаются внутренними
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// RetryAfterOf возвращает RetryAfter первой ошибки Error в цепочке err
+func RetryAfterOf(err error) time.Duration {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.RetryAfter
+	}
+	return 0
+}
+
+// FieldsOf возвращает ошибки по полям из цепочки err, если они есть
+func FieldsOf(err error) map[string]string {
+	var fielded interface{ FieldErrors() map[string]string }
+	if errors.As(err, &fielded) {
+		return fielded.FieldErrors()
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,6 +15,9 @@ import (
 type Config struct {
 	Server struct {
 		Address string
+		// Env — окружение: development или production. В production
+		// приложение не запускается с небезопасными настройками
+		Env string
 	}
 	Database struct {
 		Host     string
@@ -20,9 +27,122 @@ type Config struct {
 		Name     string
 		SSLMode  string
 	}
+	Organizations struct {
+		// DefaultCode — код организации, в которую попадают сотрудники с
+		// адресами из доменов, не закрепленных ни за одной организацией
+		DefaultCode string
+		// RowLevelSecurity включает передачу организации запроса в
+		// PostgreSQL для политик из migrations/019_row_level_security.sql.
+		// Включен по умолчанию: без него политики скрывают все строки от
+		// роли без BYPASSRLS
+		RowLevelSecurity bool
+	}
 	Auth struct {
-		SecretKey   string
-		TokenExpiry time.Duration
+		SecretKey     string
+		TokenExpiry   time.Duration
+		ResetTokenTTL time.Duration
+		// TwoFactorRoles — роли, которым двухфакторная аутентификация
+		// обязательна
+		TwoFactorRoles  []string
+		TwoFactorIssuer string
+		// Providers — провайдеры проверки пароля в порядке опроса:
+		// password (локальные учетные записи) и ldap
+		Providers []string
+		// SigningAlgorithm — алгоритм подписи токенов доступа: RS256 или
+		// EdDSA. Ключ меняется раз в KeyRotationInterval, новый ключ
+		// публикуется за KeyPrepublish до начала подписи; набор ключей
+		// перечитывается из базы раз в KeyRefreshInterval
+		SigningAlgorithm    string
+		KeyRotationInterval time.Duration
+		KeyPrepublish       time.Duration
+		KeyRefreshInterval  time.Duration
+	}
+	Registration struct {
+		// Mode — режим регистрации: disabled, invite или domain. В режиме
+		// domain ссылку регистрации можно запросить на адрес из Domains
+		Mode          string
+		Domains       []string
+		InvitationTTL time.Duration
+	}
+	APITokens struct {
+		// DefaultTTL — срок персонального токена, если он не указан при
+		// создании; MaxTTL — наибольший допустимый срок
+		DefaultTTL time.Duration
+		MaxTTL     time.Duration
+	}
+	LDAP struct {
+		URL          string
+		StartTLS     bool
+		BindDN       string
+		BindPassword string
+		BaseDN       string
+		// UserFilter — фильтр поиска сотрудника; {login} заменяется
+		// введенным логином
+		UserFilter string
+		Timeout    time.Duration
+		// Атрибуты каталога, из которых берутся данные сотрудника
+		AttrID         string
+		AttrEmail      string
+		AttrFullName   string
+		AttrPosition   string
+		AttrDepartment string
+		AttrGroups     string
+		// RoleGroups сопоставляет DN группы каталога роли; DepartmentMap —
+		// отдел в каталоге названию отдела в приложении
+		RoleGroups    map[string]string
+		DepartmentMap map[string]string
+	}
+	Login struct {
+		IPBurst            int
+		IPInterval         time.Duration
+		AccountBurst       int
+		AccountInterval    time.Duration
+		LockoutThreshold   int
+		LockoutDuration    time.Duration
+		MaxLockoutDuration time.Duration
+	}
+	OIDC struct {
+		// Issuer — адрес провайдера OpenID Connect; пустой отключает
+		// единый вход
+		Issuer       string
+		ClientID     string
+		ClientSecret string
+		RedirectURL  string
+		Scopes       []string
+	}
+	Retention struct {
+		Period   time.Duration
+		Interval time.Duration
+	}
+	API struct {
+		LegacyDeprecatedAt time.Time
+		LegacySunset       time.Time
+	}
+	Events struct {
+		HistorySize int
+	}
+	Webhooks struct {
+		Interval    time.Duration
+		Timeout     time.Duration
+		MaxAttempts int
+		RetryDelay  time.Duration
+	}
+	Mail struct {
+		Driver       string
+		From         string
+		Dir          string
+		SMTPHost     string
+		SMTPPort     int
+		SMTPUsername string
+		SMTPPassword string
+		Interval     time.Duration
+		MaxAttempts  int
+		RetryDelay   time.Duration
+	}
+	Notifications struct {
+		BaseURL          string
+		WarrantyDays     int
+		WarrantyInterval time.Duration
 	}
 }
 
@@ -36,6 +156,7 @@ func Load() (*Config, error) {
 
 	// Server config
 	cfg.Server.Address = getEnv("SERVER_ADDRESS", ":8080")
+	cfg.Server.Env = getEnv("APP_ENV", "development")
 
 	// Database config
 	cfg.Database.Host = getEnv("DB_HOST", "localhost")
@@ -45,6 +166,14 @@ func Load() (*Config, error) {
 	cfg.Database.Name = getEnv("DB_NAME", "inventory")
 	cfg.Database.SSLMode = getEnv("DB_SSLMODE", "disable")
 
+	// Organizations config
+	cfg.Organizations.DefaultCode = getEnv("DEFAULT_ORGANIZATION", "default")
+	rowLevelSecurity, err := strconv.ParseBool(getEnv("DB_ROW_LEVEL_SECURITY", "true"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Organizations.RowLevelSecurity = rowLevelSecurity
+
 	// Auth config
 	cfg.Auth.SecretKey = getEnv("AUTH_SECRET_KEY", "secret-key")
 	tokenExpiry, err := time.ParseDuration(getEnv("AUTH_TOKEN_EXPIRY", "24h"))
@@ -53,9 +182,335 @@ func Load() (*Config, error) {
 	}
 	cfg.Auth.TokenExpiry = tokenExpiry
 
+	resetTokenTTL, err := time.ParseDuration(getEnv("AUTH_RESET_TOKEN_TTL", "1h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Auth.ResetTokenTTL = resetTokenTTL
+
+	cfg.Auth.TwoFactorRoles = splitList(getEnv("AUTH_TWO_FACTOR_ROLES", "superadmin,admin,manager"))
+	cfg.Auth.TwoFactorIssuer = getEnv("AUTH_TWO_FACTOR_ISSUER", "Inventory System")
+	cfg.Auth.Providers = splitList(getEnv("AUTH_PROVIDERS", "password"))
+
+	// Access token signing keys: a new key is published in JWKS before it
+	// starts signing, so the refresh interval must be shorter than that
+	cfg.Auth.SigningAlgorithm = getEnv("AUTH_SIGNING_ALGORITHM", "RS256")
+	if cfg.Auth.SigningAlgorithm != "RS256" && cfg.Auth.SigningAlgorithm != "EdDSA" {
+		return nil, fmt.Errorf("AUTH_SIGNING_ALGORITHM must be RS256 or EdDSA, got %q", cfg.Auth.SigningAlgorithm)
+	}
+
+	keyRotationInterval, err := time.ParseDuration(getEnv("AUTH_KEY_ROTATION_INTERVAL", "720h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Auth.KeyRotationInterval = keyRotationInterval
+
+	keyPrepublish, err := time.ParseDuration(getEnv("AUTH_KEY_PREPUBLISH", "24h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Auth.KeyPrepublish = keyPrepublish
+
+	keyRefreshInterval, err := time.ParseDuration(getEnv("AUTH_KEY_REFRESH_INTERVAL", "5m"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Auth.KeyRefreshInterval = keyRefreshInterval
+
+	if keyRefreshInterval <= 0 || keyRefreshInterval >= keyPrepublish || keyPrepublish >= keyRotationInterval {
+		return nil, errors.New("signing key intervals must satisfy 0 < AUTH_KEY_REFRESH_INTERVAL < AUTH_KEY_PREPUBLISH < AUTH_KEY_ROTATION_INTERVAL")
+	}
+
+	// Registration config: REGISTRATION_DOMAINS is used in the domain mode
+	cfg.Registration.Mode = getEnv("REGISTRATION_MODE", "invite")
+	cfg.Registration.Domains = splitList(getEnv("REGISTRATION_DOMAINS", ""))
+	switch cfg.Registration.Mode {
+	case "disabled", "invite":
+	case "domain":
+		if len(cfg.Registration.Domains) == 0 {
+			return nil, errors.New("REGISTRATION_DOMAINS is required when REGISTRATION_MODE is domain")
+		}
+	default:
+		return nil, fmt.Errorf("REGISTRATION_MODE must be disabled, invite or domain, got %q", cfg.Registration.Mode)
+	}
+
+	invitationTTL, err := time.ParseDuration(getEnv("INVITATION_TTL", "168h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Registration.InvitationTTL = invitationTTL
+
+	// Personal API tokens
+	apiTokenDefaultTTL, err := time.ParseDuration(getEnv("API_TOKEN_DEFAULT_TTL", "2160h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.APITokens.DefaultTTL = apiTokenDefaultTTL
+
+	apiTokenMaxTTL, err := time.ParseDuration(getEnv("API_TOKEN_MAX_TTL", "8760h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.APITokens.MaxTTL = apiTokenMaxTTL
+
+	// LDAP config: LDAP_ROLE_GROUPS is "<group DN>=<role>;...",
+	// LDAP_DEPARTMENT_MAP is "<directory department>=<department name>;..."
+	cfg.LDAP.URL = getEnv("LDAP_URL", "ldap://localhost:389")
+	ldapStartTLS, err := strconv.ParseBool(getEnv("LDAP_START_TLS", "false"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.LDAP.StartTLS = ldapStartTLS
+	cfg.LDAP.BindDN = getEnv("LDAP_BIND_DN", "")
+	cfg.LDAP.BindPassword = getEnv("LDAP_BIND_PASSWORD", "")
+	cfg.LDAP.BaseDN = getEnv("LDAP_BASE_DN", "")
+	cfg.LDAP.UserFilter = getEnv("LDAP_USER_FILTER", "(&(objectClass=person)(|(uid={login})(sAMAccountName={login})(mail={login})))")
+
+	ldapTimeout, err := time.ParseDuration(getEnv("LDAP_TIMEOUT", "5s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.LDAP.Timeout = ldapTimeout
+
+	cfg.LDAP.AttrID = getEnv("LDAP_ATTR_ID", "")
+	cfg.LDAP.AttrEmail = getEnv("LDAP_ATTR_EMAIL", "mail")
+	cfg.LDAP.AttrFullName = getEnv("LDAP_ATTR_FULL_NAME", "displayName")
+	cfg.LDAP.AttrPosition = getEnv("LDAP_ATTR_POSITION", "title")
+	cfg.LDAP.AttrDepartment = getEnv("LDAP_ATTR_DEPARTMENT", "department")
+	cfg.LDAP.AttrGroups = getEnv("LDAP_ATTR_GROUPS", "memberOf")
+	cfg.LDAP.RoleGroups = splitMap(getEnv("LDAP_ROLE_GROUPS", ""))
+	cfg.LDAP.DepartmentMap = splitMap(getEnv("LDAP_DEPARTMENT_MAP", ""))
+
+	// Login protection config: LOGIN_*_BURST attempts in a row, then one per
+	// LOGIN_*_INTERVAL
+	loginIPBurst, err := strconv.Atoi(getEnv("LOGIN_IP_BURST", "20"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Login.IPBurst = loginIPBurst
+
+	loginIPInterval, err := time.ParseDuration(getEnv("LOGIN_IP_INTERVAL", "6s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Login.IPInterval = loginIPInterval
+
+	loginAccountBurst, err := strconv.Atoi(getEnv("LOGIN_ACCOUNT_BURST", "5"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Login.AccountBurst = loginAccountBurst
+
+	loginAccountInterval, err := time.ParseDuration(getEnv("LOGIN_ACCOUNT_INTERVAL", "30s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Login.AccountInterval = loginAccountInterval
+
+	lockoutThreshold, err := strconv.Atoi(getEnv("LOGIN_LOCKOUT_THRESHOLD", "5"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Login.LockoutThreshold = lockoutThreshold
+
+	lockoutDuration, err := time.ParseDuration(getEnv("LOGIN_LOCKOUT_DURATION", "1m"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Login.LockoutDuration = lockoutDuration
+
+	maxLockoutDuration, err := time.ParseDuration(getEnv("LOGIN_LOCKOUT_MAX_DURATION", "24h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Login.MaxLockoutDuration = maxLockoutDuration
+
+	// Retention config
+	retentionPeriod, err := time.ParseDuration(getEnv("RETENTION_PERIOD", "2160h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Retention.Period = retentionPeriod
+
+	retentionInterval, err := time.ParseDuration(getEnv("RETENTION_INTERVAL", "24h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Retention.Interval = retentionInterval
+
+	// API versioning config: dates for unversioned /api routes
+	legacyDeprecatedAt, err := time.Parse("2006-01-02", getEnv("API_LEGACY_DEPRECATED_AT", "2026-11-01"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.API.LegacyDeprecatedAt = legacyDeprecatedAt
+
+	legacySunset, err := time.Parse("2006-01-02", getEnv("API_LEGACY_SUNSET", "2027-05-01"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.API.LegacySunset = legacySunset
+
+	// Events config: how many recent events are kept for reconnecting clients
+	eventHistorySize, err := strconv.Atoi(getEnv("EVENTS_HISTORY_SIZE", "1000"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Events.HistorySize = eventHistorySize
+
+	// Webhooks config
+	webhookInterval, err := time.ParseDuration(getEnv("WEBHOOK_INTERVAL", "10s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Webhooks.Interval = webhookInterval
+
+	webhookTimeout, err := time.ParseDuration(getEnv("WEBHOOK_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Webhooks.Timeout = webhookTimeout
+
+	webhookMaxAttempts, err := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Webhooks.MaxAttempts = webhookMaxAttempts
+
+	webhookRetryDelay, err := time.ParseDuration(getEnv("WEBHOOK_RETRY_DELAY", "30s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Webhooks.RetryDelay = webhookRetryDelay
+
+	// Mail config: MAIL_DRIVER is smtp, file (MAIL_DIR) or log. The log
+	// driver is for development only and is refused in production
+	cfg.Mail.Driver = getEnv("MAIL_DRIVER", "log")
+	cfg.Mail.From = getEnv("MAIL_FROM", "Inventory System <inventory@localhost>")
+	cfg.Mail.Dir = getEnv("MAIL_DIR", "./mail")
+	cfg.Mail.SMTPHost = getEnv("SMTP_HOST", "localhost")
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "25"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Mail.SMTPPort = smtpPort
+	cfg.Mail.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	cfg.Mail.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+
+	mailInterval, err := time.ParseDuration(getEnv("MAIL_INTERVAL", "10s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Mail.Interval = mailInterval
+
+	mailMaxAttempts, err := strconv.Atoi(getEnv("MAIL_MAX_ATTEMPTS", "6"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Mail.MaxAttempts = mailMaxAttempts
+
+	mailRetryDelay, err := time.ParseDuration(getEnv("MAIL_RETRY_DELAY", "1m"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Mail.RetryDelay = mailRetryDelay
+
+	// Notifications config: links in emails and warranty reminders
+	cfg.Notifications.BaseURL = getEnv("APP_BASE_URL", "http://localhost:8080")
+
+	// OIDC config: the redirect URL must be registered with the provider
+	cfg.OIDC.Issuer = getEnv("OIDC_ISSUER", "")
+	cfg.OIDC.ClientID = getEnv("OIDC_CLIENT_ID", "")
+	cfg.OIDC.ClientSecret = getEnv("OIDC_CLIENT_SECRET", "")
+	cfg.OIDC.RedirectURL = getEnv("OIDC_REDIRECT_URL", strings.TrimSuffix(cfg.Notifications.BaseURL, "/")+"/api/v1/auth/sso/callback")
+	cfg.OIDC.Scopes = splitList(getEnv("OIDC_SCOPES", "openid,email,profile"))
+	warrantyDays, err := strconv.Atoi(getEnv("NOTIFY_WARRANTY_DAYS", "30"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Notifications.WarrantyDays = warrantyDays
+
+	warrantyInterval, err := time.ParseDuration(getEnv("NOTIFY_WARRANTY_INTERVAL", "1h"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Notifications.WarrantyInterval = warrantyInterval
+
+	if cfg.Server.Env == "production" {
+		if err := checkSecretKey(cfg.Auth.SecretKey); err != nil {
+			return nil, err
+		}
+		if err := checkMailDriver(cfg.Mail.Driver); err != nil {
+			return nil, err
+		}
+	}
+
 	return &cfg, nil
 }
 
+// minSecretKeyLength — наименьшая длина AUTH_SECRET_KEY в production:
+// 32 байта, как у ключа HMAC-SHA256
+const minSecretKeyLength = 32
+
+// weakSecretKeys — значения, которые встречаются в примерах настроек
+var weakSecretKeys = []string{"secret-key", "secret", "changeme", "change-me", "your-secret-key"}
+
+// checkSecretKey отклоняет секрет по умолчанию и слишком короткие секреты.
+// Секретом подписываются токены второго шага входа и состояние входа
+// через SSO
+func checkSecretKey(secret string) error {
+	for _, weak := range weakSecretKeys {
+		if strings.EqualFold(secret, weak) {
+			return errors.New("AUTH_SECRET_KEY must be changed from the default value in production")
+		}
+	}
+	if len(secret) < minSecretKeyLength {
+		return fmt.Errorf("AUTH_SECRET_KEY must be at least %d characters long in production", minSecretKeyLength)
+	}
+	return nil
+}
+
+// checkMailDriver отклоняет вывод писем в лог: в письмах есть ссылки для
+// сброса пароля и приглашения, и по ним из лога можно войти в чужую
+// учетную запись
+func checkMailDriver(driver string) error {
+	if driver == "log" {
+		return errors.New("MAIL_DRIVER=log is not allowed in production: it writes password reset and invitation links to the log")
+	}
+	return nil
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitMap разбирает пары "ключ=значение" через точку с запятой. Ключ
+// отделяется по последнему "=", поэтому может быть DN вида CN=...,DC=...
+func splitMap(value string) map[string]string {
+	items := make(map[string]string)
+	for _, item := range strings.Split(value, ";") {
+		key, val, ok := cutLast(item, "=")
+		if key, val = strings.TrimSpace(key), strings.TrimSpace(val); ok && key != "" && val != "" {
+			items[key] = val
+		}
+	}
+	return items
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
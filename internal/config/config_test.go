@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// productionSecret — секрет, который проходит checkSecretKey
+const productionSecret = "0123456789abcdef0123456789abcdef"
+
+func TestLoadRefusesLogMailerInProduction(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("AUTH_SECRET_KEY", productionSecret)
+
+	for _, driver := range []string{"", "log"} {
+		if driver != "" {
+			t.Setenv("MAIL_DRIVER", driver)
+		}
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), "MAIL_DRIVER") {
+			t.Errorf("MAIL_DRIVER=%q: err = %v, want MAIL_DRIVER error", driver, err)
+		}
+	}
+
+	t.Setenv("MAIL_DRIVER", "smtp")
+	if _, err := Load(); err != nil {
+		t.Errorf("MAIL_DRIVER=smtp: %v", err)
+	}
+
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("MAIL_DRIVER", "log")
+	if _, err := Load(); err != nil {
+		t.Errorf("MAIL_DRIVER=log in development: %v", err)
+	}
+}
+
+func TestLoadRefusesWeakSecretInProduction(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("MAIL_DRIVER", "smtp")
+	// t.Setenv restores AUTH_SECRET_KEY after the test, os.Unsetenv below
+	// removes it for the default value
+	t.Setenv("AUTH_SECRET_KEY", "")
+
+	tests := []struct {
+		name   string
+		secret string
+		set    bool
+	}{
+		{"default secret", "", false},
+		{"example secret", "changeme", true},
+		{"short secret", "0123456789", true},
+	}
+	for _, tt := range tests {
+		if tt.set {
+			t.Setenv("AUTH_SECRET_KEY", tt.secret)
+		} else {
+			os.Unsetenv("AUTH_SECRET_KEY")
+		}
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), "AUTH_SECRET_KEY") {
+			t.Errorf("%s: err = %v, want AUTH_SECRET_KEY error", tt.name, err)
+		}
+	}
+
+	t.Setenv("AUTH_SECRET_KEY", productionSecret)
+	if _, err := Load(); err != nil {
+		t.Errorf("strong secret: %v", err)
+	}
+
+	t.Setenv("APP_ENV", "development")
+	os.Unsetenv("AUTH_SECRET_KEY")
+	if _, err := Load(); err != nil {
+		t.Errorf("default secret in development: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+// Package events реализует шину событий внутри процесса: сервисы сообщают
+// об изменении записей, а подписчики (поток SSE) получают эти сообщения.
+// Шина хранит последние события, чтобы переподключившийся клиент получил
+// пропущенное
+package events
+
+import (
+	"context"
+	"inventory-system/internal/tenant"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Сущности, об изменении которых сообщает шина
+const (
+	EntityAsset       = "asset"
+	EntityTransfer    = "transfer"
+	EntityEmployee    = "employee"
+	EntityDepartment  = "department"
+	EntityLocation    = "location"
+	EntityCategory    = "category"
+	EntityReservation = "reservation"
+)
+
+// Entities перечисляет все сущности
+var Entities = []string{
+	EntityAsset,
+	EntityTransfer,
+	EntityEmployee,
+	EntityDepartment,
+	EntityLocation,
+	EntityCategory,
+	EntityReservation,
+}
+
+// Действия над записями
+const (
+	ActionCreated  = "created"
+	ActionUpdated  = "updated"
+	ActionDeleted  = "deleted"
+	ActionRestored = "restored"
+)
+
+// Event — сообщение об изменении записи. ID возрастает с каждым событием
+// и служит идентификатором события SSE. OrganizationID — организация,
+// которой принадлежит запись
+type Event struct {
+	ID             uint64    `json:"-"`
+	Entity         string    `json:"entity"`
+	Action         string    `json:"action"`
+	EntityID       int       `json:"id"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	OrganizationID int       `json:"-"`
+}
+
+// Name возвращает имя события вида asset.updated
+func (e Event) Name() string {
+	return e.Entity + "." + e.Action
+}
+
+// Filter отбирает события для подписчика. Пустой Entities означает все
+// сущности, нулевой EntityID — все записи. События других организаций,
+// кроме OrganizationID, подписчик не получает
+type Filter struct {
+	Entities       []string
+	EntityID       int
+	OrganizationID int
+}
+
+// Match сообщает, проходит ли событие через фильтр
+func (f Filter) Match(e Event) bool {
+	if f.OrganizationID != e.OrganizationID {
+		return false
+	}
+	if f.EntityID != 0 && f.EntityID != e.EntityID {
+		return false
+	}
+	if len(f.Entities) == 0 {
+		return true
+	}
+	for _, entity := range f.Entities {
+		if entity == e.Entity {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownEntity сообщает, является ли name одной из сущностей
+func KnownEntity(name string) bool {
+	for _, entity := range Entities {
+		if entity == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEntities разбирает список сущностей через запятую и возвращает
+// первую неизвестную сущность, если она есть
+func ParseEntities(s string) (entities []string, unknown string) {
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !KnownEntity(name) {
+			return nil, name
+		}
+		entities = append(entities, name)
+	}
+	return entities, ""
+}
+
+// subscriberBuffer — размер очереди подписчика. Подписчик, не успевающий
+// разбирать очередь, отключается и должен переподключиться
+const subscriberBuffer = 64
+
+// Bus — шина событий. Нулевой *Bus допустим: публикация в него ничего
+// не делает
+type Bus struct {
+	mu          sync.Mutex
+	startID     uint64
+	lastID      uint64
+	history     []Event
+	historySize int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewBus создает шину, хранящую historySize последних событий
+func NewBus(historySize int) *Bus {
+	// IDs continue to grow across restarts, so an ID from a previous run
+	// is recognized as stale instead of being matched against new events
+	startID := uint64(time.Now().UnixMicro())
+	return &Bus{
+		startID:     startID,
+		lastID:      startID,
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish сообщает подписчикам об изменении записи id сущности entity в
+// организации из контекста
+func (b *Bus) Publish(ctx context.Context, entity, action string, id int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.lastID++
+	organizationID, _ := tenant.OrganizationID(ctx)
+	event := Event{
+		ID:             b.lastID,
+		Entity:         entity,
+		Action:         action,
+		EntityID:       id,
+		OccurredAt:     time.Now(),
+		OrganizationID: organizationID,
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// Slow subscriber: drop it, the client resumes with Last-Event-ID
+			b.remove(sub)
+		}
+	}
+}
+
+// Backlog — события, пропущенные переподключившимся подписчиком
+type Backlog struct {
+	// Events — события после переданного в Subscribe идентификатора
+	Events []Event
+	// Complete равно false, если часть пропущенных событий уже вытеснена
+	// из истории или идентификатор выдан до перезапуска: клиенту нужно
+	// перечитать данные целиком
+	Complete bool
+	// LastID — идентификатор последнего события на момент подписки
+	LastID uint64
+}
+
+// Subscribe подписывает на события, проходящие через filter. Если задан
+// lastID, в Backlog возвращаются события после него из истории
+func (b *Bus) Subscribe(filter Filter, lastID uint64) (*Subscription, Backlog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{
+		bus:    b,
+		filter: filter,
+		events: make(chan Event, subscriberBuffer),
+	}
+	backlog := Backlog{Complete: true, LastID: b.lastID}
+	if b.closed {
+		close(sub.events)
+		return sub, backlog
+	}
+	b.subscribers[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, backlog
+	}
+
+	backlog.Complete = lastID >= b.startID && lastID <= b.lastID
+	if len(b.history) > 0 && b.history[0].ID > lastID+1 {
+		backlog.Complete = false
+	}
+	for _, event := range b.history {
+		if event.ID > lastID && filter.Match(event) {
+			backlog.Events = append(backlog.Events, event)
+		}
+	}
+	return sub, backlog
+}
+
+// Close отключает всех подписчиков. Вызывается при остановке сервера,
+// чтобы открытые потоки не задерживали завершение
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
+
+// Subscription — подписка на события шины
+type Subscription struct {
+	bus    *Bus
+	filter Filter
+	events chan Event
+}
+
+// Events возвращает канал событий. Канал закрывается, когда подписчик
+// отключен шиной
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close отменяет подписку
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
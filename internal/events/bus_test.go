@@ -0,0 +1,197 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"inventory-system/internal/tenant"
+)
+
+// inOrganization возвращает контекст публикации в организации id
+func inOrganization(id int) context.Context {
+	return tenant.WithOrganization(context.Background(), id)
+}
+
+// lastID возвращает идентификатор последнего опубликованного события
+func lastID(b *Bus) uint64 {
+	sub, backlog := b.Subscribe(Filter{}, 0)
+	sub.Close()
+	return backlog.LastID
+}
+
+func names(events []Event) []string {
+	s := make([]string, len(events))
+	for i, e := range events {
+		s[i] = fmt.Sprintf("%s %d", e.Name(), e.EntityID)
+	}
+	return s
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBusDeliversMatchingEvents(t *testing.T) {
+	b := NewBus(10)
+	sub, backlog := b.Subscribe(Filter{Entities: []string{EntityAsset}, OrganizationID: 1}, 0)
+	defer sub.Close()
+	if len(backlog.Events) != 0 || !backlog.Complete {
+		t.Errorf("backlog of a new subscriber = %+v", backlog)
+	}
+
+	b.Publish(inOrganization(2), EntityAsset, ActionUpdated, 5)
+	b.Publish(inOrganization(1), EntityLocation, ActionCreated, 6)
+	b.Publish(inOrganization(1), EntityAsset, ActionDeleted, 7)
+
+	select {
+	case e := <-sub.Events():
+		if e.Name() != "asset.deleted" || e.EntityID != 7 || e.OrganizationID != 1 {
+			t.Errorf("event = %+v, want asset.deleted 7 in organization 1", e)
+		}
+	default:
+		t.Fatal("matching event was not delivered")
+	}
+	select {
+	case e := <-sub.Events():
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	event := Event{Entity: EntityAsset, Action: ActionUpdated, EntityID: 7, OrganizationID: 1}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"everything in the organization", Filter{OrganizationID: 1}, true},
+		{"other organization", Filter{OrganizationID: 2}, false},
+		{"no organization", Filter{}, false},
+		{"entity", Filter{Entities: []string{EntityLocation, EntityAsset}, OrganizationID: 1}, true},
+		{"other entity", Filter{Entities: []string{EntityLocation}, OrganizationID: 1}, false},
+		{"record", Filter{EntityID: 7, OrganizationID: 1}, true},
+		{"other record", Filter{EntityID: 8, OrganizationID: 1}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(event); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBusReplaysBacklog(t *testing.T) {
+	b := NewBus(10)
+	b.Publish(inOrganization(1), EntityAsset, ActionCreated, 1)
+	seen := lastID(b)
+	b.Publish(inOrganization(1), EntityAsset, ActionUpdated, 1)
+	b.Publish(inOrganization(2), EntityAsset, ActionUpdated, 2)
+	b.Publish(inOrganization(1), EntityLocation, ActionCreated, 3)
+
+	sub, backlog := b.Subscribe(Filter{OrganizationID: 1}, seen)
+	defer sub.Close()
+
+	if !backlog.Complete {
+		t.Error("backlog is incomplete")
+	}
+	if got, want := names(backlog.Events), []string{"asset.updated 1", "location.created 3"}; !equal(got, want) {
+		t.Errorf("backlog = %q, want %q", got, want)
+	}
+	if backlog.LastID != lastID(b) {
+		t.Errorf("LastID = %d, want %d", backlog.LastID, lastID(b))
+	}
+	for i := 1; i < len(backlog.Events); i++ {
+		if backlog.Events[i].ID <= backlog.Events[i-1].ID {
+			t.Errorf("backlog IDs are not increasing: %d after %d", backlog.Events[i].ID, backlog.Events[i-1].ID)
+		}
+	}
+}
+
+func TestBusBacklogUpToDate(t *testing.T) {
+	b := NewBus(10)
+	b.Publish(inOrganization(1), EntityAsset, ActionCreated, 1)
+
+	sub, backlog := b.Subscribe(Filter{OrganizationID: 1}, lastID(b))
+	defer sub.Close()
+	if !backlog.Complete || len(backlog.Events) != 0 {
+		t.Errorf("backlog = %+v, want complete and empty", backlog)
+	}
+}
+
+func TestBusBacklogIncomplete(t *testing.T) {
+	b := NewBus(2)
+	b.Publish(inOrganization(1), EntityAsset, ActionCreated, 1)
+	evicted := lastID(b)
+	for id := 2; id <= 5; id++ {
+		b.Publish(inOrganization(1), EntityAsset, ActionCreated, id)
+	}
+
+	tests := []struct {
+		name   string
+		lastID uint64
+	}{
+		{"evicted from history", evicted},
+		{"issued before restart", 1},
+		{"from the future", lastID(b) + 1},
+	}
+	for _, tt := range tests {
+		sub, backlog := b.Subscribe(Filter{OrganizationID: 1}, tt.lastID)
+		sub.Close()
+		if backlog.Complete {
+			t.Errorf("%s: backlog is complete", tt.name)
+		}
+	}
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	b := NewBus(10)
+	slow, _ := b.Subscribe(Filter{OrganizationID: 1}, 0)
+	fast, _ := b.Subscribe(Filter{OrganizationID: 1}, 0)
+	defer fast.Close()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(inOrganization(1), EntityAsset, ActionUpdated, i)
+		if _, ok := <-fast.Events(); !ok {
+			t.Fatalf("subscriber that keeps up was dropped after %d events", i)
+		}
+	}
+
+	// The slow subscriber gets its buffered events and then a closed channel
+	count := 0
+	for range slow.Events() {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("slow subscriber got %d events before being dropped, want %d", count, subscriberBuffer)
+	}
+	slow.Close()
+}
+
+func TestBusClose(t *testing.T) {
+	b := NewBus(10)
+	sub, _ := b.Subscribe(Filter{OrganizationID: 1}, 0)
+	b.Close()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("subscription is open after Close")
+	}
+	b.Publish(inOrganization(1), EntityAsset, ActionCreated, 1)
+
+	late, _ := b.Subscribe(Filter{OrganizationID: 1}, 0)
+	if _, ok := <-late.Events(); ok {
+		t.Error("subscription to a closed bus is open")
+	}
+
+	var none *Bus
+	none.Publish(inOrganization(1), EntityAsset, ActionCreated, 1)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// GetMyAPITokens возвращает персональные токены текущего сотрудника
+func (h *Handler) GetMyAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.apiTokenService.GetTokens(r.Context(), currentEmployee(r).ID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+// CreateMyAPIToken выдает текущему сотруднику персональный токен. Значение
+// токена есть только в этом ответе
+func (h *Handler) CreateMyAPIToken(w http.ResponseWriter, r *http.Request) {
+	var request models.CreateAPITokenRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	token, err := h.apiTokenService.CreateToken(r.Context(), currentEmployee(r).ID, request)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, token)
+}
+
+// RevokeMyAPIToken отзывает персональный токен текущего сотрудника
+func (h *Handler) RevokeMyAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid token ID", nil))
+		return
+	}
+
+	if err := h.apiTokenService.RevokeToken(r.Context(), currentEmployee(r).ID, id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetEmployeeAPITokens возвращает персональные токены сотрудника
+func (h *Handler) GetEmployeeAPITokens(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
+		return
+	}
+
+	tokens, err := h.apiTokenService.GetTokens(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+// RevokeEmployeeAPIToken отзывает персональный токен сотрудника, например
+// после его утечки
+func (h *Handler) RevokeEmployeeAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
+		return
+	}
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid token ID", nil))
+		return
+	}
+
+	if err := h.apiTokenService.RevokeToken(r.Context(), id, tokenID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
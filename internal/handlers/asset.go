@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"inventory-system/internal/apperror"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"inventory-system/internal/models"
@@ -15,11 +18,31 @@ type AssetHandler struct {
 }
 
 func (h *Handler) GetAllAssets(w http.ResponseWriter, r *http.Request) {
-	var (
+	filter, err := parseAssetFilter(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	withDeleted, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+	if withDeleted {
+		if filter == nil {
+			filter = &models.AssetFilter{}
+		}
+		filter.IncludeDeleted = true
+	}
+
+	var assets []models.Asset
+	if filter == nil {
 		assets, err = h.assetService.GetAllAssets(r.Context())
-	)
+	} else {
+		assets, err = h.assetService.SearchAssets(r.Context(), *filter)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -29,29 +52,28 @@ func (h *Handler) GetAllAssets(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetAsset(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
 		return
 	}
 
-	asset, err := h.assetService.GetAssetByID(r.Context(), id)
+	asset, err := h.assetService.GetAssetDetails(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		respondWithError(w, r, err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, asset)
+	respondWithVersion(w, http.StatusOK, asset, asset.Version)
 }
 
 func (h *Handler) CreateAsset(w http.ResponseWriter, r *http.Request) {
-	var asset models.Asset
-	if err := json.NewDecoder(r.Body).Decode(&asset); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.AssetRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	id, err := h.assetService.CreateAsset(r.Context(), asset)
+	id, err := h.assetService.CreateAsset(r.Context(), request.Asset())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -61,34 +83,147 @@ func (h *Handler) CreateAsset(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
 		return
 	}
 
-	var asset models.Asset
-	if err := json.NewDecoder(r.Body).Decode(&asset); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.AssetRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
+	asset := request.Asset()
 	asset.ID = id
 
-	if err := h.assetService.UpdateAsset(r.Context(), asset); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	asset.Version = version
+
+	version, err = h.assetService.UpdateAsset(r.Context(), asset)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.assetService.GetAssetDetails(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) PatchAsset(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	asset, err := h.assetService.PatchAsset(r.Context(), id, version, patch)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.assetService.GetAssetDetails(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithVersion(w, http.StatusOK, asset, asset.Version)
+}
+
 func (h *Handler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
+		return
+	}
+
+	if err := h.assetService.DeleteAsset(r.Context(), id, currentEmployee(r).ID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RestoreAsset(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
+		return
+	}
+
+	if !isAdmin(r) {
+		respondWithError(w, r, apperror.Forbidden("Only administrators can restore deleted records"))
+		return
+	}
+
+	if err := h.assetService.RestoreAsset(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AttachAssetComponent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
 		return
 	}
 
-	if err := h.assetService.DeleteAsset(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var request models.AttachComponentRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	if err := h.assetService.AttachComponent(r.Context(), id, request.ComponentID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DetachAssetComponent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
+		return
+	}
+
+	componentID, err := strconv.Atoi(chi.URLParam(r, "componentID"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid component ID", nil))
+		return
+	}
+
+	if err := h.assetService.DetachComponent(r.Context(), id, componentID); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -98,19 +233,49 @@ func (h *Handler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetAssetTransfers(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
 		return
 	}
 
 	transfers, err := h.assetService.GetAssetTransferHistory(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, transfers)
 }
 
+// parseAssetFilter читает параметры отбора активов из строки запроса:
+// category_id, q (поиск) и cf.<ключ> для дополнительных полей.
+// Возвращает nil, если отбор не задан
+func parseAssetFilter(r *http.Request) (*models.AssetFilter, error) {
+	query := r.URL.Query()
+	filter := models.AssetFilter{
+		Search:       strings.TrimSpace(query.Get("q")),
+		CustomFields: make(map[string]string),
+	}
+
+	if value := query.Get("category_id"); value != "" {
+		categoryID, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, apperror.Validation("invalid category_id", map[string]string{"category_id": "must be an integer"})
+		}
+		filter.CategoryID = &categoryID
+	}
+
+	for key, values := range query {
+		if strings.HasPrefix(key, "cf.") && len(values) > 0 && len(key) > 3 {
+			filter.CustomFields[strings.TrimPrefix(key, "cf.")] = values[0]
+		}
+	}
+
+	if filter.CategoryID == nil && filter.Search == "" && len(filter.CustomFields) == 0 {
+		return nil, nil
+	}
+	return &filter, nil
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -1,79 +1,250 @@
 package handlers
 
 import (
-	"encoding/json"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/apperror"
 	"inventory-system/internal/models"
+	"inventory-system/internal/services"
+	"net"
 	"net/http"
+	"strconv"
 )
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
-	var creds struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	var creds models.LoginRequest
+	if !decodeJSON(w, r, &creds) {
+		return
+	}
+
+	employee, err := h.authService.Authenticate(r.Context(), services.LoginAttempt{
+		Email:     creds.Email,
+		Password:  creds.Password,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	h.respondWithFirstFactor(w, r, employee)
+}
+
+// respondWithFirstFactor отвечает на проверенный первый фактор входа:
+// токеном второго шага, если у сотрудника включена двухфакторная
+// аутентификация, иначе токеном доступа
+func (h *Handler) respondWithFirstFactor(w http.ResponseWriter, r *http.Request, employee *models.Employee) {
+	if employee.TwoFactorEnabled {
+		challenge, expiresAt, err := h.authService.GenerateChallenge(employee)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_at":          expiresAt,
+		})
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	h.respondWithLogin(w, r, employee)
+}
+
+// LoginTwoFactor завершает вход кодом из приложения-аутентификатора или
+// резервным кодом
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var request models.TwoFactorLoginRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	employee, err := h.authService.Authenticate(r.Context(), creds.Email, creds.Password)
+	employee, err := h.authService.VerifyTwoFactor(r.Context(), services.TwoFactorAttempt{
+		ChallengeToken: request.ChallengeToken,
+		Code:           request.Code,
+		IP:             clientIP(r),
+		UserAgent:      r.UserAgent(),
+	})
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
+		respondWithError(w, r, err)
 		return
 	}
 
+	h.respondWithLogin(w, r, employee)
+}
+
+// respondWithLogin отвечает токеном доступа. two_factor_setup_required
+// сообщает, что до настройки двухфакторной аутентификации доступны только
+// маршруты /me/two-factor
+func (h *Handler) respondWithLogin(w http.ResponseWriter, r *http.Request, employee *models.Employee) {
 	token, expiresAt, err := h.authService.GenerateToken(employee)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		respondWithError(w, r, err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"token":      token,
-		"expires_at": expiresAt,
-		"employee":   employee,
+		"token":                     token,
+		"expires_at":                expiresAt,
+		"employee":                  employee,
+		"two_factor_setup_required": !employee.TwoFactorEnabled && h.twoFactorService.Required(employee),
 	})
 }
 
+// Register создает учетную запись по приглашению
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		FullName string `json:"full_name"`
-		Position string `json:"position"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	var request models.RegisterRequest
+	if !decodeJSON(w, r, &request) {
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	// Пароль хеширует сервис сотрудников
+	id, err := h.registrationService.Register(r.Context(), request)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+// GetRegistrationSettings сообщает странице регистрации режим регистрации
+func (h *Handler) GetRegistrationSettings(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.registrationService.Settings())
+}
+
+// RequestRegistrationEmail отправляет ссылку регистрации на адрес из
+// разрешенного домена. Ответ не зависит от того, зарегистрирован ли адрес
+func (h *Handler) RequestRegistrationEmail(w http.ResponseWriter, r *http.Request) {
+	var request models.RegistrationEmailRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	if err := h.registrationService.RequestSignup(r.Context(), request.Email); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the email is not registered yet, a registration link has been sent to it",
+	})
+}
+
+// PreviewInvitation возвращает странице регистрации данные приглашения
+func (h *Handler) PreviewInvitation(w http.ResponseWriter, r *http.Request) {
+	var request models.InvitationTokenRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	// Хеширование пароля с использованием bcrypt
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	preview, err := h.registrationService.PreviewInvitation(r.Context(), request.Token)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+		respondWithError(w, r, err)
 		return
 	}
 
-	employee := models.Employee{
-		FullName:     request.FullName,
-		Position:     request.Position,
-		Email:        request.Email,
-		PasswordHash: string(hashedPassword),
-		Role:         "employee",
+	respondWithJSON(w, http.StatusOK, preview)
+}
+
+// RequestPasswordReset отправляет ссылку сброса пароля. Ответ не зависит от
+// того, зарегистрирован ли адрес
+func (h *Handler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var request models.PasswordResetRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	if err := h.passwordResetService.RequestReset(r.Context(), request.Email); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the email is registered, a password reset link has been sent to it",
+	})
+}
+
+// ConfirmPasswordReset задает новый пароль по токену из ссылки
+func (h *Handler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var request models.PasswordResetConfirmRequest
+	if !decodeJSON(w, r, &request) {
+		return
 	}
 
-	id, err := h.employeeService.CreateEmployee(r.Context(), employee)
+	if err := h.passwordResetService.ConfirmReset(r.Context(), request.Token, request.NewPassword); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockEmployeeLogin снимает блокировку входа сотрудника после неудачных
+// попыток
+func (h *Handler) UnlockEmployeeLogin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, map[string]int{"id": id})
+	if err := h.authService.UnlockAccount(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
+// GetLoginFailures возвращает журнал неудачных попыток входа, начиная с
+// последних
+func (h *Handler) GetLoginFailures(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.LoginFailureFilter{Email: query.Get("email")}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			respondWithError(w, r, apperror.Validation("Invalid limit", nil))
+			return
+		}
+		filter.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			respondWithError(w, r, apperror.Validation("Invalid offset", nil))
+			return
+		}
+		filter.Offset = offset
+	}
+
+	failures, err := h.authService.GetLoginFailures(r.Context(), filter)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, failures)
+}
+
+// GetJWKS возвращает открытые ключи, которыми другие сервисы проверяют
+// токены доступа. Ключи опубликованы заранее, поэтому ответ можно
+// кешировать
+func (h *Handler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, h.authService.JWKS())
+}
+
+// clientIP возвращает адрес клиента, с которого пришел запрос. Заголовки
+// X-Forwarded-For не учитываются: клиент может подделать их и обойти
+// ограничение частоты запросов
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
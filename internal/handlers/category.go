@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"inventory-system/internal/apperror"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/models"
+)
+
+func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.categoryService.GetAllCategories(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, categories)
+}
+
+func (h *Handler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.categoryService.GetCategoryTree(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tree)
+}
+
+func (h *Handler) GetDuplicateCategories(w http.ResponseWriter, r *http.Request) {
+	duplicates, err := h.categoryService.FindDuplicateCategories(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, duplicates)
+}
+
+func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid category ID", nil))
+		return
+	}
+
+	category, err := h.categoryService.GetCategoryByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, category)
+}
+
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var request models.CategoryRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	id, err := h.categoryService.CreateCategory(r.Context(), request.Category())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid category ID", nil))
+		return
+	}
+
+	var request models.CategoryRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	category := request.Category()
+	category.ID = id
+
+	if err := h.categoryService.UpdateCategory(r.Context(), category); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid category ID", nil))
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetCategoryFields(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid category ID", nil))
+		return
+	}
+
+	fields, err := h.categoryService.GetEffectiveFields(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, fields)
+}
+
+func (h *Handler) MergeCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid category ID", nil))
+		return
+	}
+
+	var request models.MergeCategoryRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	if err := h.categoryService.MergeCategories(r.Context(), id, request.TargetID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,18 +1,25 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"inventory-system/internal/apperror"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"inventory-system/internal/models"
+	"inventory-system/internal/services"
 )
 
 func (h *Handler) GetAllDepartments(w http.ResponseWriter, r *http.Request) {
-	departments, err := h.departmentService.GetAllDepartments(r.Context())
+	withDeleted, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+
+	departments, err := h.departmentService.GetAllDepartments(r.Context(), withDeleted)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -22,29 +29,28 @@ func (h *Handler) GetAllDepartments(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetDepartment(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid department ID", nil))
 		return
 	}
 
 	department, err := h.departmentService.GetDepartmentByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		respondWithError(w, r, err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, department)
+	respondWithVersion(w, http.StatusOK, department, department.Version)
 }
 
 func (h *Handler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
-	var department models.Department
-	if err := json.NewDecoder(r.Body).Decode(&department); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.DepartmentRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	id, err := h.departmentService.CreateDepartment(r.Context(), department)
+	id, err := h.departmentService.CreateDepartment(r.Context(), request.Department())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -54,34 +60,86 @@ func (h *Handler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateDepartment(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid department ID", nil))
 		return
 	}
 
-	var department models.Department
-	if err := json.NewDecoder(r.Body).Decode(&department); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.DepartmentRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
+	department := request.Department()
 	department.ID = id
 
-	if err := h.departmentService.UpdateDepartment(r.Context(), department); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	department.Version = version
+
+	version, err = h.departmentService.UpdateDepartment(r.Context(), department)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.departmentService.GetDepartmentByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) PatchDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid department ID", nil))
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	department, err := h.departmentService.PatchDepartment(r.Context(), id, version, patch)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.departmentService.GetDepartmentByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithVersion(w, http.StatusOK, department, department.Version)
+}
+
 func (h *Handler) DeleteDepartment(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid department ID", nil))
 		return
 	}
 
-	if err := h.departmentService.DeleteDepartment(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.departmentService.DeleteDepartment(r.Context(), id, currentEmployee(r).ID); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -91,15 +149,35 @@ func (h *Handler) DeleteDepartment(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetDepartmentEmployees(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid department ID", nil))
 		return
 	}
 
 	employees, err := h.departmentService.GetEmployeesByDepartment(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, employees)
 }
+
+func (h *Handler) RestoreDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid department ID", nil))
+		return
+	}
+
+	if !isAdmin(r) {
+		respondWithError(w, r, apperror.Forbidden("Only administrators can restore deleted records"))
+		return
+	}
+
+	if err := h.departmentService.RestoreDepartment(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,19 +1,26 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"inventory-system/internal/apperror"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"inventory-system/internal/models"
+	"inventory-system/internal/services"
 )
 
 func (h *Handler) GetAllEmployees(w http.ResponseWriter, r *http.Request) {
-	employees, err := h.employeeService.GetAllEmployees(r.Context())
+	withDeleted, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+
+	employees, err := h.employeeService.GetAllEmployees(r.Context(), withDeleted)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -23,34 +30,30 @@ func (h *Handler) GetAllEmployees(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetEmployee(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
 		return
 	}
 
 	employee, err := h.employeeService.GetEmployeeByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		respondWithError(w, r, err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, employee)
+	respondWithVersion(w, http.StatusOK, employee, employee.Version)
 }
 
 func (h *Handler) CreateEmployee(w http.ResponseWriter, r *http.Request) {
 	log.Println("CreateEmployee called")
 
-	var employee models.Employee
-	if err := json.NewDecoder(r.Body).Decode(&employee); err != nil {
-		log.Printf("Error decoding request: %v", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	var request models.CreateEmployeeRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	log.Printf("Creating employee: %+v", employee)
-
-	id, err := h.employeeService.CreateEmployee(r.Context(), employee)
+	id, err := h.employeeService.CreateEmployee(r.Context(), request.Employee())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -60,34 +63,106 @@ func (h *Handler) CreateEmployee(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateEmployee(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
 		return
 	}
 
-	var employee models.Employee
-	if err := json.NewDecoder(r.Body).Decode(&employee); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.EmployeeRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
+	employee := request.Employee()
 	employee.ID = id
 
-	if err := h.employeeService.UpdateEmployee(r.Context(), employee); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	employee.Version = version
+
+	version, err = h.employeeService.UpdateEmployee(r.Context(), employee, currentEmployee(r))
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.employeeService.GetEmployeeByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) PatchEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	employee, err := h.employeeService.PatchEmployee(r.Context(), id, version, patch, currentEmployee(r))
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.employeeService.GetEmployeeByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithVersion(w, http.StatusOK, employee, employee.Version)
+}
+
 func (h *Handler) DeleteEmployee(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
+		return
+	}
+
+	if err := h.employeeService.DeleteEmployee(r.Context(), id, currentEmployee(r).ID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RestoreEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
+		return
+	}
+
+	if !isAdmin(r) {
+		respondWithError(w, r, apperror.Forbidden("Only administrators can restore deleted records"))
 		return
 	}
 
-	if err := h.employeeService.DeleteEmployee(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.employeeService.RestoreEmployee(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
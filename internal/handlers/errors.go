@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"inventory-system/internal/apperror"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// problemContentType — медиатип ответов об ошибках (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// problem — тело ответа об ошибке в формате RFC 7807. Code повторяет вид
+// ошибки apperror, Errors содержит ошибки по отдельным полям запроса
+type problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+var kindStatus = map[apperror.Kind]int{
+	apperror.KindNotFound:           http.StatusNotFound,
+	apperror.KindValidation:         http.StatusBadRequest,
+	apperror.KindConflict:           http.StatusConflict,
+	apperror.KindForbidden:          http.StatusForbidden,
+	apperror.KindUnauthorized:       http.StatusUnauthorized,
+	apperror.KindPreconditionFailed: http.StatusPreconditionFailed,
+	apperror.KindTooManyRequests:    http.StatusTooManyRequests,
+}
+
+// respondWithError переводит ошибку в ответ problem+json по ее виду.
+// Внутренние ошибки записываются в лог, а клиент получает только общее
+// сообщение, чтобы не раскрывать детали базы данных
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	kind := apperror.KindOf(err)
+	status, ok := kindStatus[kind]
+	if !ok {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		respondWithProblem(w, r, http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if retryAfter := apperror.RetryAfterOf(err); retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+
+	p := newProblem(r, status, err.Error(), apperror.FieldsOf(err))
+	p.Code = string(kind)
+	writeProblem(w, p)
+}
+
+// respondWithProblem отвечает problem+json с явно заданным статусом. Нужен
+// для ошибок протокола (заголовки, тип содержимого), не относящихся к
+// предметной области
+func respondWithProblem(w http.ResponseWriter, r *http.Request, status int, detail string, fields map[string]string) {
+	writeProblem(w, newProblem(r, status, detail, fields))
+}
+
+func newProblem(r *http.Request, status int, detail string, fields map[string]string) problem {
+	return problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   fields,
+	}
+}
+
+func writeProblem(w http.ResponseWriter, p problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// invalidBody сообщает об ошибке разбора тела запроса
+func invalidBody(err error) error {
+	return apperror.Wrap(apperror.KindValidation, "invalid request body: "+err.Error(), err)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/openapi"
+)
+
+func problemFor(t *testing.T, err error) (*httptest.ResponseRecorder, problem) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	respondWithError(rec, httptest.NewRequest(http.MethodGet, "/api/v1/assets/7", nil), err)
+
+	if got := rec.Header().Get("Content-Type"); got != problemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, problemContentType)
+	}
+	var p problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	return rec, p
+}
+
+func TestRespondWithErrorMapsKinds(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{apperror.NotFound("asset not found"), http.StatusNotFound, "not_found"},
+		{apperror.Validation("invalid asset", nil), http.StatusBadRequest, "validation"},
+		{apperror.Conflict("serial number already exists"), http.StatusConflict, "conflict"},
+		{apperror.Forbidden("not allowed"), http.StatusForbidden, "forbidden"},
+		{apperror.Unauthorized("token expired"), http.StatusUnauthorized, "unauthorized"},
+		{apperror.PreconditionFailed("version mismatch"), http.StatusPreconditionFailed, "precondition_failed"},
+		{apperror.TooManyRequests("slow down", time.Second), http.StatusTooManyRequests, "too_many_requests"},
+		{fmt.Errorf("update: %w", apperror.NotFound("asset not found")), http.StatusNotFound, "not_found"},
+	}
+
+	for _, tt := range tests {
+		rec, p := problemFor(t, tt.err)
+		if rec.Code != tt.status || p.Status != tt.status {
+			t.Errorf("%v: status = %d (body %d), want %d", tt.err, rec.Code, p.Status, tt.status)
+		}
+		if p.Code != tt.code {
+			t.Errorf("%v: code = %q, want %q", tt.err, p.Code, tt.code)
+		}
+		if p.Type != "about:blank" || p.Title != http.StatusText(tt.status) || p.Instance != "/api/v1/assets/7" {
+			t.Errorf("%v: problem = %+v", tt.err, p)
+		}
+		if p.Detail != tt.err.Error() {
+			t.Errorf("%v: detail = %q", tt.err, p.Detail)
+		}
+	}
+}
+
+func TestRespondWithErrorHidesInternalErrors(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	rec, p := problemFor(t, errors.New(`pq: relation "assets" does not exist`))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if p.Detail != "internal server error" || p.Code != "" {
+		t.Errorf("problem = %+v", p)
+	}
+	if strings.Contains(rec.Body.String(), "relation") {
+		t.Errorf("body exposes the error: %s", rec.Body.String())
+	}
+}
+
+func TestRespondWithErrorFieldsAndRetryAfter(t *testing.T) {
+	fields := map[string]string{"serial_number": "is required"}
+	_, p := problemFor(t, apperror.Validation("invalid asset", fields))
+	if p.Errors["serial_number"] != "is required" || len(p.Errors) != 1 {
+		t.Errorf("errors = %v", p.Errors)
+	}
+
+	rec, _ := problemFor(t, apperror.TooManyRequests("slow down", 1500*time.Millisecond))
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
+
+func TestProblemCodesAreDocumented(t *testing.T) {
+	schema := openapi.New("test", "1").Components.Schemas[openapi.ProblemSchema]
+	documented := make(map[string]bool)
+	for _, code := range schema.Properties["code"].Enum {
+		documented[code] = true
+	}
+
+	for kind := range kindStatus {
+		if !documented[string(kind)] {
+			t.Errorf("code %q is missing from the Problem schema", kind)
+		}
+	}
+}
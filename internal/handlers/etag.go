@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"inventory-system/internal/apperror"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag формирует значение заголовка ETag из версии записи
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion читает версию записи из заголовка If-Match. Без заголовка
+// отвечает 428, с некорректным значением — 400 и возвращает ok = false
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (version int, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		respondWithProblem(w, r, http.StatusPreconditionRequired, "If-Match header with the record ETag is required", nil)
+		return 0, false
+	}
+
+	value := strings.TrimSuffix(strings.TrimPrefix(header, `"`), `"`)
+	version, err := strconv.Atoi(value)
+	if err != nil || value == header {
+		respondWithError(w, r, apperror.Validation("Invalid If-Match header", nil))
+		return 0, false
+	}
+
+	return version, true
+}
+
+// respondWithVersion отправляет запись вместе с ее версией в заголовке ETag
+func respondWithVersion(w http.ResponseWriter, code int, payload interface{}, version int) {
+	w.Header().Set("ETag", etag(version))
+	respondWithJSON(w, code, payload)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/tenant"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// heartbeatInterval — период комментариев-пингов, которые не дают
+	// прокси закрыть простаивающее соединение
+	heartbeatInterval = 25 * time.Second
+	// reconnectDelay — пауза перед переподключением, которую сервер
+	// сообщает клиенту полем retry
+	reconnectDelay = 3 * time.Second
+)
+
+// StreamEvents отдает поток Server-Sent Events об изменениях записей.
+// Параметр entity ограничивает поток списком сущностей через запятую,
+// entity_id — одной записью. При переподключении с заголовком
+// Last-Event-ID сначала отправляются пропущенные события; если их уже
+// нет в истории, приходит событие reset и клиент перечитывает данные.
+// Поток содержит только события организации сотрудника
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	filter, ok := eventFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.OrganizationID, _ = tenant.OrganizationID(r.Context())
+
+	// Unparsable IDs are treated as stale and answered with reset
+	var lastID uint64
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		lastID, _ = strconv.ParseUint(value, 10, 64)
+		if lastID == 0 {
+			lastID = 1
+		}
+	}
+
+	sub, backlog := h.eventBus.Subscribe(filter, lastID)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds())
+	switch {
+	case lastID == 0:
+		writeEvent(w, backlog.LastID, "ready", struct{}{})
+	case !backlog.Complete:
+		writeEvent(w, backlog.LastID, "reset", struct{}{})
+	default:
+		for _, event := range backlog.Events {
+			writeEvent(w, event.ID, event.Name(), event)
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Dropped by the bus: the client reconnects with Last-Event-ID
+				return
+			}
+			writeEvent(w, event.ID, event.Name(), event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// eventFilter читает параметры entity и entity_id. При ошибке отвечает 400
+// и возвращает ok = false
+func eventFilter(w http.ResponseWriter, r *http.Request) (filter events.Filter, ok bool) {
+	query := r.URL.Query()
+
+	entities, unknown := events.ParseEntities(query.Get("entity"))
+	if unknown != "" {
+		respondWithError(w, r, apperror.Validation("Unknown entity", map[string]string{
+			"entity": "unknown entity " + unknown,
+		}))
+		return filter, false
+	}
+	filter.Entities = entities
+
+	if value := query.Get("entity_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			respondWithError(w, r, apperror.Validation("Invalid entity ID", map[string]string{
+				"entity_id": "must be a positive integer",
+			}))
+			return filter, false
+		}
+		filter.EntityID = id
+	}
+
+	return filter, true
+}
+
+// writeEvent записывает событие SSE с данными data в формате JSON
+func writeEvent(w http.ResponseWriter, id uint64, name string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload = []byte("{}")
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, name, payload)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/events"
+	"inventory-system/internal/tenant"
+)
+
+// sseEvent — событие потока Server-Sent Events
+type sseEvent struct {
+	id   string
+	name string
+	data string
+}
+
+// sseStream читает события из ответа StreamEvents
+type sseStream struct {
+	t      *testing.T
+	resp   *http.Response
+	reader *bufio.Reader
+}
+
+// openStream подключается к потоку events сотрудника организации 1
+func openStream(t *testing.T, bus *events.Bus, query, lastEventID string) *sseStream {
+	t.Helper()
+
+	h := NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, bus)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.StreamEvents(w, r.WithContext(tenant.WithOrganization(r.Context(), 1)))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return &sseStream{t: t, resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+// next возвращает следующее событие, пропуская поле retry и комментарии
+func (s *sseStream) next() sseEvent {
+	s.t.Helper()
+	var event sseEvent
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			s.t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if event.name != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	bus := events.NewBus(10)
+	stream := openStream(t, bus, "?entity=asset", "")
+
+	if got := stream.resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	ready := stream.next()
+	if ready.name != "ready" {
+		t.Fatalf("first event = %+v, want ready", ready)
+	}
+
+	bus.Publish(tenant.WithOrganization(context.Background(), 2), events.EntityAsset, events.ActionUpdated, 5)
+	bus.Publish(tenant.WithOrganization(context.Background(), 1), events.EntityLocation, events.ActionUpdated, 6)
+	bus.Publish(tenant.WithOrganization(context.Background(), 1), events.EntityAsset, events.ActionUpdated, 7)
+
+	event := stream.next()
+	if event.name != "asset.updated" {
+		t.Fatalf("event = %+v, want asset.updated of organization 1", event)
+	}
+	var data struct {
+		Entity string `json:"entity"`
+		Action string `json:"action"`
+		ID     int    `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(event.data), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Entity != "asset" || data.Action != "updated" || data.ID != 7 {
+		t.Errorf("data = %s", event.data)
+	}
+	readyID, _ := strconv.ParseUint(ready.id, 10, 64)
+	if id, _ := strconv.ParseUint(event.id, 10, 64); id != readyID+3 {
+		t.Errorf("id = %s, want %d", event.id, readyID+3)
+	}
+}
+
+func TestStreamEventsResumes(t *testing.T) {
+	bus := events.NewBus(10)
+	ready := openStream(t, bus, "", "").next()
+
+	bus.Publish(tenant.WithOrganization(context.Background(), 1), events.EntityAsset, events.ActionCreated, 1)
+	bus.Publish(tenant.WithOrganization(context.Background(), 2), events.EntityAsset, events.ActionCreated, 2)
+	bus.Publish(tenant.WithOrganization(context.Background(), 1), events.EntityAsset, events.ActionDeleted, 1)
+
+	stream := openStream(t, bus, "", ready.id)
+	for _, want := range []string{"asset.created", "asset.deleted"} {
+		if event := stream.next(); event.name != want {
+			t.Errorf("replayed event = %+v, want %s", event, want)
+		}
+	}
+
+	bus.Publish(tenant.WithOrganization(context.Background(), 1), events.EntityAsset, events.ActionRestored, 1)
+	if event := stream.next(); event.name != "asset.restored" {
+		t.Errorf("live event after replay = %+v, want asset.restored", event)
+	}
+}
+
+func TestStreamEventsResetsStaleID(t *testing.T) {
+	bus := events.NewBus(10)
+	for _, lastEventID := range []string{"1", "not-a-number"} {
+		if event := openStream(t, bus, "", lastEventID).next(); event.name != "reset" {
+			t.Errorf("Last-Event-ID %q: first event = %+v, want reset", lastEventID, event)
+		}
+	}
+}
+
+func TestStreamEventsRejectsInvalidFilter(t *testing.T) {
+	h := NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, events.NewBus(10))
+
+	for _, query := range []string{"?entity=spaceship", "?entity_id=0", "?entity_id=x"} {
+		rec := httptest.NewRecorder()
+		h.StreamEvents(rec, httptest.NewRequest(http.MethodGet, "/api/v1/events"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/models"
 	"inventory-system/internal/services"
+	"inventory-system/internal/tenant"
 	"log"
 	"net/http"
 	"os"
@@ -9,15 +15,30 @@ import (
 )
 
 type Handler struct {
-	departmentService *services.DepartmentService
-	employeeService   *services.EmployeeService
-	assetService      *services.AssetService
-	locationService   *services.LocationService
-	transferService   *services.TransferService
-	authService       *services.AuthService
-	reportService     *services.ReportService
+	departmentService    *services.DepartmentService
+	employeeService      *services.EmployeeService
+	assetService         *services.AssetService
+	locationService      *services.LocationService
+	transferService      *services.TransferService
+	authService          *services.AuthService
+	reportService        *services.ReportService
+	categoryService      *services.CategoryService
+	reservationService   *services.ReservationService
+	webhookService       *services.WebhookService
+	notificationService  *services.NotificationService
+	passwordResetService *services.PasswordResetService
+	twoFactorService     *services.TwoFactorService
+	oidcService          *services.OIDCService
+	apiTokenService      *services.APITokenService
+	registrationService  *services.RegistrationService
+	organizationService  *services.OrganizationService
+	eventBus             *events.Bus
 }
 
+type contextKey string
+
+const employeeContextKey contextKey = "employee"
+
 func NewHandler(
 	departmentService *services.DepartmentService,
 	employeeService *services.EmployeeService,
@@ -26,48 +47,209 @@ func NewHandler(
 	transferService *services.TransferService,
 	authService *services.AuthService,
 	reportService *services.ReportService,
+	categoryService *services.CategoryService,
+	reservationService *services.ReservationService,
+	webhookService *services.WebhookService,
+	notificationService *services.NotificationService,
+	passwordResetService *services.PasswordResetService,
+	twoFactorService *services.TwoFactorService,
+	oidcService *services.OIDCService,
+	apiTokenService *services.APITokenService,
+	registrationService *services.RegistrationService,
+	organizationService *services.OrganizationService,
+	eventBus *events.Bus,
 ) *Handler {
 	return &Handler{
-		departmentService: departmentService,
-		employeeService:   employeeService,
-		assetService:      assetService,
-		locationService:   locationService,
-		transferService:   transferService,
-		authService:       authService,
-		reportService:     reportService,
+		departmentService:    departmentService,
+		employeeService:      employeeService,
+		assetService:         assetService,
+		locationService:      locationService,
+		transferService:      transferService,
+		authService:          authService,
+		reportService:        reportService,
+		categoryService:      categoryService,
+		reservationService:   reservationService,
+		webhookService:       webhookService,
+		notificationService:  notificationService,
+		passwordResetService: passwordResetService,
+		twoFactorService:     twoFactorService,
+		oidcService:          oidcService,
+		apiTokenService:      apiTokenService,
+		registrationService:  registrationService,
+		organizationService:  organizationService,
+		eventBus:             eventBus,
 	}
 }
 
-// AuthMiddleware проверяет JWT токен в заголовке Authorization
+// AuthMiddleware проверяет JWT или персональный токен в заголовке
+// Authorization
 func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Получаем токен из заголовка
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			respondWithError(w, http.StatusUnauthorized, "Authorization header required")
+			respondWithError(w, r, apperror.Unauthorized("Authorization header required"))
 			return
 		}
 
 		// Проверяем формат заголовка
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			respondWithError(w, http.StatusUnauthorized, "Invalid authorization format")
+			respondWithError(w, r, apperror.Unauthorized("Invalid authorization format"))
 			return
 		}
 
 		// Извлекаем токен
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
+		// Персональные токены проверяются отдельно от JWT
+		if services.IsAPIToken(token) {
+			h.serveWithAPIToken(w, r, next, token)
+			return
+		}
+
 		// Проверяем токен
-		_, err := h.authService.ValidateToken(r.Context(), token)
+		employee, err := h.authService.ValidateToken(r.Context(), token)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Invalid token")
+			respondWithError(w, r, apperror.Unauthorized("Invalid token"))
+			return
+		}
+
+		// Сохраняем текущего сотрудника в контексте запроса
+		next.ServeHTTP(w, r.WithContext(withEmployee(r.Context(), employee)))
+	})
+}
+
+// CalendarAuthMiddleware проверяет доступ к календарям бронирований.
+// Программы-календари (Outlook, Google Calendar) не передают заголовок
+// Authorization, поэтому для подписки персональный токен можно указать в
+// параметре token адреса. Области токена должны разрешать чтение ресурса.
+// Без параметра доступ проверяется так же, как в AuthMiddleware
+func (h *Handler) CalendarAuthMiddleware(next http.Handler) http.Handler {
+	auth := h.AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			auth.ServeHTTP(w, r)
+			return
+		}
+		// JWT в адресе попал бы в журналы и историю браузера, а отозвать
+		// его нельзя
+		if !services.IsAPIToken(token) {
+			respondWithError(w, r, apperror.Unauthorized("Only personal API tokens can be passed in the token parameter"))
+			return
+		}
+		h.serveWithAPIToken(w, r, next, token)
+	})
+}
+
+// serveWithAPIToken пропускает запрос с персональным токеном, если его
+// области разрешают запрос. Ресурс определяется по первому сегменту пути
+// относительно префикса версии API, изменением считается любой метод,
+// кроме GET и HEAD. Маршруты без подходящей области, в том числе
+// управление токенами и изменение /me, персональным токенам недоступны
+func (h *Handler) serveWithAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	employee, apiToken, err := h.apiTokenService.Authenticate(r.Context(), token, clientIP(r))
+	if err != nil {
+		respondWithError(w, r, apperror.Unauthorized("Invalid token"))
+		return
+	}
+
+	write := r.Method != http.MethodGet && r.Method != http.MethodHead
+	if !services.ScopesAllow(apiToken.Scopes, requestResource(r), write) {
+		respondWithError(w, r, services.ErrInsufficientScope)
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(withEmployee(r.Context(), employee)))
+}
+
+// withEmployee сохраняет в контексте текущего сотрудника и ограничивает
+// запрос его организацией
+func withEmployee(ctx context.Context, employee *models.Employee) context.Context {
+	ctx = context.WithValue(ctx, employeeContextKey, employee)
+	return tenant.WithOrganization(ctx, employee.OrganizationID)
+}
+
+// requestResource возвращает первый сегмент пути запроса относительно
+// смонтированного роутера: "assets" для /api/v1/assets/42
+func requestResource(r *http.Request) string {
+	path := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		path = rctx.RoutePath
+	}
+	resource, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return resource
+}
+
+// currentEmployee возвращает сотрудника, определенного в AuthMiddleware
+func currentEmployee(r *http.Request) *models.Employee {
+	employee, _ := r.Context().Value(employeeContextKey).(*models.Employee)
+	return employee
+}
+
+// isAdmin сообщает, администрирует ли текущий сотрудник свою организацию.
+// Суперадминистратор в своей организации — администратор
+func isAdmin(r *http.Request) bool {
+	employee := currentEmployee(r)
+	return employee != nil && (employee.Role == models.RoleAdmin || employee.Role == models.RoleSuperAdmin)
+}
+
+func isSuperAdmin(r *http.Request) bool {
+	employee := currentEmployee(r)
+	return employee != nil && employee.Role == models.RoleSuperAdmin
+}
+
+// RequireTwoFactor не пропускает сотрудников, которые должны, но еще не
+// настроили двухфакторную аутентификацию. Им доступны только маршруты
+// /me/two-factor
+func (h *Handler) RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		employee := currentEmployee(r)
+		if employee != nil && !employee.TwoFactorEnabled && h.twoFactorService.Required(employee) {
+			respondWithError(w, r, apperror.Forbidden("Two-factor authentication must be set up at /me/two-factor before using the API"))
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
+// RequireAdmin пропускает к обработчику только администраторов
+func (h *Handler) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r) {
+			respondWithError(w, r, apperror.Forbidden("Only administrators can manage this resource"))
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// RequireSuperAdmin пропускает к обработчику только суперадминистраторов
+// группы
+func (h *Handler) RequireSuperAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isSuperAdmin(r) {
+			respondWithError(w, r, apperror.Forbidden("Only group super administrators can access this resource"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// includeDeleted читает параметр include_deleted. Удаленные записи могут
+// просматривать только администраторы; для остальных отвечает 403 и
+// возвращает ok = false
+func includeDeleted(w http.ResponseWriter, r *http.Request) (include bool, ok bool) {
+	if r.URL.Query().Get("include_deleted") != "true" {
+		return false, true
+	}
+	if !isAdmin(r) {
+		respondWithError(w, r, apperror.Forbidden("Only administrators can view deleted records"))
+		return false, false
+	}
+	return true, true
+}
+
 // ServeIndex обрабатывает запрос к главной странице
 func (h *Handler) ServeIndex(w http.ResponseWriter, r *http.Request) {
 	if _, err := os.Stat("./frontend/static/index.html"); os.IsNotExist(err) {
@@ -88,6 +270,17 @@ func (h *Handler) ServeRegister(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, "./frontend/static/register.html")
 }
 
+// ServeResetPassword обрабатывает запрос к странице сброса пароля
+func (h *Handler) ServeResetPassword(w http.ResponseWriter, r *http.Request) {
+	http.ServeFile(w, r, "./frontend/static/reset-password.html")
+}
+
+// ServeTwoFactor обрабатывает запрос к странице настройки двухфакторной
+// аутентификации
+func (h *Handler) ServeTwoFactor(w http.ResponseWriter, r *http.Request) {
+	http.ServeFile(w, r, "./frontend/static/two-factor.html")
+}
+
 // ServeAssets обрабатывает запрос к странице активов
 func (h *Handler) ServeAssets(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, "./frontend/static/assets.html")
@@ -112,3 +305,8 @@ func (h *Handler) ServeTransfers(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ServeReports(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, "./frontend/static/reports.html")
 }
+
+// ServeDocs обрабатывает запрос к странице документации API
+func (h *Handler) ServeDocs(w http.ResponseWriter, r *http.Request) {
+	http.ServeFile(w, r, "./frontend/static/docs.html")
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// GetAllInvitations возвращает приглашения, при необходимости только в
+// состоянии status
+func (h *Handler) GetAllInvitations(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.InvitationPending, models.InvitationAccepted, models.InvitationExpired, models.InvitationRevoked:
+	default:
+		respondWithError(w, r, apperror.Validation("Invalid status", map[string]string{
+			"status": "must be one of pending, accepted, expired, revoked",
+		}))
+		return
+	}
+
+	invitations, err := h.registrationService.GetInvitations(r.Context(), status)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, invitations)
+}
+
+// CreateInvitation приглашает сотрудника и отправляет ему ссылку
+// регистрации
+func (h *Handler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var request models.InvitationRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	invitation, err := h.registrationService.CreateInvitation(r.Context(), currentEmployee(r), request)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, invitation)
+}
+
+func (h *Handler) GetInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid invitation ID", nil))
+		return
+	}
+
+	invitation, err := h.registrationService.GetInvitation(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, invitation)
+}
+
+// ResendInvitation отправляет приглашение повторно с новой ссылкой и сроком
+func (h *Handler) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid invitation ID", nil))
+		return
+	}
+
+	invitation, err := h.registrationService.ResendInvitation(r.Context(), currentEmployee(r), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, invitation)
+}
+
+// RevokeInvitation отзывает непринятое приглашение
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid invitation ID", nil))
+		return
+	}
+
+	if err := h.registrationService.RevokeInvitation(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
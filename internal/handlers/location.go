@@ -1,18 +1,25 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"inventory-system/internal/apperror"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"inventory-system/internal/models"
+	"inventory-system/internal/services"
 )
 
 func (h *Handler) GetAllLocations(w http.ResponseWriter, r *http.Request) {
-	locations, err := h.locationService.GetAllLocations(r.Context())
+	withDeleted, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+
+	locations, err := h.locationService.GetAllLocations(r.Context(), withDeleted)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -22,29 +29,28 @@ func (h *Handler) GetAllLocations(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetLocation(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid location ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid location ID", nil))
 		return
 	}
 
 	location, err := h.locationService.GetLocationByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		respondWithError(w, r, err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, location)
+	respondWithVersion(w, http.StatusOK, location, location.Version)
 }
 
 func (h *Handler) CreateLocation(w http.ResponseWriter, r *http.Request) {
-	var location models.Location
-	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.LocationRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	id, err := h.locationService.CreateLocation(r.Context(), location)
+	id, err := h.locationService.CreateLocation(r.Context(), request.Location())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -54,34 +60,106 @@ func (h *Handler) CreateLocation(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid location ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid location ID", nil))
 		return
 	}
 
-	var location models.Location
-	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.LocationRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
+	location := request.Location()
 	location.ID = id
 
-	if err := h.locationService.UpdateLocation(r.Context(), location); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	location.Version = version
+
+	version, err = h.locationService.UpdateLocation(r.Context(), location)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.locationService.GetLocationByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) PatchLocation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid location ID", nil))
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	location, err := h.locationService.PatchLocation(r.Context(), id, version, patch)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.locationService.GetLocationByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithVersion(w, http.StatusOK, location, location.Version)
+}
+
 func (h *Handler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid location ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid location ID", nil))
+		return
+	}
+
+	if err := h.locationService.DeleteLocation(r.Context(), id, currentEmployee(r).ID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RestoreLocation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid location ID", nil))
+		return
+	}
+
+	if !isAdmin(r) {
+		respondWithError(w, r, apperror.Forbidden("Only administrators can restore deleted records"))
 		return
 	}
 
-	if err := h.locationService.DeleteLocation(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.locationService.RestoreLocation(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-system/internal/models"
+	"inventory-system/internal/services"
+	"net/http"
+)
+
+// GetMe возвращает профиль текущего сотрудника
+func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+	employee, err := h.employeeService.GetEmployeeByID(r.Context(), currentEmployee(r).ID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithVersion(w, http.StatusOK, employee, employee.Version)
+}
+
+// PatchMe изменяет профиль текущего сотрудника. Менять можно имя, должность
+// и email; роль и отдел меняют администраторы через /employees/{id}
+func (h *Handler) PatchMe(w http.ResponseWriter, r *http.Request) {
+	id := currentEmployee(r).ID
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	employee, err := h.employeeService.PatchProfile(r.Context(), id, version, patch)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := h.employeeService.GetEmployeeByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		respondWithVersion(w, http.StatusPreconditionFailed, current, current.Version)
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithVersion(w, http.StatusOK, employee, employee.Version)
+}
+
+// ChangeMyPassword меняет пароль текущего сотрудника после проверки
+// текущего пароля. Смена пароля завершает все сеансы, поэтому в ответе
+// возвращается новый токен для текущего
+func (h *Handler) ChangeMyPassword(w http.ResponseWriter, r *http.Request) {
+	var request models.ChangePasswordRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	employee := currentEmployee(r)
+	err := h.employeeService.ChangePassword(r.Context(), employee.ID, request.CurrentPassword, request.NewPassword)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	token, expiresAt, err := h.authService.GenerateToken(employee)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+// GetMyAssets возвращает активы отдела текущего сотрудника
+func (h *Handler) GetMyAssets(w http.ResponseWriter, r *http.Request) {
+	employee := currentEmployee(r)
+	if employee.DepartmentID == nil {
+		respondWithJSON(w, http.StatusOK, []models.Asset{})
+		return
+	}
+
+	assets, err := h.assetService.GetAssetsByDepartment(r.Context(), *employee.DepartmentID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, assets)
+}
+
+// GetMyTransfers возвращает перемещения, оформленные текущим сотрудником
+func (h *Handler) GetMyTransfers(w http.ResponseWriter, r *http.Request) {
+	transfers, err := h.transferService.GetTransfersByEmployee(r.Context(), currentEmployee(r).ID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, transfers)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetNotificationPreferences возвращает настройки почтовых уведомлений
+// текущего сотрудника
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	prefs, err := h.notificationService.GetPreferences(r.Context(), currentEmployee(r).ID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences заменяет настройки почтовых уведомлений
+// текущего сотрудника
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	var request models.NotificationPreferencesRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	prefs := request.Preferences(currentEmployee(r).ID)
+	if err := h.notificationService.UpdatePreferences(r.Context(), prefs); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// GetNotifications возвращает страницу входящих текущего сотрудника.
+// Параметры: unread=true — только непрочитанные, limit и offset
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter models.NotificationFilter
+
+	filter.UnreadOnly = query.Get("unread") == "true"
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			respondWithError(w, r, apperror.Validation("Invalid limit", nil))
+			return
+		}
+		filter.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			respondWithError(w, r, apperror.Validation("Invalid offset", nil))
+			return
+		}
+		filter.Offset = offset
+	}
+
+	page, err := h.notificationService.GetInbox(r.Context(), currentEmployee(r).ID, filter)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid notification ID", nil))
+		return
+	}
+
+	if err := h.notificationService.MarkRead(r.Context(), currentEmployee(r).ID, id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsRead отмечает прочитанными все уведомления текущего
+// сотрудника и возвращает их число
+func (h *Handler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	count, err := h.notificationService.MarkAllRead(r.Context(), currentEmployee(r).ID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]int64{"marked": count})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/services"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// oidcFlowCookie хранит состояние входа через провайдера OpenID Connect
+// между переходом на провайдера и возвратом с него
+const oidcFlowCookie = "oidc_flow"
+
+var errSSONotConfigured = apperror.NotFound("single sign-on is not configured")
+
+// GetSSOStatus сообщает странице входа, настроен ли единый вход
+func (h *Handler) GetSSOStatus(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]bool{"enabled": h.oidcService != nil})
+}
+
+// StartSSOLogin перенаправляет браузер на страницу входа провайдера
+func (h *Handler) StartSSOLogin(w http.ResponseWriter, r *http.Request) {
+	if h.oidcService == nil {
+		respondWithError(w, r, errSSONotConfigured)
+		return
+	}
+
+	authURL, flow, err := h.oidcService.Start(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    flow,
+		Path:     "/",
+		MaxAge:   int(services.OIDCFlowExpiry.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax: cookie должна прийти с переходом обратно с провайдера
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// SSOCallback принимает возврат с провайдера и перенаправляет браузер на
+// страницу входа с билетом для CompleteSSOLogin или с кодом ошибки.
+// Билет передается во фрагменте адреса, который не уходит на сервер и не
+// попадает в журналы
+func (h *Handler) SSOCallback(w http.ResponseWriter, r *http.Request) {
+	if h.oidcService == nil {
+		respondWithError(w, r, errSSONotConfigured)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+	http.SetCookie(w, &http.Cookie{Name: oidcFlowCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: r.TLS != nil})
+	if err != nil {
+		redirectToLogin(w, r, "sso_error", "expired")
+		return
+	}
+
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		log.Printf("OIDC provider returned error %q: %s", providerError, query.Get("error_description"))
+		redirectToLogin(w, r, "sso_error", "cancelled")
+		return
+	}
+
+	employee, err := h.oidcService.Finish(r.Context(), cookie.Value, query.Get("state"), query.Get("code"))
+	if err != nil {
+		redirectToLogin(w, r, "sso_error", ssoErrorCode(err))
+		return
+	}
+
+	ticket, _, err := h.authService.GenerateSSOTicket(employee)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	redirectToLogin(w, r, "sso_ticket", ticket)
+}
+
+// CompleteSSOLogin обменивает билет единого входа на токен доступа или,
+// если включена двухфакторная аутентификация, на токен второго шага
+func (h *Handler) CompleteSSOLogin(w http.ResponseWriter, r *http.Request) {
+	var request models.SSOCompleteRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	employee, err := h.authService.CompleteSSO(r.Context(), request.Ticket, clientIP(r), r.UserAgent())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	h.respondWithFirstFactor(w, r, employee)
+}
+
+func redirectToLogin(w http.ResponseWriter, r *http.Request, key, value string) {
+	http.Redirect(w, r, "/login#"+key+"="+url.QueryEscape(value), http.StatusFound)
+}
+
+// ssoErrorCode возвращает код ошибки единого входа для страницы входа
+func ssoErrorCode(err error) string {
+	switch err {
+	case services.ErrSSONoAccount:
+		return "no_account"
+	case services.ErrSSOEmailNotVerified:
+		return "email_not_verified"
+	case services.ErrSSOFailed:
+		return "failed"
+	}
+	log.Printf("SSO callback failed: %v", err)
+	return "failed"
+}
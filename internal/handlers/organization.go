@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/tenant"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) GetAllOrganizations(w http.ResponseWriter, r *http.Request) {
+	organizations, err := h.organizationService.GetAllOrganizations(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, organizations)
+}
+
+func (h *Handler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid organization ID", nil))
+		return
+	}
+
+	organization, err := h.organizationService.GetOrganizationByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, organization)
+}
+
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var request models.OrganizationRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	id, err := h.organizationService.CreateOrganization(r.Context(), request.Organization())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+func (h *Handler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid organization ID", nil))
+		return
+	}
+
+	var request models.OrganizationRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	organization := request.Organization()
+	organization.ID = id
+
+	if err := h.organizationService.UpdateOrganization(r.Context(), organization); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateOrganizationInvitation приглашает первого сотрудника, обычно
+// администратора, в организацию id. Дальше ее сотрудников приглашают
+// администраторы самой организации
+func (h *Handler) CreateOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid organization ID", nil))
+		return
+	}
+
+	var request models.InvitationRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	if _, err := h.organizationService.GetOrganizationByID(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	ctx := tenant.WithOrganization(r.Context(), id)
+	invitation, err := h.registrationService.CreateInvitation(ctx, currentEmployee(r), request)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, invitation)
+}
+
+// GetGroupInventoryReport возвращает сводный отчет по активам всех
+// организаций группы
+func (h *Handler) GetGroupInventoryReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportService.GenerateGroupInventoryReport(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// GetGroupAssetsByStatusReport возвращает распределение активов по
+// статусам в каждой организации группы и в группе в целом
+func (h *Handler) GetGroupAssetsByStatusReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportService.GenerateGroupAssetsByStatusReport(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
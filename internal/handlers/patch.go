@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"inventory-system/internal/mergepatch"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// readMergePatch читает тело PATCH-запроса в формате JSON Merge Patch.
+// Кроме application/merge-patch+json принимается application/json.
+// При ошибке отвечает клиенту и возвращает ok = false
+func readMergePatch(w http.ResponseWriter, r *http.Request) (patch mergepatch.Patch, ok bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != mergepatch.ContentType && mediaType != "application/json") {
+		w.Header().Set("Accept-Patch", mergepatch.ContentType)
+		respondWithProblem(w, r, http.StatusUnsupportedMediaType, "Content-Type must be "+mergepatch.ContentType, nil)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		respondWithBodyError(w, r, err)
+		return nil, false
+	}
+
+	patch, err = mergepatch.Parse(body)
+	if err != nil {
+		respondWithError(w, r, invalidBody(err))
+		return nil, false
+	}
+
+	return patch, true
+}
@@ -7,7 +7,7 @@ import (
 func (h *Handler) GetAssetsByStatusReport(w http.ResponseWriter, r *http.Request) {
 	report, err := h.reportService.GenerateAssetsByStatusReport(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -17,7 +17,7 @@ func (h *Handler) GetAssetsByStatusReport(w http.ResponseWriter, r *http.Request
 func (h *Handler) GetDepartmentCostsReport(w http.ResponseWriter, r *http.Request) {
 	report, err := h.reportService.GenerateAssetsCostByDepartmentReport(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -27,7 +27,17 @@ func (h *Handler) GetDepartmentCostsReport(w http.ResponseWriter, r *http.Reques
 func (h *Handler) GetInventoryReport(w http.ResponseWriter, r *http.Request) {
 	report, err := h.reportService.GenerateInventoryReport(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+func (h *Handler) GetCategoryReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportService.GenerateCategoryReport(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-system/internal/validate"
+	"io"
+	"net/http"
+)
+
+// maxBodyBytes ограничивает размер тела запроса
+const maxBodyBytes = 1 << 20
+
+// decodeJSON разбирает тело запроса в dst и проверяет его по тегам validate.
+// Неизвестные поля, лишние данные после объекта и слишком большое тело
+// считаются ошибкой. При ошибке отвечает клиенту и возвращает false
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(dst)
+	if err == nil {
+		if _, extra := decoder.Token(); extra != io.EOF {
+			err = errors.New("body must contain a single JSON object")
+		}
+	}
+	if err != nil {
+		respondWithBodyError(w, r, err)
+		return false
+	}
+
+	if err := validate.Struct(dst); err != nil {
+		respondWithError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// respondWithBodyError отвечает на ошибку чтения тела запроса
+func respondWithBodyError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondWithProblem(w, r, http.StatusRequestEntityTooLarge, "request body is too large", nil)
+		return
+	}
+	respondWithError(w, r, invalidBody(err))
+}
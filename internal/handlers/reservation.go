@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"inventory-system/internal/apperror"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/ical"
+	"inventory-system/internal/models"
+)
+
+func (h *Handler) GetAllReservations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter models.ReservationFilter
+
+	if value := query.Get("asset_id"); value != "" {
+		assetID, err := strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, r, apperror.Validation("Invalid asset_id", nil))
+			return
+		}
+		filter.AssetID = &assetID
+	}
+	if value := query.Get("employee_id"); value != "" {
+		employeeID, err := strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, r, apperror.Validation("Invalid employee_id", nil))
+			return
+		}
+		filter.EmployeeID = &employeeID
+	}
+	if value := query.Get("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondWithError(w, r, apperror.Validation("Invalid from date format", nil))
+			return
+		}
+		filter.From = &from
+	}
+	if value := query.Get("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondWithError(w, r, apperror.Validation("Invalid to date format", nil))
+			return
+		}
+		filter.To = &to
+	}
+	filter.Status = query.Get("status")
+
+	reservations, err := h.reservationService.GetReservations(r.Context(), filter)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reservations)
+}
+
+func (h *Handler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid reservation ID", nil))
+		return
+	}
+
+	reservation, err := h.reservationService.GetReservationByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reservation)
+}
+
+func (h *Handler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	var request models.ReservationRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	id, err := h.reservationService.CreateReservation(r.Context(), request.Reservation(), currentEmployee(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+func (h *Handler) ApproveReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid reservation ID", nil))
+		return
+	}
+
+	if err := h.reservationService.ApproveReservation(r.Context(), id, currentEmployee(r)); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RejectReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid reservation ID", nil))
+		return
+	}
+
+	if err := h.reservationService.RejectReservation(r.Context(), id, currentEmployee(r)); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) CancelReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid reservation ID", nil))
+		return
+	}
+
+	if err := h.reservationService.CancelReservation(r.Context(), id, currentEmployee(r)); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAvailableAssets ищет свободные активы:
+// /api/reservations/availability?category_id=3&location_id=1&from=...&to=...
+func (h *Handler) GetAvailableAssets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var availability models.AvailabilityQuery
+	var err error
+
+	availability.From, err = time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid from date format", nil))
+		return
+	}
+	availability.To, err = time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid to date format", nil))
+		return
+	}
+
+	if value := query.Get("category_id"); value != "" {
+		categoryID, err := strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, r, apperror.Validation("Invalid category_id", nil))
+			return
+		}
+		availability.CategoryID = &categoryID
+	}
+	if value := query.Get("location_id"); value != "" {
+		locationID, err := strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, r, apperror.Validation("Invalid location_id", nil))
+			return
+		}
+		availability.LocationID = &locationID
+	}
+
+	assets, err := h.reservationService.GetAvailableAssets(r.Context(), availability)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, assets)
+}
+
+func (h *Handler) GetAssetCalendar(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid asset ID", nil))
+		return
+	}
+
+	calendar, err := h.reservationService.GetAssetCalendar(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithCalendar(w, calendar)
+}
+
+func (h *Handler) GetEmployeeCalendar(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
+		return
+	}
+
+	calendar, err := h.reservationService.GetEmployeeCalendar(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithCalendar(w, calendar)
+}
+
+func respondWithCalendar(w http.ResponseWriter, calendar *ical.Calendar) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="reservations.ics"`)
+	w.WriteHeader(http.StatusOK)
+	calendar.Write(w, time.Now())
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"encoding/json"
+	"inventory-system/internal/apperror"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,7 +13,7 @@ import (
 func (h *Handler) GetAllTransfers(w http.ResponseWriter, r *http.Request) {
 	transfers, err := h.transferService.GetAllTransfers(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -23,13 +23,13 @@ func (h *Handler) GetAllTransfers(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetTransfer(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		respondWithError(w, r, apperror.Validation("Invalid transfer ID", nil))
 		return
 	}
 
 	transfer, err := h.transferService.GetTransferByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -37,15 +37,14 @@ func (h *Handler) GetTransfer(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
-	var transfer models.AssetTransfer
-	if err := json.NewDecoder(r.Body).Decode(&transfer); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.TransferRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	id, err := h.transferService.CreateTransfer(r.Context(), transfer)
+	id, err := h.transferService.CreateTransfer(r.Context(), request.Transfer())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -62,7 +61,7 @@ func (h *Handler) GetTransfersReport(w http.ResponseWriter, r *http.Request) {
 	if fromStr != "" {
 		from, err = time.Parse("2006-01-02", fromStr)
 		if err != nil {
-			http.Error(w, "Invalid from date format", http.StatusBadRequest)
+			respondWithError(w, r, apperror.Validation("Invalid from date format", nil))
 			return
 		}
 	} else {
@@ -72,7 +71,7 @@ func (h *Handler) GetTransfersReport(w http.ResponseWriter, r *http.Request) {
 	if toStr != "" {
 		to, err = time.Parse("2006-01-02", toStr)
 		if err != nil {
-			http.Error(w, "Invalid to date format", http.StatusBadRequest)
+			respondWithError(w, r, apperror.Validation("Invalid to date format", nil))
 			return
 		}
 	} else {
@@ -81,7 +80,7 @@ func (h *Handler) GetTransfersReport(w http.ResponseWriter, r *http.Request) {
 
 	transfers, err := h.reportService.GenerateTransfersReport(r.Context(), from, to)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
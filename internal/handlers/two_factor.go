@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// GetTwoFactorStatus возвращает состояние двухфакторной аутентификации
+// текущего сотрудника
+func (h *Handler) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.twoFactorService.Status(r.Context(), currentEmployee(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// SetupTwoFactor выпускает секрет для приложения-аутентификатора
+func (h *Handler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	setup, err := h.twoFactorService.Setup(r.Context(), currentEmployee(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, setup)
+}
+
+// EnableTwoFactor включает двухфакторную аутентификацию по первому коду из
+// приложения и возвращает резервные коды
+func (h *Handler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var request models.TwoFactorCodeRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	codes, err := h.twoFactorService.Enable(r.Context(), currentEmployee(r), request.Code)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.RecoveryCodes{Codes: codes})
+}
+
+// DisableTwoFactor отключает двухфакторную аутентификацию текущего
+// сотрудника
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var request models.DisableTwoFactorRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	if err := h.twoFactorService.Disable(r.Context(), currentEmployee(r), request.Password, request.Code); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes выпускает новые резервные коды взамен прежних
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var request models.TwoFactorCodeRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(r.Context(), currentEmployee(r), request.Code)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.RecoveryCodes{Codes: codes})
+}
+
+// ResetEmployeeTwoFactor отключает двухфакторную аутентификацию сотрудника,
+// потерявшего устройство и резервные коды
+func (h *Handler) ResetEmployeeTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid employee ID", nil))
+		return
+	}
+
+	if err := h.twoFactorService.Reset(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
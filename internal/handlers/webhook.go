@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.webhookService.GetAllSubscriptions(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, subscriptions)
+}
+
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid webhook ID", nil))
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, subscription)
+}
+
+// CreateWebhook создает подписку. Ответ содержит секрет для проверки
+// подписи; позже он не возвращается
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var request models.WebhookRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	subscription, err := h.webhookService.CreateSubscription(r.Context(), request.Subscription())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, subscription)
+}
+
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid webhook ID", nil))
+		return
+	}
+
+	var request models.WebhookRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	subscription := request.Subscription()
+	subscription.ID = id
+
+	if err := h.webhookService.UpdateSubscription(r.Context(), subscription); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid webhook ID", nil))
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries возвращает журнал доставок подписки
+func (h *Handler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid webhook ID", nil))
+		return
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// RetryWebhookDelivery ставит недоставленное событие в очередь повторно
+func (h *Handler) RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid webhook ID", nil))
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		respondWithError(w, r, apperror.Validation("Invalid delivery ID", nil))
+		return
+	}
+
+	if err := h.webhookService.Redeliver(r.Context(), id, deliveryID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
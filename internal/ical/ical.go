@@ -0,0 +1,105 @@
+// Package ical формирует календари в формате iCalendar (RFC 5545)
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const timeFormat = "20060102T150405Z"
+
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+	Start       time.Time
+	End         time.Time
+	Created     time.Time
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Write записывает календарь в w. Строки разделяются CRLF и переносятся
+// после 75 байт, как требует RFC 5545
+func (c Calendar) Write(w io.Writer, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//inventory-system//reservations//RU",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escape(c.Name),
+	}
+
+	for _, e := range c.Events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escape(e.UID),
+			"DTSTAMP:"+now.UTC().Format(timeFormat),
+			"DTSTART:"+e.Start.UTC().Format(timeFormat),
+			"DTEND:"+e.End.UTC().Format(timeFormat),
+			"SUMMARY:"+escape(e.Summary),
+		)
+		if !e.Created.IsZero() {
+			lines = append(lines, "CREATED:"+e.Created.UTC().Format(timeFormat))
+		}
+		if e.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.Location != "" {
+			lines = append(lines, "LOCATION:"+escape(e.Location))
+		}
+		if e.Status != "" {
+			lines = append(lines, "STATUS:"+e.Status)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := fmt.Fprint(w, fold(line), "\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escape(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// fold переносит строку длиннее 75 байт, не разрывая многобайтовые символы
+func fold(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,213 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Классы и флаги тегов BER (X.690), используемые в LDAP
+const (
+	classApplication byte = 0x40
+	classContext     byte = 0x80
+	constructed      byte = 0x20
+)
+
+// Универсальные теги
+const (
+	tagBoolean     byte = 0x01
+	tagInteger     byte = 0x02
+	tagOctetString byte = 0x04
+	tagEnumerated  byte = 0x0a
+	tagSequence    byte = 0x10 | constructed
+)
+
+// maxPacketSize ограничивает размер ответа сервера
+const maxPacketSize = 16 << 20
+
+// packet — элемент BER: примитивный со значением value или составной с
+// вложенными элементами children. Поддерживаются только однобайтовые теги,
+// которых достаточно для LDAP
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+func (p *packet) isConstructed() bool {
+	return p.tag&constructed != 0
+}
+
+func newSequence(tag byte, children ...*packet) *packet {
+	return &packet{tag: tag, children: children}
+}
+
+func newString(tag byte, value string) *packet {
+	return &packet{tag: tag, value: []byte(value)}
+}
+
+func newInteger(tag byte, value int64) *packet {
+	// Минимальное представление в дополнительном коде
+	var b []byte
+	for {
+		b = append([]byte{byte(value)}, b...)
+		value >>= 8
+		if (value == 0 && b[0]&0x80 == 0) || (value == -1 && b[0]&0x80 != 0) {
+			break
+		}
+	}
+	return &packet{tag: tag, value: b}
+}
+
+func newBoolean(value bool) *packet {
+	if value {
+		return &packet{tag: tagBoolean, value: []byte{0xff}}
+	}
+	return &packet{tag: tagBoolean, value: []byte{0x00}}
+}
+
+// encode возвращает BER-представление элемента
+func (p *packet) encode() []byte {
+	content := p.value
+	if p.isConstructed() {
+		content = nil
+		for _, child := range p.children {
+			content = append(content, child.encode()...)
+		}
+	}
+
+	out := []byte{p.tag}
+	out = append(out, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// readPacket читает один элемент BER из r
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag&0x1f == 0x1f {
+		return nil, errors.New("ldap: multi-byte BER tags are not supported")
+	}
+
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return parsePacket(tag, content)
+}
+
+func readLength(r *bufio.Reader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+
+	count := int(first & 0x7f)
+	if count == 0 || count > 4 {
+		return 0, fmt.Errorf("ldap: unsupported BER length encoding 0x%02x", first)
+	}
+	length := 0
+	for i := 0; i < count; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketSize {
+		return 0, fmt.Errorf("ldap: packet of %d bytes is too large", length)
+	}
+	return length, nil
+}
+
+// parsePacket разбирает содержимое элемента с тегом tag
+func parsePacket(tag byte, content []byte) (*packet, error) {
+	p := &packet{tag: tag}
+	if !p.isConstructed() {
+		p.value = content
+		return p, nil
+	}
+
+	for len(content) > 0 {
+		if len(content) < 2 {
+			return nil, errors.New("ldap: truncated BER element")
+		}
+		childTag := content[0]
+		length, header, err := parseLength(content[1:])
+		if err != nil {
+			return nil, err
+		}
+		start := 1 + header
+		if len(content) < start+length {
+			return nil, errors.New("ldap: truncated BER element")
+		}
+		child, err := parsePacket(childTag, content[start:start+length])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		content = content[start+length:]
+	}
+	return p, nil
+}
+
+// parseLength разбирает длину в начале b и возвращает ее и число
+// занятых ею байт
+func parseLength(b []byte) (length, size int, err error) {
+	if b[0] < 0x80 {
+		return int(b[0]), 1, nil
+	}
+	count := int(b[0] & 0x7f)
+	if count == 0 || count > 4 || len(b) < 1+count {
+		return 0, 0, errors.New("ldap: invalid BER length")
+	}
+	for _, v := range b[1 : 1+count] {
+		length = length<<8 | int(v)
+	}
+	return length, 1 + count, nil
+}
+
+// int возвращает значение целого элемента (INTEGER, ENUMERATED)
+func (p *packet) int() int64 {
+	var value int64
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			value = -1
+		}
+		value = value<<8 | int64(b)
+	}
+	return value
+}
+
+func (p *packet) string() string {
+	return string(p.value)
+}
+
+// child возвращает i-й вложенный элемент или nil
+func (p *packet) child(i int) *packet {
+	if i < len(p.children) {
+		return p.children[i]
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, b []byte) *packet {
+	t.Helper()
+	p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatalf("readPacket(% x): %v", b, err)
+	}
+	return p
+}
+
+func TestIntegerEncoding(t *testing.T) {
+	tests := []struct {
+		value int64
+		want  []byte
+	}{
+		{0, []byte{0x02, 0x01, 0x00}},
+		{1, []byte{0x02, 0x01, 0x01}},
+		{127, []byte{0x02, 0x01, 0x7f}},
+		{128, []byte{0x02, 0x02, 0x00, 0x80}},
+		{256, []byte{0x02, 0x02, 0x01, 0x00}},
+		{-1, []byte{0x02, 0x01, 0xff}},
+		{-128, []byte{0x02, 0x01, 0x80}},
+		{-129, []byte{0x02, 0x02, 0xff, 0x7f}},
+	}
+	for _, tt := range tests {
+		got := newInteger(tagInteger, tt.value).encode()
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("newInteger(%d) = % x, want % x", tt.value, got, tt.want)
+		}
+		if back := decode(t, got).int(); back != tt.value {
+			t.Errorf("round trip of %d = %d", tt.value, back)
+		}
+	}
+
+	for _, value := range []int64{math.MaxInt32, math.MinInt32, math.MaxInt64, math.MinInt64} {
+		if back := decode(t, newInteger(tagEnumerated, value).encode()).int(); back != value {
+			t.Errorf("round trip of %d = %d", value, back)
+		}
+	}
+}
+
+func TestLengthEncoding(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0x81, 0x80}},
+		{0xff, []byte{0x81, 0xff}},
+		{0x100, []byte{0x82, 0x01, 0x00}},
+		{0x10000, []byte{0x83, 0x01, 0x00, 0x00}},
+	}
+	for _, tt := range tests {
+		if got := encodeLength(tt.n); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeLength(%d) = % x, want % x", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	msg := newSequence(tagSequence,
+		newInteger(tagInteger, 7),
+		newSequence(opSearchRequest,
+			newString(tagOctetString, "dc=example,dc=com"),
+			newInteger(tagEnumerated, ScopeWholeSubtree),
+			newBoolean(true),
+			newString(tagOctetString, long),
+			newSequence(tagSequence),
+		),
+	)
+
+	got := decode(t, msg.encode())
+	if got.tag != tagSequence || len(got.children) != 2 || got.child(0).int() != 7 {
+		t.Fatalf("envelope = %+v", got)
+	}
+	op := got.child(1)
+	if op.tag != opSearchRequest || len(op.children) != 5 {
+		t.Fatalf("operation tag 0x%02x with %d children", op.tag, len(op.children))
+	}
+	if s := op.child(0).string(); s != "dc=example,dc=com" {
+		t.Errorf("base = %q", s)
+	}
+	if op.child(1).int() != ScopeWholeSubtree {
+		t.Errorf("scope = %d", op.child(1).int())
+	}
+	if !bytes.Equal(op.child(2).value, []byte{0xff}) {
+		t.Errorf("boolean = % x", op.child(2).value)
+	}
+	if op.child(3).string() != long {
+		t.Errorf("long string of %d bytes came back as %d bytes", len(long), len(op.child(3).value))
+	}
+	if empty := op.child(4); empty.tag != tagSequence || len(empty.children) != 0 {
+		t.Errorf("empty sequence = %+v", empty)
+	}
+	if op.child(5) != nil {
+		t.Error("child past the end is not nil")
+	}
+}
+
+func TestReadPacketErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"multi-byte tag":        {0x1f, 0x01, 0x00},
+		"indefinite length":     {0x30, 0x80},
+		"too many length bytes": {0x04, 0x85, 0x01, 0x00, 0x00, 0x00, 0x00},
+		"too large":             {0x04, 0x84, 0x7f, 0xff, 0xff, 0xff},
+		"truncated content":     {0x04, 0x05, 'a', 'b'},
+		"truncated child":       {0x30, 0x03, 0x04, 0x05, 'a'},
+		"bad child length":      {0x30, 0x02, 0x04, 0x80},
+	}
+	for name, b := range tests {
+		if _, err := readPacket(bufio.NewReader(bytes.NewReader(b))); err == nil {
+			t.Errorf("%s: readPacket(% x) succeeded", name, b)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+// Package ldap — минимальный клиент LDAPv3 (RFC 4511) для аутентификации:
+// простая привязка (bind), поиск по фильтру и StartTLS. Полноценная
+// библиотека здесь не нужна, а лишняя зависимость — тем более
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Операции протокола (RFC 4511, раздел 4.2)
+const (
+	opBindRequest       = classApplication | constructed | 0
+	opBindResponse      = classApplication | constructed | 1
+	opUnbindRequest     = classApplication | 2
+	opSearchRequest     = classApplication | constructed | 3
+	opSearchEntry       = classApplication | constructed | 4
+	opSearchDone        = classApplication | constructed | 5
+	opSearchReference   = classApplication | constructed | 19
+	opExtendedRequest   = classApplication | constructed | 23
+	opExtendedResponse  = classApplication | constructed | 24
+	authSimple          = classContext | 0
+	extendedRequestName = classContext | 0
+)
+
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// Коды результата, которые клиент различает
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+)
+
+// Области поиска
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// ErrInvalidCredentials возвращается Bind, когда сервер отклонил имя или
+// пароль
+var ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+
+// ResultError — неуспешный код результата операции
+type ResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Config описывает подключение к серверу
+type Config struct {
+	// URL вида ldap://host:389 или ldaps://host:636
+	URL string
+	// StartTLS включает шифрование по StartTLS для ldap://
+	StartTLS bool
+	// TLS — настройки TLS; ServerName по умолчанию берется из URL
+	TLS *tls.Config
+	// Timeout ограничивает подключение и каждую операцию
+	Timeout time.Duration
+}
+
+// Entry — найденная запись каталога
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get возвращает первое значение атрибута без учета регистра имени или ""
+func (e *Entry) Get(name string) string {
+	if values := e.GetAll(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// GetAll возвращает все значения атрибута без учета регистра имени
+func (e *Entry) GetAll(name string) []string {
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// SearchRequest — параметры поиска
+type SearchRequest struct {
+	BaseDN     string
+	Scope      int
+	Filter     string
+	Attributes []string
+	SizeLimit  int
+}
+
+// Conn — соединение с сервером. Операции выполняются последовательно
+type Conn struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	nextID  int64
+}
+
+// Dial подключается к серверу по cfg
+func Dial(ctx context.Context, cfg Config) (*Conn, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid url: %w", err)
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+	default:
+		return nil, fmt.Errorf("ldap: unsupported scheme %q", u.Scheme)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLS != nil {
+		tlsConfig = cfg.TLS.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	var netConn net.Conn
+	if u.Scheme == "ldaps" {
+		netConn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap: dial %s: %w", cfg.URL, err)
+	}
+
+	c := &Conn{conn: netConn, reader: bufio.NewReader(netConn), timeout: cfg.Timeout}
+	if u.Scheme == "ldap" && cfg.StartTLS {
+		if err := c.startTLS(ctx, tlsConfig); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close отправляет unbind и закрывает соединение
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	msg := newSequence(tagSequence, newInteger(tagInteger, c.nextID), &packet{tag: opUnbindRequest})
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.conn.Write(msg.encode())
+	return c.conn.Close()
+}
+
+// Bind выполняет простую привязку. Пустой пароль запрещен: по RFC 4513 это
+// анонимная привязка, которую сервер принял бы для любого dn
+func (c *Conn) Bind(ctx context.Context, dn, password string) error {
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+
+	request := newSequence(opBindRequest,
+		newInteger(tagInteger, 3),
+		newString(tagOctetString, dn),
+		newString(authSimple, password),
+	)
+	responses, err := c.roundTrip(ctx, request, opBindResponse)
+	if err != nil {
+		return err
+	}
+	if err := resultError(responses[0]); err != nil {
+		var resultErr *ResultError
+		if errors.As(err, &resultErr) && resultErr.Code == ResultInvalidCredentials {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+	return nil
+}
+
+// Search выполняет поиск и возвращает найденные записи. Ссылки на другие
+// серверы (referrals) пропускаются
+func (c *Conn) Search(ctx context.Context, req SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := newSequence(tagSequence)
+	for _, attr := range req.Attributes {
+		attributes.children = append(attributes.children, newString(tagOctetString, attr))
+	}
+	request := newSequence(opSearchRequest,
+		newString(tagOctetString, req.BaseDN),
+		newInteger(tagEnumerated, int64(req.Scope)),
+		newInteger(tagEnumerated, 0), // neverDerefAliases
+		newInteger(tagInteger, int64(req.SizeLimit)),
+		newInteger(tagInteger, int64(c.timeout/time.Second)),
+		newBoolean(false),
+		filter,
+		attributes,
+	)
+
+	responses, err := c.roundTrip(ctx, request, opSearchDone)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, op := range responses {
+		switch op.tag {
+		case opSearchEntry:
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchDone:
+			if err := resultError(op); err != nil {
+				var resultErr *ResultError
+				// Превышение лимита не ошибка: вызывающий сам решает,
+				// что делать с неоднозначным результатом
+				if !errors.As(err, &resultErr) || resultErr.Code != ResultSizeLimitExceeded {
+					return nil, err
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+func (c *Conn) startTLS(ctx context.Context, config *tls.Config) error {
+	request := newSequence(opExtendedRequest, newString(extendedRequestName, startTLSOID))
+	responses, err := c.roundTrip(ctx, request, opExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := resultError(responses[0]); err != nil {
+		return fmt.Errorf("ldap: starttls: %w", err)
+	}
+
+	tlsConn := tls.Client(c.conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("ldap: starttls: %w", err)
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// roundTrip отправляет запрос и читает ответы на него до операции final
+// включительно
+func (c *Conn) roundTrip(ctx context.Context, request *packet, final byte) ([]*packet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := time.Time{}
+	if c.timeout > 0 {
+		deadline = time.Now().Add(c.timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	c.nextID++
+	id := c.nextID
+	msg := newSequence(tagSequence, newInteger(tagInteger, id), request)
+	if _, err := c.conn.Write(msg.encode()); err != nil {
+		return nil, fmt.Errorf("ldap: write: %w", err)
+	}
+
+	var responses []*packet
+	for {
+		response, err := readPacket(c.reader)
+		if err != nil {
+			return nil, fmt.Errorf("ldap: read: %w", err)
+		}
+		if response.tag != tagSequence || len(response.children) < 2 {
+			return nil, errors.New("ldap: malformed message")
+		}
+		// Уведомления с идентификатором 0 (например, о разрыве сессии) и
+		// ответы на чужие запросы пропускаются
+		if response.child(0).int() != id {
+			continue
+		}
+
+		op := response.child(1)
+		if op.tag == opSearchReference {
+			continue
+		}
+		responses = append(responses, op)
+		if op.tag == final {
+			return responses, nil
+		}
+		if op.tag != opSearchEntry {
+			return nil, fmt.Errorf("ldap: unexpected operation 0x%02x", op.tag)
+		}
+	}
+}
+
+// resultError разбирает LDAPResult и возвращает ошибку для неуспешного кода
+func resultError(op *packet) error {
+	code := op.child(0)
+	if code == nil {
+		return errors.New("ldap: malformed result")
+	}
+	if code.int() == ResultSuccess {
+		return nil
+	}
+
+	message := ""
+	if diagnostic := op.child(2); diagnostic != nil {
+		message = diagnostic.string()
+	}
+	return &ResultError{Code: int(code.int()), Message: message}
+}
+
+func parseEntry(op *packet) (*Entry, error) {
+	if len(op.children) < 2 {
+		return nil, errors.New("ldap: malformed search entry")
+	}
+
+	entry := &Entry{DN: op.child(0).string(), Attributes: make(map[string][]string)}
+	for _, attr := range op.child(1).children {
+		if len(attr.children) < 2 {
+			return nil, errors.New("ldap: malformed attribute")
+		}
+		name := attr.child(0).string()
+		for _, value := range attr.child(1).children {
+			entry.Attributes[name] = append(entry.Attributes[name], value.string())
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,134 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"inventory-system/internal/ldap/ldaptest"
+)
+
+func dialTest(t *testing.T, server *ldaptest.Server) *Conn {
+	t.Helper()
+	conn, err := Dial(context.Background(), Config{URL: server.URL, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestBind(t *testing.T) {
+	server := ldaptest.NewServer(t, ldaptest.Entry{DN: "uid=alice,ou=people,dc=example,dc=com", Password: "secret"})
+	conn := dialTest(t, server)
+	ctx := context.Background()
+
+	if err := conn.Bind(ctx, "uid=alice,ou=people,dc=example,dc=com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password: err = %v", err)
+	}
+	if err := conn.Bind(ctx, "uid=bob,ou=people,dc=example,dc=com", "secret"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown dn: err = %v", err)
+	}
+	// An empty password would be an anonymous bind that servers accept for any dn
+	if err := conn.Bind(ctx, "uid=alice,ou=people,dc=example,dc=com", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("empty password: err = %v", err)
+	}
+	if err := conn.Bind(ctx, "uid=alice,ou=people,dc=example,dc=com", "secret"); err != nil {
+		t.Errorf("valid bind: %v", err)
+	}
+
+	if binds := server.Binds(); !reflect.DeepEqual(binds, []string{"uid=alice,ou=people,dc=example,dc=com"}) {
+		t.Errorf("server saw binds %v", binds)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	server := ldaptest.NewServer(t,
+		ldaptest.Entry{
+			DN: "uid=alice,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"uid":      {"alice"},
+				"mail":     {"alice@example.com"},
+				"memberOf": {"cn=admins,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+				"secret":   {"not requested"},
+			},
+		},
+		ldaptest.Entry{
+			DN:         "uid=bob,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"bob"}, "mail": {"bob@example.com"}},
+		},
+		ldaptest.Entry{
+			DN:         "uid=carol,ou=people,dc=other,dc=com",
+			Attributes: map[string][]string{"uid": {"carol"}},
+		},
+	)
+	conn := dialTest(t, server)
+	ctx := context.Background()
+
+	entries, err := conn.Search(ctx, SearchRequest{
+		BaseDN:     "dc=example,dc=com",
+		Scope:      ScopeWholeSubtree,
+		Filter:     "(uid=" + EscapeFilter("alice") + ")",
+		Attributes: []string{"mail", "memberOf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("found %d entries, want 1", len(entries))
+	}
+	alice := entries[0]
+	if alice.DN != "uid=alice,ou=people,dc=example,dc=com" || alice.Get("MAIL") != "alice@example.com" {
+		t.Errorf("entry = %+v", alice)
+	}
+	if groups := alice.GetAll("memberof"); len(groups) != 2 {
+		t.Errorf("memberOf = %v", groups)
+	}
+	if alice.Get("secret") != "" {
+		t.Error("attribute that was not requested is returned")
+	}
+
+	// Only entries under the base are returned
+	entries, err = conn.Search(ctx, SearchRequest{BaseDN: "dc=example,dc=com", Scope: ScopeWholeSubtree, Filter: "(uid=*)"})
+	if err != nil || len(entries) != 2 {
+		t.Errorf("presence filter: %d entries, %v", len(entries), err)
+	}
+
+	// Exceeding the size limit is not an error; the caller sees the entries
+	entries, err = conn.Search(ctx, SearchRequest{BaseDN: "dc=example,dc=com", Scope: ScopeWholeSubtree, Filter: "(mail=*)", SizeLimit: 1})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("size limit: %d entries, %v", len(entries), err)
+	}
+
+	// A login with filter metacharacters matches nothing instead of everything
+	entries, err = conn.Search(ctx, SearchRequest{BaseDN: "dc=example,dc=com", Scope: ScopeWholeSubtree, Filter: "(uid=" + EscapeFilter("*") + ")"})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("escaped wildcard: %d entries, %v", len(entries), err)
+	}
+	if filters := server.Filters(); filters[len(filters)-1] != `(uid=\2a)` {
+		t.Errorf("server saw filter %q", filters[len(filters)-1])
+	}
+
+	if _, err := conn.Search(ctx, SearchRequest{Filter: "uid=alice"}); err == nil {
+		t.Error("malformed filter accepted")
+	}
+}
+
+func TestStartTLSRefused(t *testing.T) {
+	server := ldaptest.NewServer(t)
+	_, err := Dial(context.Background(), Config{URL: server.URL, StartTLS: true, Timeout: 5 * time.Second})
+	var resultErr *ResultError
+	if !errors.As(err, &resultErr) {
+		t.Errorf("err = %v, want the server's result code", err)
+	}
+}
+
+func TestDialErrors(t *testing.T) {
+	for _, url := range []string{"http://example.com", "://bad"} {
+		if _, err := Dial(context.Background(), Config{URL: url}); err == nil {
+			t.Errorf("Dial(%q) succeeded", url)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package ldap
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	tests := map[string]string{
+		"alice":              "alice",
+		"*":                  `\2a`,
+		"a*)(uid=*":          `a\2a\29\28uid=\2a`,
+		`back\slash`:         `back\5cslash`,
+		"nul\x00byte":        `nul\00byte`,
+		"Ёлкин":              "Ёлкин",
+		"*)(|(objectClass=*": `\2a\29\28|\28objectClass=\2a`,
+	}
+	for in, want := range tests {
+		if got := EscapeFilter(in); got != want {
+			t.Errorf("EscapeFilter(%q) = %q, want %q", in, got, want)
+		}
+		back, err := unescapeFilter(EscapeFilter(in))
+		if err != nil || back != in {
+			t.Errorf("unescapeFilter(EscapeFilter(%q)) = %q, %v", in, back, err)
+		}
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	equality := func(attr, value string) *packet {
+		return newSequence(filterEquality, newString(tagOctetString, attr), newString(tagOctetString, value))
+	}
+
+	tests := []struct {
+		filter string
+		want   *packet
+	}{
+		{"(uid=alice)", equality("uid", "alice")},
+		{" (mail=*) ", newString(filterPresent, "mail")},
+		{`(cn=a\2a\29b)`, equality("cn", "a*)b")},
+		{
+			"(&(objectClass=person)(|(uid=alice)(mail=alice@example.com))(!(disabled=TRUE)))",
+			newSequence(filterAnd,
+				equality("objectClass", "person"),
+				newSequence(filterOr, equality("uid", "alice"), equality("mail", "alice@example.com")),
+				newSequence(filterNot, equality("disabled", "TRUE")),
+			),
+		},
+	}
+	for _, tt := range tests {
+		got, err := compileFilter(tt.filter)
+		if err != nil {
+			t.Errorf("compileFilter(%q): %v", tt.filter, err)
+			continue
+		}
+		if !bytes.Equal(got.encode(), tt.want.encode()) {
+			t.Errorf("compileFilter(%q) = % x, want % x", tt.filter, got.encode(), tt.want.encode())
+		}
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	for _, filter := range []string{
+		"",
+		"uid=alice",
+		"(uid=alice",
+		"(uid=alice))",
+		"(&)",
+		"(&(uid=alice)",
+		"(!(uid=alice)(cn=x))",
+		"(=alice)",
+		"(uid)",
+		"(uid>=5)",
+		"(uid~=alice)",
+		"(cn:dn:=x)",
+		"(cn=ali*)",
+		`(cn=\zz)`,
+		`(cn=abc\2)`,
+	} {
+		if _, err := compileFilter(filter); err == nil {
+			t.Errorf("compileFilter(%q) succeeded", filter)
+		}
+	}
+}
+
+// TestEscapedLoginStaysOneItem проверяет, что логин со спецсимволами не
+// меняет структуру фильтра, в который он подставлен
+func TestEscapedLoginStaysOneItem(t *testing.T) {
+	login := "*)(uid=*))(|(uid=*"
+	got, err := compileFilter("(&(objectClass=person)(uid=" + EscapeFilter(login) + "))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.children) != 2 || got.child(1).child(1).string() != login {
+		t.Errorf("escaped login changed the filter: % x", got.encode())
+	}
+}
@@ -0,0 +1,355 @@
+// Package ldaptest — LDAP-сервер в памяти для тестов клиента и провайдера
+// входа через каталог. Сервер понимает простую привязку, поиск с
+// фильтрами &, |, !, равенства и наличия атрибута и unbind. Кодирование
+// BER здесь свое, независимое от клиента: иначе тесты проверяли бы
+// кодировщик им же самим
+package ldaptest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Коды результата, которые возвращает сервер
+const (
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+)
+
+// Entry — запись каталога. Password пустой у записей, к которым нельзя
+// привязаться
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server — LDAP-сервер на случайном порту 127.0.0.1
+type Server struct {
+	// URL вида ldap://127.0.0.1:port
+	URL string
+
+	listener net.Listener
+	entries  []Entry
+
+	mu      sync.Mutex
+	binds   []string
+	filters []string
+}
+
+// NewServer запускает сервер с записями entries и останавливает его по
+// завершении теста
+func NewServer(t testing.TB, entries ...Entry) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{URL: "ldap://" + listener.Addr().String(), listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+// Binds возвращает DN успешных привязок в порядке их выполнения
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+// Filters возвращает фильтры поисков в строковом виде (RFC 4515)
+func (s *Server) Filters() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.filters...)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		msg, err := readElement(reader)
+		if err != nil || len(msg.children) < 2 {
+			return
+		}
+		id, op := msg.children[0].value, msg.children[1]
+
+		var responses []*element
+		switch op.tag {
+		case 0x60: // BindRequest
+			responses = []*element{s.bind(op)}
+		case 0x63: // SearchRequest
+			responses = s.search(op)
+		case 0x42: // UnbindRequest
+			return
+		case 0x77: // ExtendedRequest
+			responses = []*element{result(0x78, resultProtocolError, "extended operations are not supported")}
+		default:
+			return
+		}
+		for _, response := range responses {
+			out := constructed(0x30, &element{tag: 0x02, value: id}, response)
+			if _, err := conn.Write(out.bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) bind(op *element) *element {
+	if len(op.children) < 3 || op.children[2].tag != 0x80 {
+		return result(0x61, resultProtocolError, "only simple bind is supported")
+	}
+	dn, password := string(op.children[1].value), string(op.children[2].value)
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			s.mu.Lock()
+			s.binds = append(s.binds, entry.DN)
+			s.mu.Unlock()
+			return result(0x61, resultSuccess, "")
+		}
+	}
+	return result(0x61, resultInvalidCredentials, "invalid credentials")
+}
+
+func (s *Server) search(op *element) []*element {
+	if len(op.children) < 8 {
+		return []*element{result(0x65, resultProtocolError, "malformed search request")}
+	}
+	base := strings.ToLower(string(op.children[0].value))
+	sizeLimit := int(integer(op.children[3].value))
+	filter := op.children[6]
+	var requested []string
+	for _, attr := range op.children[7].children {
+		requested = append(requested, string(attr.value))
+	}
+
+	s.mu.Lock()
+	s.filters = append(s.filters, formatFilter(filter))
+	s.mu.Unlock()
+
+	var responses []*element
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), base) || !matches(filter, entry) {
+			continue
+		}
+		if sizeLimit > 0 && len(responses) == sizeLimit {
+			return append(responses, result(0x65, resultSizeLimitExceeded, ""))
+		}
+		responses = append(responses, searchEntry(entry, requested))
+	}
+	return append(responses, result(0x65, resultSuccess, ""))
+}
+
+func searchEntry(entry Entry, requested []string) *element {
+	attributes := constructed(0x30)
+	for name, values := range entry.Attributes {
+		if len(requested) > 0 && !containsFold(requested, name) {
+			continue
+		}
+		set := constructed(0x31)
+		for _, value := range values {
+			set.children = append(set.children, &element{tag: 0x04, value: []byte(value)})
+		}
+		attributes.children = append(attributes.children, constructed(0x30, &element{tag: 0x04, value: []byte(name)}, set))
+	}
+	return constructed(0x64, &element{tag: 0x04, value: []byte(entry.DN)}, attributes)
+}
+
+// matches проверяет запись по фильтру поиска в BER
+func matches(filter *element, entry Entry) bool {
+	switch filter.tag {
+	case 0xa0: // and
+		for _, child := range filter.children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case 0xa1: // or
+		for _, child := range filter.children {
+			if matches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case 0xa2: // not
+		return len(filter.children) == 1 && !matches(filter.children[0], entry)
+	case 0xa3: // equalityMatch
+		if len(filter.children) != 2 {
+			return false
+		}
+		return containsFold(attribute(entry, string(filter.children[0].value)), string(filter.children[1].value))
+	case 0x87: // present
+		return len(attribute(entry, string(filter.value))) > 0
+	}
+	return false
+}
+
+// formatFilter возвращает фильтр в строковом виде с экранированием
+// значений по RFC 4515
+func formatFilter(filter *element) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	switch filter.tag {
+	case 0xa0, 0xa1, 0xa2:
+		b.WriteByte("&|!"[filter.tag-0xa0])
+		for _, child := range filter.children {
+			b.WriteString(formatFilter(child))
+		}
+	case 0xa3:
+		if len(filter.children) == 2 {
+			b.Write(filter.children[0].value)
+			b.WriteByte('=')
+			for _, c := range filter.children[1].value {
+				if c == '*' || c == '(' || c == ')' || c == '\\' || c == 0 {
+					fmt.Fprintf(&b, "\\%02x", c)
+				} else {
+					b.WriteByte(c)
+				}
+			}
+		}
+	case 0x87:
+		b.Write(filter.value)
+		b.WriteString("=*")
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func attribute(entry Entry, name string) []string {
+	for attr, values := range entry.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// result возвращает LDAPResult операции op
+func result(op byte, code int, message string) *element {
+	return constructed(op,
+		&element{tag: 0x0a, value: []byte{byte(code)}},
+		&element{tag: 0x04},
+		&element{tag: 0x04, value: []byte(message)},
+	)
+}
+
+// element — элемент BER с однобайтовым тегом
+type element struct {
+	tag      byte
+	value    []byte
+	children []*element
+}
+
+func constructed(tag byte, children ...*element) *element {
+	return &element{tag: tag, children: children}
+}
+
+func (e *element) bytes() []byte {
+	content := e.value
+	if e.tag&0x20 != 0 {
+		content = nil
+		for _, child := range e.children {
+			content = append(content, child.bytes()...)
+		}
+	}
+
+	out := []byte{e.tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+func readElement(r *bufio.Reader) (*element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length := int(first)
+	if first >= 0x80 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 3 {
+			return nil, errors.New("ldaptest: unsupported length")
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	e := &element{tag: tag, value: content}
+	if tag&0x20 != 0 {
+		inner := bufio.NewReader(bytes.NewReader(content))
+		for {
+			child, err := readElement(inner)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			e.children = append(e.children, child)
+		}
+		e.value = nil
+	}
+	return e, nil
+}
+
+func integer(b []byte) int64 {
+	var value int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			value = -1
+		}
+		value = value<<8 | int64(c)
+	}
+	return value
+}
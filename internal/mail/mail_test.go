@@ -0,0 +1,168 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testDate = time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+func parse(t *testing.T, data []byte) *mail.Message {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("message does not parse: %v\n%s", err, data)
+	}
+	return msg
+}
+
+// decodeQP раскрывает quoted-printable и проверяет, что закодированные
+// строки не длиннее 76 символов (RFC 2045, раздел 6.7)
+func decodeQP(t *testing.T, r io.Reader) string {
+	t.Helper()
+	encoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(encoded), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("encoded line of %d characters: %q", len(line), line)
+		}
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(encoded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(decoded)
+}
+
+func TestMessageBytesText(t *testing.T) {
+	text := "Здравствуйте!\n\nСсылка: https://inventory.example.com/reset?token=a=b&c=d\n" +
+		strings.Repeat("Очень длинная строка без переносов. ", 10) + "\n"
+	m := Message{To: "Алиса <alice@example.com>", Subject: "Сброс пароля", Text: text}
+
+	data, err := m.Bytes("Inventory <noreply@example.com>", testDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.ReplaceAll(string(data), "\r\n", ""), "\n") {
+		t.Error("message has bare LF line endings")
+	}
+
+	msg := parse(t, data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Сброс пароля" {
+		t.Errorf("subject = %q (%v), raw %q", subject, err, msg.Header.Get("Subject"))
+	}
+	to, err := msg.Header.AddressList("To")
+	if err != nil || len(to) != 1 || to[0].Name != "Алиса" || to[0].Address != "alice@example.com" {
+		t.Errorf("to = %v (%v)", to, err)
+	}
+	if from, err := msg.Header.AddressList("From"); err != nil || from[0].Address != "noreply@example.com" {
+		t.Errorf("from = %v (%v)", from, err)
+	}
+	if date, err := msg.Header.Date(); err != nil || !date.Equal(testDate) {
+		t.Errorf("date = %v (%v)", date, err)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("message id = %q", id)
+	}
+	if msg.Header.Get("MIME-Version") != "1.0" || msg.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+		t.Errorf("headers = %v", msg.Header)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/plain" || params["charset"] != "utf-8" {
+		t.Errorf("content type = %q", msg.Header.Get("Content-Type"))
+	}
+
+	if body := decodeQP(t, msg.Body); body != strings.ReplaceAll(text, "\n", "\r\n") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestMessageBytesQuotedPrintable(t *testing.T) {
+	m := Message{To: "alice@example.com", Subject: "Test", Text: "a=b ё\n"}
+	data, err := m.Bytes("noreply@example.com", testDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, body, _ := strings.Cut(string(data), "\r\n\r\n")
+	// '=' and non-ASCII bytes are escaped; the line ending stays a hard break
+	if body != "a=3Db =D1=91\r\n" {
+		t.Errorf("encoded body = %q", body)
+	}
+}
+
+func TestMessageBytesAlternative(t *testing.T) {
+	m := Message{
+		To:      "alice@example.com",
+		Subject: "Приглашение",
+		Text:    "Перейдите по ссылке.\n",
+		HTML:    `<p style="margin:0">Перейдите по <a href="https://example.com/?a=1&amp;b=2">ссылке</a>.</p>`,
+	}
+	data, err := m.Bytes("noreply@example.com", testDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := parse(t, data)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" || params["boundary"] == "" {
+		t.Fatalf("content type = %q", msg.Header.Get("Content-Type"))
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain", "Перейдите по ссылке.\r\n"},
+		{"text/html", m.HTML},
+	}
+	for i, w := range want {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); ct != w.contentType {
+			t.Errorf("part %d content type = %q", i, part.Header.Get("Content-Type"))
+		}
+		if part.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+			t.Errorf("part %d encoding = %q", i, part.Header.Get("Content-Transfer-Encoding"))
+		}
+		if body := decodeQP(t, part); body != w.body {
+			t.Errorf("part %d body = %q, want %q", i, body, w.body)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("extra part after text and html: %v", err)
+	}
+}
+
+func TestMessageBytesRejectsHeaderInjection(t *testing.T) {
+	for _, to := range []string{"", "not an address", "alice@example.com\r\nBcc: mallory@example.com"} {
+		if _, err := (Message{To: to, Subject: "x"}).Bytes("noreply@example.com", testDate); err == nil {
+			t.Errorf("recipient %q accepted", to)
+		}
+	}
+	if _, err := (Message{To: "alice@example.com"}).Bytes("bad sender", testDate); err == nil {
+		t.Error("invalid sender accepted")
+	}
+
+	// A line break in the subject is encoded instead of starting a new header
+	data, err := Message{To: "alice@example.com", Subject: "Hi\r\nBcc: mallory@example.com"}.Bytes("noreply@example.com", testDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := parse(t, data)
+	if msg.Header.Get("Bcc") != "" {
+		t.Error("subject injected a Bcc header")
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Hi\r\nBcc: mallory@example.com" {
+		t.Errorf("subject = %q", subject)
+	}
+}
@@ -0,0 +1,242 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpSession — то, что SMTP-сервер получил за одно соединение
+type smtpSession struct {
+	commands []string
+	auth     string
+	from     string
+	to       []string
+	data     string
+}
+
+// smtpServer — SMTP-сервер на 127.0.0.1, который принимает письма и
+// запоминает сессии. extensions объявляются в ответе на EHLO, reject
+// задает ответ на команду вместо обычного
+type smtpServer struct {
+	host string
+	port int
+
+	extensions []string
+	reject     map[string]string
+
+	mu       sync.Mutex
+	sessions []*smtpSession
+}
+
+func newSMTPServer(t *testing.T, extensions ...string) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &smtpServer{host: addr.IP.String(), port: addr.Port, extensions: extensions, reject: map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	tp := textproto.NewConn(conn)
+	session := &smtpSession{}
+	s.mu.Lock()
+	s.sessions = append(s.sessions, session)
+	s.mu.Unlock()
+
+	reply := func(format string, args ...interface{}) {
+		tp.PrintfLine(format, args...)
+	}
+	reply("220 localhost ESMTP test")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		s.mu.Lock()
+		session.commands = append(session.commands, verb)
+		rejection, rejected := s.reject[verb]
+		s.mu.Unlock()
+		if rejected {
+			reply("%s", rejection)
+			continue
+		}
+
+		switch verb {
+		case "EHLO":
+			lines := append([]string{"localhost"}, s.extensions...)
+			for i, ext := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				reply("250%s%s", sep, ext)
+			}
+		case "AUTH":
+			s.mu.Lock()
+			session.auth = strings.TrimPrefix(line, "AUTH ")
+			s.mu.Unlock()
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.mu.Lock()
+			session.from = strings.TrimPrefix(line, "MAIL FROM:")
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			session.to = append(session.to, strings.TrimPrefix(line, "RCPT TO:"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			session.data = strings.Join(lines, "\r\n")
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) last(t *testing.T) smtpSession {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) == 0 {
+		t.Fatal("no SMTP session")
+	}
+	return *s.sessions[len(s.sessions)-1]
+}
+
+func TestSMTPMailerSends(t *testing.T) {
+	server := newSMTPServer(t, "PIPELINING", "SIZE 10240000")
+	mailer := NewSMTPMailer(server.host, server.port, "", "", "Inventory <noreply@example.com>")
+
+	msg := Message{To: "Alice <alice@example.com>", Subject: "Привет", Text: "Первая строка\n.точка в начале строки\n"}
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	session := server.last(t)
+	if session.from != "<noreply@example.com>" || len(session.to) != 1 || session.to[0] != "<alice@example.com>" {
+		t.Errorf("envelope from %q to %v", session.from, session.to)
+	}
+	if session.auth != "" {
+		t.Errorf("authenticated without credentials: %q", session.auth)
+	}
+	if got := strings.Join(session.commands, " "); got != "EHLO MAIL RCPT DATA QUIT" {
+		t.Errorf("commands = %s", got)
+	}
+
+	received := parse(t, []byte(session.data+"\r\n"))
+	if body := decodeQP(t, received.Body); body != "Первая строка\r\n.точка в начале строки\r\n" {
+		t.Errorf("received body = %q", body)
+	}
+}
+
+func TestSMTPMailerAuthenticates(t *testing.T) {
+	server := newSMTPServer(t, "AUTH PLAIN LOGIN")
+	mailer := NewSMTPMailer(server.host, server.port, "mailer", "p@ss", "noreply@example.com")
+
+	if err := mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "x", Text: "y"}); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := server.last(t).auth
+	mechanism, initial, _ := strings.Cut(auth, " ")
+	decoded, _ := base64.StdEncoding.DecodeString(initial)
+	if mechanism != "PLAIN" || string(decoded) != "\x00mailer\x00p@ss" {
+		t.Errorf("AUTH %s %q", mechanism, decoded)
+	}
+}
+
+func TestSMTPMailerRequiresStartTLSWhenOffered(t *testing.T) {
+	server := newSMTPServer(t, "STARTTLS", "AUTH PLAIN")
+	server.reject["STARTTLS"] = "454 4.7.0 TLS not available"
+	mailer := NewSMTPMailer(server.host, server.port, "mailer", "p@ss", "noreply@example.com")
+
+	if err := mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "x", Text: "y"}); err == nil {
+		t.Fatal("message sent in plain text after STARTTLS failed")
+	}
+	if session := server.last(t); session.auth != "" || session.data != "" {
+		t.Errorf("credentials or message sent without TLS: %+v", session)
+	}
+}
+
+func TestSMTPMailerErrors(t *testing.T) {
+	server := newSMTPServer(t)
+	server.reject["RCPT"] = "550 5.1.1 No such user"
+	mailer := NewSMTPMailer(server.host, server.port, "", "", "noreply@example.com")
+
+	err := mailer.Send(context.Background(), Message{To: "nobody@example.com", Subject: "x", Text: "y"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("rejected recipient: err = %v", err)
+	}
+
+	// A refused connection is an error
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	closed := NewSMTPMailer("127.0.0.1", port, "", "", "noreply@example.com")
+	if err := closed.Send(context.Background(), Message{To: "alice@example.com", Subject: "x", Text: "y"}); err == nil {
+		t.Error("sent to a closed port")
+	}
+
+	// A server that never greets is abandoned at the deadline
+	silent, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer silent.Close()
+	go func() {
+		conn, err := silent.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	port = silent.Addr().(*net.TCPAddr).Port
+	start := time.Now()
+	err = NewSMTPMailer("127.0.0.1", port, "", "", "noreply@example.com").Send(ctx, Message{To: "alice@example.com", Subject: "x", Text: "y"})
+	if err == nil || time.Since(start) > time.Second {
+		t.Errorf("silent server: err = %v after %s", err, time.Since(start))
+	}
+
+	for _, msg := range []Message{{To: "bad", Subject: "x"}, {To: "alice@example.com\r\nBcc: x@example.com"}} {
+		if err := mailer.Send(context.Background(), msg); err == nil {
+			t.Errorf("recipient %q accepted", msg.To)
+		}
+	}
+	if err := NewSMTPMailer(server.host, server.port, "", "", "bad sender").Send(context.Background(), Message{To: "alice@example.com"}); err == nil {
+		t.Error("invalid sender accepted")
+	}
+}
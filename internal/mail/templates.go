@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultLanguage — язык писем, если шаблона на языке получателя нет
+const DefaultLanguage = "ru"
+
+//go:embed templates
+var templateFiles embed.FS
+
+// Templates — шаблоны писем. Шаблон name на языке lang состоит из файлов
+// templates/<name>.<lang>.txt (text/template, тема задается блоком
+// "subject") и необязательного templates/<name>.<lang>.html (html/template)
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// LoadTemplates разбирает встроенные шаблоны писем
+func LoadTemplates() (*Templates, error) {
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	err := fs.WalkDir(templateFiles, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		source, err := templateFiles.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(path, "templates/")
+		switch {
+		case strings.HasSuffix(name, ".txt"):
+			key := strings.TrimSuffix(name, ".txt")
+			tmpl, err := texttemplate.New(key).Parse(string(source))
+			if err != nil {
+				return err
+			}
+			if tmpl.Lookup("subject") == nil {
+				return fmt.Errorf("mail template %s has no subject block", path)
+			}
+			t.text[key] = tmpl
+		case strings.HasSuffix(name, ".html"):
+			key := strings.TrimSuffix(name, ".html")
+			tmpl, err := htmltemplate.New(key).Parse(string(source))
+			if err != nil {
+				return err
+			}
+			t.html[key] = tmpl
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Render заполняет шаблон name на языке lang данными data. Получатель
+// в возвращаемом письме не задан
+func (t *Templates) Render(name, lang string, data interface{}) (Message, error) {
+	key := name + "." + lang
+	text, ok := t.text[key]
+	if !ok {
+		key = name + "." + DefaultLanguage
+		if text, ok = t.text[key]; !ok {
+			return Message{}, fmt.Errorf("mail template %s not found", name)
+		}
+	}
+
+	var msg Message
+	var buf bytes.Buffer
+
+	if err := text.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return msg, err
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := text.Execute(&buf, data); err != nil {
+		return msg, err
+	}
+	msg.Text = strings.TrimSpace(buf.String()) + "\n"
+
+	if html, ok := t.html[key]; ok {
+		buf.Reset()
+		if err := html.Execute(&buf, data); err != nil {
+			return msg, err
+		}
+		msg.HTML = buf.String()
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,77 @@
+// Package mergepatch применяет изменения в формате JSON Merge Patch (RFC 7396)
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ContentType — медиатип документа JSON Merge Patch
+const ContentType = "application/merge-patch+json"
+
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Patch — разобранный документ патча верхнего уровня: ключ и исходное
+// JSON-значение. Значение null означает удаление поля
+type Patch map[string]json.RawMessage
+
+// Parse разбирает документ патча. Патч для записи всегда должен быть
+// объектом, поэтому массивы и скаляры отклоняются
+func Parse(data []byte) (Patch, error) {
+	var patch Patch
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	if patch == nil {
+		return nil, ErrNotObject
+	}
+	return patch, nil
+}
+
+// IsNull сообщает, удаляет ли патч поле key
+func (p Patch) IsNull(key string) bool {
+	value, ok := p[key]
+	return ok && string(value) == "null"
+}
+
+// Apply применяет патч к документу doc и возвращает результат
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	patch := make(map[string]interface{}, len(p))
+	for key, raw := range p {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		patch[key] = value
+	}
+
+	return json.Marshal(merge(target, patch))
+}
+
+// merge реализует алгоритм MergePatch из раздела 2 RFC 7396
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = merge(targetObject[key], value)
+	}
+
+	return targetObject
+}
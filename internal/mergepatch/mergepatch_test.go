@@ -0,0 +1,122 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// rfcExamples — примеры из приложения A RFC 7396: исходный документ, патч
+// и ожидаемый результат
+var rfcExamples = []struct {
+	target, patch, want string
+}{
+	{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+	{`{"a":"b"}`, `{"a":null}`, `{}`},
+	{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+	{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+	{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+	{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+	{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+	{`{"a":"b"}`, `["c"]`, `["c"]`},
+	{`{"a":"foo"}`, `null`, `null`},
+	{`{"a":"foo"}`, `"bar"`, `"bar"`},
+	{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+	{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+	{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+}
+
+func decode(t *testing.T, data string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return value
+}
+
+func TestMergeRFCExamples(t *testing.T) {
+	for _, tt := range rfcExamples {
+		got := merge(decode(t, tt.target), decode(t, tt.patch))
+		if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("merge(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, tt := range rfcExamples {
+		patch, err := Parse([]byte(tt.patch))
+		if err != nil {
+			// Патч записи — всегда объект, остальные примеры Parse отклоняет
+			if tt.patch[0] == '{' {
+				t.Errorf("Parse(%s): %v", tt.patch, err)
+			}
+			continue
+		}
+		got, err := patch.Apply([]byte(tt.target))
+		if err != nil {
+			t.Errorf("Apply(%s, %s): %v", tt.target, tt.patch, err)
+			continue
+		}
+		if !reflect.DeepEqual(decode(t, string(got)), decode(t, tt.want)) {
+			t.Errorf("Apply(%s, %s) = %s, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		valid bool
+	}{
+		{"object", `{"name":"Стол","comment":null}`, true},
+		{"empty object", `{}`, true},
+		{"null", `null`, false},
+		{"array", `["c"]`, false},
+		{"string", `"bar"`, false},
+		{"number", `1`, false},
+		{"malformed", `{"name":`, false},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.data))
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: Parse(%s) err = %v, want valid = %v", tt.name, tt.data, err, tt.valid)
+		}
+	}
+	if _, err := Parse([]byte(`null`)); !errors.Is(err, ErrNotObject) {
+		t.Errorf("Parse(null) err = %v, want ErrNotObject", err)
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	patch, err := Parse([]byte(`{"comment":null,"name":"null","parent_id":0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"comment", true},
+		{"name", false},
+		{"parent_id", false},
+		{"missing", false},
+	}
+	for _, tt := range tests {
+		if got := patch.IsNull(tt.key); got != tt.want {
+			t.Errorf("IsNull(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRejectsInvalidDocument(t *testing.T) {
+	patch := Patch{"name": json.RawMessage(`"Стол"`)}
+	if _, err := patch.Apply([]byte(`{"name":`)); err == nil {
+		t.Error("Apply accepted a malformed document")
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// ScopeRead разрешает токену чтение всех ресурсов, доступных владельцу
+const ScopeRead = "read"
+
+// APITokenScopes перечисляет области действия персональных токенов:
+// "<ресурс>:read" разрешает чтение ресурса, "<ресурс>:write" — также его
+// изменение. Права владельца токена при этом не расширяются
+var APITokenScopes = []string{
+	ScopeRead,
+	"assets:read", "assets:write",
+	"categories:read", "categories:write",
+	"employees:read", "employees:write",
+	"departments:read", "departments:write",
+	"locations:read", "locations:write",
+	"transfers:read", "transfers:write",
+	"reservations:read", "reservations:write",
+	"webhooks:read", "webhooks:write",
+	"invitations:read", "invitations:write",
+	"reports:read",
+	"events:read",
+	"login-failures:read",
+	"me:read",
+}
+
+// APIToken — персональный токен доступа к API. Сам токен показывается
+// только при создании
+type APIToken struct {
+	ID         int        `json:"id"`
+	EmployeeID int        `json:"employee_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP *string    `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIToken — ответ на создание токена вместе с его значением
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -1,15 +1,36 @@
 package models
 
+import "time"
+
 type Asset struct {
-	ID                int     `json:"id"`
-	Name              string  `json:"name"`
-	Category          string  `json:"category"`
-	AcquisitionDate   string  `json:"acquisition_date"`
-	Cost              float64 `json:"cost"`
-	StatusID          int     `json:"status_id"`
-	Status            string  `json:"status"`
-	CurrentLocationID int     `json:"current_location_id"`
-	Location          string  `json:"location"`
-	DepartmentID      *int    `json:"department_id,omitempty"`
-	Department        *string `json:"department,omitempty"`
+	ID                int                    `json:"id"`
+	Name              string                 `json:"name"`
+	Category          string                 `json:"category"`
+	CategoryID        *int                   `json:"category_id,omitempty"`
+	CustomFields      map[string]interface{} `json:"custom_fields,omitempty"`
+	AcquisitionDate   string                 `json:"acquisition_date"`
+	Cost              float64                `json:"cost"`
+	WarrantyUntil     *string                `json:"warranty_until,omitempty"`
+	StatusID          int                    `json:"status_id"`
+	Status            string                 `json:"status"`
+	CurrentLocationID int                    `json:"current_location_id"`
+	Location          string                 `json:"location"`
+	DepartmentID      *int                   `json:"department_id,omitempty"`
+	Department        *string                `json:"department,omitempty"`
+	ParentID          *int                   `json:"parent_id,omitempty"`
+	Components        []Asset                `json:"components,omitempty"`
+	TotalCost         *float64               `json:"total_cost,omitempty"`
+	DeletedAt         *time.Time             `json:"deleted_at,omitempty"`
+	DeletedBy         *int                   `json:"deleted_by,omitempty"`
+	Version           int                    `json:"version"`
+	// OrganizationID — организация-владелец актива
+	OrganizationID int `json:"-"`
+}
+
+// AssetFilter описывает условия отбора активов в списке
+type AssetFilter struct {
+	CategoryID     *int
+	Search         string
+	CustomFields   map[string]string
+	IncludeDeleted bool
 }
@@ -0,0 +1,31 @@
+package models
+
+const (
+	FieldTypeText    = "text"
+	FieldTypeNumber  = "number"
+	FieldTypeDate    = "date"
+	FieldTypeEnum    = "enum"
+	FieldTypeBoolean = "boolean"
+)
+
+type Category struct {
+	ID               int           `json:"id"`
+	Name             string        `json:"name"`
+	ParentID         *int          `json:"parent_id,omitempty"`
+	RequiresApproval bool          `json:"requires_approval"`
+	Fields           []CustomField `json:"fields"`
+	Children         []Category    `json:"children,omitempty"`
+}
+
+// CustomField описывает дополнительное поле активов категории
+type CustomField struct {
+	Key       string   `json:"key"`
+	Label     string   `json:"label"`
+	Type      string   `json:"type"`
+	Required  bool     `json:"required"`
+	Options   []string `json:"options,omitempty"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+}
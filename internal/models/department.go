@@ -1,9 +1,14 @@
 package models
 
+import "time"
+
 type Department struct {
-	ID       int     `json:"id"`
-	Name     string  `json:"name"`
-	Location string  `json:"location"`
-	HeadID   *int    `json:"head_id,omitempty"`
-	HeadName *string `json:"head_name,omitempty"`
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Location  string     `json:"location"`
+	HeadID    *int       `json:"head_id,omitempty"`
+	HeadName  *string    `json:"head_name,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int       `json:"deleted_by,omitempty"`
+	Version   int        `json:"version"`
 }
@@ -1,12 +1,55 @@
 package models
 
+import "time"
+
+const (
+	RoleEmployee = "employee"
+	RoleManager  = "manager"
+	RoleAdmin    = "admin"
+	// RoleSuperAdmin видит сводные отчеты по всем организациям группы и
+	// управляет организациями. Назначается только в базе данных
+	RoleSuperAdmin = "superadmin"
+)
+
 type Employee struct {
-	ID           int     `json:"id"`
-	FullName     string  `json:"full_name"`
-	Position     string  `json:"position"`
-	Email        string  `json:"email"`
-	PasswordHash string  `json:"-"`
-	Role         string  `json:"role"`
-	DepartmentID *int    `json:"department_id,omitempty"`
-	Department   *string `json:"department,omitempty"`
+	ID           int        `json:"id"`
+	FullName     string     `json:"full_name"`
+	Position     string     `json:"position"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"`
+	Role         string     `json:"role"`
+	DepartmentID *int       `json:"department_id,omitempty"`
+	Department   *string    `json:"department,omitempty"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy    *int       `json:"deleted_by,omitempty"`
+	Version      int        `json:"version"`
+	// PasswordChangedAt — время последней смены пароля. Токены входа,
+	// выданные раньше, недействительны
+	PasswordChangedAt *time.Time `json:"-"`
+	// FailedLogins — число неудачных попыток входа подряд; LockedUntil —
+	// до какого времени вход заблокирован
+	FailedLogins int        `json:"-"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	// TwoFactorEnabled — включен ли вход с кодом TOTP
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+	// AuthSource — внешний провайдер, который проверяет пароль сотрудника
+	// (например, "ldap"); пусто для локальных учетных записей. ExternalID —
+	// идентификатор сотрудника в этом провайдере
+	AuthSource string `json:"auth_source,omitempty"`
+	ExternalID string `json:"-"`
+	// OrganizationID — организация, к данным которой у сотрудника есть доступ
+	OrganizationID int `json:"organization_id"`
+}
+
+// ExternalProfile — данные сотрудника из внешнего каталога, по которым он
+// создается при первом входе и обновляется при следующих. Пустая роль и
+// nil вместо отдела означают, что каталог их не определяет
+type ExternalProfile struct {
+	Source       string
+	ExternalID   string
+	Email        string
+	FullName     string
+	Position     string
+	Role         string
+	DepartmentID *int
 }
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Режимы регистрации
+const (
+	// RegistrationDisabled — регистрации нет, сотрудников создают
+	// администраторы
+	RegistrationDisabled = "disabled"
+	// RegistrationInvite — регистрация только по приглашениям
+	// администраторов
+	RegistrationInvite = "invite"
+	// RegistrationDomain — кроме приглашений, сотрудник с адресом из
+	// разрешенного домена может сам запросить ссылку регистрации
+	RegistrationDomain = "domain"
+)
+
+// Состояния приглашения
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationExpired  = "expired"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation — приглашение к регистрации с заранее заданными ролью и
+// отделом. Токен приглашения отправляется в письме и не хранится
+type Invitation struct {
+	ID           int        `json:"id"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	DepartmentID *int       `json:"department_id,omitempty"`
+	InvitedBy    *int       `json:"invited_by,omitempty"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	AcceptedAt   *time.Time `json:"accepted_at,omitempty"`
+	EmployeeID   *int       `json:"employee_id,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	// OrganizationID — организация, в которой будет зарегистрирован сотрудник
+	OrganizationID int `json:"organization_id"`
+}
+
+// StatusAt возвращает состояние приглашения в момент now
+func (i Invitation) StatusAt(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	}
+	return InvitationPending
+}
+
+// CreatedInvitation — приглашение вместе со ссылкой регистрации. Ссылка
+// возвращается администратору при создании и повторной отправке, чтобы ее
+// можно было передать, если почта не настроена
+type CreatedInvitation struct {
+	Invitation
+	Link string `json:"link"`
+}
+
+// InvitationPreview — данные приглашения для страницы регистрации
+type InvitationPreview struct {
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	DepartmentID *int      `json:"department_id,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// RegistrationSettings — режим регистрации для страницы регистрации
+type RegistrationSettings struct {
+	Mode    string   `json:"mode"`
+	Domains []string `json:"domains,omitempty"`
+}
@@ -1,7 +1,12 @@
 package models
 
+import "time"
+
 type Location struct {
-	ID      int    `json:"id"`
-	Address string `json:"address"`
-	Type    string `json:"type"`
+	ID        int        `json:"id"`
+	Address   string     `json:"address"`
+	Type      string     `json:"type"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int       `json:"deleted_by,omitempty"`
+	Version   int        `json:"version"`
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Причины неудачного входа
+const (
+	LoginUnknownEmail  = "unknown_email"
+	LoginWrongPassword = "wrong_password"
+	LoginLocked        = "locked"
+	LoginWrongCode     = "wrong_code"
+)
+
+// LoginFailure — запись журнала неудачных попыток входа. EmployeeID и
+// OrganizationID пусты, если email не принадлежит ни одному сотруднику
+type LoginFailure struct {
+	ID             int64     `json:"id"`
+	Email          string    `json:"email"`
+	EmployeeID     *int      `json:"employee_id,omitempty"`
+	OrganizationID *int      `json:"organization_id,omitempty"`
+	IP             string    `json:"ip"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	Reason         string    `json:"reason"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// LoginFailureFilter — отбор записей журнала неудачных входов
+type LoginFailureFilter struct {
+	Email  string
+	Limit  int
+	Offset int
+}
+
+// TwoFactorStatus — состояние двухфакторной аутентификации сотрудника.
+// Required означает, что роль сотрудника не позволяет работать без нее
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup — данные для добавления учетной записи в приложение-
+// аутентификатор. ProvisioningURI кодируется в QR-код
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodes — резервные коды. Показываются один раз при выпуске
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
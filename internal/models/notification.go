@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Языки писем
+const (
+	LanguageRussian = "ru"
+	LanguageEnglish = "en"
+)
+
+// Состояния письма в очереди отправки
+const (
+	MailPending = "pending"
+	MailSent    = "sent"
+	MailFailed  = "failed"
+)
+
+// Виды уведомлений во входящих
+const (
+	NotificationTransfer            = "transfer"
+	NotificationAssetAssigned       = "asset_assigned"
+	NotificationDepartmentHead      = "department_head"
+	NotificationApprovalRequest     = "approval_request"
+	NotificationReservationApproved = "reservation_approved"
+	NotificationReservationRejected = "reservation_rejected"
+	NotificationWarranty            = "warranty"
+)
+
+// Notification — уведомление во входящих сотрудника. Entity и EntityID
+// указывают на запись, к которой оно относится
+type Notification struct {
+	ID         int64      `json:"id"`
+	EmployeeID int        `json:"-"`
+	Kind       string     `json:"kind"`
+	Title      string     `json:"title"`
+	Link       string     `json:"link,omitempty"`
+	Entity     string     `json:"entity,omitempty"`
+	EntityID   *int       `json:"entity_id,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NotificationFilter описывает страницу входящих
+type NotificationFilter struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// NotificationPage — страница входящих вместе с общим числом уведомлений
+// и числом непрочитанных
+type NotificationPage struct {
+	Items  []Notification `json:"items"`
+	Total  int            `json:"total"`
+	Unread int            `json:"unread"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// NotificationPreferences — настройки уведомлений сотрудника по почте
+type NotificationPreferences struct {
+	EmployeeID   int    `json:"-"`
+	Language     string `json:"language"`
+	Transfers    bool   `json:"transfers"`
+	Reservations bool   `json:"reservations"`
+	Warranty     bool   `json:"warranty"`
+}
+
+// DefaultNotificationPreferences возвращает настройки сотрудника, который
+// их не менял: русский язык, все уведомления включены
+func DefaultNotificationPreferences(employeeID int) NotificationPreferences {
+	return NotificationPreferences{
+		EmployeeID:   employeeID,
+		Language:     LanguageRussian,
+		Transfers:    true,
+		Reservations: true,
+		Warranty:     true,
+	}
+}
+
+// MailMessage — письмо в очереди отправки
+type MailMessage struct {
+	ID            int64
+	Recipient     string
+	Subject       string
+	TextBody      string
+	HTMLBody      string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Organization — юридическое лицо группы. Данные организаций изолированы
+// друг от друга. EmailDomains — домены рабочих адресов: по ним выбирается
+// организация сотрудника, который входит через внешний каталог или сам
+// запрашивает регистрацию
+type Organization struct {
+	ID           int       `json:"id"`
+	Code         string    `json:"code"`
+	Name         string    `json:"name"`
+	EmailDomains []string  `json:"email_domains"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GroupInventoryReport — сводный отчет по активам всех организаций группы
+type GroupInventoryReport struct {
+	TotalAssets   int                         `json:"total_assets"`
+	TotalValue    float64                     `json:"total_value"`
+	ByStatus      map[string]int              `json:"by_status"`
+	Organizations []OrganizationInventoryLine `json:"organizations"`
+	GeneratedAt   time.Time                   `json:"generated_at"`
+}
+
+// OrganizationInventoryLine — показатели одной организации в сводном отчете
+type OrganizationInventoryLine struct {
+	OrganizationID int            `json:"organization_id"`
+	Organization   string         `json:"organization"`
+	TotalAssets    int            `json:"total_assets"`
+	TotalValue     float64        `json:"total_value"`
+	ByStatus       map[string]int `json:"by_status"`
+}
+
+// GroupAssetsByStatusReport — распределение активов группы по статусам:
+// итоги по группе и по каждой организации
+type GroupAssetsByStatusReport struct {
+	Totals        []AssetsByStatusReport       `json:"totals"`
+	Organizations []OrganizationAssetsByStatus `json:"organizations"`
+}
+
+// OrganizationAssetsByStatus — распределение активов одной организации по
+// статусам
+type OrganizationAssetsByStatus struct {
+	OrganizationID int                    `json:"organization_id"`
+	Organization   string                 `json:"organization"`
+	ByStatus       []AssetsByStatusReport `json:"by_status"`
+}
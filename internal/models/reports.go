@@ -24,3 +24,15 @@ type InventoryReport struct {
 	RecentTransfers []AssetTransfer `json:"recent_transfers"`
 	GeneratedAt     time.Time       `json:"generated_at"`
 }
+
+// CategoryReport содержит показатели по категории: собственные активы и
+// итоги с учетом всех подкатегорий
+type CategoryReport struct {
+	CategoryID  int              `json:"category_id"`
+	Category    string           `json:"category"`
+	Count       int              `json:"count"`
+	TotalCost   float64          `json:"total_cost"`
+	RollupCount int              `json:"rollup_count"`
+	RollupCost  float64          `json:"rollup_cost"`
+	Children    []CategoryReport `json:"children,omitempty"`
+}
@@ -0,0 +1,372 @@
+package models
+
+import (
+	"inventory-system/internal/validate"
+	"strings"
+	"time"
+)
+
+// Тела входящих запросов. Обработчики разбирают JSON в эти структуры,
+// проверяют их по тегам validate и только затем переводят в модели
+
+// LoginRequest — тело запроса на вход. Email — адрес сотрудника или логин
+// во внешнем каталоге, который не обязан быть адресом
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RegisterRequest — тело запроса на регистрацию по приглашению. Email,
+// роль и отдел берутся из приглашения
+type RegisterRequest struct {
+	InvitationToken string `json:"invitation_token" validate:"required"`
+	FullName        string `json:"full_name" validate:"required,max=100"`
+	Position        string `json:"position" validate:"required,max=100"`
+	Password        string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// RegistrationEmailRequest — тело запроса ссылки регистрации на адрес из
+// разрешенного домена
+type RegistrationEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=100"`
+}
+
+// InvitationTokenRequest — тело запроса данных приглашения по токену
+type InvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// InvitationRequest — тело запроса на создание приглашения. Без
+// expires_at приглашение действует срок по умолчанию
+type InvitationRequest struct {
+	Email        string     `json:"email" validate:"required,email,max=100"`
+	Role         string     `json:"role" validate:"required,oneof=employee manager admin"`
+	DepartmentID *int       `json:"department_id" validate:"min=1"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// OrganizationRequest — тело запросов на создание и замену организации
+type OrganizationRequest struct {
+	Code         string   `json:"code" validate:"required,max=50"`
+	Name         string   `json:"name" validate:"required,max=255"`
+	EmailDomains []string `json:"email_domains"`
+}
+
+// Check проверяет, что домены указаны без адреса и не пусты
+func (r *OrganizationRequest) Check(errs validate.Errors) {
+	for _, domain := range r.EmailDomains {
+		domain = strings.TrimPrefix(strings.TrimSpace(domain), "@")
+		if domain == "" || strings.ContainsAny(domain, "@ ") {
+			errs.Add("email_domains", "must contain domain names, e.g. example.com")
+			return
+		}
+	}
+}
+
+// Organization переводит запрос в модель организации. Домены приводятся к
+// нижнему регистру без начального @
+func (r OrganizationRequest) Organization() Organization {
+	domains := make([]string, 0, len(r.EmailDomains))
+	for _, domain := range r.EmailDomains {
+		domains = append(domains, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@")))
+	}
+	return Organization{
+		Code:         strings.TrimSpace(r.Code),
+		Name:         r.Name,
+		EmailDomains: domains,
+	}
+}
+
+// AssetRequest — тело запросов на создание и замену актива
+type AssetRequest struct {
+	Name              string                 `json:"name" validate:"required,max=100"`
+	Category          string                 `json:"category" validate:"max=50"`
+	CategoryID        *int                   `json:"category_id" validate:"min=1"`
+	CustomFields      map[string]interface{} `json:"custom_fields"`
+	AcquisitionDate   string                 `json:"acquisition_date" validate:"required,date,notfuture"`
+	Cost              float64                `json:"cost" validate:"min=0,max=99999999.99"`
+	WarrantyUntil     *string                `json:"warranty_until" validate:"date"`
+	StatusID          int                    `json:"status_id" validate:"required,min=1"`
+	CurrentLocationID int                    `json:"current_location_id" validate:"required,min=1"`
+	DepartmentID      *int                   `json:"department_id" validate:"min=1"`
+	ParentID          *int                   `json:"parent_id" validate:"min=1"`
+}
+
+// Check требует текстовую категорию, если не выбрана категория из справочника
+func (r *AssetRequest) Check(errs validate.Errors) {
+	if r.CategoryID == nil && isBlank(r.Category) {
+		errs.Add("category", "is required when category_id is not set")
+	}
+}
+
+// Asset переводит запрос в модель актива
+func (r AssetRequest) Asset() Asset {
+	return Asset{
+		Name:              r.Name,
+		Category:          r.Category,
+		CategoryID:        r.CategoryID,
+		CustomFields:      r.CustomFields,
+		AcquisitionDate:   r.AcquisitionDate,
+		Cost:              r.Cost,
+		WarrantyUntil:     r.WarrantyUntil,
+		StatusID:          r.StatusID,
+		CurrentLocationID: r.CurrentLocationID,
+		DepartmentID:      r.DepartmentID,
+		ParentID:          r.ParentID,
+	}
+}
+
+// EmployeeRequest — тело запроса на замену сотрудника. Пароль здесь не
+// меняется
+type EmployeeRequest struct {
+	FullName     string `json:"full_name" validate:"required,max=100"`
+	Position     string `json:"position" validate:"required,max=100"`
+	Email        string `json:"email" validate:"required,email,max=100"`
+	Role         string `json:"role" validate:"required,oneof=employee manager admin"`
+	DepartmentID *int   `json:"department_id" validate:"min=1"`
+}
+
+// Employee переводит запрос в модель сотрудника
+func (r EmployeeRequest) Employee() Employee {
+	return Employee{
+		FullName:     r.FullName,
+		Position:     r.Position,
+		Email:        r.Email,
+		Role:         r.Role,
+		DepartmentID: r.DepartmentID,
+	}
+}
+
+// CreateEmployeeRequest — тело запроса на создание сотрудника
+type CreateEmployeeRequest struct {
+	EmployeeRequest
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// Employee переводит запрос в модель сотрудника. Пароль передается
+// открытым текстом и хешируется сервисом
+func (r CreateEmployeeRequest) Employee() Employee {
+	employee := r.EmployeeRequest.Employee()
+	employee.PasswordHash = r.Password
+	return employee
+}
+
+// DepartmentRequest — тело запросов на создание и замену отдела
+type DepartmentRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Location string `json:"location" validate:"required,max=255"`
+	HeadID   *int   `json:"head_id" validate:"min=1"`
+}
+
+// Department переводит запрос в модель отдела
+func (r DepartmentRequest) Department() Department {
+	return Department{
+		Name:     r.Name,
+		Location: r.Location,
+		HeadID:   r.HeadID,
+	}
+}
+
+// LocationRequest — тело запросов на создание и замену местоположения
+type LocationRequest struct {
+	Address string `json:"address" validate:"required,max=255"`
+	Type    string `json:"type" validate:"required,max=50"`
+}
+
+// Location переводит запрос в модель местоположения
+func (r LocationRequest) Location() Location {
+	return Location{
+		Address: r.Address,
+		Type:    r.Type,
+	}
+}
+
+// CategoryRequest — тело запросов на создание и замену категории.
+// Описания дополнительных полей проверяет сервис категорий
+type CategoryRequest struct {
+	Name             string        `json:"name" validate:"required,max=100"`
+	ParentID         *int          `json:"parent_id" validate:"min=1"`
+	RequiresApproval bool          `json:"requires_approval"`
+	Fields           []CustomField `json:"fields"`
+}
+
+// Category переводит запрос в модель категории
+func (r CategoryRequest) Category() Category {
+	return Category{
+		Name:             r.Name,
+		ParentID:         r.ParentID,
+		RequiresApproval: r.RequiresApproval,
+		Fields:           r.Fields,
+	}
+}
+
+// MergeCategoryRequest — тело запроса на слияние категорий
+type MergeCategoryRequest struct {
+	TargetID int `json:"target_id" validate:"required,min=1"`
+}
+
+// AttachComponentRequest — тело запроса на добавление компонента в комплект
+type AttachComponentRequest struct {
+	ComponentID int `json:"component_id" validate:"required,min=1"`
+}
+
+// TransferRequest — тело запроса на перемещение актива. Без даты
+// перемещение регистрируется текущим моментом
+type TransferRequest struct {
+	AssetID        int    `json:"asset_id" validate:"required,min=1"`
+	EmployeeID     int    `json:"employee_id" validate:"required,min=1"`
+	FromLocationID int    `json:"from_location_id" validate:"required,min=1"`
+	ToLocationID   int    `json:"to_location_id" validate:"required,min=1"`
+	TransferDate   string `json:"transfer_date" validate:"omitempty,datetime,notfuture"`
+	Notes          string `json:"notes" validate:"max=2000"`
+}
+
+// Check запрещает перемещение в то же местоположение
+func (r *TransferRequest) Check(errs validate.Errors) {
+	if r.FromLocationID != 0 && r.FromLocationID == r.ToLocationID {
+		errs.Add("to_location_id", "must differ from from_location_id")
+	}
+}
+
+// Transfer переводит запрос в модель перемещения
+func (r TransferRequest) Transfer() AssetTransfer {
+	transferDate := time.Now()
+	if r.TransferDate != "" {
+		transferDate, _ = validate.ParseDateTime(r.TransferDate)
+	}
+
+	return AssetTransfer{
+		AssetID:        r.AssetID,
+		EmployeeID:     r.EmployeeID,
+		FromLocationID: r.FromLocationID,
+		ToLocationID:   r.ToLocationID,
+		TransferDate:   transferDate,
+		Notes:          r.Notes,
+	}
+}
+
+// ReservationRequest — тело запроса на бронирование. Без employee_id
+// актив бронируется для автора запроса
+type ReservationRequest struct {
+	AssetID    int       `json:"asset_id" validate:"required,min=1"`
+	EmployeeID int       `json:"employee_id" validate:"omitempty,min=1"`
+	StartsAt   time.Time `json:"starts_at" validate:"required"`
+	EndsAt     time.Time `json:"ends_at" validate:"required"`
+	Purpose    string    `json:"purpose" validate:"max=1000"`
+}
+
+// Check требует, чтобы бронирование заканчивалось позже начала
+func (r *ReservationRequest) Check(errs validate.Errors) {
+	if !r.StartsAt.IsZero() && !r.EndsAt.IsZero() && !r.EndsAt.After(r.StartsAt) {
+		errs.Add("ends_at", "must be after starts_at")
+	}
+}
+
+// Reservation переводит запрос в модель бронирования
+func (r ReservationRequest) Reservation() Reservation {
+	return Reservation{
+		AssetID:    r.AssetID,
+		EmployeeID: r.EmployeeID,
+		StartsAt:   r.StartsAt,
+		EndsAt:     r.EndsAt,
+		Purpose:    r.Purpose,
+	}
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+// WebhookRequest — тело запросов на создание и замену подписки на вебхуки.
+// Без секрета он генерируется сервером
+type WebhookRequest struct {
+	URL        string   `json:"url" validate:"required,max=2048"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16,max=255"`
+	EventTypes []string `json:"event_types" validate:"required"`
+	Active     *bool    `json:"active"`
+}
+
+// Subscription переводит запрос в подписку. По умолчанию подписка активна
+func (r WebhookRequest) Subscription() WebhookSubscription {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return WebhookSubscription{
+		URL:        r.URL,
+		Secret:     r.Secret,
+		EventTypes: r.EventTypes,
+		Active:     active,
+	}
+}
+
+// NotificationPreferencesRequest — тело запроса на изменение настроек
+// уведомлений. Все поля обязательны
+type NotificationPreferencesRequest struct {
+	Language     string `json:"language" validate:"required,oneof=ru en"`
+	Transfers    *bool  `json:"transfers" validate:"required"`
+	Reservations *bool  `json:"reservations" validate:"required"`
+	Warranty     *bool  `json:"warranty" validate:"required"`
+}
+
+// Preferences переводит запрос в настройки сотрудника employeeID
+func (r NotificationPreferencesRequest) Preferences(employeeID int) NotificationPreferences {
+	return NotificationPreferences{
+		EmployeeID:   employeeID,
+		Language:     r.Language,
+		Transfers:    *r.Transfers,
+		Reservations: *r.Reservations,
+		Warranty:     *r.Warranty,
+	}
+}
+
+// TwoFactorLoginRequest — второй шаг входа: токен, полученный после
+// проверки пароля, и код из приложения или резервный код
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,max=32"`
+}
+
+// SSOCompleteRequest — билет, полученный страницей входа после возврата с
+// провайдера единого входа
+type SSOCompleteRequest struct {
+	Ticket string `json:"ticket" validate:"required"`
+}
+
+// CreateAPITokenRequest — тело запроса на создание персонального токена.
+// Без expires_at токен действует срок по умолчанию
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,max=30"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// TwoFactorCodeRequest — тело запроса с кодом подтверждения
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// DisableTwoFactorRequest — тело запроса на отключение двухфакторной
+// аутентификации
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+// PasswordResetRequest — тело запроса ссылки для сброса пароля
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PasswordResetConfirmRequest — тело запроса на установку нового пароля по
+// токену из ссылки
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,max=72"`
+}
+
+// ChangePasswordRequest — тело запроса на смену своего пароля
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,max=72"`
+}
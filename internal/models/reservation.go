@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	ReservationPending   = "pending"
+	ReservationApproved  = "approved"
+	ReservationRejected  = "rejected"
+	ReservationCancelled = "cancelled"
+)
+
+type Reservation struct {
+	ID           int       `json:"id"`
+	AssetID      int       `json:"asset_id"`
+	AssetName    string    `json:"asset_name"`
+	EmployeeID   int       `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	Purpose      string    `json:"purpose,omitempty"`
+	Status       string    `json:"status"`
+	ReviewedBy   *int      `json:"reviewed_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ReservationFilter описывает условия отбора бронирований
+type ReservationFilter struct {
+	AssetID    *int
+	EmployeeID *int
+	Status     string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AvailabilityQuery описывает поиск свободных активов на интервал времени
+type AvailabilityQuery struct {
+	CategoryID *int
+	LocationID *int
+	From       time.Time
+	To         time.Time
+}
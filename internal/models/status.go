@@ -1,5 +1,10 @@
 package models
 
+// StatusWrittenOff — название статуса списанного актива из
+// migrations/001_init.sql. Переход в этот статус порождает событие
+// asset.written_off
+const StatusWrittenOff = "Списан"
+
 type AssetStatus struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
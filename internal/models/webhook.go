@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы событий, на которые можно подписать вебхук
+const (
+	EventAssetCreated       = "asset.created"
+	EventAssetUpdated       = "asset.updated"
+	EventAssetStatusChanged = "asset.status_changed"
+	EventAssetTransferred   = "asset.transferred"
+	EventAssetWrittenOff    = "asset.written_off"
+	EventAssetDeleted       = "asset.deleted"
+	EventAssetRestored      = "asset.restored"
+)
+
+// EventTypes перечисляет все типы событий
+var EventTypes = []string{
+	EventAssetCreated,
+	EventAssetUpdated,
+	EventAssetStatusChanged,
+	EventAssetTransferred,
+	EventAssetWrittenOff,
+	EventAssetDeleted,
+	EventAssetRestored,
+}
+
+// Состояния доставки вебхука
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Event — событие предметной области из очереди outbox
+type Event struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	// OrganizationID — организация, в которой произошло событие. Событие
+	// получают только подписчики этой организации
+	OrganizationID int `json:"-"`
+}
+
+// WebhookSubscription — подписка внешней системы на события
+type WebhookSubscription struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDelivery — запись журнала доставки события подписчику
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int        `json:"subscription_id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// DueDelivery — доставка, готовая к отправке, вместе с адресом, секретом
+// подписки и самим событием
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+	Event  Event
+}
+
+// StatusChange — данные события asset.status_changed
+type StatusChange struct {
+	AssetID      int   `json:"asset_id"`
+	FromStatusID int   `json:"from_status_id"`
+	ToStatusID   int   `json:"to_status_id"`
+	Asset        Asset `json:"asset"`
+}
@@ -0,0 +1,243 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"inventory-system/internal/oidc/oidctest"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestProvider(t *testing.T, server *oidctest.Server, clock *fakeClock) *Provider {
+	server.Now = clock.Now
+	return NewProvider(Config{
+		Issuer:       server.URL + "/",
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "https://inventory.example.com/auth/sso/callback",
+		Scopes:       []string{"openid", "email"},
+		HTTPClient:   server.Client(),
+		Now:          clock.Now,
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	server := oidctest.NewServer(t, "inventory", "s3cr3t/+")
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	provider := newTestProvider(t, server, clock)
+	ctx := context.Background()
+
+	claims, err := provider.VerifyIDToken(ctx, server.Sign(server.IDTokenClaims("n-1")), "n-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "248289761001" || claims.Email != "alice@example.com" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	with := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := server.IDTokenClaims("n-1")
+		for name, value := range overrides {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, with(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HS256 signed with the client secret: a provider key is required
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, with(nil)).SignedString([]byte(server.ClientSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+		want  error
+	}{
+		{"wrong issuer", server.Sign(with(jwt.MapClaims{"iss": "https://evil.example.com"})), "n-1", ErrInvalidIDToken},
+		{"wrong audience", server.Sign(with(jwt.MapClaims{"aud": "another-client"})), "n-1", ErrInvalidIDToken},
+		{"foreign azp", server.Sign(with(jwt.MapClaims{"aud": []string{"inventory", "another-client"}, "azp": "another-client"})), "n-1", ErrInvalidIDToken},
+		{"expired", server.Sign(with(jwt.MapClaims{"exp": clock.Now().Add(-2 * time.Minute).Unix()})), "n-1", ErrInvalidIDToken},
+		{"no expiry", server.Sign(with(jwt.MapClaims{"exp": nil})), "n-1", ErrInvalidIDToken},
+		{"issued in the future", server.Sign(with(jwt.MapClaims{"iat": clock.Now().Add(10 * time.Minute).Unix()})), "n-1", ErrInvalidIDToken},
+		{"no subject", server.Sign(with(jwt.MapClaims{"sub": nil})), "n-1", ErrInvalidIDToken},
+		{"alg none", none, "n-1", ErrInvalidIDToken},
+		{"alg HS256", hs256, "n-1", ErrInvalidIDToken},
+		{"tampered payload", tamper(server.Sign(with(nil))), "n-1", ErrInvalidIDToken},
+		{"nonce mismatch", server.Sign(with(nil)), "n-2", ErrNonceMismatch},
+		{"nonce missing in token", server.Sign(with(jwt.MapClaims{"nonce": nil})), "n-1", ErrNonceMismatch},
+		{"no nonce expected", server.Sign(with(jwt.MapClaims{"nonce": ""})), "", ErrNonceMismatch},
+	}
+	for _, tt := range tests {
+		if _, err := provider.VerifyIDToken(ctx, tt.token, tt.nonce); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// Several audiences are fine when the token was issued to us
+	multi := server.Sign(with(jwt.MapClaims{"aud": []string{"inventory", "another-client"}, "azp": "inventory"}))
+	if _, err := provider.VerifyIDToken(ctx, multi, "n-1"); err != nil {
+		t.Errorf("several audiences with our azp: %v", err)
+	}
+}
+
+// tamper меняет утверждения токена, сохраняя подпись
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	claims = []byte(strings.Replace(string(claims), "alice@example.com", "admin@example.com", 1))
+	parts[1] = base64.RawURLEncoding.EncodeToString(claims)
+	return strings.Join(parts, ".")
+}
+
+func TestKeyRotation(t *testing.T) {
+	server := oidctest.NewServer(t, "inventory", "")
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	provider := newTestProvider(t, server, clock)
+	ctx := context.Background()
+
+	verify := func(kid string) error {
+		_, err := provider.VerifyIDToken(ctx, server.SignWith(kid, server.IDTokenClaims("n")), "n")
+		return err
+	}
+
+	if err := verify("key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify("key-1"); err != nil || server.JWKSRequests() != 1 {
+		t.Fatalf("cached key: err = %v, %d jwks requests", err, server.JWKSRequests())
+	}
+
+	// The provider rotates its key. Right after a fetch an unknown kid is
+	// refused without asking the provider again
+	server.RotateKey()
+	if err := verify("key-2"); !errors.Is(err, ErrInvalidIDToken) || server.JWKSRequests() != 1 {
+		t.Fatalf("unknown kid within the refresh interval: err = %v, %d jwks requests", err, server.JWKSRequests())
+	}
+
+	// Later an unknown kid triggers a refresh that picks up the new key
+	clock.Advance(keysRefreshInterval + time.Second)
+	if err := verify("key-2"); err != nil || server.JWKSRequests() != 2 {
+		t.Fatalf("rotated key: err = %v, %d jwks requests", err, server.JWKSRequests())
+	}
+	// The retired key is gone after the refresh
+	if err := verify("key-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("retired key: err = %v", err)
+	}
+
+	// Forged kids do not make every request hit the provider
+	for i := 0; i < 3; i++ {
+		if err := verify("forged"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("forged kid: err = %v", err)
+		}
+	}
+	if n := server.JWKSRequests(); n != 2 {
+		t.Errorf("forged kids caused %d jwks requests, want 2", n)
+	}
+
+	// Keys expire after keysTTL even when every kid is known
+	clock.Advance(keysTTL + time.Second)
+	if err := verify("key-2"); err != nil || server.JWKSRequests() != 3 {
+		t.Errorf("stale keys: err = %v, %d jwks requests", err, server.JWKSRequests())
+	}
+}
+
+func TestExchange(t *testing.T) {
+	for _, secret := range []string{"s3cr3t/+", ""} {
+		server := oidctest.NewServer(t, "inventory", secret)
+		clock := &fakeClock{now: time.Now()}
+		provider := newTestProvider(t, server, clock)
+		ctx := context.Background()
+
+		verifier, err := RandomString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := authorize(t, server, authURL, "state-1")
+
+		if _, err := provider.Exchange(ctx, code, "wrong-verifier", "nonce-1"); err == nil {
+			t.Errorf("secret %q: exchange with a wrong PKCE verifier succeeded", secret)
+		}
+
+		code = authorize(t, server, authURL, "state-1")
+		claims, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+		if err != nil {
+			t.Fatalf("secret %q: %v", secret, err)
+		}
+		if claims.Email != "alice@example.com" {
+			t.Errorf("secret %q: claims = %+v", secret, claims)
+		}
+
+		// Codes are single-use
+		if _, err := provider.Exchange(ctx, code, verifier, "nonce-1"); err == nil {
+			t.Errorf("secret %q: a code was exchanged twice", secret)
+		}
+	}
+}
+
+// authorize открывает страницу входа провайдера и возвращает код из
+// перенаправления обратно в приложение
+func authorize(t *testing.T, server *oidctest.Server, authURL, state string) string {
+	t.Helper()
+	resp, err := server.Client().Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorization: status %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("state") != state {
+		t.Fatalf("authorization returned state %q", location.Query().Get("state"))
+	}
+	return location.Query().Get("code")
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer(t, "inventory", "")
+	// The same server under another name publishes a document for its own issuer
+	issuer := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	provider := NewProvider(Config{Issuer: issuer, ClientID: "inventory", HTTPClient: server.Client()})
+	if _, err := provider.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("discovery for another issuer: err = %v", err)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636, appendix B
+	if got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("CodeChallenge = %q", got)
+	}
+}
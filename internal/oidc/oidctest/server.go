@@ -0,0 +1,295 @@
+// Package oidctest — провайдер OpenID Connect для тестов: документ
+// обнаружения, JWKS, страница входа, которая сразу выдает код, и обмен
+// кода на ID-токен с проверкой PKCE
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// grant — выданный код авторизации
+type grant struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+}
+
+type signingKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+// Server — провайдер на httptest.Server. URL служит издателем (issuer)
+type Server struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	// Claims дополняют и переопределяют утверждения выдаваемых
+	// ID-токенов; значение nil удаляет утверждение
+	Claims map[string]interface{}
+	// Now — время провайдера для iat и exp
+	Now func() time.Time
+
+	server *httptest.Server
+
+	mu            sync.Mutex
+	keys          []signingKey
+	codes         map[string]grant
+	jwksRequests  int
+	tokenRequests int
+}
+
+// NewServer запускает провайдер с одним ключом RSA и останавливает его по
+// завершении теста
+func NewServer(t testing.TB, clientID, clientSecret string) *Server {
+	t.Helper()
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Now:          time.Now,
+		codes:        make(map[string]grant),
+	}
+	s.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// Client возвращает HTTP-клиент, который не следует перенаправлениям:
+// так тест получает код со страницы входа
+func (s *Server) Client() *http.Client {
+	client := *s.server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &client
+}
+
+// RotateKey добавляет новый ключ подписи и публикует его в JWKS вместо
+// прежних. Возвращает kid нового ключа
+func (s *Server) RotateKey() string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kid := "key-" + strconv.Itoa(len(s.keys)+1)
+	s.keys = append(s.keys, signingKey{kid: kid, key: key})
+	return kid
+}
+
+// JWKSRequests возвращает число загрузок JWKS
+func (s *Server) JWKSRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksRequests
+}
+
+// TokenRequests возвращает число обращений к обмену кода
+func (s *Server) TokenRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokenRequests
+}
+
+// IDTokenClaims возвращает утверждения ID-токена по умолчанию с nonce
+func (s *Server) IDTokenClaims(nonce string) jwt.MapClaims {
+	now := s.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            s.ClientID,
+		"sub":            "248289761001",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice Liddell",
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range s.Claims {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+// Sign подписывает claims текущим ключом с его kid в заголовке
+func (s *Server) Sign(claims jwt.MapClaims) string {
+	s.mu.Lock()
+	current := s.keys[len(s.keys)-1]
+	s.mu.Unlock()
+	return s.SignWith(current.kid, claims)
+}
+
+// SignWith подписывает claims ключом kid, в том числе уже снятым с
+// публикации, или новым неопубликованным ключом, если такого kid нет
+func (s *Server) SignWith(kid string, claims jwt.MapClaims) string {
+	s.mu.Lock()
+	var key *rsa.PrivateKey
+	for _, k := range s.keys {
+		if k.kid == kid {
+			key = k.key
+		}
+	}
+	s.mu.Unlock()
+	if key == nil {
+		var err error
+		if key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			panic(err)
+		}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.jwksRequests++
+	current := s.keys[len(s.keys)-1]
+	s.mu.Unlock()
+
+	encode := base64.RawURLEncoding.EncodeToString
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{
+		// Ключи шифрования и неизвестных типов клиент должен пропускать
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": encode(current.key.N.Bytes()), "e": "AQAB"},
+		{"kty": "oct", "kid": "symmetric", "k": "c2VjcmV0"},
+		{
+			"kty": "RSA",
+			"kid": current.kid,
+			"use": "sig",
+			"n":   encode(current.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(current.key.E)).Bytes()),
+		},
+	}})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != s.ClientID ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomCode()
+	s.mu.Lock()
+	s.codes[code] = grant{
+		redirectURI: query.Get("redirect_uri"),
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+	}
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.tokenRequests++
+	s.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if !s.clientAuthenticated(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	g, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomCode(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     s.Sign(s.IDTokenClaims(g.nonce)),
+	})
+}
+
+// clientAuthenticated проверяет client_secret_basic или client_id в форме
+// для публичного клиента
+func (s *Server) clientAuthenticated(r *http.Request) bool {
+	if s.ClientSecret == "" {
+		return r.PostForm.Get("client_id") == s.ClientID
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	return id == s.ClientID && secret == s.ClientSecret
+}
+
+func randomCode() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("oidctest: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,343 @@
+// Package openapi собирает документ OpenAPI 3 из описаний операций.
+// Схемы тел запросов и ответов строятся по типам Go: имена полей берутся из
+// тегов json, ограничения — из тегов validate
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version — версия спецификации OpenAPI, которой соответствует документ
+const Version = "3.0.3"
+
+// Document — корневой объект спецификации
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+	Security   []SecurityRequirement            `json:"security,omitempty"`
+	Tags       []Tag                            `json:"tags,omitempty"`
+
+	once sync.Once
+	body []byte
+	err  error
+}
+
+// Info описывает API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server — базовый адрес API
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag группирует операции в документации
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components содержит переиспользуемые схемы и способы аутентификации
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme описывает способ аутентификации
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// SecurityRequirement перечисляет схемы аутентификации операции
+type SecurityRequirement map[string][]string
+
+// Operation описывает обработку одного метода на одном пути
+type Operation struct {
+	Tags        []string               `json:"tags,omitempty"`
+	Summary     string                 `json:"summary,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	OperationID string                 `json:"operationId,omitempty"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]*Response   `json:"responses"`
+	Security    *[]SecurityRequirement `json:"security,omitempty"`
+	Deprecated  bool                   `json:"deprecated,omitempty"`
+
+	doc *Document
+}
+
+// Parameter — параметр пути, строки запроса или заголовка
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody описывает тело запроса
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response описывает ответ с определенным статусом
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header описывает заголовок ответа
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType связывает тип содержимого со схемой
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// ProblemSchema — имя схемы ответа об ошибке (RFC 7807)
+const ProblemSchema = "Problem"
+
+// New создает пустой документ с описанием ошибок и аутентификацией по
+// токену Bearer
+func New(title, version string) *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				ProblemSchema: problemSchema(),
+			},
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []SecurityRequirement{{"bearerAuth": {}}},
+	}
+	return d
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Op добавляет операцию и возвращает ее для дальнейшего описания.
+// Параметры пути вида {id} добавляются автоматически как целые числа,
+// ответ об ошибке по умолчанию — как Problem
+func (d *Document) Op(method, path, tag, summary string) *Operation {
+	op := &Operation{
+		Summary:   summary,
+		Responses: make(map[string]*Response),
+		doc:       d,
+	}
+	if tag != "" {
+		op.Tags = []string{tag}
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer"},
+		})
+	}
+
+	op.Responses["default"] = &Response{
+		Description: "Ошибка",
+		Content: map[string]MediaType{
+			"application/problem+json": {Schema: Ref(ProblemSchema)},
+		},
+	}
+
+	method = strings.ToLower(method)
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(map[string]*Operation)
+	}
+	d.Paths[path][method] = op
+	op.OperationID = operationID(method, path)
+	return op
+}
+
+// Has сообщает, описана ли операция method на пути path
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[path][strings.ToLower(method)]
+	return ok
+}
+
+// Operations возвращает пары «метод путь» всех описанных операций
+func (d *Document) Operations() []string {
+	var ops []string
+	for path, item := range d.Paths {
+		for method := range item {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// ServeHTTP отдает документ в формате JSON
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.once.Do(func() {
+		d.body, d.err = json.MarshalIndent(d, "", "  ")
+	})
+	if d.err != nil {
+		http.Error(w, d.err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d.body)
+}
+
+// Public отмечает операцию как доступную без токена
+func (o *Operation) Public() *Operation {
+	none := []SecurityRequirement{}
+	o.Security = &none
+	return o
+}
+
+// Describe задает подробное описание операции
+func (o *Operation) Describe(description string) *Operation {
+	o.Description = description
+	return o
+}
+
+// Query добавляет параметр строки запроса
+func (o *Operation) Query(name, typ, description string, required bool) *Operation {
+	o.Parameters = append(o.Parameters, Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Required:    required,
+		Schema:      scalar(typ),
+	})
+	return o
+}
+
+// Header добавляет параметр-заголовок запроса
+func (o *Operation) Header(name, description string, required bool) *Operation {
+	o.Parameters = append(o.Parameters, Parameter{
+		Name:        name,
+		In:          "header",
+		Description: description,
+		Required:    required,
+		Schema:      &Schema{Type: "string"},
+	})
+	return o
+}
+
+// Body задает тело запроса application/json по образцу v
+func (o *Operation) Body(v interface{}) *Operation {
+	return o.BodyAs("application/json", v)
+}
+
+// BodyAs задает тело запроса с типом содержимого contentType
+func (o *Operation) BodyAs(contentType string, v interface{}) *Operation {
+	if o.RequestBody == nil {
+		o.RequestBody = &RequestBody{Required: true, Content: make(map[string]MediaType)}
+	}
+	o.RequestBody.Content[contentType] = MediaType{Schema: o.doc.SchemaOf(v)}
+	return o
+}
+
+// Returns добавляет ответ application/json по образцу v. Если v равен nil,
+// ответ не содержит тела
+func (o *Operation) Returns(status int, description string, v interface{}) *Operation {
+	if v == nil {
+		return o.ReturnsAs(status, description, "", nil)
+	}
+	return o.ReturnsAs(status, description, "application/json", o.doc.SchemaOf(v))
+}
+
+// ReturnsAs добавляет ответ с произвольным типом содержимого
+func (o *Operation) ReturnsAs(status int, description, contentType string, schema *Schema) *Operation {
+	response := &Response{Description: description}
+	if contentType != "" {
+		response.Content = map[string]MediaType{contentType: {Schema: schema}}
+	}
+	o.Responses[statusKey(status)] = response
+	return o
+}
+
+// ResponseHeader описывает заголовок ответа со статусом status
+func (o *Operation) ResponseHeader(status int, name, description string) *Operation {
+	response, ok := o.Responses[statusKey(status)]
+	if !ok {
+		return o
+	}
+	if response.Headers == nil {
+		response.Headers = make(map[string]Header)
+	}
+	response.Headers[name] = Header{Description: description, Schema: &Schema{Type: "string"}}
+	return o
+}
+
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
+
+// operationID строит идентификатор операции из метода и пути:
+// get /api/assets/{id} -> getApiAssetsById
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+	upper := true
+	for _, r := range path {
+		switch {
+		case r == '{':
+			b.WriteString("By")
+			upper = true
+		case r == '/' || r == '-' || r == '.' || r == '}':
+			upper = true
+		default:
+			if upper {
+				b.WriteString(strings.ToUpper(string(r)))
+				upper = false
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+func problemSchema() *Schema {
+	return &Schema{
+		Type:        "object",
+		Description: "Описание ошибки в формате RFC 7807",
+		Properties: map[string]*Schema{
+			"type":     {Type: "string"},
+			"title":    {Type: "string"},
+			"status":   {Type: "integer"},
+			"detail":   {Type: "string"},
+			"instance": {Type: "string"},
+			"code":     {Type: "string", Enum: []string{"not_found", "validation", "conflict", "forbidden", "unauthorized", "precondition_failed", "too_many_requests"}},
+			"errors": {
+				Type:                 "object",
+				Description:          "Ошибки по полям запроса",
+				AdditionalProperties: &Schema{Type: "string"},
+			},
+		},
+		Required: []string{"type", "title", "status"},
+	}
+}
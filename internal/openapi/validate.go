@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResponseSchema возвращает схему тела ответа операции method на пути path
+// со статусом status. Если статус не описан, берется ответ default.
+// contentType — тип содержимого, под которым описано тело
+func (d *Document) ResponseSchema(method, path string, status int) (contentType string, schema *Schema, err error) {
+	op, ok := d.Paths[path][strings.ToLower(method)]
+	if !ok {
+		return "", nil, fmt.Errorf("operation %s %s is not documented", method, path)
+	}
+
+	response, ok := op.Responses[statusKey(status)]
+	if !ok {
+		response, ok = op.Responses["default"]
+	}
+	if !ok {
+		return "", nil, fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+
+	for contentType, media := range response.Content {
+		return contentType, media.Schema, nil
+	}
+	return "", nil, nil
+}
+
+// ValidateResponse проверяет, что ответ со статусом status, типом
+// содержимого contentType и телом body описан в документе
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	want, schema, err := d.ResponseSchema(method, path, status)
+	if err != nil {
+		return err
+	}
+
+	if want == "" {
+		if len(body) > 0 {
+			return fmt.Errorf("%s %s: status %d must have no body", method, path, status)
+		}
+		return nil
+	}
+
+	got, _, _ := mime.ParseMediaType(contentType)
+	if got != want {
+		return fmt.Errorf("%s %s: status %d content type %q, documented %q", method, path, status, got, want)
+	}
+	if !strings.HasSuffix(want, "json") {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: status %d: %v", method, path, status, err)
+	}
+	if err := d.Validate(schema, value); err != nil {
+		return fmt.Errorf("%s %s: status %d: %v", method, path, status, err)
+	}
+	return nil
+}
+
+// Validate проверяет значение value, полученное из json.Unmarshal, по
+// схеме schema. Поля объекта, не описанные в схеме с перечисленными
+// полями, считаются ошибкой: проверка нужна, чтобы документ не отставал
+// от ответов
+func (d *Document) Validate(schema *Schema, value interface{}) error {
+	return d.validate(schema, value, "$")
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, at string) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, schema.Ref)
+		}
+		return d.validate(resolved, value, at)
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(at, schema.Type, value)
+		}
+		return d.validateObject(schema, object, at)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return typeError(at, schema.Type, value)
+		}
+		for i, item := range items {
+			if err := d.validate(schema.Items, item, at+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return typeError(at, schema.Type, value)
+		}
+		return validateString(schema, s, at)
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return typeError(at, schema.Type, value)
+		}
+		return nil
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return typeError(at, schema.Type, value)
+		}
+		return nil
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(at, schema.Type, value)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: unsupported schema type %s", at, schema.Type)
+}
+
+func (d *Document) validateObject(schema *Schema, object map[string]interface{}, at string) error {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: required property %s is missing", at, name)
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			switch additional := schema.AdditionalProperties.(type) {
+			case *Schema:
+				property = additional
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: property %s is not documented", at, name)
+				}
+				continue
+			default:
+				// An object without described properties is free-form
+				if len(schema.Properties) > 0 {
+					return fmt.Errorf("%s: property %s is not documented", at, name)
+				}
+				continue
+			}
+		}
+		if err := d.validate(property, object[name], at+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(schema *Schema, s, at string) error {
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if s == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %q is not one of %v", at, s, schema.Enum)
+		}
+	}
+
+	switch schema.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			return fmt.Errorf("%s: %q is not a date-time", at, s)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return fmt.Errorf("%s: %q is not a date", at, s)
+		}
+	}
+	return nil
+}
+
+func typeError(at, want string, value interface{}) error {
+	return fmt.Errorf("%s: %T is not %s", at, value, want)
+}
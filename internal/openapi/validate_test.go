@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type validateItem struct {
+	ID    int       `json:"id"`
+	Name  string    `json:"name" validate:"required,oneof=a b"`
+	At    time.Time `json:"at"`
+	Note  *string   `json:"note"`
+	Items []int     `json:"items"`
+}
+
+func TestValidate(t *testing.T) {
+	d := New("test", "1")
+	schema := d.SchemaOf(validateItem{})
+
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"valid", `{"id":1,"name":"a","at":"2026-01-02T03:04:05Z","note":null,"items":[1,2]}`, true},
+		{"missing required", `{"id":1}`, false},
+		{"undocumented property", `{"id":1,"name":"a","extra":true}`, false},
+		{"wrong type", `{"id":"1","name":"a"}`, false},
+		{"fraction for integer", `{"id":1.5,"name":"a"}`, false},
+		{"not in enum", `{"name":"c"}`, false},
+		{"bad date-time", `{"name":"a","at":"yesterday"}`, false},
+		{"null for array", `{"name":"a","items":null}`, false},
+		{"wrong item", `{"name":"a","items":[1,"2"]}`, false},
+	}
+
+	for _, tt := range tests {
+		var value interface{}
+		if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+			t.Fatal(err)
+		}
+		err := d.Validate(schema, value)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	d := New("test", "1")
+	d.Op("GET", "/items/{id}", "items", "Item").
+		Returns(200, "Item", validateItem{}).
+		Returns(204, "Nothing", nil)
+
+	problem := []byte(`{"type":"about:blank","title":"Not Found","status":404,"code":"not_found"}`)
+	if err := d.ValidateResponse("GET", "/items/{id}", 404, "application/problem+json", problem); err != nil {
+		t.Errorf("problem: %v", err)
+	}
+	if err := d.ValidateResponse("GET", "/items/{id}", 404, "application/json", problem); err == nil {
+		t.Error("problem with application/json content type passed")
+	}
+	if err := d.ValidateResponse("GET", "/items/{id}", 200, "application/json; charset=utf-8", []byte(`{"name":"b"}`)); err != nil {
+		t.Errorf("item: %v", err)
+	}
+	if err := d.ValidateResponse("GET", "/items/{id}", 204, "", []byte("x")); err == nil {
+		t.Error("body for 204 passed")
+	}
+	if err := d.ValidateResponse("POST", "/items/{id}", 200, "application/json", nil); err == nil {
+		t.Error("undocumented operation passed")
+	}
+}
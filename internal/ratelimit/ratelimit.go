@@ -0,0 +1,104 @@
+// Package ratelimit ограничивает частоту запросов по ключу (IP-адресу,
+// учетной записи) алгоритмом token bucket. Состояние корзин хранится в
+// Store: MemoryStore подходит для одного экземпляра сервера, для
+// нескольких нужна общая реализация Store
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Clock — источник текущего времени. Позволяет подменять время в проверках
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock — часы системы
+var SystemClock Clock = systemClock{}
+
+// Limit — параметры корзины: Burst запросов подряд, затем один запрос в
+// Interval
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// Decision — результат проверки запроса. Если запрос отклонен, RetryAfter
+// показывает, когда в корзине появится следующий токен
+type Decision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store хранит корзины по ключам
+type Store interface {
+	// Take забирает из корзины key один токен, если он есть к моменту now
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error)
+	// Reset возвращает корзину key в начальное (полное) состояние
+	Reset(ctx context.Context, key string) error
+}
+
+// Limiter применяет один Limit к ключам с общим префиксом
+type Limiter struct {
+	store  Store
+	prefix string
+	limit  Limit
+	clock  Clock
+}
+
+func NewLimiter(store Store, prefix string, limit Limit, clock Clock) *Limiter {
+	return &Limiter{store: store, prefix: prefix, limit: limit, clock: clock}
+}
+
+// Allow проверяет очередной запрос с ключом key
+func (l *Limiter) Allow(ctx context.Context, key string) (Decision, error) {
+	return l.store.Take(ctx, l.prefix+":"+key, l.limit, l.clock.Now())
+}
+
+// Reset снимает ограничение с ключа key
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, l.prefix+":"+key)
+}
+
+// bucket — состояние корзины: число токенов на момент updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill возвращает число токенов в корзине к моменту now
+func (b bucket) refill(limit Limit, now time.Time) float64 {
+	elapsed := now.Sub(b.updated)
+	if elapsed <= 0 || limit.Interval <= 0 {
+		return b.tokens
+	}
+	return math.Min(float64(limit.Burst), b.tokens+float64(elapsed)/float64(limit.Interval))
+}
+
+// take забирает токен из корзины и возвращает решение. Новая корзина
+// считается полной
+func take(b *bucket, exists bool, limit Limit, now time.Time) Decision {
+	if !exists {
+		b.tokens = float64(limit.Burst)
+	} else {
+		b.tokens = b.refill(limit, now)
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return Decision{Allowed: true, Remaining: int(b.tokens)}
+	}
+
+	missing := 1 - b.tokens
+	return Decision{RetryAfter: time.Duration(math.Ceil(missing * float64(limit.Interval)))}
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// near сравнивает паузы с точностью до округления дробного числа токенов
+func near(got, want time.Duration) bool {
+	return got >= want && got-want < time.Microsecond
+}
+
+func allow(t *testing.T, l *Limiter, key string) Decision {
+	t.Helper()
+	decision, err := l.Allow(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decision
+}
+
+func TestLimiterRefillsTokens(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewLimiter(NewMemoryStore(), "test", Limit{Burst: 3, Interval: time.Minute}, clock)
+
+	for want := 2; want >= 0; want-- {
+		decision := allow(t, l, "alice")
+		if !decision.Allowed || decision.Remaining != want {
+			t.Fatalf("burst request: %+v, want allowed with %d remaining", decision, want)
+		}
+	}
+
+	decision := allow(t, l, "alice")
+	if decision.Allowed || !near(decision.RetryAfter, time.Minute) {
+		t.Fatalf("empty bucket: %+v, want denied for a minute", decision)
+	}
+
+	clock.Advance(40 * time.Second)
+	decision = allow(t, l, "alice")
+	if decision.Allowed || !near(decision.RetryAfter, 20*time.Second) {
+		t.Fatalf("partly refilled bucket: %+v, want denied for 20s", decision)
+	}
+
+	clock.Advance(20 * time.Second)
+	if decision := allow(t, l, "alice"); !decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("after one interval: %+v, want one token", decision)
+	}
+
+	// Other keys have their own buckets
+	if decision := allow(t, l, "bob"); !decision.Allowed || decision.Remaining != 2 {
+		t.Errorf("other key: %+v", decision)
+	}
+
+	// The bucket never holds more than Burst tokens
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !allow(t, l, "alice").Allowed {
+			t.Fatalf("request %d after an hour was denied", i+1)
+		}
+	}
+	if allow(t, l, "alice").Allowed {
+		t.Error("bucket refilled beyond Burst")
+	}
+}
+
+func TestLimiterReset(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewLimiter(NewMemoryStore(), "test", Limit{Burst: 1, Interval: time.Hour}, clock)
+
+	allow(t, l, "alice")
+	if allow(t, l, "alice").Allowed {
+		t.Fatal("second request was allowed")
+	}
+
+	if err := l.Reset(context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if !allow(t, l, "alice").Allowed {
+		t.Error("request after Reset was denied")
+	}
+}
+
+func TestLimitersWithPrefixesDoNotShareBuckets(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	limit := Limit{Burst: 1, Interval: time.Hour}
+	ip := NewLimiter(store, "ip", limit, clock)
+	account := NewLimiter(store, "account", limit, clock)
+
+	allow(t, ip, "key")
+	if !allow(t, account, "key").Allowed {
+		t.Error("limiters with different prefixes share a bucket")
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	limit := Limit{Burst: 2, Interval: time.Second}
+	l := NewLimiter(store, "test", limit, clock)
+
+	allow(t, l, "a")
+	allow(t, l, "b")
+	allow(t, l, "b")
+
+	clock.Advance(sweepInterval)
+	allow(t, l, "c")
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.buckets["test:a"]; ok {
+		t.Error("refilled bucket was kept")
+	}
+	if _, ok := store.buckets["test:c"]; !ok {
+		t.Error("bucket in use was removed")
+	}
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"inventory-system/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APITokenRepository хранит персональные токены доступа. Сам токен не
+// сохраняется, только его хеш
+type APITokenRepository struct {
+	db *sql.DB
+}
+
+func NewAPITokenRepository(db *sql.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+const apiTokenColumns = "id, employee_id, name, token_prefix, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at"
+
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	var t models.APIToken
+	err := row.Scan(
+		&t.ID,
+		&t.EmployeeID,
+		&t.Name,
+		&t.Prefix,
+		pq.Array(&t.Scopes),
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.LastUsedIP,
+		&t.RevokedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create сохраняет токен с хешем tokenHash и возвращает его id
+func (r *APITokenRepository) Create(ctx context.Context, token models.APIToken, tokenHash string) (int, error) {
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`INSERT INTO api_tokens (employee_id, name, token_prefix, token_hash, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id`,
+		token.EmployeeID,
+		token.Name,
+		token.Prefix,
+		tokenHash,
+		pq.Array(token.Scopes),
+		token.ExpiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+// GetByID возвращает токен сотрудника employeeID или nil. Токены
+// сотрудников других организаций не видны
+func (r *APITokenRepository) GetByID(ctx context.Context, employeeID, id int) (*models.APIToken, error) {
+	row := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT "+apiTokenColumns+` FROM api_tokens WHERE id = $1 AND employee_id = $2
+		   AND employee_id IN (SELECT id FROM employees WHERE $3 = 0 OR organization_id = $3)`,
+		id,
+		employeeID,
+		tenantScope(ctx),
+	)
+	t, err := scanAPIToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// GetActiveByHash возвращает неотозванный токен с хешем tokenHash,
+// действующий к now, или nil
+func (r *APITokenRepository) GetActiveByHash(ctx context.Context, tokenHash string, now time.Time) (*models.APIToken, error) {
+	row := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT "+apiTokenColumns+" FROM api_tokens WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2",
+		tokenHash,
+		now,
+	)
+	t, err := scanAPIToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// GetByEmployee возвращает токены сотрудника, начиная с новых
+func (r *APITokenRepository) GetByEmployee(ctx context.Context, employeeID int) ([]models.APIToken, error) {
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		"SELECT "+apiTokenColumns+" FROM api_tokens WHERE employee_id = $1 ORDER BY created_at DESC, id DESC",
+		employeeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// CountActive возвращает число действующих к now токенов сотрудника
+func (r *APITokenRepository) CountActive(ctx context.Context, employeeID int, now time.Time) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM api_tokens WHERE employee_id = $1 AND revoked_at IS NULL AND expires_at > $2",
+		employeeID,
+		now,
+	).Scan(&count)
+	return count, err
+}
+
+// TouchLastUsed записывает время и адрес последнего использования токена
+func (r *APITokenRepository) TouchLastUsed(ctx context.Context, id int, at time.Time, ip string) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE api_tokens SET last_used_at = $2, last_used_ip = NULLIF($3, '') WHERE id = $1",
+		id,
+		at,
+		ip,
+	)
+	return err
+}
+
+// Revoke отзывает токен сотрудника employeeID. Возвращает false, если
+// такого неотозванного токена нет или сотрудник из другой организации
+func (r *APITokenRepository) Revoke(ctx context.Context, employeeID, id int, at time.Time) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE api_tokens SET revoked_at = $3
+		 WHERE id = $1 AND employee_id = $2 AND revoked_at IS NULL
+		   AND employee_id IN (SELECT id FROM employees WHERE $4 = 0 OR organization_id = $4)`,
+		id,
+		employeeID,
+		at,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RevokeForEmployee отзывает все неотозванные токены сотрудника employeeID
+func (r *APITokenRepository) RevokeForEmployee(ctx context.Context, employeeID int, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE api_tokens SET revoked_at = $2 WHERE employee_id = $1 AND revoked_at IS NULL",
+		employeeID,
+		at,
+	)
+	return err
+}
@@ -3,9 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"inventory-system/internal/models"
 	"log"
+	"sort"
+	"strings"
+	"time"
 )
 
 type AssetRepository struct {
@@ -16,100 +21,86 @@ func NewAssetRepository(db *sql.DB) *AssetRepository {
 	return &AssetRepository{db: db}
 }
 
-func (r *AssetRepository) GetAll(ctx context.Context) ([]models.Asset, error) {
-	query := `
-        SELECT a.id, a.name, a.category, a.acquisition_date, a.cost,
-               a.status_id, s.name as status_name,
-               a.current_location_id, l.address as location_address,
-               a.department_id, d.name as department_name
-        FROM assets a
-        JOIN asset_statuses s ON a.status_id = s.id
-        JOIN locations l ON a.current_location_id = l.id
-        LEFT JOIN departments d ON a.department_id = d.id
-        ORDER BY a.name
-    `
-
-	// Добавьте логирование
-	log.Println("Executing query:", query)
-
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		log.Printf("Error querying assets: %v", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var assets []models.Asset
-	for rows.Next() {
-		var a models.Asset
-		var deptID sql.NullInt64
-		var deptName sql.NullString
-
-		err := rows.Scan(
-			&a.ID,
-			&a.Name,
-			&a.Category,
-			&a.AcquisitionDate,
-			&a.Cost,
-			&a.StatusID,
-			&a.Status,
-			&a.CurrentLocationID,
-			&a.Location,
-			&deptID,
-			&deptName,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if deptID.Valid {
-			id := int(deptID.Int64)
-			a.DepartmentID = &id
-			name := deptName.String
-			a.Department = &name
-		}
-
-		assets = append(assets, a)
-	}
-
-	return assets, nil
+const assetSelect = `
+	SELECT a.id, a.name, a.category, a.category_id, a.custom_fields,
+	       a.acquisition_date, a.cost, a.warranty_until,
+	       a.status_id, s.name as status_name,
+	       a.current_location_id, l.address as location_address,
+	       a.department_id, d.name as department_name, a.parent_id,
+	       a.deleted_at, a.deleted_by, a.version, a.organization_id
+	FROM assets a
+	JOIN asset_statuses s ON a.status_id = s.id
+	JOIN locations l ON a.current_location_id = l.id
+	LEFT JOIN departments d ON a.department_id = d.id
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func (r *AssetRepository) GetByID(ctx context.Context, id int) (*models.Asset, error) {
-	query := `
-		SELECT a.id, a.name, a.category, a.acquisition_date, a.cost,
-		       a.status_id, s.name as status_name,
-		       a.current_location_id, l.address as location_address,
-		       a.department_id, d.name as department_name
-		FROM assets a
-		JOIN asset_statuses s ON a.status_id = s.id
-		JOIN locations l ON a.current_location_id = l.id
-		LEFT JOIN departments d ON a.department_id = d.id
-		WHERE a.id = $1
-	`
-
+func scanAsset(row rowScanner) (models.Asset, error) {
 	var a models.Asset
+	var categoryID sql.NullInt64
+	var customFields []byte
 	var deptID sql.NullInt64
 	var deptName sql.NullString
+	var parentID sql.NullInt64
+	var warrantyUntil sql.NullTime
+	var deletedAt sql.NullTime
+	var deletedBy sql.NullInt64
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&a.ID,
 		&a.Name,
 		&a.Category,
+		&categoryID,
+		&customFields,
 		&a.AcquisitionDate,
 		&a.Cost,
+		&warrantyUntil,
 		&a.StatusID,
 		&a.Status,
 		&a.CurrentLocationID,
 		&a.Location,
 		&deptID,
 		&deptName,
+		&parentID,
+		&deletedAt,
+		&deletedBy,
+		&a.Version,
+		&a.OrganizationID,
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+		return a, err
+	}
+
+	if warrantyUntil.Valid {
+		date := warrantyUntil.Time.Format("2006-01-02")
+		a.WarrantyUntil = &date
+	}
+
+	if deletedAt.Valid {
+		a.DeletedAt = &deletedAt.Time
+	}
+	if deletedBy.Valid {
+		id := int(deletedBy.Int64)
+		a.DeletedBy = &id
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		a.ParentID = &id
+	}
+
+	if categoryID.Valid {
+		id := int(categoryID.Int64)
+		a.CategoryID = &id
+	}
+
+	if len(customFields) > 0 {
+		if err := json.Unmarshal(customFields, &a.CustomFields); err != nil {
+			return a, err
 		}
-		return nil, err
 	}
 
 	if deptID.Valid {
@@ -119,28 +110,142 @@ func (r *AssetRepository) GetByID(ctx context.Context, id int) (*models.Asset, e
 		a.Department = &name
 	}
 
+	return a, nil
+}
+
+func scanAssets(rows *queryRows) ([]models.Asset, error) {
+	var assets []models.Asset
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, a)
+	}
+
+	return assets, rows.Err()
+}
+
+func marshalCustomFields(values map[string]interface{}) (string, error) {
+	if values == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *AssetRepository) GetAll(ctx context.Context) ([]models.Asset, error) {
+	query := assetSelect + " WHERE a.deleted_at IS NULL AND ($1 = 0 OR a.organization_id = $1) ORDER BY a.name"
+
+	// Добавьте логирование
+	log.Println("Executing query:", query)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantScope(ctx))
+	if err != nil {
+		log.Printf("Error querying assets: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAssets(rows)
+}
+
+// Search возвращает активы, подходящие под фильтр: категория, значения
+// дополнительных полей (точное совпадение) и текстовый поиск по названию,
+// категории и значениям дополнительных полей
+func (r *AssetRepository) Search(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error) {
+	conditions := []string{"($1 = 0 OR a.organization_id = $1)"}
+	args := []interface{}{tenantScope(ctx)}
+
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "a.deleted_at IS NULL")
+	}
+
+	if filter.CategoryID != nil {
+		args = append(args, *filter.CategoryID)
+		conditions = append(conditions, fmt.Sprintf("a.category_id = $%d", len(args)))
+	}
+
+	keys := make([]string, 0, len(filter.CustomFields))
+	for key := range filter.CustomFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, key, filter.CustomFields[key])
+		conditions = append(conditions, fmt.Sprintf("a.custom_fields ->> $%d = $%d", len(args)-1, len(args)))
+	}
+
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf(`(a.name ILIKE $%d OR a.category ILIKE $%d
+		    OR EXISTS(SELECT 1 FROM jsonb_each_text(a.custom_fields) f WHERE f.value ILIKE $%d))`, n, n, n))
+	}
+
+	query := assetSelect + " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY a.name"
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAssets(rows)
+}
+
+func (r *AssetRepository) GetByID(ctx context.Context, id int) (*models.Asset, error) {
+	query := assetSelect + " WHERE a.id = $1 AND a.deleted_at IS NULL AND ($2 = 0 OR a.organization_id = $2)"
+
+	a, err := scanAsset(conn(ctx, r.db).QueryRowContext(ctx, query, id, tenantScope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
 	return &a, nil
 }
 
 func (r *AssetRepository) Create(ctx context.Context, asset models.Asset) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-		INSERT INTO assets (name, category, acquisition_date, cost, 
-		                  status_id, current_location_id, department_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO assets (name, category, category_id, custom_fields, acquisition_date,
+		                  cost, status_id, current_location_id, department_id, parent_id,
+		                  warranty_until, organization_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
 	`
 
+	customFields, err := marshalCustomFields(asset.CustomFields)
+	if err != nil {
+		return 0, err
+	}
+
 	var id int
-	err := r.db.QueryRowContext(
+	err = conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		asset.Name,
 		asset.Category,
+		asset.CategoryID,
+		customFields,
 		asset.AcquisitionDate,
 		asset.Cost,
 		asset.StatusID,
 		asset.CurrentLocationID,
 		asset.DepartmentID,
+		asset.ParentID,
+		asset.WarrantyUntil,
+		organizationID,
 	).Scan(&id)
 
 	if err != nil {
@@ -150,219 +255,310 @@ func (r *AssetRepository) Create(ctx context.Context, asset models.Asset) (int,
 	return id, nil
 }
 
-func (r *AssetRepository) Update(ctx context.Context, asset models.Asset) error {
+// Update сохраняет актив, если его версия совпадает с asset.Version,
+// и возвращает новую версию. Иначе возвращает ErrVersionConflict
+func (r *AssetRepository) Update(ctx context.Context, asset models.Asset) (int, error) {
 	query := `
 		UPDATE assets
-		SET name = $1, category = $2, acquisition_date = $3, cost = $4,
-		    status_id = $5, current_location_id = $6, department_id = $7
-		WHERE id = $8
+		SET name = $1, category = $2, category_id = $3, custom_fields = $4,
+		    acquisition_date = $5, cost = $6, status_id = $7,
+		    current_location_id = $8, department_id = $9, parent_id = $10,
+		    warranty_until = $13, version = version + 1
+		WHERE id = $11 AND version = $12 AND deleted_at IS NULL AND ($14 = 0 OR organization_id = $14)
+		RETURNING version
 	`
 
-	_, err := r.db.ExecContext(
+	customFields, err := marshalCustomFields(asset.CustomFields)
+	if err != nil {
+		return 0, err
+	}
+
+	return scanVersion(conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		asset.Name,
 		asset.Category,
+		asset.CategoryID,
+		customFields,
 		asset.AcquisitionDate,
 		asset.Cost,
 		asset.StatusID,
 		asset.CurrentLocationID,
 		asset.DepartmentID,
+		asset.ParentID,
 		asset.ID,
+		asset.Version,
+		asset.WarrantyUntil,
+		tenantScope(ctx),
+	))
+}
+
+var assetPatchColumns = map[string]bool{
+	"name":                true,
+	"category":            true,
+	"category_id":         true,
+	"custom_fields":       true,
+	"acquisition_date":    true,
+	"cost":                true,
+	"status_id":           true,
+	"current_location_id": true,
+	"department_id":       true,
+	"parent_id":           true,
+	"warranty_until":      true,
+}
+
+// Patch обновляет только столбцы из changes (имя столбца — новое значение)
+// и возвращает новую версию актива. При устаревшей версии возвращает
+// ErrVersionConflict
+func (r *AssetRepository) Patch(ctx context.Context, id, version int, changes map[string]interface{}) (int, error) {
+	if values, ok := changes["custom_fields"]; ok {
+		customFields, err := marshalCustomFields(values.(map[string]interface{}))
+		if err != nil {
+			return 0, err
+		}
+		changes["custom_fields"] = customFields
+	}
+
+	return patchRow(ctx, conn(ctx, r.db), "assets", assetPatchColumns, id, version, changes)
+}
+
+// Delete помечает актив удаленным. Компоненты удаленного комплекта
+// отвязываются от него
+func (r *AssetRepository) Delete(ctx context.Context, id int, deletedBy int) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE assets SET deleted_at = NOW(), deleted_by = $2, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR organization_id = $3)`,
+		id,
+		deletedBy,
+		tenantScope(ctx),
 	)
+	if err != nil {
+		return err
+	}
 
+	_, err = conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE assets SET parent_id = NULL, version = version + 1 WHERE parent_id = $1 AND ($2 = 0 OR organization_id = $2)",
+		id,
+		tenantScope(ctx),
+	)
 	return err
 }
 
-func (r *AssetRepository) Delete(ctx context.Context, id int) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM assets WHERE id = $1", id)
-	return err
+// Restore снимает пометку об удалении. Возвращает false, если удаленного
+// актива с таким id нет
+func (r *AssetRepository) Restore(ctx context.Context, id int) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE assets SET deleted_at = NULL, deleted_by = NULL, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR organization_id = $2)`,
+		id,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// PurgeDeleted окончательно удаляет активы, помеченные удаленными раньше
+// before. Активы, оставшиеся в истории перемещений или бронирований,
+// сохраняются вместе с этой историей. Очистка выполняется по всем
+// организациям
+func (r *AssetRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`DELETE FROM assets a
+		 WHERE a.deleted_at < $1
+		   AND NOT EXISTS(SELECT 1 FROM asset_transfers t WHERE t.asset_id = a.id)
+		   AND NOT EXISTS(SELECT 1 FROM reservations res WHERE res.asset_id = a.id)`,
+		before,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
 }
 
 func (r *AssetRepository) GetByStatus(ctx context.Context, statusID int) ([]models.Asset, error) {
-	query := `
-		SELECT a.id, a.name, a.category, a.acquisition_date, a.cost,
-		       a.status_id, s.name as status_name,
-		       a.current_location_id, l.address as location_address,
-		       a.department_id, d.name as department_name
-		FROM assets a
-		JOIN asset_statuses s ON a.status_id = s.id
-		JOIN locations l ON a.current_location_id = l.id
-		LEFT JOIN departments d ON a.department_id = d.id
-		WHERE a.status_id = $1
-		ORDER BY a.name
-	`
+	query := assetSelect + " WHERE a.status_id = $1 AND a.deleted_at IS NULL AND ($2 = 0 OR a.organization_id = $2) ORDER BY a.name"
 
-	rows, err := r.db.QueryContext(ctx, query, statusID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, statusID, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var assets []models.Asset
-	for rows.Next() {
-		var a models.Asset
-		var deptID sql.NullInt64
-		var deptName sql.NullString
+	return scanAssets(rows)
+}
 
-		err := rows.Scan(
-			&a.ID,
-			&a.Name,
-			&a.Category,
-			&a.AcquisitionDate,
-			&a.Cost,
-			&a.StatusID,
-			&a.Status,
-			&a.CurrentLocationID,
-			&a.Location,
-			&deptID,
-			&deptName,
-		)
-		if err != nil {
-			return nil, err
-		}
+func (r *AssetRepository) GetByLocation(ctx context.Context, locationID int) ([]models.Asset, error) {
+	query := assetSelect + " WHERE a.current_location_id = $1 AND a.deleted_at IS NULL AND ($2 = 0 OR a.organization_id = $2) ORDER BY a.name"
 
-		if deptID.Valid {
-			id := int(deptID.Int64)
-			a.DepartmentID = &id
-			name := deptName.String
-			a.Department = &name
-		}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, locationID, tenantScope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		assets = append(assets, a)
+	return scanAssets(rows)
+}
+
+func (r *AssetRepository) GetByDepartment(ctx context.Context, departmentID int) ([]models.Asset, error) {
+	query := assetSelect + " WHERE a.department_id = $1 AND a.deleted_at IS NULL AND ($2 = 0 OR a.organization_id = $2) ORDER BY a.name"
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, departmentID, tenantScope(ctx))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return assets, nil
+	return scanAssets(rows)
 }
 
-func (r *AssetRepository) GetByLocation(ctx context.Context, locationID int) ([]models.Asset, error) {
-	query := `
-		SELECT a.id, a.name, a.category, a.acquisition_date, a.cost,
-		       a.status_id, s.name as status_name,
-		       a.current_location_id, l.address as location_address,
-		       a.department_id, d.name as department_name
-		FROM assets a
-		JOIN asset_statuses s ON a.status_id = s.id
-		JOIN locations l ON a.current_location_id = l.id
-		LEFT JOIN departments d ON a.department_id = d.id
-		WHERE a.current_location_id = $1
-		ORDER BY a.name
+// GetWarrantyExpiring возвращает активы, гарантия которых заканчивается в
+// промежутке [from, until] и о которых еще не отправлялось напоминание
+func (r *AssetRepository) GetWarrantyExpiring(ctx context.Context, from, until time.Time) ([]models.Asset, error) {
+	query := assetSelect + `
+		WHERE a.deleted_at IS NULL
+		  AND ($3 = 0 OR a.organization_id = $3)
+		  AND a.warranty_until BETWEEN $1::date AND $2::date
+		  AND NOT EXISTS (
+			SELECT 1 FROM warranty_notices n
+			WHERE n.asset_id = a.id AND n.warranty_until = a.warranty_until
+		  )
+		ORDER BY a.warranty_until, a.name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, locationID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, from, until, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var assets []models.Asset
-	for rows.Next() {
-		var a models.Asset
-		var deptID sql.NullInt64
-		var deptName sql.NullString
+	return scanAssets(rows)
+}
 
-		err := rows.Scan(
-			&a.ID,
-			&a.Name,
-			&a.Category,
-			&a.AcquisitionDate,
-			&a.Cost,
-			&a.StatusID,
-			&a.Status,
-			&a.CurrentLocationID,
-			&a.Location,
-			&deptID,
-			&deptName,
-		)
-		if err != nil {
-			return nil, err
-		}
+// MarkWarrantyNotice отмечает, что напоминание о гарантии актива до даты
+// warrantyUntil отправлено
+func (r *AssetRepository) MarkWarrantyNotice(ctx context.Context, id int, warrantyUntil string) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO warranty_notices (asset_id, warranty_until)
+		 VALUES ($1, $2)
+		 ON CONFLICT (asset_id, warranty_until) DO NOTHING`,
+		id,
+		warrantyUntil,
+	)
+	return err
+}
 
-		if deptID.Valid {
-			id := int(deptID.Int64)
-			a.DepartmentID = &id
-			name := deptName.String
-			a.Department = &name
-		}
+// GetDescendants возвращает все компоненты комплекта на всех уровнях вложенности
+func (r *AssetRepository) GetDescendants(ctx context.Context, id int) ([]models.Asset, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM assets WHERE parent_id = $1 AND deleted_at IS NULL
+			UNION
+			SELECT c.id FROM assets c JOIN subtree s ON c.parent_id = s.id
+			WHERE c.deleted_at IS NULL
+		)
+	` + assetSelect + ` WHERE a.id IN (SELECT id FROM subtree) AND a.deleted_at IS NULL
+		AND ($2 = 0 OR a.organization_id = $2) ORDER BY a.name`
 
-		assets = append(assets, a)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id, tenantScope(ctx))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return assets, nil
+	return scanAssets(rows)
 }
 
-func (r *AssetRepository) GetByDepartment(ctx context.Context, departmentID int) ([]models.Asset, error) {
+// IsDescendant проверяет, входит ли candidateID в состав комплекта id
+// на любом уровне вложенности
+func (r *AssetRepository) IsDescendant(ctx context.Context, id, candidateID int) (bool, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM assets WHERE parent_id = $1 AND deleted_at IS NULL
+			UNION
+			SELECT c.id FROM assets c JOIN subtree s ON c.parent_id = s.id
+			WHERE c.deleted_at IS NULL
+		)
+		SELECT EXISTS(SELECT 1 FROM subtree WHERE id = $2)
+	`
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, candidateID).Scan(&exists)
+	return exists, err
+}
+
+// LockHierarchy блокирует до конца транзакции строку актива id, строки
+// всех комплектов, в которые он входит, и строку extraID (0 — без нее).
+// Строки блокируются в порядке id, чтобы встречные транзакции не
+// взаимоблокировались. Привязки, затрагивающие одну цепочку комплектов,
+// выполняются по очереди, и проверка цикла внутри транзакции видит
+// изменения, зафиксированные до нее
+func (r *AssetRepository) LockHierarchy(ctx context.Context, id, extraID int) error {
 	query := `
-		SELECT a.id, a.name, a.category, a.acquisition_date, a.cost,
-		       a.status_id, s.name as status_name,
-		       a.current_location_id, l.address as location_address,
-		       a.department_id, d.name as department_name
-		FROM assets a
-		JOIN asset_statuses s ON a.status_id = s.id
-		JOIN locations l ON a.current_location_id = l.id
-		LEFT JOIN departments d ON a.department_id = d.id
-		WHERE a.department_id = $1
-		ORDER BY a.name
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM assets WHERE id = $1
+			UNION
+			SELECT a.id, a.parent_id FROM assets a JOIN ancestors s ON a.id = s.parent_id
+		)
+		SELECT id FROM assets
+		WHERE (id IN (SELECT id FROM ancestors) OR id = $2) AND ($3 = 0 OR organization_id = $3)
+		ORDER BY id
+		FOR UPDATE
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, departmentID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id, extraID, tenantScope(ctx))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var assets []models.Asset
 	for rows.Next() {
-		var a models.Asset
-		var deptID sql.NullInt64
-		var deptName sql.NullString
-
-		err := rows.Scan(
-			&a.ID,
-			&a.Name,
-			&a.Category,
-			&a.AcquisitionDate,
-			&a.Cost,
-			&a.StatusID,
-			&a.Status,
-			&a.CurrentLocationID,
-			&a.Location,
-			&deptID,
-			&deptName,
-		)
-		if err != nil {
-			return nil, err
+		var locked int
+		if err := rows.Scan(&locked); err != nil {
+			return err
 		}
-
-		if deptID.Valid {
-			id := int(deptID.Int64)
-			a.DepartmentID = &id
-			name := deptName.String
-			a.Department = &name
-		}
-
-		assets = append(assets, a)
 	}
+	return rows.Err()
+}
 
-	return assets, nil
+func (r *AssetRepository) SetParent(ctx context.Context, id int, parentID *int) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE assets SET parent_id = $1, version = version + 1 WHERE id = $2 AND ($3 = 0 OR organization_id = $3)",
+		parentID,
+		id,
+		tenantScope(ctx),
+	)
+	return err
 }
 
 func (r *AssetRepository) UpdateStatus(ctx context.Context, id int, statusID int) error {
-	_, err := r.db.ExecContext(
+	_, err := conn(ctx, r.db).ExecContext(
 		ctx,
-		"UPDATE assets SET status_id = $1 WHERE id = $2",
+		"UPDATE assets SET status_id = $1, version = version + 1 WHERE id = $2 AND ($3 = 0 OR organization_id = $3)",
 		statusID,
 		id,
+		tenantScope(ctx),
 	)
 	return err
 }
 
 func (r *AssetRepository) UpdateLocation(ctx context.Context, id int, locationID int) error {
-	_, err := r.db.ExecContext(
+	_, err := conn(ctx, r.db).ExecContext(
 		ctx,
-		"UPDATE assets SET current_location_id = $1 WHERE id = $2",
+		"UPDATE assets SET current_location_id = $1, version = version + 1 WHERE id = $2 AND ($3 = 0 OR organization_id = $3)",
 		locationID,
 		id,
+		tenantScope(ctx),
 	)
 	return err
 }
@@ -379,11 +575,11 @@ func (r *AssetRepository) GetTransferHistory(ctx context.Context, assetID int) (
 		JOIN employees e ON t.employee_id = e.id
 		JOIN locations fl ON t.from_location_id = fl.id
 		JOIN locations tl ON t.to_location_id = tl.id
-		WHERE t.asset_id = $1
+		WHERE t.asset_id = $1 AND ($2 = 0 OR t.organization_id = $2)
 		ORDER BY t.transfer_date DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, assetID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, assetID, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -417,10 +613,11 @@ func (r *AssetRepository) GetTransferHistory(ctx context.Context, assetID int) (
 
 func (r *AssetRepository) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM assets WHERE id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM assets WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2))",
 		id,
+		tenantScope(ctx),
 	).Scan(&exists)
 
 	return exists, err
@@ -428,7 +625,7 @@ func (r *AssetRepository) Exists(ctx context.Context, id int) (bool, error) {
 
 func (r *AssetRepository) HasTransfers(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
 		"SELECT EXISTS(SELECT 1 FROM asset_transfers WHERE asset_id = $1)",
 		id,
@@ -19,13 +19,13 @@ func (r *AuthRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 	query := `
 		SELECT id, full_name, position, email, password_hash, role, department_id
 		FROM employees
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	var e models.Employee
 	var deptID sql.NullInt64
 
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
 		&e.ID,
 		&e.FullName,
 		&e.Position,
@@ -55,7 +55,7 @@ func (r *AuthRepository) CreateSession(ctx context.Context, employeeID int, toke
 		VALUES ($1, $2, $3)
 	`
 
-	_, err := r.db.ExecContext(
+	_, err := conn(ctx, r.db).ExecContext(
 		ctx,
 		query,
 		employeeID,
@@ -71,13 +71,13 @@ func (r *AuthRepository) GetSession(ctx context.Context, token string) (*models.
 		SELECT e.id, e.full_name, e.position, e.email, e.role, e.department_id
 		FROM sessions s
 		JOIN employees e ON s.employee_id = e.id
-		WHERE s.token = $1 AND s.expires_at > NOW()
+		WHERE s.token = $1 AND s.expires_at > NOW() AND e.deleted_at IS NULL
 	`
 
 	var e models.Employee
 	var deptID sql.NullInt64
 
-	err := r.db.QueryRowContext(ctx, query, token).Scan(
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, token).Scan(
 		&e.ID,
 		&e.FullName,
 		&e.Position,
@@ -101,7 +101,7 @@ func (r *AuthRepository) GetSession(ctx context.Context, token string) (*models.
 }
 
 func (r *AuthRepository) DeleteSession(ctx context.Context, token string) error {
-	_, err := r.db.ExecContext(
+	_, err := conn(ctx, r.db).ExecContext(
 		ctx,
 		"DELETE FROM sessions WHERE token = $1",
 		token,
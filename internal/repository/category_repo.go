@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"inventory-system/internal/models"
+)
+
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+func scanCategory(row rowScanner) (models.Category, error) {
+	var c models.Category
+	var parentID sql.NullInt64
+	var fields []byte
+
+	if err := row.Scan(&c.ID, &c.Name, &parentID, &c.RequiresApproval, &fields); err != nil {
+		return c, err
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+
+	c.Fields = []models.CustomField{}
+	if len(fields) > 0 {
+		if err := json.Unmarshal(fields, &c.Fields); err != nil {
+			return c, err
+		}
+	}
+
+	return c, nil
+}
+
+func marshalFields(fields []models.CustomField) (string, error) {
+	if fields == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func scanCategories(rows *queryRows) ([]models.Category, error) {
+	var categories []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *CategoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
+	query := `
+		SELECT id, name, parent_id, requires_approval, fields
+		FROM categories
+		WHERE $1 = 0 OR organization_id = $1
+		ORDER BY name
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantScope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCategories(rows)
+}
+
+// GetAncestors возвращает цепочку категорий от корня до указанной включительно
+func (r *CategoryRepository) GetAncestors(ctx context.Context, id int) ([]models.Category, error) {
+	query := `
+		WITH RECURSIVE chain AS (
+			SELECT id, name, parent_id, requires_approval, fields, 0 AS depth
+			FROM categories
+			WHERE id = $1 AND ($2 = 0 OR organization_id = $2)
+			UNION ALL
+			SELECT c.id, c.name, c.parent_id, c.requires_approval, c.fields, chain.depth + 1
+			FROM categories c
+			JOIN chain ON c.id = chain.parent_id
+		)
+		SELECT id, name, parent_id, requires_approval, fields
+		FROM chain
+		ORDER BY depth DESC
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id, tenantScope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCategories(rows)
+}
+
+// IsDescendant проверяет, находится ли candidateID в поддереве категории id
+func (r *CategoryRepository) IsDescendant(ctx context.Context, id, candidateID int) (bool, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE parent_id = $1
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT EXISTS(SELECT 1 FROM subtree WHERE id = $2)
+	`
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, candidateID).Scan(&exists)
+	return exists, err
+}
+
+func (r *CategoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	query := `
+		SELECT id, name, parent_id, requires_approval, fields
+		FROM categories
+		WHERE id = $1 AND ($2 = 0 OR organization_id = $2)
+	`
+
+	c, err := scanCategory(conn(ctx, r.db).QueryRowContext(ctx, query, id, tenantScope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (r *CategoryRepository) Create(ctx context.Context, category models.Category) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO categories (name, parent_id, requires_approval, fields, organization_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	fields, err := marshalFields(category.Fields)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		category.Name,
+		category.ParentID,
+		category.RequiresApproval,
+		fields,
+		organizationID,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *CategoryRepository) Update(ctx context.Context, category models.Category) error {
+	query := `
+		UPDATE categories
+		SET name = $1, parent_id = $2, requires_approval = $3, fields = $4
+		WHERE id = $5 AND ($6 = 0 OR organization_id = $6)
+	`
+
+	fields, err := marshalFields(category.Fields)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		category.Name,
+		category.ParentID,
+		category.RequiresApproval,
+		fields,
+		category.ID,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Название категории дублируется в assets.category для старых отчетов
+	_, err = conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE assets SET category = $1, version = version + 1
+		 WHERE category_id = $2 AND category <> $1 AND ($3 = 0 OR organization_id = $3)`,
+		category.Name,
+		category.ID,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+func (r *CategoryRepository) Delete(ctx context.Context, id int) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"DELETE FROM categories WHERE id = $1 AND ($2 = 0 OR organization_id = $2)",
+		id,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+func (r *CategoryRepository) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1 AND ($2 = 0 OR organization_id = $2))",
+		id,
+		tenantScope(ctx),
+	).Scan(&exists)
+
+	return exists, err
+}
+
+// NameExists проверяет, занято ли название среди категорий того же уровня
+// в организации
+func (r *CategoryRepository) NameExists(ctx context.Context, name string, parentID *int, excludeID int) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM categories
+		 WHERE LOWER(name) = LOWER($1) AND COALESCE(parent_id, 0) = COALESCE($2, 0) AND id <> $3
+		   AND ($4 = 0 OR organization_id = $4))`,
+		name,
+		parentID,
+		excludeID,
+		tenantScope(ctx),
+	).Scan(&exists)
+
+	return exists, err
+}
+
+func (r *CategoryRepository) HasAssets(ctx context.Context, id int) (bool, error) {
+	var hasAssets bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM assets WHERE category_id = $1)",
+		id,
+	).Scan(&hasAssets)
+
+	return hasAssets, err
+}
+
+func (r *CategoryRepository) HasChildren(ctx context.Context, id int) (bool, error) {
+	var hasChildren bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM categories WHERE parent_id = $1)",
+		id,
+	).Scan(&hasChildren)
+
+	return hasChildren, err
+}
+
+// Merge переносит активы и подкатегории из sourceID в targetID, сохраняет
+// объединенную схему полей и удаляет исходную категорию
+func (r *CategoryRepository) Merge(ctx context.Context, sourceID, targetID int, fields []models.CustomField) error {
+	mergedFields, err := marshalFields(fields)
+	if err != nil {
+		return err
+	}
+
+	return NewTransactor(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		scope := tenantScope(ctx)
+
+		_, err := q.ExecContext(
+			ctx,
+			"UPDATE categories SET fields = $1 WHERE id = $2 AND ($3 = 0 OR organization_id = $3)",
+			mergedFields,
+			targetID,
+			scope,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(
+			ctx,
+			`UPDATE assets
+			 SET category_id = $1, category = (SELECT name FROM categories WHERE id = $1),
+			     version = version + 1
+			 WHERE category_id = $2 AND ($3 = 0 OR organization_id = $3)`,
+			targetID,
+			sourceID,
+			scope,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(
+			ctx,
+			"UPDATE categories SET parent_id = $1 WHERE parent_id = $2 AND ($3 = 0 OR organization_id = $3)",
+			targetID,
+			sourceID,
+			scope,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(
+			ctx,
+			"DELETE FROM categories WHERE id = $1 AND ($2 = 0 OR organization_id = $2)",
+			sourceID,
+			scope,
+		)
+		return err
+	})
+}
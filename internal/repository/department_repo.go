@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"inventory-system/internal/apperror"
 	"inventory-system/internal/models"
+	"time"
 )
 
 type DepartmentRepository struct {
@@ -15,16 +17,18 @@ func NewDepartmentRepository(db *sql.DB) *DepartmentRepository {
 	return &DepartmentRepository{db: db}
 }
 
-func (r *DepartmentRepository) GetAll(ctx context.Context) ([]models.Department, error) {
+func (r *DepartmentRepository) GetAll(ctx context.Context, includeDeleted bool) ([]models.Department, error) {
 	query := `
 		SELECT d.id, d.name, d.location, 
-		       d.head_id, e.full_name as head_name
+		       d.head_id, e.full_name as head_name,
+		       d.deleted_at, d.deleted_by, d.version
 		FROM departments d
 		LEFT JOIN employees e ON d.head_id = e.id
+		WHERE ($1 OR d.deleted_at IS NULL) AND ($2 = 0 OR d.organization_id = $2)
 		ORDER BY d.name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, includeDeleted, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +39,8 @@ func (r *DepartmentRepository) GetAll(ctx context.Context) ([]models.Department,
 		var d models.Department
 		var headID sql.NullInt64
 		var headName sql.NullString
+		var deletedAt sql.NullTime
+		var deletedBy sql.NullInt64
 
 		err := rows.Scan(
 			&d.ID,
@@ -42,11 +48,22 @@ func (r *DepartmentRepository) GetAll(ctx context.Context) ([]models.Department,
 			&d.Location,
 			&headID,
 			&headName,
+			&deletedAt,
+			&deletedBy,
+			&d.Version,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if deletedAt.Valid {
+			d.DeletedAt = &deletedAt.Time
+		}
+		if deletedBy.Valid {
+			id := int(deletedBy.Int64)
+			d.DeletedBy = &id
+		}
+
 		if headID.Valid {
 			id := int(headID.Int64)
 			d.HeadID = &id
@@ -63,22 +80,23 @@ func (r *DepartmentRepository) GetAll(ctx context.Context) ([]models.Department,
 func (r *DepartmentRepository) GetByID(ctx context.Context, id int) (*models.Department, error) {
 	query := `
 		SELECT d.id, d.name, d.location, 
-		       d.head_id, e.full_name as head_name
+		       d.head_id, e.full_name as head_name, d.version
 		FROM departments d
 		LEFT JOIN employees e ON d.head_id = e.id
-		WHERE d.id = $1
+		WHERE d.id = $1 AND d.deleted_at IS NULL AND ($2 = 0 OR d.organization_id = $2)
 	`
 
 	var d models.Department
 	var headID sql.NullInt64
 	var headName sql.NullString
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, tenantScope(ctx)).Scan(
 		&d.ID,
 		&d.Name,
 		&d.Location,
 		&headID,
 		&headName,
+		&d.Version,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -98,19 +116,25 @@ func (r *DepartmentRepository) GetByID(ctx context.Context, id int) (*models.Dep
 }
 
 func (r *DepartmentRepository) Create(ctx context.Context, department models.Department) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-		INSERT INTO departments (name, location, head_id)
-		VALUES ($1, $2, $3)
+		INSERT INTO departments (name, location, head_id, organization_id)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id
 	`
 
 	var id int
-	err := r.db.QueryRowContext(
+	err = conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		department.Name,
 		department.Location,
 		department.HeadID,
+		organizationID,
 	).Scan(&id)
 
 	if err != nil {
@@ -120,31 +144,46 @@ func (r *DepartmentRepository) Create(ctx context.Context, department models.Dep
 	return id, nil
 }
 
-func (r *DepartmentRepository) Update(ctx context.Context, department models.Department) error {
+// Update сохраняет отдел, если его версия совпадает с department.Version,
+// и возвращает новую версию. Иначе возвращает ErrVersionConflict
+func (r *DepartmentRepository) Update(ctx context.Context, department models.Department) (int, error) {
 	query := `
 		UPDATE departments
-		SET name = $1, location = $2, head_id = $3
-		WHERE id = $4
+		SET name = $1, location = $2, head_id = $3, version = version + 1
+		WHERE id = $4 AND version = $5 AND deleted_at IS NULL AND ($6 = 0 OR organization_id = $6)
+		RETURNING version
 	`
 
-	_, err := r.db.ExecContext(
+	return scanVersion(conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		department.Name,
 		department.Location,
 		department.HeadID,
 		department.ID,
-	)
+		department.Version,
+		tenantScope(ctx),
+	))
+}
 
-	return err
+var departmentPatchColumns = map[string]bool{
+	"name":     true,
+	"location": true,
+	"head_id":  true,
+}
+
+// Patch обновляет только столбцы из changes и возвращает новую версию
+// отдела. При устаревшей версии возвращает ErrVersionConflict
+func (r *DepartmentRepository) Patch(ctx context.Context, id, version int, changes map[string]interface{}) (int, error) {
+	return patchRow(ctx, conn(ctx, r.db), "departments", departmentPatchColumns, id, version, changes)
 }
 
-func (r *DepartmentRepository) Delete(ctx context.Context, id int) error {
+func (r *DepartmentRepository) Delete(ctx context.Context, id int, deletedBy int) error {
 	// Проверяем, есть ли сотрудники в отделе
 	var hasEmployees bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM employees WHERE department_id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM employees WHERE department_id = $1 AND deleted_at IS NULL)",
 		id,
 	).Scan(&hasEmployees)
 
@@ -153,22 +192,59 @@ func (r *DepartmentRepository) Delete(ctx context.Context, id int) error {
 	}
 
 	if hasEmployees {
-		return errors.New("cannot delete department with employees")
+		return apperror.Conflict("cannot delete department with employees")
 	}
 
-	_, err = r.db.ExecContext(ctx, "DELETE FROM departments WHERE id = $1", id)
+	_, err = conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE departments SET deleted_at = NOW(), deleted_by = $2, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR organization_id = $3)`,
+		id,
+		deletedBy,
+		tenantScope(ctx),
+	)
 	return err
 }
 
+// Restore снимает пометку об удалении. Возвращает false, если удаленного
+// отдела с таким id нет
+func (r *DepartmentRepository) Restore(ctx context.Context, id int) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE departments SET deleted_at = NULL, deleted_by = NULL, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR organization_id = $2)`,
+		id,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// PurgeDeleted окончательно удаляет отделы, помеченные удаленными раньше
+// before во всех организациях. Ссылки на них у активов и сотрудников
+// обнуляются внешними ключами
+func (r *DepartmentRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM departments WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (r *DepartmentRepository) GetEmployees(ctx context.Context, departmentID int) ([]models.Employee, error) {
 	query := `
 		SELECT id, full_name, position, email, role
 		FROM employees
-		WHERE department_id = $1
+		WHERE department_id = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2)
 		ORDER BY full_name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, departmentID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, departmentID, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -196,20 +272,39 @@ func (r *DepartmentRepository) GetEmployees(ctx context.Context, departmentID in
 
 func (r *DepartmentRepository) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM departments WHERE id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM departments WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2))",
 		id,
+		tenantScope(ctx),
 	).Scan(&exists)
 
 	return exists, err
 }
 
+// FindIDByName возвращает id действующего отдела с названием name без учета
+// регистра или 0, если такого отдела нет
+func (r *DepartmentRepository) FindIDByName(ctx context.Context, name string) (int, error) {
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT id FROM departments
+		 WHERE LOWER(name) = LOWER($1) AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2)
+		 ORDER BY id LIMIT 1`,
+		name,
+		tenantScope(ctx),
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
 func (r *DepartmentRepository) IsEmployeeDepartmentHead(ctx context.Context, employeeID int, b *bool) bool {
 	var isHead bool
-	_ = r.db.QueryRowContext(
+	_ = conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM departments WHERE head_id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM departments WHERE head_id = $1 AND deleted_at IS NULL)",
 		employeeID,
 	).Scan(&isHead)
 
@@ -4,9 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"inventory-system/internal/apperror"
 	"inventory-system/internal/models"
+	"inventory-system/internal/tenant"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// ErrEmailTaken возвращается, когда email уже принадлежит другому сотруднику
+var ErrEmailTaken = apperror.Conflict("email already taken")
+
 type EmployeeRepository struct {
 	db *sql.DB
 }
@@ -15,16 +23,18 @@ func NewEmployeeRepository(db *sql.DB) *EmployeeRepository {
 	return &EmployeeRepository{db: db}
 }
 
-func (r *EmployeeRepository) GetAll(ctx context.Context) ([]models.Employee, error) {
+func (r *EmployeeRepository) GetAll(ctx context.Context, includeDeleted bool) ([]models.Employee, error) {
 	query := `
 		SELECT e.id, e.full_name, e.position, e.email, e.role, 
-		       e.department_id, d.name as department_name
+		       e.department_id, d.name as department_name,
+		       e.deleted_at, e.deleted_by, e.version, e.totp_enabled, e.organization_id
 		FROM employees e
 		LEFT JOIN departments d ON e.department_id = d.id
+		WHERE ($1 OR e.deleted_at IS NULL) AND ($2 = 0 OR e.organization_id = $2)
 		ORDER BY e.full_name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, includeDeleted, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +45,8 @@ func (r *EmployeeRepository) GetAll(ctx context.Context) ([]models.Employee, err
 		var e models.Employee
 		var deptID sql.NullInt64
 		var deptName sql.NullString
+		var deletedAt sql.NullTime
+		var deletedBy sql.NullInt64
 
 		err := rows.Scan(
 			&e.ID,
@@ -44,11 +56,24 @@ func (r *EmployeeRepository) GetAll(ctx context.Context) ([]models.Employee, err
 			&e.Role,
 			&deptID,
 			&deptName,
+			&deletedAt,
+			&deletedBy,
+			&e.Version,
+			&e.TwoFactorEnabled,
+			&e.OrganizationID,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if deletedAt.Valid {
+			e.DeletedAt = &deletedAt.Time
+		}
+		if deletedBy.Valid {
+			id := int(deletedBy.Int64)
+			e.DeletedBy = &id
+		}
+
 		if deptID.Valid {
 			id := int(deptID.Int64)
 			e.DepartmentID = &id
@@ -65,17 +90,19 @@ func (r *EmployeeRepository) GetAll(ctx context.Context) ([]models.Employee, err
 func (r *EmployeeRepository) GetByID(ctx context.Context, id int) (*models.Employee, error) {
 	query := `
 		SELECT e.id, e.full_name, e.position, e.email, e.role, 
-		       e.department_id, d.name as department_name
+		       e.department_id, d.name as department_name, e.version,
+		       e.password_changed_at, e.locked_until, e.totp_enabled,
+		       COALESCE(e.auth_source, ''), COALESCE(e.external_id, ''), e.organization_id
 		FROM employees e
 		LEFT JOIN departments d ON e.department_id = d.id
-		WHERE e.id = $1
+		WHERE e.id = $1 AND e.deleted_at IS NULL AND ($2 = 0 OR e.organization_id = $2)
 	`
 
 	var e models.Employee
 	var deptID sql.NullInt64
 	var deptName sql.NullString
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, tenantScope(ctx)).Scan(
 		&e.ID,
 		&e.FullName,
 		&e.Position,
@@ -83,6 +110,13 @@ func (r *EmployeeRepository) GetByID(ctx context.Context, id int) (*models.Emplo
 		&e.Role,
 		&deptID,
 		&deptName,
+		&e.Version,
+		&e.PasswordChangedAt,
+		&e.LockedUntil,
+		&e.TwoFactorEnabled,
+		&e.AuthSource,
+		&e.ExternalID,
+		&e.OrganizationID,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -101,17 +135,23 @@ func (r *EmployeeRepository) GetByID(ctx context.Context, id int) (*models.Emplo
 	return &e, nil
 }
 
+// GetByEmail ищет сотрудника во всех организациях: email уникален в
+// группе, и по нему при входе определяется организация сотрудника
 func (r *EmployeeRepository) GetByEmail(ctx context.Context, email string) (*models.Employee, error) {
 	query := `
-		SELECT id, full_name, position, email, password_hash, role, department_id
+		SELECT id, full_name, position, email, password_hash, role, department_id,
+		       failed_logins, locked_until, totp_enabled,
+		       COALESCE(auth_source, ''), COALESCE(external_id, ''), organization_id
 		FROM employees
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
+	ctx = tenant.WithAllOrganizations(ctx)
+
 	var e models.Employee
 	var deptID sql.NullInt64
 
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
 		&e.ID,
 		&e.FullName,
 		&e.Position,
@@ -119,6 +159,12 @@ func (r *EmployeeRepository) GetByEmail(ctx context.Context, email string) (*mod
 		&e.PasswordHash,
 		&e.Role,
 		&deptID,
+		&e.FailedLogins,
+		&e.LockedUntil,
+		&e.TwoFactorEnabled,
+		&e.AuthSource,
+		&e.ExternalID,
+		&e.OrganizationID,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -135,15 +181,65 @@ func (r *EmployeeRepository) GetByEmail(ctx context.Context, email string) (*mod
 	return &e, nil
 }
 
+// GetByDepartment возвращает действующих сотрудников отдела
+func (r *EmployeeRepository) GetByDepartment(ctx context.Context, departmentID int) ([]models.Employee, error) {
+	return r.queryContacts(ctx, "department_id = $1", departmentID)
+}
+
+// GetByRole возвращает действующих сотрудников с ролью role
+func (r *EmployeeRepository) GetByRole(ctx context.Context, role string) ([]models.Employee, error) {
+	return r.queryContacts(ctx, "role = $1", role)
+}
+
+// queryContacts выбирает имя, email и роль сотрудников, подходящих под
+// условие where
+func (r *EmployeeRepository) queryContacts(ctx context.Context, where string, arg interface{}) ([]models.Employee, error) {
+	query := `
+		SELECT id, full_name, email, role, department_id
+		FROM employees
+		WHERE ` + where + ` AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2)
+		ORDER BY full_name
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, arg, tenantScope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var employees []models.Employee
+	for rows.Next() {
+		var e models.Employee
+		var deptID sql.NullInt64
+
+		if err := rows.Scan(&e.ID, &e.FullName, &e.Email, &e.Role, &deptID); err != nil {
+			return nil, err
+		}
+		if deptID.Valid {
+			id := int(deptID.Int64)
+			e.DepartmentID = &id
+		}
+		employees = append(employees, e)
+	}
+
+	return employees, rows.Err()
+}
+
 func (r *EmployeeRepository) Create(ctx context.Context, employee models.Employee) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-        INSERT INTO employees (full_name, position, email, password_hash, role, department_id)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO employees (full_name, position, email, password_hash, role, department_id,
+                               auth_source, external_id, organization_id)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9)
         RETURNING id
     `
 
 	var id int
-	err := r.db.QueryRowContext(
+	err = conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		employee.FullName,
@@ -152,6 +248,9 @@ func (r *EmployeeRepository) Create(ctx context.Context, employee models.Employe
 		employee.PasswordHash, // Убедитесь, что это хеш, а не plain text
 		employee.Role,
 		employee.DepartmentID,
+		employee.AuthSource,
+		employee.ExternalID,
+		organizationID,
 	).Scan(&id)
 
 	if err != nil {
@@ -161,15 +260,18 @@ func (r *EmployeeRepository) Create(ctx context.Context, employee models.Employe
 	return id, nil
 }
 
-func (r *EmployeeRepository) Update(ctx context.Context, employee models.Employee) error {
+// Update сохраняет сотрудника, если его версия совпадает с employee.Version,
+// и возвращает новую версию. Иначе возвращает ErrVersionConflict
+func (r *EmployeeRepository) Update(ctx context.Context, employee models.Employee) (int, error) {
 	query := `
 		UPDATE employees
 		SET full_name = $1, position = $2, email = $3, 
-		    role = $4, department_id = $5
-		WHERE id = $6
+		    role = $4, department_id = $5, version = version + 1
+		WHERE id = $6 AND version = $7 AND deleted_at IS NULL AND ($8 = 0 OR organization_id = $8)
+		RETURNING version
 	`
 
-	_, err := r.db.ExecContext(
+	return scanVersion(conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		employee.FullName,
@@ -178,27 +280,148 @@ func (r *EmployeeRepository) Update(ctx context.Context, employee models.Employe
 		employee.Role,
 		employee.DepartmentID,
 		employee.ID,
-	)
+		employee.Version,
+		tenantScope(ctx),
+	))
+}
+
+var employeePatchColumns = map[string]bool{
+	"full_name":     true,
+	"position":      true,
+	"email":         true,
+	"role":          true,
+	"department_id": true,
+}
 
+// Patch обновляет только столбцы из changes и возвращает новую версию
+// сотрудника. Возвращает ErrVersionConflict при устаревшей версии и
+// ErrEmailTaken, если новый email уже занят
+func (r *EmployeeRepository) Patch(ctx context.Context, id, version int, changes map[string]interface{}) (int, error) {
+	newVersion, err := patchRow(ctx, conn(ctx, r.db), "employees", employeePatchColumns, id, version, changes)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, ErrEmailTaken
+	}
+	return newVersion, err
+}
+
+// FindByExternalID возвращает id сотрудника с идентификатором externalID во
+// внешнем провайдере source и признак того, что сотрудник удален. Если
+// такого сотрудника нет в организации из контекста, возвращает 0
+func (r *EmployeeRepository) FindByExternalID(ctx context.Context, source, externalID string) (int, bool, error) {
+	var id int
+	var deleted bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT id, deleted_at IS NOT NULL FROM employees
+		 WHERE auth_source = $1 AND external_id = $2 AND ($3 = 0 OR organization_id = $3)`,
+		source,
+		externalID,
+		tenantScope(ctx),
+	).Scan(&id, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return id, deleted, err
+}
+
+// ExternalOrganization возвращает организацию сотрудника, привязанного к
+// записи externalID во внешнем провайдере source, или 0, если такого
+// сотрудника нет. Поиск идет по всем организациям: при входе через внешний
+// каталог организация сотрудника еще неизвестна
+func (r *EmployeeRepository) ExternalOrganization(ctx context.Context, source, externalID string) (int, error) {
+	ctx = tenant.WithAllOrganizations(ctx)
+	var organizationID int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT organization_id FROM employees WHERE auth_source = $1 AND external_id = $2",
+		source,
+		externalID,
+	).Scan(&organizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return organizationID, err
+}
+
+// LinkExternal привязывает сотрудника к записи во внешнем провайдере
+func (r *EmployeeRepository) LinkExternal(ctx context.Context, id int, source, externalID string) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE employees SET auth_source = $2, external_id = $3 WHERE id = $1",
+		id,
+		source,
+		externalID,
+	)
 	return err
 }
 
-func (r *EmployeeRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
-	_, err := r.db.ExecContext(
+// GetPasswordHash возвращает хеш пароля действующего сотрудника
+func (r *EmployeeRepository) GetPasswordHash(ctx context.Context, id int) (string, error) {
+	var hash string
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT password_hash FROM employees WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	).Scan(&hash)
+	return hash, err
+}
+
+// UpdatePassword сохраняет новый хеш пароля и время смены пароля, после
+// которого прежние токены входа перестают действовать
+func (r *EmployeeRepository) UpdatePassword(ctx context.Context, id int, passwordHash string, changedAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(
 		ctx,
-		"UPDATE employees SET password_hash = $1 WHERE id = $2",
+		"UPDATE employees SET password_hash = $1, password_changed_at = $3 WHERE id = $2",
 		passwordHash,
 		id,
+		changedAt,
 	)
 	return err
 }
 
-func (r *EmployeeRepository) Delete(ctx context.Context, id int) error {
+// RecordLoginFailure увеличивает счетчик неудачных попыток входа подряд и
+// возвращает его новое значение
+func (r *EmployeeRepository) RecordLoginFailure(ctx context.Context, id int) (int, error) {
+	var failures int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"UPDATE employees SET failed_logins = failed_logins + 1 WHERE id = $1 RETURNING failed_logins",
+		id,
+	).Scan(&failures)
+	return failures, err
+}
+
+// LockLogin блокирует вход сотрудника до until
+func (r *EmployeeRepository) LockLogin(ctx context.Context, id int, until time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE employees SET locked_until = $2 WHERE id = $1", id, until)
+	return err
+}
+
+// ResetLoginFailures обнуляет счетчик неудачных попыток входа и снимает
+// блокировку. Возвращает false, если действующего сотрудника с таким id нет
+func (r *EmployeeRepository) ResetLoginFailures(ctx context.Context, id int) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE employees SET failed_logins = 0, locked_until = NULL
+		 WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2)`,
+		id,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *EmployeeRepository) Delete(ctx context.Context, id int, deletedBy int) error {
 	// Проверяем, является ли сотрудник главой отдела
 	var isHead bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM departments WHERE head_id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM departments WHERE head_id = $1 AND deleted_at IS NULL)",
 		id,
 	).Scan(&isHead)
 
@@ -207,19 +430,77 @@ func (r *EmployeeRepository) Delete(ctx context.Context, id int) error {
 	}
 
 	if isHead {
-		return errors.New("cannot delete employee who is a department head")
+		return apperror.Conflict("cannot delete employee who is a department head")
 	}
 
-	_, err = r.db.ExecContext(ctx, "DELETE FROM employees WHERE id = $1", id)
+	_, err = conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE employees SET deleted_at = NOW(), deleted_by = $2, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR organization_id = $3)`,
+		id,
+		deletedBy,
+		tenantScope(ctx),
+	)
 	return err
 }
 
+// Restore снимает пометку об удалении. Возвращает false, если удаленного
+// сотрудника с таким id нет, и ErrEmailTaken, если его email уже занят
+func (r *EmployeeRepository) Restore(ctx context.Context, id int) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE employees SET deleted_at = NULL, deleted_by = NULL, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR organization_id = $2)`,
+		id,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return false, ErrEmailTaken
+		}
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// PurgeDeleted окончательно удаляет сотрудников, помеченных удаленными
+// раньше before. Сотрудники, оставшиеся в истории перемещений или
+// бронирований, сохраняются. Очистка выполняется по всем организациям
+func (r *EmployeeRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := NewTransactor(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		stale := `
+			SELECT e.id FROM employees e
+			WHERE e.deleted_at < $1
+			  AND NOT EXISTS(SELECT 1 FROM asset_transfers t WHERE t.employee_id = e.id)
+			  AND NOT EXISTS(SELECT 1 FROM reservations res WHERE res.employee_id = e.id)
+		`
+
+		if _, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM sessions WHERE employee_id IN ("+stale+")", before); err != nil {
+			return err
+		}
+
+		result, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM employees WHERE id IN ("+stale+")", before)
+		if err != nil {
+			return err
+		}
+		purged, err = result.RowsAffected()
+		return err
+	})
+
+	return purged, err
+}
+
 func (r *EmployeeRepository) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM employees WHERE id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM employees WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2))",
 		id,
+		tenantScope(ctx),
 	).Scan(&exists)
 
 	return exists, err
@@ -227,9 +508,9 @@ func (r *EmployeeRepository) Exists(ctx context.Context, id int) (bool, error) {
 
 func (r *EmployeeRepository) IsEmployeeDepartmentHead(ctx context.Context, id int) (bool, error) {
 	var isHead bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM departments WHERE head_id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM departments WHERE head_id = $1 AND deleted_at IS NULL)",
 		id,
 	).Scan(&isHead)
 
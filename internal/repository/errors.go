@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"inventory-system/internal/apperror"
+
+	"github.com/lib/pq"
+)
+
+// translateError переводит нарушения ограничений PostgreSQL в ошибки
+// apperror. Исходная *pq.Error остается в цепочке, поэтому репозитории
+// по-прежнему могут проверять конкретные коды через errors.As
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return apperror.Wrap(apperror.KindConflict, "a record with the same value already exists", err)
+	case "23503": // foreign_key_violation
+		return apperror.Wrap(apperror.KindConflict, "the operation conflicts with related records", err)
+	case "23P01": // exclusion_violation
+		return apperror.Wrap(apperror.KindConflict, "the record overlaps with an existing one", err)
+	case "23502", "23514": // not_null_violation, check_violation
+		return apperror.Wrap(apperror.KindValidation, "the record violates a data constraint", err)
+	case "22001", "22003", "22007", "22008", "22P02": // неверный формат или диапазон значения
+		return apperror.Wrap(apperror.KindValidation, "invalid value format", err)
+	}
+	return err
+}
+
+// translatingQuerier оборачивает *sql.DB или *sql.Tx и переводит ошибки
+// через translateError
+type translatingQuerier struct {
+	q sqlQuerier
+}
+
+func (t translatingQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := t.q.ExecContext(ctx, query, args...)
+	return result, translateError(err)
+}
+
+func (t translatingQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*queryRows, error) {
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &queryRows{Rows: rows}, nil
+}
+
+func (t translatingQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) rowScanner {
+	return translatingRow{t.q.QueryRowContext(ctx, query, args...)}
+}
+
+type translatingRow struct {
+	row *sql.Row
+}
+
+func (r translatingRow) Scan(dest ...interface{}) error {
+	return translateError(r.row.Scan(dest...))
+}
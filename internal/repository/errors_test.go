@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"inventory-system/internal/apperror"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		code pq.ErrorCode
+		kind apperror.Kind
+	}{
+		{"23505", apperror.KindConflict},
+		{"23503", apperror.KindConflict},
+		{"23P01", apperror.KindConflict},
+		{"22P02", apperror.KindValidation},
+		{"23502", apperror.KindValidation},
+		{"23514", apperror.KindValidation},
+		{"22001", apperror.KindValidation},
+		{"40001", apperror.KindInternal},
+	}
+
+	for _, tt := range tests {
+		pqErr := &pq.Error{Code: tt.code, Message: "secret detail from the database"}
+		err := translateError(fmt.Errorf("query: %w", pqErr))
+
+		if kind := apperror.KindOf(err); kind != tt.kind {
+			t.Errorf("%s: kind = %s, want %s", tt.code, kind, tt.kind)
+		}
+		var original *pq.Error
+		if !errors.As(err, &original) || original != pqErr {
+			t.Errorf("%s: pq error is lost from the chain", tt.code)
+		}
+		if tt.kind != apperror.KindInternal && err.Error() == pqErr.Error() {
+			t.Errorf("%s: message exposes the database error", tt.code)
+		}
+	}
+}
+
+func TestTranslateErrorKeepsOtherErrors(t *testing.T) {
+	if err := translateError(nil); err != nil {
+		t.Errorf("translateError(nil) = %v", err)
+	}
+	if err := translateError(sql.ErrNoRows); err != sql.ErrNoRows {
+		t.Errorf("translateError(sql.ErrNoRows) = %v", err)
+	}
+}
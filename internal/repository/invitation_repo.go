@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"inventory-system/internal/models"
+	"inventory-system/internal/tenant"
+	"time"
+)
+
+// InvitationRepository хранит приглашения к регистрации. Токен
+// приглашения хранится только в виде хеша
+type InvitationRepository struct {
+	db *sql.DB
+}
+
+func NewInvitationRepository(db *sql.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+const invitationColumns = "id, email, role, department_id, invited_by, expires_at, accepted_at, employee_id, revoked_at, created_at, organization_id"
+
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	var i models.Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Role,
+		&i.DepartmentID,
+		&i.InvitedBy,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+		&i.EmployeeID,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.OrganizationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// invitationStatusFilters — условия выборки приглашений по состоянию; $2 —
+// текущее время
+var invitationStatusFilters = map[string]string{
+	models.InvitationPending:  "accepted_at IS NULL AND revoked_at IS NULL AND expires_at > $2",
+	models.InvitationAccepted: "accepted_at IS NOT NULL",
+	models.InvitationRevoked:  "accepted_at IS NULL AND revoked_at IS NOT NULL",
+	models.InvitationExpired:  "accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= $2",
+}
+
+// Create сохраняет приглашение в организацию из контекста с хешем токена
+// tokenHash и возвращает его id
+func (r *InvitationRepository) Create(ctx context.Context, invitation models.Invitation, tokenHash string) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`INSERT INTO invitations (email, role, department_id, invited_by, token_hash, expires_at, organization_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id`,
+		invitation.Email,
+		invitation.Role,
+		invitation.DepartmentID,
+		invitation.InvitedBy,
+		tokenHash,
+		invitation.ExpiresAt,
+		organizationID,
+	).Scan(&id)
+	return id, err
+}
+
+// GetByID возвращает приглашение или nil
+func (r *InvitationRepository) GetByID(ctx context.Context, id int) (*models.Invitation, error) {
+	row := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT "+invitationColumns+" FROM invitations WHERE id = $1 AND ($2 = 0 OR organization_id = $2)",
+		id,
+		tenantScope(ctx),
+	)
+	i, err := scanInvitation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return i, err
+}
+
+// GetByHash возвращает приглашение с хешем токена tokenHash или nil. Поиск
+// идет по всем организациям: организацию приглашенного определяет само
+// приглашение
+func (r *InvitationRepository) GetByHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	ctx = tenant.WithAllOrganizations(ctx)
+	row := conn(ctx, r.db).QueryRowContext(ctx, "SELECT "+invitationColumns+" FROM invitations WHERE token_hash = $1", tokenHash)
+	i, err := scanInvitation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return i, err
+}
+
+// GetAll возвращает приглашения в состоянии status к моменту now, начиная
+// с новых. Пустой status — все приглашения
+func (r *InvitationRepository) GetAll(ctx context.Context, status string, now time.Time) ([]models.Invitation, error) {
+	query := "SELECT " + invitationColumns + " FROM invitations WHERE ($1 = 0 OR organization_id = $1)"
+	args := []interface{}{tenantScope(ctx)}
+	if filter, ok := invitationStatusFilters[status]; ok {
+		query += " AND " + filter
+		args = append(args, now)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query+" ORDER BY created_at DESC, id DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		i, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *i)
+	}
+	return invitations, rows.Err()
+}
+
+// CountCreatedSince возвращает число приглашений на адрес email,
+// созданных после since
+func (r *InvitationRepository) CountCreatedSince(ctx context.Context, email string, since time.Time) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM invitations
+		 WHERE LOWER(email) = LOWER($1) AND created_at > $2 AND ($3 = 0 OR organization_id = $3)`,
+		email,
+		since,
+		tenantScope(ctx),
+	).Scan(&count)
+	return count, err
+}
+
+// SetMail связывает приглашение с письмом mailID в очереди отправки
+func (r *InvitationRepository) SetMail(ctx context.Context, id int, mailID int64) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE invitations SET mail_id = $2 WHERE id = $1 AND ($3 = 0 OR organization_id = $3)",
+		id,
+		mailID,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+// DiscardMail удаляет из очереди еще не отправленное письмо с
+// приглашением id
+func (r *InvitationRepository) DiscardMail(ctx context.Context, id int) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`DELETE FROM mail_queue
+		 WHERE status = 'pending'
+		   AND id IN (SELECT mail_id FROM invitations WHERE id = $1 AND ($2 = 0 OR organization_id = $2))`,
+		id,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+// DiscardMailForEmail удаляет из очереди еще не отправленные письма с
+// непринятыми приглашениями на адрес email
+func (r *InvitationRepository) DiscardMailForEmail(ctx context.Context, email string) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`DELETE FROM mail_queue
+		 WHERE status = 'pending'
+		   AND id IN (SELECT mail_id FROM invitations
+		              WHERE LOWER(email) = LOWER($1) AND accepted_at IS NULL AND revoked_at IS NULL
+		                AND ($2 = 0 OR organization_id = $2))`,
+		email,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+// RevokePendingForEmail отзывает непринятые приглашения на адрес email
+func (r *InvitationRepository) RevokePendingForEmail(ctx context.Context, email string, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE invitations SET revoked_at = $2
+		 WHERE LOWER(email) = LOWER($1) AND accepted_at IS NULL AND revoked_at IS NULL
+		   AND ($3 = 0 OR organization_id = $3)`,
+		email,
+		at,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+// Revoke отзывает непринятое приглашение. Возвращает false, если такого
+// приглашения нет
+func (r *InvitationRepository) Revoke(ctx context.Context, id int, at time.Time) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE invitations SET revoked_at = $2
+		 WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND ($3 = 0 OR organization_id = $3)`,
+		id,
+		at,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Renew заменяет токен и срок непринятого и неотозванного приглашения.
+// Возвращает false, если такого приглашения нет
+func (r *InvitationRepository) Renew(ctx context.Context, id int, tokenHash string, expiresAt time.Time) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE invitations SET token_hash = $2, expires_at = $3
+		 WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND ($4 = 0 OR organization_id = $4)`,
+		id,
+		tokenHash,
+		expiresAt,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Accept отмечает действующее к at приглашение принятым сотрудником
+// employeeID. Возвращает false, если приглашение уже принято, отозвано
+// или истекло
+func (r *InvitationRepository) Accept(ctx context.Context, id, employeeID int, at time.Time) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE invitations SET accepted_at = $3, employee_id = $2
+		 WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > $3
+		   AND ($4 = 0 OR organization_id = $4)`,
+		id,
+		employeeID,
+		at,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
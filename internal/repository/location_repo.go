@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"inventory-system/internal/apperror"
 	"inventory-system/internal/models"
+	"time"
 )
 
 type LocationRepository struct {
@@ -15,14 +17,15 @@ func NewLocationRepository(db *sql.DB) *LocationRepository {
 	return &LocationRepository{db: db}
 }
 
-func (r *LocationRepository) GetAll(ctx context.Context) ([]models.Location, error) {
+func (r *LocationRepository) GetAll(ctx context.Context, includeDeleted bool) ([]models.Location, error) {
 	query := `
-		SELECT id, address, type
+		SELECT id, address, type, deleted_at, deleted_by, version
 		FROM locations
+		WHERE ($1 OR deleted_at IS NULL) AND ($2 = 0 OR organization_id = $2)
 		ORDER BY address
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, includeDeleted, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -31,10 +34,22 @@ func (r *LocationRepository) GetAll(ctx context.Context) ([]models.Location, err
 	var locations []models.Location
 	for rows.Next() {
 		var l models.Location
-		err := rows.Scan(&l.ID, &l.Address, &l.Type)
+		var deletedAt sql.NullTime
+		var deletedBy sql.NullInt64
+
+		err := rows.Scan(&l.ID, &l.Address, &l.Type, &deletedAt, &deletedBy, &l.Version)
 		if err != nil {
 			return nil, err
 		}
+
+		if deletedAt.Valid {
+			l.DeletedAt = &deletedAt.Time
+		}
+		if deletedBy.Valid {
+			id := int(deletedBy.Int64)
+			l.DeletedBy = &id
+		}
+
 		locations = append(locations, l)
 	}
 
@@ -43,13 +58,13 @@ func (r *LocationRepository) GetAll(ctx context.Context) ([]models.Location, err
 
 func (r *LocationRepository) GetByID(ctx context.Context, id int) (*models.Location, error) {
 	query := `
-		SELECT id, address, type
+		SELECT id, address, type, version
 		FROM locations
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2)
 	`
 
 	var l models.Location
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&l.ID, &l.Address, &l.Type)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, tenantScope(ctx)).Scan(&l.ID, &l.Address, &l.Type, &l.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -61,18 +76,24 @@ func (r *LocationRepository) GetByID(ctx context.Context, id int) (*models.Locat
 }
 
 func (r *LocationRepository) Create(ctx context.Context, location models.Location) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-		INSERT INTO locations (address, type)
-		VALUES ($1, $2)
+		INSERT INTO locations (address, type, organization_id)
+		VALUES ($1, $2, $3)
 		RETURNING id
 	`
 
 	var id int
-	err := r.db.QueryRowContext(
+	err = conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		location.Address,
 		location.Type,
+		organizationID,
 	).Scan(&id)
 
 	if err != nil {
@@ -82,30 +103,44 @@ func (r *LocationRepository) Create(ctx context.Context, location models.Locatio
 	return id, nil
 }
 
-func (r *LocationRepository) Update(ctx context.Context, location models.Location) error {
+// Update сохраняет местоположение, если его версия совпадает с
+// location.Version, и возвращает новую версию. Иначе возвращает ErrVersionConflict
+func (r *LocationRepository) Update(ctx context.Context, location models.Location) (int, error) {
 	query := `
 		UPDATE locations
-		SET address = $1, type = $2
-		WHERE id = $3
+		SET address = $1, type = $2, version = version + 1
+		WHERE id = $3 AND version = $4 AND deleted_at IS NULL AND ($5 = 0 OR organization_id = $5)
+		RETURNING version
 	`
 
-	_, err := r.db.ExecContext(
+	return scanVersion(conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		location.Address,
 		location.Type,
 		location.ID,
-	)
+		location.Version,
+		tenantScope(ctx),
+	))
+}
 
-	return err
+var locationPatchColumns = map[string]bool{
+	"address": true,
+	"type":    true,
+}
+
+// Patch обновляет только столбцы из changes и возвращает новую версию
+// местоположения. При устаревшей версии возвращает ErrVersionConflict
+func (r *LocationRepository) Patch(ctx context.Context, id, version int, changes map[string]interface{}) (int, error) {
+	return patchRow(ctx, conn(ctx, r.db), "locations", locationPatchColumns, id, version, changes)
 }
 
-func (r *LocationRepository) Delete(ctx context.Context, id int) error {
+func (r *LocationRepository) Delete(ctx context.Context, id int, deletedBy int) error {
 	// Проверяем, есть ли связанные активы
 	var hasAssets bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM assets WHERE current_location_id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM assets WHERE current_location_id = $1 AND deleted_at IS NULL)",
 		id,
 	).Scan(&hasAssets)
 
@@ -114,38 +149,68 @@ func (r *LocationRepository) Delete(ctx context.Context, id int) error {
 	}
 
 	if hasAssets {
-		return errors.New("cannot delete location with assigned assets")
+		return apperror.Conflict("cannot delete location with assigned assets")
 	}
 
-	// Проверяем, есть ли связанные перемещения
-	var hasTransfers bool
-	err = r.db.QueryRowContext(
+	// История перемещений сохраняется: местоположение только помечается удаленным
+	_, err = conn(ctx, r.db).ExecContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM asset_transfers WHERE from_location_id = $1 OR to_location_id = $1)",
+		`UPDATE locations SET deleted_at = NOW(), deleted_by = $2, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR organization_id = $3)`,
 		id,
-	).Scan(&hasTransfers)
+		deletedBy,
+		tenantScope(ctx),
+	)
+	return err
+}
 
+// Restore снимает пометку об удалении. Возвращает false, если удаленного
+// местоположения с таким id нет
+func (r *LocationRepository) Restore(ctx context.Context, id int) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE locations SET deleted_at = NULL, deleted_by = NULL, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR organization_id = $2)`,
+		id,
+		tenantScope(ctx),
+	)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	if hasTransfers {
-		return errors.New("cannot delete location with transfer history")
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// PurgeDeleted окончательно удаляет местоположения, помеченные удаленными
+// раньше before. Местоположения, на которые ссылаются активы или история
+// перемещений, сохраняются. Очистка выполняется по всем организациям
+func (r *LocationRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`DELETE FROM locations l
+		 WHERE l.deleted_at < $1
+		   AND NOT EXISTS(SELECT 1 FROM assets a WHERE a.current_location_id = l.id)
+		   AND NOT EXISTS(SELECT 1 FROM asset_transfers t
+		                  WHERE t.from_location_id = l.id OR t.to_location_id = l.id)`,
+		before,
+	)
+	if err != nil {
+		return 0, err
 	}
 
-	_, err = r.db.ExecContext(ctx, "DELETE FROM locations WHERE id = $1", id)
-	return err
+	return result.RowsAffected()
 }
 
 func (r *LocationRepository) GetByType(ctx context.Context, locationType string) ([]models.Location, error) {
 	query := `
 		SELECT id, address, type
 		FROM locations
-		WHERE type = $1
+		WHERE type = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2)
 		ORDER BY address
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, locationType)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, locationType, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -166,10 +231,11 @@ func (r *LocationRepository) GetByType(ctx context.Context, locationType string)
 
 func (r *LocationRepository) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM locations WHERE id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM locations WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR organization_id = $2))",
 		id,
+		tenantScope(ctx),
 	).Scan(&exists)
 
 	return exists, err
@@ -177,22 +243,11 @@ func (r *LocationRepository) Exists(ctx context.Context, id int) (bool, error) {
 
 func (r *LocationRepository) HasAssets(ctx context.Context, id int) (bool, error) {
 	var hasAssets bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		"SELECT EXISTS(SELECT 1 FROM assets WHERE current_location_id = $1)",
+		"SELECT EXISTS(SELECT 1 FROM assets WHERE current_location_id = $1 AND deleted_at IS NULL)",
 		id,
 	).Scan(&hasAssets)
 
 	return hasAssets, err
 }
-
-func (r *LocationRepository) HasTransfers(ctx context.Context, id int) (bool, error) {
-	var hasTransfers bool
-	err := r.db.QueryRowContext(
-		ctx,
-		"SELECT EXISTS(SELECT 1 FROM asset_transfers WHERE from_location_id = $1 OR to_location_id = $1)",
-		id,
-	).Scan(&hasTransfers)
-
-	return hasTransfers, err
-}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"inventory-system/internal/models"
+)
+
+// LoginAuditRepository ведет журнал неудачных попыток входа
+type LoginAuditRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAuditRepository(db *sql.DB) *LoginAuditRepository {
+	return &LoginAuditRepository{db: db}
+}
+
+// RecordFailure добавляет запись о неудачной попытке входа
+func (r *LoginAuditRepository) RecordFailure(ctx context.Context, f models.LoginFailure) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO login_failures (email, employee_id, organization_id, ip, user_agent, reason)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		f.Email,
+		f.EmployeeID,
+		f.OrganizationID,
+		f.IP,
+		f.UserAgent,
+		f.Reason,
+	)
+	return err
+}
+
+// GetFailures возвращает записи журнала, начиная с последних. Пустой
+// filter.Email означает все адреса. Попытки входа с адресами, которые не
+// принадлежат ни одному сотруднику, видны только в контексте всех
+// организаций
+func (r *LoginAuditRepository) GetFailures(ctx context.Context, filter models.LoginFailureFilter) ([]models.LoginFailure, error) {
+	query := `
+		SELECT id, email, employee_id, organization_id, ip, user_agent, reason, created_at
+		FROM login_failures
+		WHERE ($1 = '' OR email = $1) AND ($4 = 0 OR organization_id = $4)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, filter.Email, filter.Limit, filter.Offset, tenantScope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failures := []models.LoginFailure{}
+	for rows.Next() {
+		var f models.LoginFailure
+		var employeeID, organizationID sql.NullInt64
+
+		err := rows.Scan(&f.ID, &f.Email, &employeeID, &organizationID, &f.IP, &f.UserAgent, &f.Reason, &f.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if employeeID.Valid {
+			id := int(employeeID.Int64)
+			f.EmployeeID = &id
+		}
+		if organizationID.Valid {
+			id := int(organizationID.Int64)
+			f.OrganizationID = &id
+		}
+		failures = append(failures, f)
+	}
+
+	return failures, rows.Err()
+}
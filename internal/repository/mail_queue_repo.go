@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"inventory-system/internal/models"
+	"time"
+)
+
+type MailQueueRepository struct {
+	db *sql.DB
+}
+
+func NewMailQueueRepository(db *sql.DB) *MailQueueRepository {
+	return &MailQueueRepository{db: db}
+}
+
+// Enqueue ставит письмо в очередь отправки. В транзакции письмо
+// становится видно диспетчеру только после ее фиксации
+func (r *MailQueueRepository) Enqueue(ctx context.Context, m models.MailMessage) (int64, error) {
+	var id int64
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"INSERT INTO mail_queue (recipient, subject, text_body, html_body) VALUES ($1, $2, $3, $4) RETURNING id",
+		m.Recipient,
+		m.Subject,
+		m.TextBody,
+		m.HTMLBody,
+	).Scan(&id)
+	return id, err
+}
+
+// ClaimDue выбирает до limit писем, срок отправки которых наступил к now,
+// и откладывает их до leaseUntil, чтобы другой экземпляр диспетчера не
+// отправил их одновременно
+func (r *MailQueueRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.MailMessage, error) {
+	query := `
+		UPDATE mail_queue
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM mail_queue
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, text_body, html_body, status, attempts,
+		          next_attempt_at, last_error, created_at, sent_at
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.MailMessage
+	for rows.Next() {
+		var m models.MailMessage
+		err := rows.Scan(
+			&m.ID,
+			&m.Recipient,
+			&m.Subject,
+			&m.TextBody,
+			&m.HTMLBody,
+			&m.Status,
+			&m.Attempts,
+			&m.NextAttemptAt,
+			&m.LastError,
+			&m.CreatedAt,
+			&m.SentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// SaveAttempt записывает результат попытки отправки. Текст отправленного
+// или окончательно не отправленного письма стирается: в письмах бывают
+// одноразовые ссылки со сбросом пароля и приглашениями
+func (r *MailQueueRepository) SaveAttempt(ctx context.Context, m models.MailMessage) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE mail_queue
+		 SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, sent_at = $5,
+		     text_body = CASE WHEN $1 = 'pending' THEN text_body ELSE '' END,
+		     html_body = CASE WHEN $1 = 'pending' THEN html_body ELSE '' END
+		 WHERE id = $6`,
+		m.Status,
+		m.Attempts,
+		m.NextAttemptAt,
+		m.LastError,
+		m.SentAt,
+		m.ID,
+	)
+	return err
+}
+
+// PurgeSent удаляет письма, отправленные раньше before
+func (r *MailQueueRepository) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"DELETE FROM mail_queue WHERE status = 'sent' AND sent_at < $1",
+		before,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"inventory-system/internal/models"
+	"time"
+)
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// GetPreferences возвращает настройки уведомлений сотрудника или nil,
+// если он их не менял
+func (r *NotificationRepository) GetPreferences(ctx context.Context, employeeID int) (*models.NotificationPreferences, error) {
+	query := `
+		SELECT employee_id, language, transfers, reservations, warranty
+		FROM notification_preferences
+		WHERE employee_id = $1
+	`
+
+	var p models.NotificationPreferences
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, employeeID).Scan(
+		&p.EmployeeID,
+		&p.Language,
+		&p.Transfers,
+		&p.Reservations,
+		&p.Warranty,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SavePreferences создает или заменяет настройки уведомлений сотрудника
+func (r *NotificationRepository) SavePreferences(ctx context.Context, p models.NotificationPreferences) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO notification_preferences (employee_id, language, transfers, reservations, warranty)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (employee_id) DO UPDATE
+		 SET language = EXCLUDED.language, transfers = EXCLUDED.transfers,
+		     reservations = EXCLUDED.reservations, warranty = EXCLUDED.warranty,
+		     updated_at = NOW()`,
+		p.EmployeeID,
+		p.Language,
+		p.Transfers,
+		p.Reservations,
+		p.Warranty,
+	)
+	return err
+}
+
+// Create добавляет уведомление во входящие сотрудника
+func (r *NotificationRepository) Create(ctx context.Context, n models.Notification) (int64, error) {
+	var id int64
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`INSERT INTO notifications (employee_id, kind, title, link, entity, entity_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id`,
+		n.EmployeeID,
+		n.Kind,
+		n.Title,
+		n.Link,
+		n.Entity,
+		n.EntityID,
+	).Scan(&id)
+	return id, err
+}
+
+// GetPage возвращает страницу входящих сотрудника, начиная с новых
+func (r *NotificationRepository) GetPage(ctx context.Context, employeeID int, filter models.NotificationFilter) ([]models.Notification, error) {
+	query := `
+		SELECT id, employee_id, kind, title, link, entity, entity_id, read_at, created_at
+		FROM notifications
+		WHERE employee_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, employeeID, filter.UnreadOnly, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var entityID sql.NullInt64
+
+		err := rows.Scan(
+			&n.ID,
+			&n.EmployeeID,
+			&n.Kind,
+			&n.Title,
+			&n.Link,
+			&n.Entity,
+			&entityID,
+			&n.ReadAt,
+			&n.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if entityID.Valid {
+			id := int(entityID.Int64)
+			n.EntityID = &id
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// Count возвращает общее число уведомлений сотрудника и число непрочитанных
+func (r *NotificationRepository) Count(ctx context.Context, employeeID int) (total, unread int, err error) {
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE read_at IS NULL) FROM notifications WHERE employee_id = $1",
+		employeeID,
+	).Scan(&total, &unread)
+	return total, unread, err
+}
+
+// MarkRead отмечает уведомление сотрудника прочитанным. Возвращает false,
+// если у сотрудника нет такого уведомления
+func (r *NotificationRepository) MarkRead(ctx context.Context, employeeID int, id int64, at time.Time) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE notifications SET read_at = COALESCE(read_at, $1) WHERE id = $2 AND employee_id = $3",
+		at,
+		id,
+		employeeID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// MarkAllRead отмечает прочитанными все уведомления сотрудника и
+// возвращает их число
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, employeeID int, at time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE notifications SET read_at = $1 WHERE employee_id = $2 AND read_at IS NULL",
+		at,
+		employeeID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// MarkEntityRead отмечает прочитанными у всех сотрудников уведомления вида
+// kind о записи entity/entityID, например запросы на подтверждение уже
+// рассмотренной брони
+func (r *NotificationRepository) MarkEntityRead(ctx context.Context, kind, entity string, entityID int, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE notifications SET read_at = $1
+		 WHERE kind = $2 AND entity = $3 AND entity_id = $4 AND read_at IS NULL`,
+		at,
+		kind,
+		entity,
+		entityID,
+	)
+	return err
+}
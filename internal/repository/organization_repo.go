@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrOrganizationCodeTaken возвращается, когда код уже принадлежит другой
+// организации
+var ErrOrganizationCodeTaken = apperror.Conflict("organization code already taken")
+
+// OrganizationRepository хранит справочник организаций группы. Сам
+// справочник общий для всех организаций
+type OrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewOrganizationRepository(db *sql.DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+const organizationColumns = "id, code, name, email_domains, created_at"
+
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	var o models.Organization
+	err := row.Scan(&o.ID, &o.Code, &o.Name, pq.Array(&o.EmailDomains), &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if o.EmailDomains == nil {
+		o.EmailDomains = []string{}
+	}
+	return &o, nil
+}
+
+func (r *OrganizationRepository) GetAll(ctx context.Context) ([]models.Organization, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, "SELECT "+organizationColumns+" FROM organizations ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizations := []models.Organization{}
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, *o)
+	}
+
+	return organizations, rows.Err()
+}
+
+func (r *OrganizationRepository) GetByID(ctx context.Context, id int) (*models.Organization, error) {
+	row := conn(ctx, r.db).QueryRowContext(ctx, "SELECT "+organizationColumns+" FROM organizations WHERE id = $1", id)
+	o, err := scanOrganization(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return o, err
+}
+
+// GetByCode возвращает организацию с кодом code или nil
+func (r *OrganizationRepository) GetByCode(ctx context.Context, code string) (*models.Organization, error) {
+	row := conn(ctx, r.db).QueryRowContext(ctx, "SELECT "+organizationColumns+" FROM organizations WHERE code = $1", code)
+	o, err := scanOrganization(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return o, err
+}
+
+// FindByDomain возвращает id организации, которой принадлежит почтовый
+// домен domain, или 0
+func (r *OrganizationRepository) FindByDomain(ctx context.Context, domain string) (int, error) {
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT id FROM organizations WHERE $1 = ANY(email_domains) ORDER BY id LIMIT 1",
+		domain,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// DomainOwner возвращает id организации, кроме excludeID, которой уже
+// принадлежит один из доменов domains, и сам домен. Если таких нет,
+// возвращает 0
+func (r *OrganizationRepository) DomainOwner(ctx context.Context, domains []string, excludeID int) (int, string, error) {
+	var id int
+	var domain string
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT o.id, d.domain
+		 FROM organizations o, unnest(o.email_domains) AS d(domain)
+		 WHERE d.domain = ANY($1) AND o.id <> $2
+		 ORDER BY o.id LIMIT 1`,
+		pq.Array(domains),
+		excludeID,
+	).Scan(&id, &domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", nil
+	}
+	return id, domain, err
+}
+
+func (r *OrganizationRepository) Create(ctx context.Context, o models.Organization) (int, error) {
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"INSERT INTO organizations (code, name, email_domains) VALUES ($1, $2, $3) RETURNING id",
+		o.Code,
+		o.Name,
+		pq.Array(o.EmailDomains),
+	).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, ErrOrganizationCodeTaken
+	}
+	return id, err
+}
+
+// Update сохраняет организацию. Возвращает false, если ее нет
+func (r *OrganizationRepository) Update(ctx context.Context, o models.Organization) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE organizations SET code = $1, name = $2, email_domains = $3 WHERE id = $4",
+		o.Code,
+		o.Name,
+		pq.Array(o.EmailDomains),
+		o.ID,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return false, ErrOrganizationCodeTaken
+		}
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"inventory-system/internal/models"
+	"time"
+)
+
+// OutboxRepository хранит очередь событий для внешних систем. События
+// добавляются в той же транзакции, что и изменение данных
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Add записывает событие с данными payload в организации из контекста.
+// Вызывается с контекстом Transactor.WithinTx, чтобы событие сохранялось
+// вместе с изменением
+func (r *OutboxRepository) Add(ctx context.Context, eventType string, payload interface{}) (int64, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"INSERT INTO outbox_events (event_type, payload, organization_id) VALUES ($1, $2, $3) RETURNING id",
+		eventType,
+		data,
+		organizationID,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Pending возвращает до limit еще не разосланных событий всех организаций
+// и блокирует их до конца транзакции, чтобы другой экземпляр диспетчера их
+// пропустил
+func (r *OutboxRepository) Pending(ctx context.Context, limit int) ([]models.Event, error) {
+	query := `
+		SELECT id, event_type, payload, created_at, organization_id
+		FROM outbox_events
+		WHERE dispatched_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.OccurredAt, &e.OrganizationID); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *OutboxRepository) MarkDispatched(ctx context.Context, id int64, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE outbox_events SET dispatched_at = $1 WHERE id = $2", at, id)
+	return err
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PasswordResetRepository хранит одноразовые токены сброса пароля. Сам
+// токен не сохраняется, только его хеш
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create сохраняет хеш нового токена сброса пароля сотрудника
+func (r *PasswordResetRepository) Create(ctx context.Context, employeeID int, tokenHash string, expiresAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"INSERT INTO password_reset_tokens (employee_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		employeeID,
+		tokenHash,
+		expiresAt,
+	)
+	return err
+}
+
+// Consume отмечает использованным действующий к now токен с хешем
+// tokenHash и возвращает id его сотрудника. Возвращает 0, если такого
+// токена нет, он истек или уже использован
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (int, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING employee_id
+	`
+
+	var employeeID int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash, now).Scan(&employeeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return employeeID, err
+}
+
+// RevokeForEmployee отменяет все неиспользованные токены сотрудника
+func (r *PasswordResetRepository) RevokeForEmployee(ctx context.Context, employeeID int, now time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE password_reset_tokens SET used_at = $2 WHERE employee_id = $1 AND used_at IS NULL",
+		employeeID,
+		now,
+	)
+	return err
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// patchRow обновляет только переданные столбцы записи, если ее версия равна
+// version, и возвращает новую версию. Допустимые столбцы задает вызывающий
+// репозиторий, поэтому имена из changes не попадают в запрос непроверенными.
+// Записи других организаций не обновляются
+func patchRow(
+	ctx context.Context,
+	q querier,
+	table string,
+	allowed map[string]bool,
+	id, version int,
+	changes map[string]interface{},
+) (int, error) {
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		if !allowed[column] {
+			return 0, fmt.Errorf("column %q of %s cannot be patched", column, table)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	set := make([]string, 0, len(columns)+1)
+	args := make([]interface{}, 0, len(columns)+2)
+	for i, column := range columns {
+		set = append(set, fmt.Sprintf("%s = $%d", column, i+1))
+		args = append(args, changes[column])
+	}
+	set = append(set, "version = version + 1")
+	args = append(args, id, version, tenantScope(ctx))
+
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE id = $%d AND version = $%d AND deleted_at IS NULL"+
+			" AND ($%d = 0 OR organization_id = $%d) RETURNING version",
+		table,
+		strings.Join(set, ", "),
+		len(columns)+1,
+		len(columns)+2,
+		len(columns)+3,
+		len(columns)+3,
+	)
+
+	return scanVersion(q.QueryRowContext(ctx, query, args...))
+}
@@ -0,0 +1,109 @@
+// Package repotest предоставляет базу данных для тестов, которые
+// проверяют сервисы и маршруты вместе с настоящими репозиториями без
+// PostgreSQL: каждый запрос передается обработчику теста
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+// Result — ответ базы на запрос: строки для SELECT и RETURNING или число
+// измененных строк для команды
+type Result struct {
+	Columns  []string
+	Rows     [][]driver.Value
+	Affected int64
+}
+
+// Handler отвечает на запрос query с аргументами args
+type Handler func(query string, args []driver.Value) (Result, error)
+
+// Open возвращает *sql.DB, который передает каждый запрос handle.
+// Транзакции ничего не откатывают: обработчик видит команды в том
+// порядке, в котором их выполнил репозиторий
+func Open(t testing.TB, handle Handler) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(connector{handle: handle})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type connector struct {
+	handle Handler
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{handle: c.handle}, nil
+}
+
+func (c connector) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("repotest: use Open")
+}
+
+type conn struct {
+	handle Handler
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("repotest: prepared statements are not supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.handle(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(result.Affected), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.handle(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: result.Columns, rows: result.Rows}, nil
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vs := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vs[i] = arg.Value
+	}
+	return vs
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
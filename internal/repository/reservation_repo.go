@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ErrReservationOverlap возвращается, когда ограничение базы данных
+// отклонило пересекающееся бронирование
+var ErrReservationOverlap = apperror.Conflict("reservation overlaps with an existing one")
+
+type ReservationRepository struct {
+	db *sql.DB
+}
+
+func NewReservationRepository(db *sql.DB) *ReservationRepository {
+	return &ReservationRepository{db: db}
+}
+
+const reservationSelect = `
+	SELECT r.id, r.asset_id, a.name as asset_name,
+	       r.employee_id, e.full_name as employee_name,
+	       lower(r.period), upper(r.period), r.purpose, r.status,
+	       r.reviewed_by, r.created_at
+	FROM reservations r
+	JOIN assets a ON r.asset_id = a.id
+	JOIN employees e ON r.employee_id = e.id
+`
+
+func scanReservation(row rowScanner) (models.Reservation, error) {
+	var res models.Reservation
+	var reviewedBy sql.NullInt64
+
+	err := row.Scan(
+		&res.ID,
+		&res.AssetID,
+		&res.AssetName,
+		&res.EmployeeID,
+		&res.EmployeeName,
+		&res.StartsAt,
+		&res.EndsAt,
+		&res.Purpose,
+		&res.Status,
+		&reviewedBy,
+		&res.CreatedAt,
+	)
+	if err != nil {
+		return res, err
+	}
+
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		res.ReviewedBy = &id
+	}
+
+	return res, nil
+}
+
+func scanReservations(rows *queryRows) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, res)
+	}
+
+	return reservations, rows.Err()
+}
+
+func (r *ReservationRepository) GetAll(ctx context.Context, filter models.ReservationFilter) ([]models.Reservation, error) {
+	conditions := []string{"($1 = 0 OR r.organization_id = $1)"}
+	args := []interface{}{tenantScope(ctx)}
+
+	if filter.AssetID != nil {
+		args = append(args, *filter.AssetID)
+		conditions = append(conditions, fmt.Sprintf("r.asset_id = $%d", len(args)))
+	}
+	if filter.EmployeeID != nil {
+		args = append(args, *filter.EmployeeID)
+		conditions = append(conditions, fmt.Sprintf("r.employee_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("r.status = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("upper(r.period) > $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("lower(r.period) < $%d", len(args)))
+	}
+
+	query := reservationSelect + " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY lower(r.period)"
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanReservations(rows)
+}
+
+func (r *ReservationRepository) GetByID(ctx context.Context, id int) (*models.Reservation, error) {
+	query := reservationSelect + " WHERE r.id = $1 AND ($2 = 0 OR r.organization_id = $2)"
+
+	res, err := scanReservation(conn(ctx, r.db).QueryRowContext(ctx, query, id, tenantScope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (r *ReservationRepository) Create(ctx context.Context, res models.Reservation) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO reservations (asset_id, employee_id, period, purpose, status, organization_id)
+		VALUES ($1, $2, tstzrange($3, $4, '[)'), $5, $6, $7)
+		RETURNING id
+	`
+
+	var id int
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		res.AssetID,
+		res.EmployeeID,
+		res.StartsAt,
+		res.EndsAt,
+		res.Purpose,
+		res.Status,
+		organizationID,
+	).Scan(&id)
+
+	if err != nil {
+		return 0, translateOverlap(err)
+	}
+
+	return id, nil
+}
+
+func (r *ReservationRepository) UpdateStatus(ctx context.Context, id int, status string, reviewedBy *int) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE reservations SET status = $1, reviewed_by = COALESCE($2, reviewed_by)
+		 WHERE id = $3 AND ($4 = 0 OR organization_id = $4)`,
+		status,
+		reviewedBy,
+		id,
+		tenantScope(ctx),
+	)
+	return translateOverlap(err)
+}
+
+// HasOverlap проверяет наличие активной брони актива, пересекающейся с интервалом
+func (r *ReservationRepository) HasOverlap(ctx context.Context, res models.Reservation) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM reservations
+		 WHERE asset_id = $1 AND id <> $2 AND status IN ('pending', 'approved')
+		   AND period && tstzrange($3, $4, '[)'))`,
+		res.AssetID,
+		res.ID,
+		res.StartsAt,
+		res.EndsAt,
+	).Scan(&exists)
+
+	return exists, err
+}
+
+// GetAvailableAssets возвращает активы категории (включая подкатегории) и
+// местоположения, у которых нет активных броней на заданный интервал
+func (r *ReservationRepository) GetAvailableAssets(ctx context.Context, q models.AvailabilityQuery) ([]models.Asset, error) {
+	args := []interface{}{q.From, q.To, tenantScope(ctx)}
+	conditions := []string{"a.deleted_at IS NULL", "($3 = 0 OR a.organization_id = $3)", `NOT EXISTS(SELECT 1 FROM reservations r
+		WHERE r.asset_id = a.id AND r.status IN ('pending', 'approved')
+		  AND r.period && tstzrange($1, $2, '[)'))`}
+
+	prefix := ""
+	if q.CategoryID != nil {
+		args = append(args, *q.CategoryID)
+		prefix = fmt.Sprintf(`
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $%d
+				UNION
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+			)`, len(args))
+		conditions = append(conditions, "a.category_id IN (SELECT id FROM subtree)")
+	}
+	if q.LocationID != nil {
+		args = append(args, *q.LocationID)
+		conditions = append(conditions, fmt.Sprintf("a.current_location_id = $%d", len(args)))
+	}
+
+	query := prefix + assetSelect + " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY a.name"
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAssets(rows)
+}
+
+// translateOverlap превращает нарушение ограничения excl_reservations_overlap
+// в ErrReservationOverlap
+func translateOverlap(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23P01" {
+		return ErrReservationOverlap
+	}
+	return err
+}
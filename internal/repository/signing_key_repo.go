@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"inventory-system/internal/signing"
+	"time"
+)
+
+// signingKeysLock — ключ рекомендательной блокировки PostgreSQL, под
+// которой экземпляры приложения по очереди проверяют ротацию ключей
+const signingKeysLock = 0x6a776b73
+
+// SigningKeyRepository хранит ключи подписи токенов доступа, общие для
+// всех экземпляров приложения
+type SigningKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSigningKeyRepository(db *sql.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// Lock берет блокировку ротации до конца текущей транзакции
+func (r *SigningKeyRepository) Lock(ctx context.Context) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", signingKeysLock)
+	return err
+}
+
+// GetAll возвращает все сохраненные ключи
+func (r *SigningKeyRepository) GetAll(ctx context.Context) ([]*signing.Key, error) {
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		`SELECT kid, algorithm, private_key, activates_at, retires_at, expires_at, created_at
+		 FROM signing_keys
+		 ORDER BY activates_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*signing.Key
+	for rows.Next() {
+		var key signing.Key
+		var der []byte
+		var retiresAt, expiresAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Algorithm, &der, &key.ActivatesAt, &retiresAt, &expiresAt, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		key.Private, err = signing.ParsePrivateKey(key.Algorithm, der)
+		if err != nil {
+			return nil, err
+		}
+		key.RetiresAt = retiresAt.Time
+		key.ExpiresAt = expiresAt.Time
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+// Create сохраняет новый ключ
+func (r *SigningKeyRepository) Create(ctx context.Context, key *signing.Key) error {
+	der, err := signing.MarshalPrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO signing_keys (kid, algorithm, private_key, activates_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		key.ID,
+		key.Algorithm,
+		der,
+		key.ActivatesAt,
+		key.CreatedAt,
+	)
+	return err
+}
+
+// Retire назначает ключу kid конец подписи и публикации
+func (r *SigningKeyRepository) Retire(ctx context.Context, kid string, retiresAt, expiresAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE signing_keys SET retires_at = $2, expires_at = $3 WHERE kid = $1",
+		kid,
+		retiresAt,
+		expiresAt,
+	)
+	return err
+}
+
+// DeleteExpired удаляет ключи, снятые с публикации до before, и
+// возвращает их число
+func (r *SigningKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM signing_keys WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
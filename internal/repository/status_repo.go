@@ -22,7 +22,7 @@ func (r *StatusRepository) GetAll(ctx context.Context) ([]models.AssetStatus, er
 		ORDER BY id
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +49,7 @@ func (r *StatusRepository) GetByID(ctx context.Context, id int) (*models.AssetSt
 	`
 
 	var s models.AssetStatus
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&s.ID, &s.Name)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&s.ID, &s.Name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -68,7 +68,7 @@ func (r *StatusRepository) GetByName(ctx context.Context, name string) (*models.
 	`
 
 	var s models.AssetStatus
-	err := r.db.QueryRowContext(ctx, query, name).Scan(&s.ID, &s.Name)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, name).Scan(&s.ID, &s.Name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -81,7 +81,7 @@ func (r *StatusRepository) GetByName(ctx context.Context, name string) (*models.
 
 func (r *StatusRepository) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
 		"SELECT EXISTS(SELECT 1 FROM asset_statuses WHERE id = $1)",
 		id,
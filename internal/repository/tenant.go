@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"inventory-system/internal/tenant"
+	"strconv"
+	"sync/atomic"
+)
+
+// ErrNoOrganization возвращается при создании записи в контексте без
+// конкретной организации
+var ErrNoOrganization = errors.New("repository: organization is not set in context")
+
+// noOrganization — значение условия организации, если она не задана в
+// контексте: идентификаторы положительны, поэтому ни одна запись не
+// подходит, и забытая область дает пустой результат, а не чужие данные
+const noOrganization = -1
+
+// tenantScope возвращает значение параметра для условия
+// "($n = 0 OR organization_id = $n)": организацию из контекста, 0 для
+// контекста всех организаций и noOrganization, если организация не задана
+func tenantScope(ctx context.Context) int {
+	if id, ok := tenant.OrganizationID(ctx); ok {
+		return id
+	}
+	if tenant.AllOrganizations(ctx) {
+		return 0
+	}
+	return noOrganization
+}
+
+// ownerOrganization возвращает организацию, которой принадлежит новая
+// запись
+func ownerOrganization(ctx context.Context) (int, error) {
+	id, ok := tenant.OrganizationID(ctx)
+	if !ok {
+		return 0, ErrNoOrganization
+	}
+	return id, nil
+}
+
+// rowLevelSecurity включает передачу организации запроса в PostgreSQL для
+// политик row-level security (migrations/019_row_level_security.sql)
+var rowLevelSecurity atomic.Bool
+
+// EnableRowLevelSecurity включает режим row-level security: каждый запрос
+// выполняется в транзакции с параметром app.organization_id, по которому
+// политики базы данных дополнительно отсекают строки чужих организаций.
+// Вызывается до начала обработки запросов
+func EnableRowLevelSecurity() {
+	rowLevelSecurity.Store(true)
+}
+
+// RowLevelSecurityEnforced сообщает, действуют ли политики row-level
+// security на роль, от имени которой подключено приложение. Суперпользователь
+// и роль с BYPASSRLS политики не видят. Без режима EnableRowLevelSecurity
+// такой роли не видна ни одна строка
+func RowLevelSecurityEnforced(ctx context.Context, db *sql.DB) (bool, error) {
+	var enforced bool
+	err := db.QueryRowContext(
+		ctx,
+		`SELECT c.relrowsecurity AND NOT (r.rolsuper OR r.rolbypassrls)
+		 FROM pg_class c, pg_roles r
+		 WHERE c.oid = to_regclass('employees') AND r.rolname = current_user`,
+	).Scan(&enforced)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return enforced, err
+}
+
+// setTenant передает организацию из контекста в параметр
+// app.organization_id до конца транзакции tx
+func setTenant(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"SELECT set_config('app.organization_id', $1, true)",
+		strconv.Itoa(tenantScope(ctx)),
+	)
+	return err
+}
+
+// tenantQuerier выполняет каждый запрос вне Transactor.WithinTx в
+// отдельной транзакции с параметром app.organization_id. Транзакция
+// завершается после выполнения команды, чтения строки или закрытия
+// результата запроса
+type tenantQuerier struct {
+	db *sql.DB
+}
+
+func (q tenantQuerier) begin(ctx context.Context) (*sql.Tx, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := setTenant(ctx, tx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (q tenantQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	tx, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, translateError(err)
+	}
+	return result, tx.Commit()
+}
+
+func (q tenantQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*queryRows, error) {
+	tx, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, translateError(err)
+	}
+	return &queryRows{Rows: rows, done: tx.Commit}, nil
+}
+
+func (q tenantQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) rowScanner {
+	tx, err := q.begin(ctx)
+	if err != nil {
+		return errorRow{err}
+	}
+	return tenantRow{row: tx.QueryRowContext(ctx, query, args...), tx: tx}
+}
+
+// tenantRow завершает транзакцию запроса после чтения строки
+type tenantRow struct {
+	row *sql.Row
+	tx  *sql.Tx
+}
+
+func (r tenantRow) Scan(dest ...interface{}) error {
+	if err := r.row.Scan(dest...); err != nil {
+		r.tx.Rollback()
+		return translateError(err)
+	}
+	return r.tx.Commit()
+}
+
+// errorRow — строка запроса, который не удалось начать
+type errorRow struct {
+	err error
+}
+
+func (r errorRow) Scan(dest ...interface{}) error {
+	return r.err
+}
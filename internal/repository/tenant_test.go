@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"inventory-system/internal/repository/repotest"
+	"inventory-system/internal/tenant"
+)
+
+// rlsDatabase — база данных в памяти, которая записывает начало и конец
+// транзакций, установку app.organization_id и запросы вместе с
+// организацией, действующей в момент запроса. Как и в PostgreSQL,
+// set_config(..., true) действует до конца транзакции. Таблица locations
+// отфильтрована так, как ее отфильтровала бы политика tenant_isolation
+type rlsDatabase struct {
+	mu     sync.Mutex
+	events []string
+
+	// failSetConfig и failQuery задают ошибки базы данных
+	failSetConfig bool
+	failQuery     bool
+}
+
+// rlsLocation — строка locations: id, address, type, version, organization_id
+type rlsLocation struct {
+	id, organizationID int64
+	address            string
+}
+
+var rlsLocations = []rlsLocation{{1, 7, "Офис A"}, {2, 8, "Офис B"}}
+
+func newRLSDatabase(t *testing.T, enabled bool) (*rlsDatabase, *sql.DB) {
+	t.Helper()
+	previous := rowLevelSecurity.Load()
+	rowLevelSecurity.Store(enabled)
+	t.Cleanup(func() { rowLevelSecurity.Store(previous) })
+
+	d := &rlsDatabase{}
+	db := sql.OpenDB(d)
+	// One connection keeps the order of events deterministic
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return d, db
+}
+
+func (d *rlsDatabase) Connect(context.Context) (driver.Conn, error) { return &rlsConn{d: d}, nil }
+func (d *rlsDatabase) Driver() driver.Driver                        { return rlsDriver{d} }
+
+type rlsDriver struct{ d *rlsDatabase }
+
+func (r rlsDriver) Open(string) (driver.Conn, error) { return &rlsConn{d: r.d}, nil }
+
+func (d *rlsDatabase) record(event string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+}
+
+// take возвращает записанные события и очищает журнал
+func (d *rlsDatabase) take() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	events := d.events
+	d.events = nil
+	return events
+}
+
+type rlsConn struct {
+	d    *rlsDatabase
+	inTx bool
+	// organization — значение app.organization_id в текущей транзакции
+	organization string
+}
+
+func (c *rlsConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+func (c *rlsConn) Close() error { return nil }
+func (c *rlsConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *rlsConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.inTx = true
+	c.d.record("begin")
+	return rlsTx{c}, nil
+}
+
+type rlsTx struct{ c *rlsConn }
+
+func (tx rlsTx) Commit() error   { return tx.end("commit") }
+func (tx rlsTx) Rollback() error { return tx.end("rollback") }
+
+func (tx rlsTx) end(event string) error {
+	tx.c.inTx, tx.c.organization = false, ""
+	tx.c.d.record(event)
+	return nil
+}
+
+// statement записывает запрос вместе с организацией, которую увидели бы
+// политики row-level security
+func (c *rlsConn) statement(kind, query string) error {
+	scope := "outside tx"
+	if c.inTx {
+		scope = "org=" + c.organization
+	}
+	c.d.record(kind + " " + strings.Join(strings.Fields(query), " ") + " [" + scope + "]")
+	if c.d.failQuery {
+		return errors.New("query failed")
+	}
+	return nil
+}
+
+func (c *rlsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.HasPrefix(query, "SELECT set_config('app.organization_id', $1, true)") {
+		value := args[0].Value.(string)
+		c.d.record("set " + value)
+		if c.d.failSetConfig {
+			return nil, errors.New("set_config failed")
+		}
+		if c.inTx {
+			c.organization = value
+		}
+		return driver.RowsAffected(0), nil
+	}
+	if err := c.statement("exec", query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *rlsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.statement("query", query); err != nil {
+		return nil, err
+	}
+	rows := &rlsRows{columns: []string{"id", "address", "type", "version"}}
+	if !strings.Contains(query, "FROM locations") {
+		return rows, nil
+	}
+	// The policy: current_organization_id() IN (0, organization_id)
+	for _, l := range rlsLocations {
+		if strings.Contains(query, "WHERE id = $1") && args[0].Value != l.id {
+			continue
+		}
+		if c.organization == "0" || c.organization == strconv.FormatInt(l.organizationID, 10) {
+			rows.values = append(rows.values, []driver.Value{l.id, l.address, "Офис", int64(1)})
+		}
+	}
+	return rows, nil
+}
+
+type rlsRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rlsRows) Columns() []string { return r.columns }
+func (r *rlsRows) Close() error      { return nil }
+
+func (r *rlsRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func checkEvents(t *testing.T, step string, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s:\n got %q\nwant %q", step, got, want)
+	}
+}
+
+func TestTenantQuerierSetsOrganizationInsideTransaction(t *testing.T) {
+	d, db := newRLSDatabase(t, true)
+	ctx := tenant.WithOrganization(context.Background(), 7)
+
+	if _, err := conn(ctx, db).ExecContext(ctx, "UPDATE locations SET address = $1", "x"); err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, "exec", d.take(),
+		"begin", "set 7", "exec UPDATE locations SET address = $1 [org=7]", "commit")
+
+	var id int
+	if err := conn(ctx, db).QueryRowContext(ctx, "SELECT id FROM assets").Scan(&id); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("query row: err = %v", err)
+	}
+	checkEvents(t, "query row without rows", d.take(),
+		"begin", "set 7", "query SELECT id FROM assets [org=7]", "rollback")
+
+	// A result set keeps its transaction open until it is closed
+	rows, err := conn(ctx, db).QueryContext(ctx, "SELECT id, address, type, version FROM locations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for rows.Next() {
+		var l struct {
+			id, version   int
+			address, kind string
+		}
+		if err := rows.Scan(&l.id, &l.address, &l.kind, &l.version); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, l.id)
+	}
+	checkEvents(t, "query before close", d.take(),
+		"begin", "set 7", "query SELECT id, address, type, version FROM locations [org=7]")
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, "query after close", d.take(), "commit")
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("organization 7 sees locations %v, want [1]", ids)
+	}
+}
+
+func TestTenantQuerierScopes(t *testing.T) {
+	d, db := newRLSDatabase(t, true)
+	repo := NewLocationRepository(db)
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		set   string
+		found []int
+	}{
+		{"own organization", tenant.WithOrganization(context.Background(), 7), "7", []int{1}},
+		{"other organization", tenant.WithOrganization(context.Background(), 8), "8", []int{2}},
+		{"all organizations", tenant.WithAllOrganizations(context.Background()), "0", []int{1, 2}},
+		{"no organization", context.Background(), "-1", nil},
+	}
+	for _, tt := range tests {
+		var found []int
+		for _, id := range []int{1, 2} {
+			l, err := repo.GetByID(tt.ctx, id)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if l != nil {
+				found = append(found, l.ID)
+			}
+		}
+		if fmt.Sprint(found) != fmt.Sprint(tt.found) {
+			t.Errorf("%s: found locations %v, want %v", tt.name, found, tt.found)
+		}
+
+		events := d.take()
+		if len(events) < 2 || events[0] != "begin" || events[1] != "set "+tt.set {
+			t.Errorf("%s: events %q, want the transaction to start with set %s", tt.name, events, tt.set)
+		}
+	}
+}
+
+func TestTenantQuerierRollsBackOnError(t *testing.T) {
+	d, db := newRLSDatabase(t, true)
+	ctx := tenant.WithOrganization(context.Background(), 7)
+
+	d.failQuery = true
+	if _, err := conn(ctx, db).ExecContext(ctx, "DELETE FROM locations"); err == nil {
+		t.Error("failed exec returned no error")
+	}
+	checkEvents(t, "failed exec", d.take(),
+		"begin", "set 7", "exec DELETE FROM locations [org=7]", "rollback")
+
+	if _, err := conn(ctx, db).QueryContext(ctx, "SELECT id FROM locations"); err == nil {
+		t.Error("failed query returned no error")
+	}
+	checkEvents(t, "failed query", d.take(),
+		"begin", "set 7", "query SELECT id FROM locations [org=7]", "rollback")
+
+	// Without the organization the query must not run at all
+	d.failQuery, d.failSetConfig = false, true
+	if _, err := conn(ctx, db).ExecContext(ctx, "DELETE FROM locations"); err == nil {
+		t.Error("exec ran after set_config failed")
+	}
+	checkEvents(t, "failed set_config before exec", d.take(), "begin", "set 7", "rollback")
+
+	var id int
+	if err := conn(ctx, db).QueryRowContext(ctx, "SELECT id FROM locations").Scan(&id); err == nil {
+		t.Error("query row ran after set_config failed")
+	}
+	checkEvents(t, "failed set_config before query row", d.take(), "begin", "set 7", "rollback")
+}
+
+func TestWithinTxSetsOrganizationOnce(t *testing.T) {
+	d, db := newRLSDatabase(t, true)
+	transactor := NewTransactor(db)
+	ctx := tenant.WithOrganization(context.Background(), 7)
+
+	err := transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := conn(ctx, db).ExecContext(ctx, "UPDATE assets SET name = $1", "x"); err != nil {
+			return err
+		}
+		if _, err := NewLocationRepository(db).GetByID(ctx, 1); err != nil {
+			return err
+		}
+		// A nested call joins the open transaction
+		return transactor.WithinTx(ctx, func(ctx context.Context) error {
+			_, err := conn(ctx, db).ExecContext(ctx, "INSERT INTO outbox_events DEFAULT VALUES")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := d.take()
+	checkEvents(t, "within tx", events[:3], "begin", "set 7", "exec UPDATE assets SET name = $1 [org=7]")
+	if !strings.HasPrefix(events[3], "query SELECT id, address, type, version FROM locations") || !strings.HasSuffix(events[3], "[org=7]") {
+		t.Errorf("repository query in the transaction: %q", events[3])
+	}
+	checkEvents(t, "within tx", events[4:], "exec INSERT INTO outbox_events DEFAULT VALUES [org=7]", "commit")
+
+	failure := errors.New("stop")
+	err = transactor.WithinTx(ctx, func(ctx context.Context) error {
+		conn(ctx, db).ExecContext(ctx, "UPDATE assets SET name = $1", "y")
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("err = %v", err)
+	}
+	checkEvents(t, "failed tx", d.take(),
+		"begin", "set 7", "exec UPDATE assets SET name = $1 [org=7]", "rollback")
+
+	d.failSetConfig = true
+	called := false
+	err = transactor.WithinTx(ctx, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("set_config failed: err = %v, fn called = %v", err, called)
+	}
+	checkEvents(t, "failed set_config", d.take(), "begin", "set 7", "rollback")
+}
+
+func TestRowLevelSecurityDisabled(t *testing.T) {
+	d, db := newRLSDatabase(t, false)
+	ctx := tenant.WithOrganization(context.Background(), 7)
+
+	if _, err := conn(ctx, db).ExecContext(ctx, "UPDATE assets SET name = $1", "x"); err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, "exec", d.take(), "exec UPDATE assets SET name = $1 [outside tx]")
+
+	err := NewTransactor(db).WithinTx(ctx, func(ctx context.Context) error {
+		_, err := conn(ctx, db).ExecContext(ctx, "UPDATE assets SET name = $1", "y")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, "within tx", d.take(), "begin", "exec UPDATE assets SET name = $1 [org=]", "commit")
+}
+
+func TestRowLevelSecurityEnforced(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]driver.Value
+		want bool
+	}{
+		{"policies apply to the role", [][]driver.Value{{true}}, true},
+		{"role bypasses policies", [][]driver.Value{{false}}, false},
+		{"employees table is missing", nil, false},
+	}
+
+	for _, tt := range tests {
+		db := repotest.Open(t, func(query string, args []driver.Value) (repotest.Result, error) {
+			return repotest.Result{Columns: []string{"enforced"}, Rows: tt.rows}, nil
+		})
+		got, err := RowLevelSecurityEnforced(context.Background(), db)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: enforced = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -28,10 +28,11 @@ func (r *TransferRepository) GetAll(ctx context.Context) ([]models.AssetTransfer
 		JOIN employees e ON t.employee_id = e.id
 		JOIN locations fl ON t.from_location_id = fl.id
 		JOIN locations tl ON t.to_location_id = tl.id
+		WHERE $1 = 0 OR t.organization_id = $1
 		ORDER BY t.transfer_date DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -75,11 +76,11 @@ func (r *TransferRepository) GetByID(ctx context.Context, id int) (*models.Asset
 		JOIN employees e ON t.employee_id = e.id
 		JOIN locations fl ON t.from_location_id = fl.id
 		JOIN locations tl ON t.to_location_id = tl.id
-		WHERE t.id = $1
+		WHERE t.id = $1 AND ($2 = 0 OR t.organization_id = $2)
 	`
 
 	var t models.AssetTransfer
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, tenantScope(ctx)).Scan(
 		&t.ID,
 		&t.AssetID,
 		&t.AssetName,
@@ -103,17 +104,22 @@ func (r *TransferRepository) GetByID(ctx context.Context, id int) (*models.Asset
 }
 
 func (r *TransferRepository) Create(ctx context.Context, transfer models.AssetTransfer) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
 		INSERT INTO asset_transfers (
 			asset_id, employee_id, from_location_id, 
-			to_location_id, transfer_date, notes
+			to_location_id, transfer_date, notes, organization_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id
 	`
 
 	var id int
-	err := r.db.QueryRowContext(
+	err = conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		transfer.AssetID,
@@ -122,6 +128,7 @@ func (r *TransferRepository) Create(ctx context.Context, transfer models.AssetTr
 		transfer.ToLocationID,
 		transfer.TransferDate,
 		transfer.Notes,
+		organizationID,
 	).Scan(&id)
 
 	if err != nil {
@@ -143,11 +150,11 @@ func (r *TransferRepository) GetByAsset(ctx context.Context, assetID int) ([]mod
 		JOIN employees e ON t.employee_id = e.id
 		JOIN locations fl ON t.from_location_id = fl.id
 		JOIN locations tl ON t.to_location_id = tl.id
-		WHERE t.asset_id = $1
+		WHERE t.asset_id = $1 AND ($2 = 0 OR t.organization_id = $2)
 		ORDER BY t.transfer_date DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, assetID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, assetID, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -191,11 +198,11 @@ func (r *TransferRepository) GetByEmployee(ctx context.Context, employeeID int)
 		JOIN employees e ON t.employee_id = e.id
 		JOIN locations fl ON t.from_location_id = fl.id
 		JOIN locations tl ON t.to_location_id = tl.id
-		WHERE t.employee_id = $1
+		WHERE t.employee_id = $1 AND ($2 = 0 OR t.organization_id = $2)
 		ORDER BY t.transfer_date DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, employeeID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, employeeID, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -239,11 +246,11 @@ func (r *TransferRepository) GetByLocation(ctx context.Context, locationID int)
 		JOIN employees e ON t.employee_id = e.id
 		JOIN locations fl ON t.from_location_id = fl.id
 		JOIN locations tl ON t.to_location_id = tl.id
-		WHERE t.from_location_id = $1 OR t.to_location_id = $1
+		WHERE (t.from_location_id = $1 OR t.to_location_id = $1) AND ($2 = 0 OR t.organization_id = $2)
 		ORDER BY t.transfer_date DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, locationID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, locationID, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -287,11 +294,11 @@ func (r *TransferRepository) GetByDateRange(ctx context.Context, from, to time.T
 		JOIN employees e ON t.employee_id = e.id
 		JOIN locations fl ON t.from_location_id = fl.id
 		JOIN locations tl ON t.to_location_id = tl.id
-		WHERE t.transfer_date BETWEEN $1 AND $2
+		WHERE t.transfer_date BETWEEN $1 AND $2 AND ($3 = 0 OR t.organization_id = $3)
 		ORDER BY t.transfer_date DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, from, to)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, from, to, tenantScope(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// TwoFactorRepository хранит секреты TOTP сотрудников и их резервные коды
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// GetSecret возвращает секрет TOTP сотрудника и включена ли проверка кода.
+// Пустой секрет означает, что двухфакторная аутентификация не настроена
+func (r *TwoFactorRepository) GetSecret(ctx context.Context, employeeID int) (secret string, enabled bool, err error) {
+	var value sql.NullString
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT totp_secret, totp_enabled FROM employees WHERE id = $1 AND deleted_at IS NULL",
+		employeeID,
+	).Scan(&value, &enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	return value.String, enabled, err
+}
+
+// SavePendingSecret сохраняет новый секрет, который еще не подтвержден кодом
+func (r *TwoFactorRepository) SavePendingSecret(ctx context.Context, employeeID int, secret string) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE employees SET totp_secret = $2, totp_enabled = FALSE, totp_last_step = NULL WHERE id = $1",
+		employeeID,
+		secret,
+	)
+	return err
+}
+
+// Enable включает проверку кода после подтверждения секрета
+func (r *TwoFactorRepository) Enable(ctx context.Context, employeeID int) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE employees SET totp_enabled = TRUE WHERE id = $1", employeeID)
+	return err
+}
+
+// UseStep отмечает шаг step принятым. Возвращает false, если код этого или
+// более позднего шага уже принимался
+func (r *TwoFactorRepository) UseStep(ctx context.Context, employeeID int, step int64) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE employees SET totp_last_step = $2 WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)",
+		employeeID,
+		step,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Disable удаляет секрет и резервные коды сотрудника
+func (r *TwoFactorRepository) Disable(ctx context.Context, employeeID int) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE employees SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = NULL WHERE id = $1",
+		employeeID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, "DELETE FROM recovery_codes WHERE employee_id = $1", employeeID)
+	return err
+}
+
+// ReplaceRecoveryCodes заменяет резервные коды сотрудника хешами hashes
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, employeeID int, hashes []string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM recovery_codes WHERE employee_id = $1", employeeID); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		_, err := conn(ctx, r.db).ExecContext(
+			ctx,
+			"INSERT INTO recovery_codes (employee_id, code_hash) VALUES ($1, $2)",
+			employeeID,
+			hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode отмечает использованным резервный код с хешем hash.
+// Возвращает false, если такого неиспользованного кода нет
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, employeeID int, hash string, at time.Time) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"UPDATE recovery_codes SET used_at = $3 WHERE employee_id = $1 AND code_hash = $2 AND used_at IS NULL",
+		employeeID,
+		hash,
+		at,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// CountRecoveryCodes возвращает число неиспользованных резервных кодов
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, employeeID int) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM recovery_codes WHERE employee_id = $1 AND used_at IS NULL",
+		employeeID,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type txKey struct{}
+
+// sqlQuerier объединяет методы *sql.DB и *sql.Tx, которые используют репозитории
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// querier — то же, что sqlQuerier, но ошибки ограничений PostgreSQL
+// возвращаются переведенными через translateError
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*queryRows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) rowScanner
+}
+
+// queryRows — результат QueryContext. Если для запроса открывалась
+// отдельная транзакция, Close завершает и ее
+type queryRows struct {
+	*sql.Rows
+	done func() error
+}
+
+func (r *queryRows) Close() error {
+	err := r.Rows.Close()
+	if r.done == nil {
+		return err
+	}
+	if doneErr := r.done(); err == nil {
+		err = doneErr
+	}
+	r.done = nil
+	return err
+}
+
+// conn возвращает транзакцию из контекста, если она открыта через
+// Transactor.WithinTx, иначе само соединение с базой. В режиме row-level
+// security запросы вне транзакции выполняются через tenantQuerier
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return translatingQuerier{tx}
+	}
+	if rowLevelSecurity.Load() {
+		return tenantQuerier{db}
+	}
+	return translatingQuerier{db}
+}
+
+// Transactor позволяет сервисам выполнять вызовы нескольких репозиториев
+// в одной транзакции
+type Transactor struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTx выполняет fn в транзакции. Репозитории, вызванные с переданным
+// в fn контекстом, работают внутри этой транзакции. Вложенные вызовы
+// используют уже открытую транзакцию
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if rowLevelSecurity.Load() {
+		if err := setTenant(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"inventory-system/internal/apperror"
+)
+
+// ErrVersionConflict возвращается, когда запись была изменена после того,
+// как клиент получил ее версию
+var ErrVersionConflict = apperror.PreconditionFailed("record version is stale")
+
+// scanVersion читает новую версию из UPDATE ... RETURNING version.
+// Отсутствие строки означает, что версия в условии устарела
+func scanVersion(row rowScanner) (int, error) {
+	var version int
+	if err := row.Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrVersionConflict
+		}
+		return 0, err
+	}
+	return version, nil
+}
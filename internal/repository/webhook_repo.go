@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"inventory-system/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookColumns = "id, url, secret, event_types, active, created_at"
+
+func scanWebhook(row rowScanner) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	err := row.Scan(&s.ID, &s.URL, &s.Secret, pq.Array(&s.EventTypes), &s.Active, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *WebhookRepository) GetAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		"SELECT "+webhookColumns+" FROM webhook_subscriptions WHERE $1 = 0 OR organization_id = $1 ORDER BY id",
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+	for rows.Next() {
+		s, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *s)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	row := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT "+webhookColumns+" FROM webhook_subscriptions WHERE id = $1 AND ($2 = 0 OR organization_id = $2)",
+		id,
+		tenantScope(ctx),
+	)
+	s, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, s models.WebhookSubscription) (int, error) {
+	organizationID, err := ownerOrganization(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`INSERT INTO webhook_subscriptions (url, secret, event_types, active, organization_id)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		s.URL,
+		s.Secret,
+		pq.Array(s.EventTypes),
+		s.Active,
+		organizationID,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, s models.WebhookSubscription) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE webhook_subscriptions SET url = $1, secret = $2, event_types = $3, active = $4
+		 WHERE id = $5 AND ($6 = 0 OR organization_id = $6)`,
+		s.URL,
+		s.Secret,
+		pq.Array(s.EventTypes),
+		s.Active,
+		s.ID,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id int) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		"DELETE FROM webhook_subscriptions WHERE id = $1 AND ($2 = 0 OR organization_id = $2)",
+		id,
+		tenantScope(ctx),
+	)
+	return err
+}
+
+// SubscribersOf возвращает идентификаторы активных подписок организации
+// organizationID на событие
+func (r *WebhookRepository) SubscribersOf(ctx context.Context, organizationID int, eventType string) ([]int, error) {
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		`SELECT id FROM webhook_subscriptions
+		 WHERE active AND organization_id = $1 AND $2 = ANY(event_types)
+		 ORDER BY id`,
+		organizationID,
+		eventType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// CreateDelivery ставит событие в очередь доставки подписчику. Повторная
+// постановка того же события игнорируется
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, subscriptionID int, eventID int64) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_id)
+		 VALUES ($1, $2)
+		 ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		subscriptionID,
+		eventID,
+	)
+	return err
+}
+
+// ClaimDue выбирает до limit доставок, срок которых наступил к now, и
+// откладывает их до leaseUntil, чтобы другой экземпляр диспетчера не
+// отправил их одновременно
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.DueDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET next_attempt_at = $2
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= $1
+				ORDER BY next_attempt_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, subscription_id, event_id, status, attempts, next_attempt_at,
+			          last_error, response_status, created_at, delivered_at
+		)
+		SELECT c.id, c.subscription_id, c.event_id, c.status, c.attempts, c.next_attempt_at,
+		       c.last_error, c.response_status, c.created_at, c.delivered_at,
+		       s.url, s.secret, e.event_type, e.payload, e.created_at
+		FROM claimed c
+		JOIN webhook_subscriptions s ON s.id = c.subscription_id
+		JOIN outbox_events e ON e.id = c.event_id
+		ORDER BY c.id
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.DueDelivery
+	for rows.Next() {
+		var d models.DueDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastError,
+			&d.ResponseStatus,
+			&d.CreatedAt,
+			&d.DeliveredAt,
+			&d.URL,
+			&d.Secret,
+			&d.Event.Type,
+			&d.Event.Payload,
+			&d.Event.OccurredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		d.Event.ID = d.EventID
+		d.EventType = d.Event.Type
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// SaveAttempt записывает результат попытки доставки
+func (r *WebhookRepository) SaveAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4,
+		     response_status = $5, delivered_at = $6
+		 WHERE id = $7`,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.LastError,
+		d.ResponseStatus,
+		d.DeliveredAt,
+		d.ID,
+	)
+	return err
+}
+
+// GetDeliveries возвращает журнал доставок подписки, начиная с последних
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT d.id, d.subscription_id, d.event_id, e.event_type, d.status, d.attempts,
+		       d.next_attempt_at, d.last_error, d.response_status, d.created_at, d.delivered_at
+		FROM webhook_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id
+		WHERE d.subscription_id = $1
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $2
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastError,
+			&d.ResponseStatus,
+			&d.CreatedAt,
+			&d.DeliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Redeliver возвращает в очередь доставку, попавшую в dead. Возвращает
+// false, если такой доставки нет
+func (r *WebhookRepository) Redeliver(ctx context.Context, subscriptionID int, deliveryID int64, at time.Time) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+		 SET status = 'pending', attempts = 0, next_attempt_at = $1
+		 WHERE id = $2 AND subscription_id = $3 AND status = 'dead'
+		   AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE $4 = 0 OR organization_id = $4)`,
+		at,
+		deliveryID,
+		subscriptionID,
+		tenantScope(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *WebhookRepository) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM webhook_subscriptions WHERE id = $1 AND ($2 = 0 OR organization_id = $2))",
+		id,
+		tenantScope(ctx),
+	).Scan(&exists)
+	return exists, err
+}
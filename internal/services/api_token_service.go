@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/tenant"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAPITokenNotFound  = apperror.NotFound("API token not found")
+	ErrInvalidScope      = apperror.Validation("unknown API token scope", nil)
+	ErrInvalidExpiry     = apperror.Validation("invalid API token expiry", nil)
+	ErrTooManyAPITokens  = apperror.Conflict("too many active API tokens")
+	ErrInsufficientScope = apperror.Forbidden("API token scope does not allow this request")
+)
+
+// Персональный токен: APITokenPrefix и 32 случайных байта в base64url.
+// Префикс отличает токен от JWT в заголовке Authorization и помогает
+// сканерам секретов находить утекшие токены
+const (
+	APITokenPrefix = "inv_pat_"
+	apiTokenBytes  = 32
+	// apiTokenPrefixLength — сколько первых символов токена сохраняется
+	// открыто, чтобы владелец мог узнать токен в списке
+	apiTokenPrefixLength = 16
+	// maxAPITokens — сколько действующих токенов может быть у сотрудника
+	maxAPITokens = 50
+	// lastUsedResolution — как часто обновляется время последнего
+	// использования, чтобы не писать в базу на каждый запрос
+	lastUsedResolution = time.Minute
+)
+
+// APITokenService выдает персональные токены доступа для скриптов и
+// интеграций. Токен действует от имени владельца, но только в пределах
+// выбранных областей. В базе хранится только хеш токена
+type APITokenService struct {
+	tokenRepo    *repository.APITokenRepository
+	employeeRepo *repository.EmployeeRepository
+	clock        ratelimit.Clock
+	defaultTTL   time.Duration
+	maxTTL       time.Duration
+}
+
+func NewAPITokenService(
+	tokenRepo *repository.APITokenRepository,
+	employeeRepo *repository.EmployeeRepository,
+	clock ratelimit.Clock,
+	defaultTTL time.Duration,
+	maxTTL time.Duration,
+) *APITokenService {
+	return &APITokenService{
+		tokenRepo:    tokenRepo,
+		employeeRepo: employeeRepo,
+		clock:        clock,
+		defaultTTL:   defaultTTL,
+		maxTTL:       maxTTL,
+	}
+}
+
+// CreateToken выдает сотруднику токен. Без срока действия токен действует
+// defaultTTL; срок больше maxTTL не допускается. Значение токена
+// возвращается только здесь
+func (s *APITokenService) CreateToken(ctx context.Context, employeeID int, request models.CreateAPITokenRequest) (*models.CreatedAPIToken, error) {
+	scopes, err := normalizeScopes(request.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().UTC()
+	expiresAt := now.Add(s.defaultTTL)
+	if request.ExpiresAt != nil {
+		expiresAt = request.ExpiresAt.UTC()
+		if !expiresAt.After(now) {
+			return nil, invalidField(ErrInvalidExpiry, "expires_at", "must be in the future")
+		}
+		if expiresAt.Sub(now) > s.maxTTL {
+			return nil, invalidField(ErrInvalidExpiry, "expires_at", "must be within "+s.maxTTL.String())
+		}
+	}
+
+	count, err := s.tokenRepo.CountActive(ctx, employeeID, now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAPITokens {
+		return nil, ErrTooManyAPITokens
+	}
+
+	secret, err := newAPIToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token := models.APIToken{
+		EmployeeID: employeeID,
+		Name:       strings.TrimSpace(request.Name),
+		Prefix:     secret[:apiTokenPrefixLength],
+		Scopes:     scopes,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}
+	token.ID, err = s.tokenRepo.Create(ctx, token, hashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedAPIToken{APIToken: token, Token: secret}, nil
+}
+
+// GetTokens возвращает токены сотрудника, включая отозванные и истекшие
+func (s *APITokenService) GetTokens(ctx context.Context, employeeID int) ([]models.APIToken, error) {
+	employee, err := s.employeeRepo.GetByID(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if employee == nil {
+		return nil, ErrEmployeeNotFound
+	}
+	return s.tokenRepo.GetByEmployee(ctx, employeeID)
+}
+
+// RevokeToken отзывает токен сотрудника. Повторный отзыв ничего не делает
+func (s *APITokenService) RevokeToken(ctx context.Context, employeeID, id int) error {
+	revoked, err := s.tokenRepo.Revoke(ctx, employeeID, id, s.clock.Now().UTC())
+	if err != nil || revoked {
+		return err
+	}
+
+	token, err := s.tokenRepo.GetByID(ctx, employeeID, id)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Authenticate возвращает владельца действующего токена и сам токен
+func (s *APITokenService) Authenticate(ctx context.Context, secret, ip string) (*models.Employee, *models.APIToken, error) {
+	now := s.clock.Now().UTC()
+
+	token, err := s.tokenRepo.GetActiveByHash(ctx, hashToken(secret), now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	// Организация владельца становится известна только по самому токену
+	employee, err := s.employeeRepo.GetByID(tenant.WithAllOrganizations(ctx), token.EmployeeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if employee == nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		// Ошибка учета использования не должна мешать запросу
+		if err := s.tokenRepo.TouchLastUsed(ctx, token.ID, now, ip); err != nil {
+			log.Printf("Failed to record use of API token %d: %v", token.ID, err)
+		}
+	}
+	return employee, token, nil
+}
+
+// IsAPIToken сообщает, является ли значение заголовка Authorization
+// персональным токеном, а не JWT
+func IsAPIToken(value string) bool {
+	return strings.HasPrefix(value, APITokenPrefix)
+}
+
+// ScopesAllow сообщает, разрешают ли области токена запрос к ресурсу:
+// чтение — при областях read, "<ресурс>:read" или "<ресурс>:write",
+// изменение — только при "<ресурс>:write"
+func ScopesAllow(scopes []string, resource string, write bool) bool {
+	for _, scope := range scopes {
+		switch scope {
+		case resource + ":write":
+			return true
+		case models.ScopeRead, resource + ":read":
+			if !write {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeScopes проверяет области токена и убирает повторы
+func normalizeScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool, len(models.APITokenScopes))
+	for _, scope := range models.APITokenScopes {
+		known[scope] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !known[scope] {
+			return nil, invalidField(ErrInvalidScope, "scopes", "contains unknown scope "+scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// newAPIToken возвращает случайное значение персонального токена
+func newAPIToken() (string, error) {
+	b := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return APITokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/tenant"
+)
+
+// assetEventsDB — таблица assets с одним активом и журнал событий,
+// добавленных в outbox
+type assetEventsDB struct {
+	statusID int64
+	deleted  bool
+	events   []string
+}
+
+var assetEventStatuses = map[int64]string{1: "В эксплуатации", 3: "В ремонте", 4: models.StatusWrittenOff}
+
+func (d *assetEventsDB) handle(t *testing.T) fakeHandler {
+	return func(query string, args []driver.Value) (fakeResult, error) {
+		switch {
+		case strings.Contains(query, "FROM assets a"):
+			if d.deleted {
+				return fakeResult{columns: []string{"id"}}, nil
+			}
+			return fakeResult{
+				columns: make([]string, 19),
+				rows: [][]driver.Value{{
+					int64(10), "Ноутбук", "Техника", nil, nil,
+					time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), float64(1000), nil,
+					d.statusID, assetEventStatuses[d.statusID], int64(1), "Офис",
+					nil, nil, nil, nil, nil, int64(1), int64(1),
+				}},
+			}, nil
+		case strings.Contains(query, "FROM asset_statuses"):
+			_, ok := assetEventStatuses[args[0].(int64)]
+			return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{ok}}}, nil
+		case strings.HasPrefix(query, "UPDATE assets SET status_id"):
+			d.statusID = args[0].(int64)
+			return fakeResult{affected: 1}, nil
+		case strings.Contains(query, "SET deleted_at = NOW()"):
+			d.deleted = true
+			return fakeResult{affected: 1}, nil
+		case strings.Contains(query, "SET parent_id = NULL"):
+			return fakeResult{}, nil
+		case strings.HasPrefix(query, "INSERT INTO outbox_events"):
+			d.events = append(d.events, args[0].(string))
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{int64(len(d.events))}}}, nil
+		}
+		t.Errorf("unexpected query %q", query)
+		return fakeResult{}, nil
+	}
+}
+
+func newAssetEventsService(t *testing.T) (*AssetService, *assetEventsDB) {
+	d := &assetEventsDB{statusID: 1}
+	db := newFakeDB(t, d.handle(t))
+	service := NewAssetService(
+		repository.NewAssetRepository(db),
+		repository.NewStatusRepository(db),
+		nil, nil, nil,
+		repository.NewOutboxRepository(db),
+		repository.NewTransactor(db),
+		nil, nil,
+	)
+	return service, d
+}
+
+func TestAssetEvents(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+
+	tests := []struct {
+		name   string
+		change func(s *AssetService) error
+		want   []string
+	}{
+		{
+			"status change",
+			func(s *AssetService) error { return s.UpdateStatus(ctx, 10, 3) },
+			[]string{models.EventAssetStatusChanged},
+		},
+		{
+			"write-off status",
+			func(s *AssetService) error { return s.UpdateStatus(ctx, 10, 4) },
+			[]string{models.EventAssetStatusChanged, models.EventAssetWrittenOff},
+		},
+		{
+			"soft delete",
+			func(s *AssetService) error { return s.DeleteAsset(ctx, 10, 3) },
+			[]string{models.EventAssetDeleted},
+		},
+	}
+
+	for _, tt := range tests {
+		service, d := newAssetEventsService(t)
+		if err := tt.change(service); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(d.events, tt.want) {
+			t.Errorf("%s: events = %q, want %q", tt.name, d.events, tt.want)
+		}
+	}
+}
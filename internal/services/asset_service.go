@@ -2,13 +2,17 @@ package services
 
 import (
 	"context"
-	"errors"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/mergepatch"
 	"inventory-system/internal/models"
 	"inventory-system/internal/repository"
 )
 
 var (
-	ErrStatusNotFound = errors.New("status not found")
+	ErrStatusNotFound       = apperror.NotFound("status not found")
+	ErrAssetCycle           = apperror.Validation("asset cannot be a component of itself or of its own component", nil)
+	ErrComponentNotAttached = apperror.Conflict("asset is not a component of this kit")
 )
 
 type AssetService struct {
@@ -16,6 +20,11 @@ type AssetService struct {
 	statusRepo     *repository.StatusRepository
 	locationRepo   *repository.LocationRepository
 	departmentRepo *repository.DepartmentRepository
+	categoryRepo   *repository.CategoryRepository
+	outboxRepo     *repository.OutboxRepository
+	transactor     *repository.Transactor
+	notifier       *NotificationService
+	bus            *events.Bus
 }
 
 func NewAssetService(
@@ -23,12 +32,22 @@ func NewAssetService(
 	statusRepo *repository.StatusRepository,
 	locationRepo *repository.LocationRepository,
 	departmentRepo *repository.DepartmentRepository,
+	categoryRepo *repository.CategoryRepository,
+	outboxRepo *repository.OutboxRepository,
+	transactor *repository.Transactor,
+	notifier *NotificationService,
+	bus *events.Bus,
 ) *AssetService {
 	return &AssetService{
 		assetRepo:      assetRepo,
 		statusRepo:     statusRepo,
 		locationRepo:   locationRepo,
 		departmentRepo: departmentRepo,
+		categoryRepo:   categoryRepo,
+		outboxRepo:     outboxRepo,
+		transactor:     transactor,
+		notifier:       notifier,
+		bus:            bus,
 	}
 }
 
@@ -36,6 +55,20 @@ func (s *AssetService) GetAllAssets(ctx context.Context) ([]models.Asset, error)
 	return s.assetRepo.GetAll(ctx)
 }
 
+func (s *AssetService) SearchAssets(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error) {
+	if filter.CategoryID != nil {
+		exists, err := s.categoryRepo.Exists(ctx, *filter.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, invalidReference("category_id", ErrCategoryNotFound)
+		}
+	}
+
+	return s.assetRepo.Search(ctx, filter)
+}
+
 func (s *AssetService) GetAssetByID(ctx context.Context, id int) (*models.Asset, error) {
 	asset, err := s.assetRepo.GetByID(ctx, id)
 	if err != nil {
@@ -47,6 +80,44 @@ func (s *AssetService) GetAssetByID(ctx context.Context, id int) (*models.Asset,
 	return asset, nil
 }
 
+// GetAssetDetails возвращает актив вместе с деревом компонентов и общей
+// стоимостью комплекта
+func (s *AssetService) GetAssetDetails(ctx context.Context, id int) (*models.Asset, error) {
+	asset, err := s.GetAssetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	descendants, err := s.assetRepo.GetDescendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	byParent := make(map[int][]models.Asset)
+	for _, d := range descendants {
+		byParent[*d.ParentID] = append(byParent[*d.ParentID], d)
+	}
+
+	var build func(parentID int) ([]models.Asset, float64)
+	build = func(parentID int) ([]models.Asset, float64) {
+		components := byParent[parentID]
+		var total float64
+		for i := range components {
+			children, childrenCost := build(components[i].ID)
+			components[i].Components = children
+			total += components[i].Cost + childrenCost
+		}
+		return components, total
+	}
+
+	components, componentsCost := build(asset.ID)
+	asset.Components = components
+	totalCost := asset.Cost + componentsCost
+	asset.TotalCost = &totalCost
+
+	return asset, nil
+}
+
 func (s *AssetService) CreateAsset(ctx context.Context, asset models.Asset) (int, error) {
 	// Validate status
 	exists, err := s.statusRepo.Exists(ctx, asset.StatusID)
@@ -54,7 +125,7 @@ func (s *AssetService) CreateAsset(ctx context.Context, asset models.Asset) (int
 		return 0, err
 	}
 	if !exists {
-		return 0, ErrStatusNotFound
+		return 0, invalidReference("status_id", ErrStatusNotFound)
 	}
 
 	// Validate location
@@ -63,7 +134,7 @@ func (s *AssetService) CreateAsset(ctx context.Context, asset models.Asset) (int
 		return 0, err
 	}
 	if !exists {
-		return 0, ErrLocationNotFound
+		return 0, invalidReference("current_location_id", ErrLocationNotFound)
 	}
 
 	// Validate department if specified
@@ -73,62 +144,325 @@ func (s *AssetService) CreateAsset(ctx context.Context, asset models.Asset) (int
 			return 0, err
 		}
 		if !exists {
-			return 0, ErrDepartmentNotFound
+			return 0, invalidReference("department_id", ErrDepartmentNotFound)
 		}
 	}
 
-	return s.assetRepo.Create(ctx, asset)
+	// Validate category and custom fields
+	if err := s.applyCategory(ctx, &asset); err != nil {
+		return 0, err
+	}
+
+	// Validate parent kit
+	if err := s.validateParent(ctx, asset.ID, asset.ParentID); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		id, err = s.assetRepo.Create(ctx, asset)
+		if err != nil {
+			return err
+		}
+		if err := s.recordEvent(ctx, models.EventAssetCreated, id); err != nil {
+			return err
+		}
+		return s.notifier.NotifyAssetAssigned(ctx, id, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionCreated, id)
+	return id, nil
 }
 
-func (s *AssetService) UpdateAsset(ctx context.Context, asset models.Asset) error {
+// UpdateAsset сохраняет актив с версией asset.Version и возвращает новую
+// версию. Если актив успели изменить, возвращает ErrVersionConflict
+func (s *AssetService) UpdateAsset(ctx context.Context, asset models.Asset) (int, error) {
+	// Check if asset exists
+	current, err := s.GetAssetByID(ctx, asset.ID)
+	if err != nil {
+		return 0, err
+	}
+
 	// Validate status
 	exists, err := s.statusRepo.Exists(ctx, asset.StatusID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if !exists {
-		return ErrStatusNotFound
+		return 0, invalidReference("status_id", ErrStatusNotFound)
 	}
 
 	// Validate location
 	exists, err = s.locationRepo.Exists(ctx, asset.CurrentLocationID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if !exists {
-		return ErrLocationNotFound
+		return 0, invalidReference("current_location_id", ErrLocationNotFound)
 	}
 
 	// Validate department if specified
 	if asset.DepartmentID != nil {
 		exists, err = s.departmentRepo.Exists(ctx, *asset.DepartmentID)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, invalidReference("department_id", ErrDepartmentNotFound)
+		}
+	}
+
+	// Validate category and custom fields
+	if err := s.applyCategory(ctx, &asset); err != nil {
+		return 0, err
+	}
+
+	// Validate parent kit
+	if err := s.validateParent(ctx, asset.ID, asset.ParentID); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if !sameInt(asset.ParentID, current.ParentID) {
+			if err := s.checkCycle(ctx, asset.ID, asset.ParentID); err != nil {
+				return err
+			}
+		}
+		version, err = checkVersion(s.assetRepo.Update(ctx, asset))
 		if err != nil {
 			return err
 		}
+		if err := s.recordUpdate(ctx, asset.ID, current.StatusID); err != nil {
+			return err
+		}
+		return s.notifier.NotifyAssetAssigned(ctx, asset.ID, current.DepartmentID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, asset.ID)
+	return version, nil
+}
+
+var assetPatchFields = patchFields{
+	"name":                false,
+	"category":            false,
+	"category_id":         true,
+	"custom_fields":       true,
+	"acquisition_date":    false,
+	"cost":                false,
+	"status_id":           false,
+	"current_location_id": false,
+	"department_id":       true,
+	"parent_id":           true,
+	"warranty_until":      true,
+}
+
+// PatchAsset применяет к активу JSON Merge Patch. Проверяются только
+// измененные поля, и в базу записываются только они. Если актив успели
+// изменить после получения версии, возвращает ErrVersionConflict
+func (s *AssetService) PatchAsset(ctx context.Context, id, version int, patch mergepatch.Patch) (*models.Asset, error) {
+	current, err := s.GetAssetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	var patched models.Asset
+	var request models.AssetRequest
+	if err := applyPatch(current, patch, assetPatchFields, &patched, &request); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]interface{})
+
+	if patched.Name != current.Name {
+		changes["name"] = patched.Name
+	}
+	if patched.AcquisitionDate != current.AcquisitionDate {
+		changes["acquisition_date"] = patched.AcquisitionDate
+	}
+	if patched.Cost != current.Cost {
+		changes["cost"] = patched.Cost
+	}
+	if !sameString(patched.WarrantyUntil, current.WarrantyUntil) {
+		changes["warranty_until"] = patched.WarrantyUntil
+	}
+
+	// Validate status
+	if patched.StatusID != current.StatusID {
+		exists, err := s.statusRepo.Exists(ctx, patched.StatusID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, invalidReference("status_id", ErrStatusNotFound)
+		}
+		changes["status_id"] = patched.StatusID
+	}
+
+	// Validate location
+	if patched.CurrentLocationID != current.CurrentLocationID {
+		exists, err := s.locationRepo.Exists(ctx, patched.CurrentLocationID)
+		if err != nil {
+			return nil, err
+		}
 		if !exists {
-			return ErrDepartmentNotFound
+			return nil, invalidReference("current_location_id", ErrLocationNotFound)
+		}
+		changes["current_location_id"] = patched.CurrentLocationID
+	}
+
+	// Validate department if specified
+	if !sameInt(patched.DepartmentID, current.DepartmentID) {
+		if patched.DepartmentID != nil {
+			exists, err := s.departmentRepo.Exists(ctx, *patched.DepartmentID)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, invalidReference("department_id", ErrDepartmentNotFound)
+			}
+		}
+		changes["department_id"] = patched.DepartmentID
+	}
+
+	// Validate parent kit
+	if !sameInt(patched.ParentID, current.ParentID) {
+		if err := s.validateParent(ctx, id, patched.ParentID); err != nil {
+			return nil, err
+		}
+		changes["parent_id"] = patched.ParentID
+	}
+
+	// Validate category and custom fields
+	if !sameInt(patched.CategoryID, current.CategoryID) ||
+		!sameValues(patched.CustomFields, current.CustomFields) ||
+		patched.Category != current.Category {
+		if err := s.applyCategory(ctx, &patched); err != nil {
+			return nil, err
+		}
+		if !sameInt(patched.CategoryID, current.CategoryID) {
+			changes["category_id"] = patched.CategoryID
+		}
+		if !sameValues(patched.CustomFields, current.CustomFields) {
+			changes["custom_fields"] = patched.CustomFields
+		}
+		if patched.Category != current.Category {
+			changes["category"] = patched.Category
 		}
 	}
 
-	return s.assetRepo.Update(ctx, asset)
+	if len(changes) > 0 {
+		err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			if _, ok := changes["parent_id"]; ok {
+				if err := s.checkCycle(ctx, id, patched.ParentID); err != nil {
+					return err
+				}
+			}
+			if _, err := checkVersion(s.assetRepo.Patch(ctx, id, version, changes)); err != nil {
+				return err
+			}
+			if err := s.recordUpdate(ctx, id, current.StatusID); err != nil {
+				return err
+			}
+			return s.notifier.NotifyAssetAssigned(ctx, id, current.DepartmentID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, id)
+	}
+
+	return s.GetAssetDetails(ctx, id)
 }
 
-func (s *AssetService) DeleteAsset(ctx context.Context, id int) error {
-	// Check if asset exists
-	exists, err := s.assetRepo.Exists(ctx, id)
+// applyCategory проверяет дополнительные поля актива по схеме его категории
+// с учетом полей родительских категорий
+func (s *AssetService) applyCategory(ctx context.Context, asset *models.Asset) error {
+	if asset.CategoryID == nil {
+		if len(asset.CustomFields) > 0 {
+			return ErrCustomFieldsNoCategory
+		}
+		asset.CustomFields = nil
+		return nil
+	}
+
+	category, err := s.categoryRepo.GetByID(ctx, *asset.CategoryID)
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		return invalidReference("category_id", ErrCategoryNotFound)
+	}
+
+	// Subcategories inherit the fields of their parents
+	fields, err := effectiveFields(ctx, s.categoryRepo, category.ID)
+	if err != nil {
+		return err
+	}
+
+	values, err := validateCustomFields(fields, asset.CustomFields)
+	if err != nil {
+		return err
+	}
+
+	asset.Category = category.Name
+	asset.CustomFields = values
+	return nil
+}
+
+// validateParent проверяет, что комплект существует и не совпадает с
+// самим активом. Цикл проверяет checkCycle внутри транзакции
+func (s *AssetService) validateParent(ctx context.Context, assetID int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == assetID {
+		return ErrAssetCycle
+	}
+
+	exists, err := s.assetRepo.Exists(ctx, *parentID)
 	if err != nil {
 		return err
 	}
 	if !exists {
-		return ErrAssetNotFound
+		return invalidReference("parent_id", ErrAssetNotFound)
+	}
+	return nil
+}
+
+// checkCycle блокирует актив и цепочку комплектов над parentID и
+// проверяет, что привязка актива к parentID не создает цикл. Вызывается
+// внутри транзакции, которая сохраняет привязку: иначе две встречные
+// привязки (A в B и B в A) могут одновременно пройти проверку
+func (s *AssetService) checkCycle(ctx context.Context, assetID int, parentID *int) error {
+	if parentID == nil || assetID == 0 {
+		return nil
+	}
+	if err := s.assetRepo.LockHierarchy(ctx, *parentID, assetID); err != nil {
+		return err
 	}
 
-	return s.assetRepo.Delete(ctx, id)
+	isDescendant, err := s.assetRepo.IsDescendant(ctx, assetID, *parentID)
+	if err != nil {
+		return err
+	}
+	if isDescendant {
+		return ErrAssetCycle
+	}
+	return nil
 }
 
-func (s *AssetService) UpdateStatus(ctx context.Context, assetID, statusID int) error {
-	// Validate asset
-	exists, err := s.assetRepo.Exists(ctx, assetID)
+// AttachComponent включает актив componentID в состав комплекта kitID
+func (s *AssetService) AttachComponent(ctx context.Context, kitID, componentID int) error {
+	// Check if kit exists
+	exists, err := s.assetRepo.Exists(ctx, kitID)
 	if err != nil {
 		return err
 	}
@@ -136,16 +470,133 @@ func (s *AssetService) UpdateStatus(ctx context.Context, assetID, statusID int)
 		return ErrAssetNotFound
 	}
 
+	// Validate component
+	exists, err = s.assetRepo.Exists(ctx, componentID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return invalidReference("component_id", ErrAssetNotFound)
+	}
+
+	if err := s.validateParent(ctx, componentID, &kitID); err != nil {
+		return err
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkCycle(ctx, componentID, &kitID); err != nil {
+			return err
+		}
+		if err := s.assetRepo.SetParent(ctx, componentID, &kitID); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.EventAssetUpdated, componentID)
+	})
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, componentID)
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, kitID)
+	return nil
+}
+
+// DetachComponent исключает актив componentID из состава комплекта kitID
+func (s *AssetService) DetachComponent(ctx context.Context, kitID, componentID int) error {
+	component, err := s.GetAssetByID(ctx, componentID)
+	if err != nil {
+		return err
+	}
+	if component.ParentID == nil || *component.ParentID != kitID {
+		return ErrComponentNotAttached
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.assetRepo.SetParent(ctx, componentID, nil); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.EventAssetUpdated, componentID)
+	})
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, componentID)
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, kitID)
+	return nil
+}
+
+func (s *AssetService) DeleteAsset(ctx context.Context, id int, deletedBy int) error {
+	// Check if asset exists
+	asset, err := s.GetAssetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.assetRepo.Delete(ctx, id, deletedBy); err != nil {
+			return err
+		}
+		_, err := s.outboxRepo.Add(ctx, models.EventAssetDeleted, asset)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionDeleted, id)
+	return nil
+}
+
+func (s *AssetService) RestoreAsset(ctx context.Context, id int) error {
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		restored, err := s.assetRepo.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ErrNothingToRestore
+		}
+		return s.recordEvent(ctx, models.EventAssetRestored, id)
+	})
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionRestored, id)
+	return nil
+}
+
+func (s *AssetService) UpdateStatus(ctx context.Context, assetID, statusID int) error {
+	// Validate asset
+	current, err := s.GetAssetByID(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
 	// Validate status
-	exists, err = s.statusRepo.Exists(ctx, statusID)
+	exists, err := s.statusRepo.Exists(ctx, statusID)
 	if err != nil {
 		return err
 	}
 	if !exists {
-		return ErrStatusNotFound
+		return invalidReference("status_id", ErrStatusNotFound)
 	}
 
-	return s.assetRepo.UpdateStatus(ctx, assetID, statusID)
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.assetRepo.UpdateStatus(ctx, assetID, statusID); err != nil {
+			return err
+		}
+		if statusID == current.StatusID {
+			return nil
+		}
+		asset, err := s.assetRepo.GetByID(ctx, assetID)
+		if err != nil {
+			return err
+		}
+		return s.recordStatusChange(ctx, current.StatusID, asset)
+	})
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, assetID)
+	return nil
 }
 
 func (s *AssetService) UpdateLocation(ctx context.Context, assetID, locationID int) error {
@@ -164,10 +615,71 @@ func (s *AssetService) UpdateLocation(ctx context.Context, assetID, locationID i
 		return err
 	}
 	if !exists {
-		return ErrLocationNotFound
+		return invalidReference("location_id", ErrLocationNotFound)
 	}
 
-	return s.assetRepo.UpdateLocation(ctx, assetID, locationID)
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.assetRepo.UpdateLocation(ctx, assetID, locationID); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.EventAssetUpdated, assetID)
+	})
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, assetID)
+	return nil
+}
+
+// recordEvent добавляет в outbox событие eventType с текущим состоянием
+// актива. Вызывается внутри транзакции, изменившей актив
+func (s *AssetService) recordEvent(ctx context.Context, eventType string, id int) error {
+	asset, err := s.assetRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return ErrAssetNotFound
+	}
+
+	_, err = s.outboxRepo.Add(ctx, eventType, asset)
+	return err
+}
+
+// recordUpdate добавляет событие asset.updated и, если статус актива
+// отличается от previousStatusID, событие asset.status_changed
+func (s *AssetService) recordUpdate(ctx context.Context, id, previousStatusID int) error {
+	asset, err := s.assetRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return ErrAssetNotFound
+	}
+
+	if _, err := s.outboxRepo.Add(ctx, models.EventAssetUpdated, asset); err != nil {
+		return err
+	}
+	if asset.StatusID == previousStatusID {
+		return nil
+	}
+	return s.recordStatusChange(ctx, previousStatusID, asset)
+}
+
+// recordStatusChange добавляет событие asset.status_changed и, если актив
+// перешел в статус списания, событие asset.written_off
+func (s *AssetService) recordStatusChange(ctx context.Context, previousStatusID int, asset *models.Asset) error {
+	_, err := s.outboxRepo.Add(ctx, models.EventAssetStatusChanged, models.StatusChange{
+		AssetID:      asset.ID,
+		FromStatusID: previousStatusID,
+		ToStatusID:   asset.StatusID,
+		Asset:        *asset,
+	})
+	if err != nil || asset.Status != models.StatusWrittenOff {
+		return err
+	}
+	_, err = s.outboxRepo.Add(ctx, models.EventAssetWrittenOff, asset)
+	return err
 }
 
 func (s *AssetService) GetAssetsByStatus(ctx context.Context, statusID int) ([]models.Asset, error) {
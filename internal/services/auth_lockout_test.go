@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/tenant"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestLockoutDuration(t *testing.T) {
+	policy := LoginPolicy{LockoutThreshold: 3, LockoutDuration: time.Minute, MaxLockoutDuration: 10 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := lockoutDuration(policy, tt.failures); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+
+	policy.MaxLockoutDuration = 0
+	if got := lockoutDuration(policy, 6); got != 8*time.Minute {
+		t.Errorf("without a cap: lockoutDuration(6) = %s, want 8m", got)
+	}
+	if got := lockoutDuration(policy, 1000); got <= 0 {
+		t.Errorf("without a cap: lockoutDuration(1000) = %s overflowed", got)
+	}
+}
+
+// lockoutEmployee — строка employees, с которой работает блокировка входа
+type lockoutEmployee struct {
+	failures    int
+	lockedUntil *time.Time
+	audit       []string
+}
+
+func (e *lockoutEmployee) handle(query string, args []driver.Value) (fakeResult, error) {
+	switch {
+	case strings.Contains(query, "FROM employees e") && strings.Contains(query, "e.id = $1"):
+		var lockedUntil driver.Value
+		if e.lockedUntil != nil {
+			lockedUntil = *e.lockedUntil
+		}
+		return fakeResult{
+			columns: make([]string, 14),
+			rows: [][]driver.Value{{
+				int64(1), "Alice", "Engineer", "Alice@example.com", models.RoleEmployee,
+				nil, nil, int64(1), nil, lockedUntil, false, "", "", int64(1),
+			}},
+		}, nil
+	case strings.Contains(query, "failed_logins = failed_logins + 1"):
+		e.failures++
+		return fakeResult{columns: []string{"failed_logins"}, rows: [][]driver.Value{{int64(e.failures)}}}, nil
+	case strings.Contains(query, "SET locked_until = $2"):
+		until := args[1].(time.Time)
+		e.lockedUntil = &until
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "SET failed_logins = 0"):
+		e.failures = 0
+		e.lockedUntil = nil
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "INSERT INTO login_failures"):
+		e.audit = append(e.audit, args[5].(string))
+		return fakeResult{affected: 1}, nil
+	}
+	return fakeResult{}, errors.New("unexpected query: " + query)
+}
+
+func newLockoutService(t *testing.T, employee *lockoutEmployee, clock ratelimit.Clock) *AuthService {
+	db := newFakeDB(t, employee.handle)
+	policy := LoginPolicy{
+		IPLimit:            ratelimit.Limit{Burst: 100, Interval: time.Second},
+		AccountLimit:       ratelimit.Limit{Burst: 2, Interval: time.Hour},
+		LockoutThreshold:   3,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: 4 * time.Minute,
+	}
+	return NewAuthService(
+		repository.NewEmployeeRepository(db),
+		repository.NewLoginAuditRepository(db),
+		nil,
+		nil,
+		ratelimit.NewMemoryStore(),
+		policy,
+		clock,
+		nil,
+		"secret",
+		time.Hour,
+	)
+}
+
+func TestLoginLockout(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	employee := &lockoutEmployee{}
+	s := newLockoutService(t, employee, clock)
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	attempt := LoginAttempt{Email: "alice@example.com", IP: "192.0.2.1"}
+
+	for i := 1; i < 3; i++ {
+		if err := s.countFailure(ctx, 1, clock.Now()); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: err = %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+
+	wantLocks := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	for i, want := range wantLocks {
+		err := s.countFailure(ctx, 1, clock.Now())
+		if apperror.KindOf(err) != apperror.KindTooManyRequests || apperror.RetryAfterOf(err) != want {
+			t.Fatalf("failure %d: err = %v (retry after %s), want lock for %s", i+3, err, apperror.RetryAfterOf(err), want)
+		}
+		if employee.lockedUntil == nil || !employee.lockedUntil.Equal(clock.Now().Add(want)) {
+			t.Fatalf("failure %d: locked until %v, want %v", i+3, employee.lockedUntil, clock.Now().Add(want))
+		}
+	}
+
+	// While locked, even a correct password is refused and the refusal is audited
+	locked, err := s.employeeRepo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(3 * time.Minute)
+	err = s.checkLocked(ctx, attempt, locked, clock.Now())
+	if apperror.KindOf(err) != apperror.KindTooManyRequests || apperror.RetryAfterOf(err) != time.Minute {
+		t.Errorf("locked login: err = %v (retry after %s)", err, apperror.RetryAfterOf(err))
+	}
+	if n := len(employee.audit); n != 1 || employee.audit[0] != models.LoginLocked {
+		t.Errorf("audit = %v, want one %q record", employee.audit, models.LoginLocked)
+	}
+
+	// The lock expires by itself
+	clock.Advance(time.Minute)
+	if err := s.checkLocked(ctx, attempt, locked, clock.Now()); err != nil {
+		t.Errorf("expired lock: err = %v", err)
+	}
+}
+
+func TestUnlockAccount(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	until := clock.Now().Add(time.Hour)
+	employee := &lockoutEmployee{failures: 5, lockedUntil: &until}
+	s := newLockoutService(t, employee, clock)
+	ctx := tenant.WithOrganization(context.Background(), 1)
+
+	// Exhaust the per-account limit for the employee's address
+	for i := 0; i < 2; i++ {
+		if err := s.throttle(ctx, s.accountLimiter, "alice@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.throttle(ctx, s.accountLimiter, "alice@example.com"); apperror.KindOf(err) != apperror.KindTooManyRequests {
+		t.Fatalf("limit not reached: err = %v", err)
+	}
+
+	if err := s.UnlockAccount(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if employee.failures != 0 || employee.lockedUntil != nil {
+		t.Errorf("after unlock: failures = %d, locked until %v", employee.failures, employee.lockedUntil)
+	}
+	if err := s.throttle(ctx, s.accountLimiter, "alice@example.com"); err != nil {
+		t.Errorf("after unlock the account limit still applies: %v", err)
+	}
+
+	// The counter starts over: the next failure does not lock again
+	if err := s.countFailure(ctx, 1, clock.Now()); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("first failure after unlock: err = %v", err)
+	}
+}
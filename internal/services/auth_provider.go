@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AuthProvider проверяет логин и пароль в одном источнике учетных записей
+type AuthProvider interface {
+	// Name — имя провайдера в AUTH_PROVIDERS и в auth_source сотрудника
+	Name() string
+	// Verify возвращает сотрудника, если пароль верен. Если провайдер не
+	// знает логин или пароль неверен, возвращает ErrInvalidCredentials;
+	// другие ошибки означают, что провайдер недоступен
+	Verify(ctx context.Context, login, password string) (*models.Employee, error)
+}
+
+// PasswordProvider проверяет пароли локальных учетных записей по хешу
+// bcrypt. Сотрудников из внешних каталогов он не принимает
+type PasswordProvider struct {
+	employeeRepo *repository.EmployeeRepository
+}
+
+func NewPasswordProvider(employeeRepo *repository.EmployeeRepository) *PasswordProvider {
+	return &PasswordProvider{employeeRepo: employeeRepo}
+}
+
+func (p *PasswordProvider) Name() string {
+	return "password"
+}
+
+func (p *PasswordProvider) Verify(ctx context.Context, login, password string) (*models.Employee, error) {
+	employee, err := p.employeeRepo.GetByEmail(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if employee == nil || employee.AuthSource != "" {
+		// Сравнение с фиктивным хешем выравнивает время ответа для
+		// известных и неизвестных адресов
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(employee.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return employee, nil
+}
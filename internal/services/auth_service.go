@@ -3,10 +3,17 @@ package services
 import (
 	"context"
 	"errors"
+	"inventory-system/internal/apperror"
 	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
 	"inventory-system/internal/repository"
+	"inventory-system/internal/signing"
+	"inventory-system/internal/tenant"
 	"log"
+	"math"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,63 +21,440 @@ import (
 )
 
 var (
-	ErrInvalidToken       = errors.New("invalid token")
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = apperror.Unauthorized("invalid token")
+	ErrInvalidCredentials = apperror.Unauthorized("invalid credentials")
 )
 
+// tooManyLoginAttempts — ответ при блокировке учетной записи и при
+// превышении частоты попыток: для клиента они выглядят одинаково
+const tooManyLoginAttempts = "too many login attempts, try again later"
+
+// maxUserAgentLength — сколько символов User-Agent сохраняется в журнале
+const maxUserAgentLength = 512
+
+// Токен второго шага входа: выдается после проверки пароля сотруднику с
+// двухфакторной аутентификацией и обменивается на токен доступа вместе с
+// кодом. Как токен доступа он не принимается
+const (
+	purposeClaim       = "purpose"
+	purposeTwoFactor   = "two_factor"
+	challengeExpiry    = 5 * time.Minute
+	challengeKeyPrefix = "login-2fa"
+)
+
+// Билет входа через внешнего провайдера (SSO): передается странице входа
+// после возврата с провайдера и обменивается на токен доступа
+const (
+	purposeSSO      = "sso"
+	ssoTicketExpiry = 2 * time.Minute
+)
+
+// LoginPolicy — ограничения на попытки входа. После LockoutThreshold
+// неудачных попыток подряд учетная запись блокируется на LockoutDuration,
+// и каждая следующая неудачная попытка удваивает срок блокировки, но не
+// больше чем до MaxLockoutDuration
+type LoginPolicy struct {
+	IPLimit            ratelimit.Limit
+	AccountLimit       ratelimit.Limit
+	LockoutThreshold   int
+	LockoutDuration    time.Duration
+	MaxLockoutDuration time.Duration
+}
+
+// LoginAttempt — попытка входа вместе с адресом и клиентом, с которых она
+// сделана
+type LoginAttempt struct {
+	Email     string
+	Password  string
+	IP        string
+	UserAgent string
+}
+
+// TwoFactorAttempt — второй шаг входа: токен из первого шага и код
+type TwoFactorAttempt struct {
+	ChallengeToken string
+	Code           string
+	IP             string
+	UserAgent      string
+}
+
 type AuthService struct {
-	employeeRepo *repository.EmployeeRepository
-	secretKey    []byte
-	tokenExpiry  time.Duration
+	employeeRepo   *repository.EmployeeRepository
+	loginAuditRepo *repository.LoginAuditRepository
+	twoFactor      *TwoFactorService
+	providers      []AuthProvider
+	ipLimiter      *ratelimit.Limiter
+	accountLimiter *ratelimit.Limiter
+	codeLimiter    *ratelimit.Limiter
+	policy         LoginPolicy
+	clock          ratelimit.Clock
+	keys           *signing.KeyRing
+	secretKey      []byte
+	tokenExpiry    time.Duration
 }
 
 func NewAuthService(
 	employeeRepo *repository.EmployeeRepository,
+	loginAuditRepo *repository.LoginAuditRepository,
+	twoFactor *TwoFactorService,
+	providers []AuthProvider,
+	limiterStore ratelimit.Store,
+	policy LoginPolicy,
+	clock ratelimit.Clock,
+	keys *signing.KeyRing,
 	secretKey string,
 	tokenExpiry time.Duration,
 ) *AuthService {
 	return &AuthService{
-		employeeRepo: employeeRepo,
-		secretKey:    []byte(secretKey),
-		tokenExpiry:  tokenExpiry,
+		employeeRepo:   employeeRepo,
+		loginAuditRepo: loginAuditRepo,
+		twoFactor:      twoFactor,
+		providers:      providers,
+		ipLimiter:      ratelimit.NewLimiter(limiterStore, "login-ip", policy.IPLimit, clock),
+		accountLimiter: ratelimit.NewLimiter(limiterStore, "login-account", policy.AccountLimit, clock),
+		codeLimiter:    ratelimit.NewLimiter(limiterStore, challengeKeyPrefix, policy.AccountLimit, clock),
+		policy:         policy,
+		clock:          clock,
+		keys:           keys,
+		secretKey:      []byte(secretKey),
+		tokenExpiry:    tokenExpiry,
 	}
 }
-func (s *AuthService) Authenticate(ctx context.Context, email, password string) (*models.Employee, error) {
-	log.Printf("Authenticating user: %s", email)
-	employee, err := s.employeeRepo.GetByEmail(ctx, email)
+
+// Authenticate проверяет логин и пароль у провайдеров по порядку. Частота
+// попыток ограничена отдельно для IP-адреса и для логина; неудачные
+// попытки записываются в журнал и при повторении блокируют учетную запись.
+// Если у сотрудника включена двухфакторная аутентификация, вход завершает
+// VerifyTwoFactor
+func (s *AuthService) Authenticate(ctx context.Context, attempt LoginAttempt) (*models.Employee, error) {
+	email := strings.ToLower(strings.TrimSpace(attempt.Email))
+
+	if err := s.throttle(ctx, s.ipLimiter, attempt.IP); err != nil {
+		return nil, err
+	}
+	if err := s.throttle(ctx, s.accountLimiter, email); err != nil {
+		return nil, err
+	}
+
+	// Логин внешнего каталога может не быть email: тогда сотрудник
+	// становится известен только после проверки пароля
+	employee, err := s.employeeRepo.GetByEmail(ctx, attempt.Email)
 	if err != nil {
-		log.Printf("Database error: %v", err)
 		return nil, err
 	}
-	if employee == nil {
-		log.Printf("User not found: %s", email)
-		return nil, ErrInvalidCredentials
+	if employee != nil {
+		ctx = tenant.WithOrganization(ctx, employee.OrganizationID)
 	}
 
-	log.Printf("Stored hash: %s", employee.PasswordHash)
+	now := s.clock.Now().UTC()
+	if err := s.checkLocked(ctx, attempt, employee, now); err != nil {
+		return nil, err
+	}
 
-	// Сравнение пароля с хешем
-	err = bcrypt.CompareHashAndPassword([]byte(employee.PasswordHash), []byte(password))
+	authenticated, err := s.verifyPassword(ctx, attempt.Email, attempt.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		if employee == nil {
+			if err := s.recordFailure(ctx, attempt, nil, models.LoginUnknownEmail); err != nil {
+				return nil, err
+			}
+			return nil, ErrInvalidCredentials
+		}
+		if err := s.recordFailure(ctx, attempt, employee, models.LoginWrongPassword); err != nil {
+			return nil, err
+		}
+		return nil, s.countFailure(ctx, employee.ID, now)
+	}
 	if err != nil {
-		log.Printf("Password mismatch: %v", err)
+		return nil, err
+	}
+
+	employee = authenticated
+	ctx = tenant.WithOrganization(ctx, employee.OrganizationID)
+	if err := s.checkLocked(ctx, attempt, employee, now); err != nil {
+		return nil, err
+	}
 
-		// Дополнительная отладочная информация
-		log.Printf("Input password: %s", password)
-		log.Printf("Hash length: %d", len(employee.PasswordHash))
+	if employee.TwoFactorEnabled {
+		// Счетчики неудач сбрасываются только после проверки кода
+		return employee, nil
+	}
+	if err := s.loginSucceeded(ctx, employee); err != nil {
+		return nil, err
+	}
+	return employee, nil
+}
 
-		return nil, ErrInvalidCredentials
+// verifyPassword опрашивает провайдеров по порядку; сотрудника определяет
+// первый, принявший пароль. Если ни один не принял, но какой-то был
+// недоступен, возвращается его ошибка: сбой каталога не должен выглядеть
+// как неверный пароль
+func (s *AuthService) verifyPassword(ctx context.Context, login, password string) (*models.Employee, error) {
+	var failure error
+	for _, provider := range s.providers {
+		employee, err := provider.Verify(ctx, login, password)
+		if err == nil {
+			return employee, nil
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		log.Printf("Auth provider %s failed: %v", provider.Name(), err)
+		if failure == nil {
+			failure = err
+		}
+	}
+	if failure != nil {
+		return nil, failure
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// checkLocked отклоняет вход, если он заблокирован после неудачных попыток
+func (s *AuthService) checkLocked(ctx context.Context, attempt LoginAttempt, employee *models.Employee, now time.Time) error {
+	if employee == nil || employee.LockedUntil == nil || !employee.LockedUntil.After(now) {
+		return nil
+	}
+	if err := s.recordFailure(ctx, attempt, employee, models.LoginLocked); err != nil {
+		return err
+	}
+	return apperror.TooManyRequests(tooManyLoginAttempts, employee.LockedUntil.Sub(now))
+}
+
+// VerifyTwoFactor завершает вход сотрудника с двухфакторной
+// аутентификацией: проверяет токен первого шага и код из приложения или
+// резервный код. Неверные коды учитываются так же, как неверные пароли
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, attempt TwoFactorAttempt) (*models.Employee, error) {
+	if err := s.throttle(ctx, s.ipLimiter, attempt.IP); err != nil {
+		return nil, err
 	}
 
+	claims, err := s.parsePurposeToken(attempt.ChallengeToken)
+	if err != nil || claims[purposeClaim] != purposeTwoFactor {
+		return nil, ErrInvalidToken
+	}
+	employee, err := s.tokenEmployee(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tenant.WithOrganization(ctx, employee.OrganizationID)
+
+	if err := s.throttle(ctx, s.codeLimiter, strconv.Itoa(employee.ID)); err != nil {
+		return nil, err
+	}
+
+	login := LoginAttempt{Email: employee.Email, IP: attempt.IP, UserAgent: attempt.UserAgent}
+	now := s.clock.Now().UTC()
+	if err := s.checkLocked(ctx, login, employee, now); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.twoFactor.VerifyCode(ctx, employee.ID, attempt.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.recordFailure(ctx, login, employee, models.LoginWrongCode); err != nil {
+			return nil, err
+		}
+		return nil, s.countFailure(ctx, employee.ID, now)
+	}
+
+	if err := s.codeLimiter.Reset(ctx, strconv.Itoa(employee.ID)); err != nil {
+		return nil, err
+	}
+	if err := s.loginSucceeded(ctx, employee); err != nil {
+		return nil, err
+	}
 	return employee, nil
 }
 
+// loginSucceeded сбрасывает счетчики неудачных попыток после входа
+func (s *AuthService) loginSucceeded(ctx context.Context, employee *models.Employee) error {
+	if _, err := s.employeeRepo.ResetLoginFailures(ctx, employee.ID); err != nil {
+		return err
+	}
+	return s.accountLimiter.Reset(ctx, strings.ToLower(strings.TrimSpace(employee.Email)))
+}
+
+// throttle забирает токен попытки входа для ключа key. Пустой ключ не
+// ограничивается
+func (s *AuthService) throttle(ctx context.Context, limiter *ratelimit.Limiter, key string) error {
+	if key == "" {
+		return nil
+	}
+	decision, err := limiter.Allow(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed {
+		return apperror.TooManyRequests(tooManyLoginAttempts, decision.RetryAfter)
+	}
+	return nil
+}
+
+// recordFailure записывает неудачную попытку входа в журнал. Пароль не
+// записывается ни в журнал, ни в лог
+func (s *AuthService) recordFailure(ctx context.Context, attempt LoginAttempt, employee *models.Employee, reason string) error {
+	failure := models.LoginFailure{
+		Email:     strings.ToValidUTF8(truncate(attempt.Email, 255), ""),
+		IP:        attempt.IP,
+		UserAgent: strings.ToValidUTF8(truncate(attempt.UserAgent, maxUserAgentLength), ""),
+		Reason:    reason,
+	}
+	if employee != nil {
+		failure.EmployeeID = &employee.ID
+		failure.OrganizationID = &employee.OrganizationID
+	} else {
+		// Попытка с неизвестным адресом не относится ни к одной организации
+		ctx = tenant.WithAllOrganizations(ctx)
+	}
+	log.Printf("Failed login for %q from %s: %s", failure.Email, failure.IP, reason)
+
+	return s.loginAuditRepo.RecordFailure(ctx, failure)
+}
+
+// countFailure увеличивает счетчик неудачных попыток сотрудника и при
+// достижении порога блокирует вход. Возвращает ошибку для ответа клиенту
+func (s *AuthService) countFailure(ctx context.Context, employeeID int, now time.Time) error {
+	failures, err := s.employeeRepo.RecordLoginFailure(ctx, employeeID)
+	if err != nil {
+		return err
+	}
+	if s.policy.LockoutThreshold <= 0 || failures < s.policy.LockoutThreshold {
+		return ErrInvalidCredentials
+	}
+
+	duration := lockoutDuration(s.policy, failures)
+	if err := s.employeeRepo.LockLogin(ctx, employeeID, now.Add(duration)); err != nil {
+		return err
+	}
+	log.Printf("Login for employee %d locked for %s after %d failed attempts", employeeID, duration, failures)
+	return apperror.TooManyRequests(tooManyLoginAttempts, duration)
+}
+
+// lockoutDuration возвращает срок блокировки после failures неудачных
+// попыток подряд. Нулевой MaxLockoutDuration не ограничивает срок
+func lockoutDuration(policy LoginPolicy, failures int) time.Duration {
+	duration := policy.LockoutDuration
+	for i := policy.LockoutThreshold; i < failures && duration < math.MaxInt64/2; i++ {
+		if policy.MaxLockoutDuration > 0 && duration >= policy.MaxLockoutDuration {
+			break
+		}
+		duration *= 2
+	}
+	if policy.MaxLockoutDuration > 0 && duration > policy.MaxLockoutDuration {
+		duration = policy.MaxLockoutDuration
+	}
+	return duration
+}
+
+// UnlockAccount снимает блокировку входа сотрудника и обнуляет счетчик
+// неудачных попыток
+func (s *AuthService) UnlockAccount(ctx context.Context, employeeID int) error {
+	employee, err := s.employeeRepo.GetByID(ctx, employeeID)
+	if err != nil {
+		return err
+	}
+	if employee == nil {
+		return ErrEmployeeNotFound
+	}
+
+	if _, err := s.employeeRepo.ResetLoginFailures(ctx, employeeID); err != nil {
+		return err
+	}
+	return s.accountLimiter.Reset(ctx, strings.ToLower(employee.Email))
+}
+
+// Размер страницы журнала неудачных входов
+const (
+	DefaultLoginFailureLimit = 50
+	MaxLoginFailureLimit     = 500
+)
+
+// GetLoginFailures возвращает журнал неудачных попыток входа
+func (s *AuthService) GetLoginFailures(ctx context.Context, filter models.LoginFailureFilter) ([]models.LoginFailure, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultLoginFailureLimit
+	}
+	if filter.Limit > MaxLoginFailureLimit {
+		filter.Limit = MaxLoginFailureLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.loginAuditRepo.GetFailures(ctx, filter)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash возвращает хеш случайного пароля для сравнения при
+// входе с неизвестным email
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password-for-timing"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// GenerateToken выдает токен доступа, подписанный текущим ключом из
+// набора. Заголовок kid указывает ключ, которым токен проверяется, в том
+// числе другими сервисами по /.well-known/jwks.json
 func (s *AuthService) GenerateToken(employee *models.Employee) (string, time.Time, error) {
-	expiresAt := time.Now().Add(s.tokenExpiry)
+	issuedAt := s.clock.Now()
+	expiresAt := issuedAt.Add(s.tokenExpiry)
+
+	key, err := s.keys.SigningKey(issuedAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
 
 	claims := jwt.MapClaims{
 		"sub":  employee.ID,
+		"iat":  issuedAt.Unix(),
 		"exp":  expiresAt.Unix(),
 		"role": employee.Role,
+		"org":  employee.OrganizationID,
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	tokenString, err := token.SignedString(key.Private)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// GenerateChallenge выдает токен второго шага входа для сотрудника с
+// двухфакторной аутентификацией
+func (s *AuthService) GenerateChallenge(employee *models.Employee) (string, time.Time, error) {
+	return s.generatePurposeToken(employee, purposeTwoFactor, challengeExpiry)
+}
+
+// GenerateSSOTicket выдает билет входа после проверки сотрудника внешним
+// провайдером. Билет передается странице входа и обменивается на токен
+// доступа в CompleteSSO
+func (s *AuthService) GenerateSSOTicket(employee *models.Employee) (string, time.Time, error) {
+	return s.generatePurposeToken(employee, purposeSSO, ssoTicketExpiry)
+}
+
+// generatePurposeToken выдает короткоживущий токен с утверждением purpose.
+// Он подписывается секретным ключом приложения, а не ключами из набора:
+// сервисы, проверяющие токены доступа по JWKS, не должны принимать его
+func (s *AuthService) generatePurposeToken(employee *models.Employee, purpose string, expiry time.Duration) (string, time.Time, error) {
+	issuedAt := s.clock.Now()
+	expiresAt := issuedAt.Add(expiry)
+
+	claims := jwt.MapClaims{
+		"sub":        employee.ID,
+		"iat":        issuedAt.Unix(),
+		"exp":        expiresAt.Unix(),
+		"org":        employee.OrganizationID,
+		purposeClaim: purpose,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -82,36 +466,136 @@ func (s *AuthService) GenerateToken(employee *models.Employee) (string, time.Tim
 	return tokenString, expiresAt, nil
 }
 
+// CompleteSSO обменивает билет входа через внешнего провайдера на
+// сотрудника. Блокировка входа действует и здесь; если у сотрудника
+// включена двухфакторная аутентификация, вход завершает VerifyTwoFactor
+func (s *AuthService) CompleteSSO(ctx context.Context, ticket, ip, userAgent string) (*models.Employee, error) {
+	if err := s.throttle(ctx, s.ipLimiter, ip); err != nil {
+		return nil, err
+	}
+
+	claims, err := s.parsePurposeToken(ticket)
+	if err != nil || claims[purposeClaim] != purposeSSO {
+		return nil, ErrInvalidToken
+	}
+	employee, err := s.tokenEmployee(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tenant.WithOrganization(ctx, employee.OrganizationID)
+
+	login := LoginAttempt{Email: employee.Email, IP: ip, UserAgent: userAgent}
+	if err := s.checkLocked(ctx, login, employee, s.clock.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	if employee.TwoFactorEnabled {
+		return employee, nil
+	}
+	if err := s.loginSucceeded(ctx, employee); err != nil {
+		return nil, err
+	}
+	return employee, nil
+}
+
 func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*models.Employee, error) {
 	// Удаляем префикс "Bearer" если он есть
 	if strings.HasPrefix(tokenString, "Bearer ") {
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return s.secretKey, nil
-	})
-
+	claims, err := s.parseAccessToken(tokenString)
 	if err != nil {
+		return nil, err
+	}
+	// Токены второго шага входа не дают доступа к API
+	if _, ok := claims[purposeClaim]; ok {
 		return nil, ErrInvalidToken
 	}
+	return s.tokenEmployee(ctx, claims)
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		employeeID := int(claims["sub"].(float64))
-		employee, err := s.employeeRepo.GetByID(ctx, employeeID)
+// JWKS возвращает открытые ключи, которыми проверяются токены доступа
+func (s *AuthService) JWKS() signing.JWKSet {
+	return s.keys.JWKS(s.clock.Now())
+}
+
+// parseAccessToken проверяет подпись токена доступа ключом kid из набора
+// и срок действия токена и возвращает его утверждения
+func (s *AuthService) parseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	now := s.clock.Now()
+	return parseClaims(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := s.keys.Key(kid, now)
 		if err != nil {
 			return nil, err
 		}
-		if employee == nil {
+		// Алгоритм задает ключ, а не заголовок токена
+		if token.Method.Alg() != key.Algorithm {
 			return nil, ErrInvalidToken
 		}
-		return employee, nil
+		return key.Public(), nil
+	}, jwt.WithValidMethods([]string{signing.AlgorithmRS256, signing.AlgorithmEdDSA}), jwt.WithTimeFunc(s.clock.Now))
+}
+
+// parsePurposeToken проверяет подпись и срок действия токена из
+// generatePurposeToken и возвращает его утверждения
+func (s *AuthService) parsePurposeToken(tokenString string) (jwt.MapClaims, error) {
+	return parseClaims(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return s.secretKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithTimeFunc(s.clock.Now))
+}
+
+func parseClaims(tokenString string, keyFunc jwt.Keyfunc, options ...jwt.ParserOption) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, keyFunc, options...)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// tokenEmployee возвращает сотрудника, которому выдан токен. Сотрудник
+// ищется только в организации из утверждения org, поэтому токены без него,
+// выданные до появления организаций, отклоняются, как и токены, выданные
+// до смены пароля
+func (s *AuthService) tokenEmployee(ctx context.Context, claims jwt.MapClaims) (*models.Employee, error) {
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return nil, ErrInvalidToken
 	}
+	org, ok := claims["org"].(float64)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	employee, err := s.employeeRepo.GetByID(tenant.WithOrganization(ctx, int(org)), int(sub))
+	if err != nil {
+		return nil, err
+	}
+	if employee == nil || issuedBeforePasswordChange(claims, employee) {
+		return nil, ErrInvalidToken
+	}
+	return employee, nil
+}
 
-	return nil, ErrInvalidToken
+// issuedBeforePasswordChange сообщает, выдан ли токен до последней смены
+// пароля сотрудника. Время выдачи хранится с точностью до секунды, поэтому
+// токен, выданный в ту же секунду, что и смена пароля, остается
+// действительным. Токены без iat выданы до появления этой проверки
+func issuedBeforePasswordChange(claims jwt.MapClaims, employee *models.Employee) bool {
+	if employee.PasswordChangedAt == nil {
+		return false
+	}
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return true
+	}
+	return issuedAt.Unix() < employee.PasswordChangedAt.Unix()
 }
 
 func (s *AuthService) InvalidateToken(tokenString string) error {
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/signing"
+)
+
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestAccessTokensAcrossKeyRotation(t *testing.T) {
+	db := newFakeDB(t, func(query string, args []driver.Value) (fakeResult, error) {
+		if strings.Contains(query, "FROM employees e") && strings.Contains(query, "e.id = $1") {
+			return fakeResult{
+				columns: make([]string, 14),
+				rows: [][]driver.Value{{
+					int64(1), "Alice", "Engineer", "alice@example.com", models.RoleEmployee,
+					nil, nil, int64(1), nil, nil, false, "", "", int64(1),
+				}},
+			}, nil
+		}
+		t.Errorf("unexpected query %q", query)
+		return fakeResult{}, nil
+	})
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	rotation := start.Add(24 * time.Hour)
+	old, err := signing.Generate(signing.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.ActivatesAt = start
+	next, err := signing.Generate(signing.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.ActivatesAt = rotation
+
+	keys := signing.NewKeyRing()
+	keys.Replace([]*signing.Key{old})
+	clock := &fakeClock{now: rotation.Add(-time.Minute)}
+	// Токены действуют дольше, чем публикуется старый ключ: после снятия
+	// ключа с публикации токен отклоняет проверка kid, а не срок токена
+	auth := NewAuthService(
+		repository.NewEmployeeRepository(db), nil, nil, nil,
+		ratelimit.NewMemoryStore(), LoginPolicy{}, clock, keys, "test-secret", 24*time.Hour,
+	)
+	employee := &models.Employee{ID: 1, Role: models.RoleEmployee, OrganizationID: 1}
+
+	oldToken, _, err := auth.GenerateToken(employee)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ротация: старый ключ перестает подписывать и публикуется еще час
+	old.RetiresAt = rotation
+	old.ExpiresAt = rotation.Add(time.Hour)
+	keys.Replace([]*signing.Key{old, next})
+	clock.now = rotation.Add(30 * time.Minute)
+
+	newToken, _, err := auth.GenerateToken(employee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKeyID(t, newToken); kid != next.ID {
+		t.Errorf("token after rotation is signed with %q, want the new key %q", kid, next.ID)
+	}
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := auth.ValidateToken(context.Background(), token); err != nil {
+			t.Errorf("%s key token during overlap: %v", name, err)
+		}
+	}
+
+	clock.now = old.ExpiresAt
+	if _, err := auth.ValidateToken(context.Background(), oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token of the retired key: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := auth.ValidateToken(context.Background(), newToken); err != nil {
+		t.Errorf("new key token after the old key expired: %v", err)
+	}
+}
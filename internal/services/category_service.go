@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"strings"
+)
+
+var (
+	ErrCategoryNotFound      = apperror.NotFound("category not found")
+	ErrCategoryNameRequired  = apperror.Validation("category name is required", nil)
+	ErrCategoryAlreadyExists = apperror.Conflict("category already exists")
+	ErrCategoryHasAssets     = apperror.Conflict("cannot delete category with assigned assets")
+	ErrCategoryHasChildren   = apperror.Conflict("cannot delete category with subcategories")
+	ErrCategoryCycle         = apperror.Validation("category cannot be moved under itself or its subcategory", nil)
+	ErrCategoryMergeSelf     = apperror.Validation("cannot merge category into itself", nil)
+)
+
+type CategoryService struct {
+	categoryRepo *repository.CategoryRepository
+	bus          *events.Bus
+}
+
+func NewCategoryService(categoryRepo *repository.CategoryRepository, bus *events.Bus) *CategoryService {
+	return &CategoryService{
+		categoryRepo: categoryRepo,
+		bus:          bus,
+	}
+}
+
+func (s *CategoryService) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	return s.categoryRepo.GetAll(ctx)
+}
+
+// GetCategoryTree возвращает категории в виде дерева
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]models.Category, error) {
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCategoryTree(categories), nil
+}
+
+func buildCategoryTree(categories []models.Category) []models.Category {
+	children := make(map[int][]models.Category)
+	for _, c := range categories {
+		parentID := 0
+		if c.ParentID != nil {
+			parentID = *c.ParentID
+		}
+		children[parentID] = append(children[parentID], c)
+	}
+
+	var attach func(parentID int) []models.Category
+	attach = func(parentID int) []models.Category {
+		nodes := children[parentID]
+		for i := range nodes {
+			nodes[i].Children = attach(nodes[i].ID)
+		}
+		return nodes
+	}
+
+	tree := attach(0)
+	if tree == nil {
+		tree = []models.Category{}
+	}
+	return tree
+}
+
+func (s *CategoryService) GetCategoryByID(ctx context.Context, id int) (*models.Category, error) {
+	category, err := s.categoryRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, category models.Category) (int, error) {
+	if err := s.validateCategory(ctx, &category); err != nil {
+		return 0, err
+	}
+
+	id, err := s.categoryRepo.Create(ctx, category)
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityCategory, events.ActionCreated, id)
+	return id, nil
+}
+
+func (s *CategoryService) UpdateCategory(ctx context.Context, category models.Category) error {
+	// Check if category exists
+	exists, err := s.categoryRepo.Exists(ctx, category.ID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCategoryNotFound
+	}
+
+	if err := s.validateCategory(ctx, &category); err != nil {
+		return err
+	}
+
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityCategory, events.ActionUpdated, category.ID)
+	return nil
+}
+
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int) error {
+	// Check if category exists
+	exists, err := s.categoryRepo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCategoryNotFound
+	}
+
+	// Check if category has subcategories
+	hasChildren, err := s.categoryRepo.HasChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return ErrCategoryHasChildren
+	}
+
+	// Check if category has assets
+	hasAssets, err := s.categoryRepo.HasAssets(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasAssets {
+		return ErrCategoryHasAssets
+	}
+
+	if err := s.categoryRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityCategory, events.ActionDeleted, id)
+	return nil
+}
+
+func (s *CategoryService) validateCategory(ctx context.Context, category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return ErrCategoryNameRequired
+	}
+
+	// Validate parent and prevent cycles
+	if category.ParentID != nil {
+		if *category.ParentID == category.ID {
+			return ErrCategoryCycle
+		}
+
+		exists, err := s.categoryRepo.Exists(ctx, *category.ParentID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return invalidReference("parent_id", ErrCategoryNotFound)
+		}
+
+		if category.ID != 0 {
+			isDescendant, err := s.categoryRepo.IsDescendant(ctx, category.ID, *category.ParentID)
+			if err != nil {
+				return err
+			}
+			if isDescendant {
+				return ErrCategoryCycle
+			}
+		}
+	}
+
+	// Check if name is taken by another category on the same level
+	taken, err := s.categoryRepo.NameExists(ctx, category.Name, category.ParentID, category.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrCategoryAlreadyExists
+	}
+
+	return validateFieldDefinitions(category.Fields)
+}
+
+// GetEffectiveFields возвращает схему полей категории вместе с полями,
+// унаследованными от родительских категорий. Поле подкатегории
+// переопределяет одноименное поле родителя
+func (s *CategoryService) GetEffectiveFields(ctx context.Context, id int) ([]models.CustomField, error) {
+	return effectiveFields(ctx, s.categoryRepo, id)
+}
+
+func effectiveFields(ctx context.Context, categoryRepo *repository.CategoryRepository, id int) ([]models.CustomField, error) {
+	chain, err := categoryRepo.GetAncestors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, ErrCategoryNotFound
+	}
+
+	var fields []models.CustomField
+	index := make(map[string]int)
+	for _, c := range chain {
+		for _, f := range c.Fields {
+			if i, ok := index[f.Key]; ok {
+				fields[i] = f
+				continue
+			}
+			index[f.Key] = len(fields)
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// MergeCategories объединяет дубликат sourceID с категорией targetID:
+// активы и подкатегории переносятся, поля исходной категории, которых
+// нет у целевой, добавляются к ней как необязательные
+func (s *CategoryService) MergeCategories(ctx context.Context, sourceID, targetID int) error {
+	if sourceID == targetID {
+		return ErrCategoryMergeSelf
+	}
+
+	source, err := s.GetCategoryByID(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	target, err := s.GetCategoryByID(ctx, targetID)
+	if errors.Is(err, ErrCategoryNotFound) {
+		return invalidReference("target_id", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Target inside the source subtree would end up as its own parent
+	isDescendant, err := s.categoryRepo.IsDescendant(ctx, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+	if isDescendant {
+		return ErrCategoryCycle
+	}
+
+	fields := target.Fields
+	existing := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		existing[f.Key] = true
+	}
+	for _, f := range source.Fields {
+		if !existing[f.Key] {
+			f.Required = false
+			fields = append(fields, f)
+		}
+	}
+
+	if err := s.categoryRepo.Merge(ctx, sourceID, targetID, fields); err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityCategory, events.ActionDeleted, sourceID)
+	s.bus.Publish(ctx, events.EntityCategory, events.ActionUpdated, targetID)
+	return nil
+}
+
+// FindDuplicateCategories группирует категории, названия которых совпадают
+// без учета регистра, пробелов и окончаний множественного числа
+func (s *CategoryService) FindDuplicateCategories(ctx context.Context) ([][]models.Category, error) {
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]models.Category)
+	var order []string
+	for _, c := range categories {
+		key := normalizeCategoryName(c.Name)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], c)
+	}
+
+	duplicates := [][]models.Category{}
+	for _, key := range order {
+		if len(groups[key]) > 1 {
+			duplicates = append(duplicates, groups[key])
+		}
+	}
+	return duplicates, nil
+}
+
+func normalizeCategoryName(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	for _, suffix := range []string{"и", "ы", "es", "s"} {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && len([]rune(trimmed)) > 2 {
+			return trimmed
+		}
+	}
+	return name
+}
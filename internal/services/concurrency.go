@@ -0,0 +1,19 @@
+package services
+
+import (
+	"errors"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/repository"
+)
+
+// ErrVersionConflict возвращается, когда запись изменили после того,
+// как клиент получил ее версию
+var ErrVersionConflict = apperror.PreconditionFailed("record was modified by another user")
+
+// checkVersion переводит конфликт версий репозитория в ошибку сервиса
+func checkVersion(version int, err error) (int, error) {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return 0, ErrVersionConflict
+	}
+	return version, err
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCustomFields    = apperror.Validation("invalid custom fields", nil)
+	ErrInvalidFieldDefinition = apperror.Validation("invalid custom field definition", nil)
+	ErrCustomFieldsNoCategory = apperror.Validation("custom fields require a category", nil)
+)
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CustomFieldErrors собирает ошибки по каждому дополнительному полю,
+// чтобы клиент увидел их все за один запрос
+type CustomFieldErrors map[string]string
+
+func (e CustomFieldErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+": "+e[key])
+	}
+	return ErrInvalidCustomFields.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Unwrap связывает ошибки полей с ErrInvalidCustomFields, чтобы работали
+// errors.Is и определение вида ошибки через apperror
+func (e CustomFieldErrors) Unwrap() error {
+	return ErrInvalidCustomFields
+}
+
+// FieldErrors возвращает ошибки по каждому полю
+func (e CustomFieldErrors) FieldErrors() map[string]string {
+	return e
+}
+
+// validateFieldDefinitions проверяет схему дополнительных полей категории
+func validateFieldDefinitions(fields []models.CustomField) error {
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if !fieldKeyPattern.MatchString(f.Key) {
+			return fmt.Errorf("%w: key %q must be lowercase latin letters, digits or underscores", ErrInvalidFieldDefinition, f.Key)
+		}
+		if seen[f.Key] {
+			return fmt.Errorf("%w: duplicate key %q", ErrInvalidFieldDefinition, f.Key)
+		}
+		seen[f.Key] = true
+
+		switch f.Type {
+		case models.FieldTypeText:
+			if f.Pattern != "" {
+				if _, err := regexp.Compile(f.Pattern); err != nil {
+					return fmt.Errorf("%w: field %q has invalid pattern", ErrInvalidFieldDefinition, f.Key)
+				}
+			}
+		case models.FieldTypeNumber:
+			if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+				return fmt.Errorf("%w: field %q has min greater than max", ErrInvalidFieldDefinition, f.Key)
+			}
+		case models.FieldTypeEnum:
+			if len(f.Options) == 0 {
+				return fmt.Errorf("%w: enum field %q has no options", ErrInvalidFieldDefinition, f.Key)
+			}
+		case models.FieldTypeDate, models.FieldTypeBoolean:
+		default:
+			return fmt.Errorf("%w: field %q has unknown type %q", ErrInvalidFieldDefinition, f.Key, f.Type)
+		}
+	}
+	return nil
+}
+
+// validateCustomFields проверяет значения дополнительных полей актива по
+// схеме категории и возвращает их в нормализованном виде
+func validateCustomFields(fields []models.CustomField, values map[string]interface{}) (map[string]interface{}, error) {
+	errs := CustomFieldErrors{}
+	result := make(map[string]interface{})
+
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.Key] = true
+
+		value, ok := values[f.Key]
+		if !ok || value == nil || value == "" {
+			if f.Required {
+				errs[f.Key] = "is required"
+			}
+			continue
+		}
+
+		normalized, msg := validateFieldValue(f, value)
+		if msg != "" {
+			errs[f.Key] = msg
+			continue
+		}
+		result[f.Key] = normalized
+	}
+
+	for key := range values {
+		if !known[key] {
+			errs[key] = "is not defined for this category"
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return result, nil
+}
+
+func validateFieldValue(f models.CustomField, value interface{}) (interface{}, string) {
+	switch f.Type {
+	case models.FieldTypeText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, "must be a string"
+		}
+		if f.MaxLength > 0 && len([]rune(s)) > f.MaxLength {
+			return nil, fmt.Sprintf("must be at most %d characters", f.MaxLength)
+		}
+		if f.Pattern != "" {
+			re, err := regexp.Compile(f.Pattern)
+			if err != nil || !re.MatchString(s) {
+				return nil, "has invalid format"
+			}
+		}
+		return s, ""
+
+	case models.FieldTypeNumber:
+		n, ok := value.(float64)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, "must be a number"
+		}
+		if f.Min != nil && n < *f.Min {
+			return nil, fmt.Sprintf("must be at least %v", *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return nil, fmt.Sprintf("must be at most %v", *f.Max)
+		}
+		return n, ""
+
+	case models.FieldTypeDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, "must be a date in YYYY-MM-DD format"
+		}
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, "must be a date in YYYY-MM-DD format"
+		}
+		return d.Format("2006-01-02"), ""
+
+	case models.FieldTypeEnum:
+		s, ok := value.(string)
+		if !ok {
+			return nil, "must be one of: " + strings.Join(f.Options, ", ")
+		}
+		for _, option := range f.Options {
+			if s == option {
+				return s, ""
+			}
+		}
+		return nil, "must be one of: " + strings.Join(f.Options, ", ")
+
+	case models.FieldTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, "must be true or false"
+		}
+		return b, ""
+	}
+
+	return nil, "has unknown type"
+}
@@ -2,33 +2,44 @@ package services
 
 import (
 	"context"
-	"errors"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/mergepatch"
 	"inventory-system/internal/models"
 	"inventory-system/internal/repository"
 )
 
 var (
-	ErrDepartmentNotFound     = errors.New("department not found")
-	ErrDepartmentHasEmployees = errors.New("cannot delete department with employees")
+	ErrDepartmentNotFound     = apperror.NotFound("department not found")
+	ErrDepartmentHasEmployees = apperror.Conflict("cannot delete department with employees")
 )
 
 type DepartmentService struct {
 	departmentRepo *repository.DepartmentRepository
 	employeeRepo   *repository.EmployeeRepository
+	transactor     *repository.Transactor
+	notifier       *NotificationService
+	bus            *events.Bus
 }
 
 func NewDepartmentService(
 	departmentRepo *repository.DepartmentRepository,
 	employeeRepo *repository.EmployeeRepository,
+	transactor *repository.Transactor,
+	notifier *NotificationService,
+	bus *events.Bus,
 ) *DepartmentService {
 	return &DepartmentService{
 		departmentRepo: departmentRepo,
 		employeeRepo:   employeeRepo,
+		transactor:     transactor,
+		notifier:       notifier,
+		bus:            bus,
 	}
 }
 
-func (s *DepartmentService) GetAllDepartments(ctx context.Context) ([]models.Department, error) {
-	return s.departmentRepo.GetAll(ctx)
+func (s *DepartmentService) GetAllDepartments(ctx context.Context, includeDeleted bool) ([]models.Department, error) {
+	return s.departmentRepo.GetAll(ctx, includeDeleted)
 }
 
 func (s *DepartmentService) GetDepartmentByID(ctx context.Context, id int) (*models.Department, error) {
@@ -49,28 +60,130 @@ func (s *DepartmentService) CreateDepartment(ctx context.Context, department mod
 			return 0, err
 		}
 		if !exists {
-			return 0, ErrEmployeeNotFound
+			return 0, invalidReference("head_id", ErrEmployeeNotFound)
 		}
 	}
 
-	return s.departmentRepo.Create(ctx, department)
+	var id int
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.departmentRepo.Create(ctx, department)
+		if err != nil {
+			return err
+		}
+		department.ID = id
+		return s.notifier.NotifyDepartmentHead(ctx, department, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityDepartment, events.ActionCreated, id)
+	return id, nil
 }
 
-func (s *DepartmentService) UpdateDepartment(ctx context.Context, department models.Department) error {
+// UpdateDepartment сохраняет отдел с версией department.Version и возвращает
+// новую версию. Если отдел успели изменить, возвращает ErrVersionConflict
+func (s *DepartmentService) UpdateDepartment(ctx context.Context, department models.Department) (int, error) {
+	current, err := s.GetDepartmentByID(ctx, department.ID)
+	if err != nil {
+		return 0, err
+	}
+
 	if department.HeadID != nil {
 		exists, err := s.employeeRepo.Exists(ctx, *department.HeadID)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		if !exists {
-			return ErrEmployeeNotFound
+			return 0, invalidReference("head_id", ErrEmployeeNotFound)
+		}
+	}
+
+	var version int
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		version, err = checkVersion(s.departmentRepo.Update(ctx, department))
+		if err != nil {
+			return err
 		}
+		return s.notifier.NotifyDepartmentHead(ctx, department, current.HeadID)
+	})
+	if err != nil {
+		return 0, err
 	}
+	s.bus.Publish(ctx, events.EntityDepartment, events.ActionUpdated, department.ID)
+	return version, nil
+}
 
-	return s.departmentRepo.Update(ctx, department)
+var departmentPatchFields = patchFields{
+	"name":     false,
+	"location": false,
+	"head_id":  true,
 }
 
-func (s *DepartmentService) DeleteDepartment(ctx context.Context, id int) error {
+// PatchDepartment применяет к отделу JSON Merge Patch, проверяя и сохраняя
+// только измененные поля
+func (s *DepartmentService) PatchDepartment(ctx context.Context, id, version int, patch mergepatch.Patch) (*models.Department, error) {
+	current, err := s.GetDepartmentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	var patched models.Department
+	var request models.DepartmentRequest
+	if err := applyPatch(current, patch, departmentPatchFields, &patched, &request); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]interface{})
+
+	if patched.Name != current.Name {
+		changes["name"] = patched.Name
+	}
+	if patched.Location != current.Location {
+		changes["location"] = patched.Location
+	}
+
+	if !sameInt(patched.HeadID, current.HeadID) {
+		if patched.HeadID != nil {
+			exists, err := s.employeeRepo.Exists(ctx, *patched.HeadID)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, invalidReference("head_id", ErrEmployeeNotFound)
+			}
+		}
+		changes["head_id"] = patched.HeadID
+	}
+
+	if len(changes) > 0 {
+		err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			if _, err := checkVersion(s.departmentRepo.Patch(ctx, id, version, changes)); err != nil {
+				return err
+			}
+			return s.notifier.NotifyDepartmentHead(ctx, patched, current.HeadID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.bus.Publish(ctx, events.EntityDepartment, events.ActionUpdated, id)
+	}
+
+	return s.GetDepartmentByID(ctx, id)
+}
+
+func (s *DepartmentService) DeleteDepartment(ctx context.Context, id int, deletedBy int) error {
+	exists, err := s.departmentRepo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrDepartmentNotFound
+	}
+
 	employees, err := s.departmentRepo.GetEmployees(ctx, id)
 	if err != nil {
 		return err
@@ -80,7 +193,23 @@ func (s *DepartmentService) DeleteDepartment(ctx context.Context, id int) error
 		return ErrDepartmentHasEmployees
 	}
 
-	return s.departmentRepo.Delete(ctx, id)
+	if err := s.departmentRepo.Delete(ctx, id, deletedBy); err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityDepartment, events.ActionDeleted, id)
+	return nil
+}
+
+func (s *DepartmentService) RestoreDepartment(ctx context.Context, id int) error {
+	restored, err := s.departmentRepo.Restore(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return ErrNothingToRestore
+	}
+	s.bus.Publish(ctx, events.EntityDepartment, events.ActionRestored, id)
+	return nil
 }
 
 func (s *DepartmentService) GetEmployeesByDepartment(ctx context.Context, departmentID int) ([]models.Employee, error) {
@@ -88,7 +217,7 @@ func (s *DepartmentService) GetEmployeesByDepartment(ctx context.Context, depart
 }
 
 func (s *DepartmentService) GetPotentialHeads(ctx context.Context) ([]models.Employee, error) {
-	return s.employeeRepo.GetAll(ctx)
+	return s.employeeRepo.GetAll(ctx, false)
 }
 
 func (s *DepartmentService) IsDepartmentHead(ctx context.Context, employeeID int) (bool, bool) {
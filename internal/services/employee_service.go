@@ -2,35 +2,71 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"golang.org/x/crypto/bcrypt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/mergepatch"
 	"inventory-system/internal/models"
 	"inventory-system/internal/repository"
+	"inventory-system/internal/tenant"
+	"time"
 )
 
 var (
-	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrEmailAlreadyExists = apperror.Conflict("email already exists")
 
-	ErrEmployeeNotFound = errors.New("employee not found")
+	ErrEmployeeNotFound = apperror.NotFound("employee not found")
+
+	ErrWrongPassword = apperror.Validation("current password is incorrect", nil)
+	ErrSamePassword  = apperror.Validation("new password must differ from the current one", nil)
+
+	ErrExternalAccount = apperror.Conflict("password is managed by an external directory")
+
+	// ErrSuperAdminRole — роль superadmin назначается и снимается только в
+	// базе данных
+	ErrSuperAdminRole = apperror.Forbidden("superadmin role cannot be changed")
+
+	ErrRoleChangeForbidden = apperror.Forbidden("only administrators can change employee roles")
 )
 
 type EmployeeService struct {
 	employeeRepo   *repository.EmployeeRepository
 	departmentRepo *repository.DepartmentRepository
+	apiTokenRepo   *repository.APITokenRepository
+	organizations  *OrganizationService
+	transactor     *repository.Transactor
+	bus            *events.Bus
 }
 
 func NewEmployeeService(
 	employeeRepo *repository.EmployeeRepository,
 	departmentRepo *repository.DepartmentRepository,
+	apiTokenRepo *repository.APITokenRepository,
+	organizations *OrganizationService,
+	transactor *repository.Transactor,
+	bus *events.Bus,
 ) *EmployeeService {
 	return &EmployeeService{
 		employeeRepo:   employeeRepo,
 		departmentRepo: departmentRepo,
+		apiTokenRepo:   apiTokenRepo,
+		organizations:  organizations,
+		transactor:     transactor,
+		bus:            bus,
 	}
 }
 
-func (s *EmployeeService) GetAllEmployees(ctx context.Context) ([]models.Employee, error) {
-	return s.employeeRepo.GetAll(ctx)
+// canManageEmployees сообщает, может ли сотрудник менять роли и отделы
+// других сотрудников
+func canManageEmployees(employee *models.Employee) bool {
+	return employee != nil && (employee.Role == models.RoleAdmin || employee.Role == models.RoleSuperAdmin)
+}
+
+func (s *EmployeeService) GetAllEmployees(ctx context.Context, includeDeleted bool) ([]models.Employee, error) {
+	return s.employeeRepo.GetAll(ctx, includeDeleted)
 }
 
 func (s *EmployeeService) GetEmployeeByID(ctx context.Context, id int) (*models.Employee, error) {
@@ -52,10 +88,15 @@ func (s *EmployeeService) CreateEmployee(ctx context.Context, employee models.Em
 			return 0, err
 		}
 		if !exists {
-			return 0, ErrDepartmentNotFound
+			return 0, invalidReference("department_id", ErrDepartmentNotFound)
 		}
 	}
 
+	// Check password policy
+	if reason := checkPasswordPolicy(employee.PasswordHash, employee); reason != "" {
+		return 0, invalidField(ErrWeakPassword, "password", reason)
+	}
+
 	// Check if email already exists
 	existing, err := s.employeeRepo.GetByEmail(ctx, employee.Email)
 	if err != nil {
@@ -72,44 +113,200 @@ func (s *EmployeeService) CreateEmployee(ctx context.Context, employee models.Em
 	}
 	employee.PasswordHash = string(hashedPassword)
 
-	return s.employeeRepo.Create(ctx, employee)
+	id, err := s.employeeRepo.Create(ctx, employee)
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityEmployee, events.ActionCreated, id)
+	return id, nil
 }
 
-func (s *EmployeeService) UpdateEmployee(ctx context.Context, employee models.Employee) error {
+// UpdateEmployee сохраняет сотрудника с версией employee.Version и возвращает
+// новую версию. Если сотрудника успели изменить, возвращает ErrVersionConflict.
+// Роль может поменять только администратор
+func (s *EmployeeService) UpdateEmployee(ctx context.Context, employee models.Employee, actor *models.Employee) (int, error) {
+	// Check if employee exists
+	current, err := s.GetEmployeeByID(ctx, employee.ID)
+	if err != nil {
+		return 0, err
+	}
+	if employee.Role != current.Role {
+		if current.Role == models.RoleSuperAdmin {
+			return 0, ErrSuperAdminRole
+		}
+		if !canManageEmployees(actor) {
+			return 0, ErrRoleChangeForbidden
+		}
+	}
+
 	// Validate department if specified
 	if employee.DepartmentID != nil {
 		exists, err := s.departmentRepo.Exists(ctx, *employee.DepartmentID)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		if !exists {
-			return ErrDepartmentNotFound
+			return 0, invalidReference("department_id", ErrDepartmentNotFound)
 		}
 	}
 
 	// Check if email belongs to another employee
 	existing, err := s.employeeRepo.GetByEmail(ctx, employee.Email)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if existing != nil && existing.ID != employee.ID {
-		return ErrEmailAlreadyExists
+		return 0, ErrEmailAlreadyExists
+	}
+
+	version, err := checkVersion(s.employeeRepo.Update(ctx, employee))
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityEmployee, events.ActionUpdated, employee.ID)
+	return version, nil
+}
+
+var employeePatchFields = patchFields{
+	"full_name":     false,
+	"position":      false,
+	"email":         false,
+	"role":          false,
+	"department_id": true,
+}
+
+// profilePatchFields — поля, которые сотрудник может менять в своем
+// профиле. Роль и отдел меняют только администраторы
+var profilePatchFields = patchFields{
+	"full_name": false,
+	"position":  false,
+	"email":     false,
+}
+
+// PatchEmployee применяет к сотруднику JSON Merge Patch, проверяя и
+// сохраняя только измененные поля. Роль может поменять только администратор
+func (s *EmployeeService) PatchEmployee(ctx context.Context, id, version int, patch mergepatch.Patch, actor *models.Employee) (*models.Employee, error) {
+	return s.patchEmployee(ctx, id, version, patch, employeePatchFields, actor)
+}
+
+// PatchProfile применяет JSON Merge Patch к профилю сотрудника id. В отличие
+// от PatchEmployee, менять можно только profilePatchFields
+func (s *EmployeeService) PatchProfile(ctx context.Context, id, version int, patch mergepatch.Patch) (*models.Employee, error) {
+	return s.patchEmployee(ctx, id, version, patch, profilePatchFields, nil)
+}
+
+func (s *EmployeeService) patchEmployee(ctx context.Context, id, version int, patch mergepatch.Patch, fields patchFields, actor *models.Employee) (*models.Employee, error) {
+	current, err := s.GetEmployeeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	var patched models.Employee
+	var request models.EmployeeRequest
+	if err := applyPatch(current, patch, fields, &patched, &request); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]interface{})
+
+	if patched.FullName != current.FullName {
+		changes["full_name"] = patched.FullName
+	}
+	if patched.Position != current.Position {
+		changes["position"] = patched.Position
+	}
+	if patched.Role != current.Role {
+		if current.Role == models.RoleSuperAdmin {
+			return nil, ErrSuperAdminRole
+		}
+		if !canManageEmployees(actor) {
+			return nil, ErrRoleChangeForbidden
+		}
+		changes["role"] = patched.Role
+	}
+
+	// Validate department if specified
+	if !sameInt(patched.DepartmentID, current.DepartmentID) {
+		if patched.DepartmentID != nil {
+			exists, err := s.departmentRepo.Exists(ctx, *patched.DepartmentID)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, invalidReference("department_id", ErrDepartmentNotFound)
+			}
+		}
+		changes["department_id"] = patched.DepartmentID
 	}
 
-	return s.employeeRepo.Update(ctx, employee)
+	// Check if email belongs to another employee
+	if patched.Email != current.Email {
+		existing, err := s.employeeRepo.GetByEmail(ctx, patched.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.ID != id {
+			return nil, ErrEmailAlreadyExists
+		}
+		changes["email"] = patched.Email
+	}
+
+	if len(changes) > 0 {
+		_, err := checkVersion(s.employeeRepo.Patch(ctx, id, version, changes))
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return nil, ErrEmailAlreadyExists
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.bus.Publish(ctx, events.EntityEmployee, events.ActionUpdated, id)
+	}
+
+	return s.GetEmployeeByID(ctx, id)
 }
 
-func (s *EmployeeService) DeleteEmployee(ctx context.Context, id int) error {
+func (s *EmployeeService) DeleteEmployee(ctx context.Context, id int, deletedBy int) error {
+	// Check if employee exists
+	exists, err := s.employeeRepo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrEmployeeNotFound
+	}
+
 	// Check if employee is a department head
 	isHead, err := s.employeeRepo.IsEmployeeDepartmentHead(ctx, id)
 	if err != nil {
 		return err
 	}
 	if isHead {
-		return errors.New("cannot delete employee who is a department head")
+		return ErrEmployeeIsDepartmentHead
 	}
 
-	return s.employeeRepo.Delete(ctx, id)
+	if err := s.employeeRepo.Delete(ctx, id, deletedBy); err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityEmployee, events.ActionDeleted, id)
+	return nil
+}
+
+func (s *EmployeeService) RestoreEmployee(ctx context.Context, id int) error {
+	restored, err := s.employeeRepo.Restore(ctx, id)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return ErrEmailAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return ErrNothingToRestore
+	}
+	s.bus.Publish(ctx, events.EntityEmployee, events.ActionRestored, id)
+	return nil
 }
 
 func (s *EmployeeService) Authenticate(ctx context.Context, email, password string) (*models.Employee, error) {
@@ -129,11 +326,185 @@ func (s *EmployeeService) Authenticate(ctx context.Context, email, password stri
 	return employee, nil
 }
 
+// ExternalScope возвращает контекст организации сотрудника, который входит
+// через внешний каталог: организации уже известного сотрудника, а при
+// первом входе — организации, которой принадлежит домен его адреса
+func (s *EmployeeService) ExternalScope(ctx context.Context, profile models.ExternalProfile) (context.Context, error) {
+	organizationID, err := s.employeeRepo.ExternalOrganization(ctx, profile.Source, profile.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	if organizationID == 0 {
+		existing, err := s.employeeRepo.GetByEmail(ctx, profile.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			organizationID = existing.OrganizationID
+		}
+	}
+	if organizationID == 0 {
+		organizationID, err = s.organizations.ForEmail(ctx, profile.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tenant.WithOrganization(ctx, organizationID), nil
+}
+
+// SyncExternalEmployee создает или обновляет сотрудника по данным из
+// внешнего каталога при входе через него (JIT-provisioning). Сотрудник
+// ищется по идентификатору в каталоге; при первом входе существующая
+// локальная учетная запись с тем же email привязывается к каталогу, и
+// дальше ее пароль проверяет только каталог. Удаленным сотрудникам вход
+// закрыт. ctx должен быть ограничен организацией из ExternalScope
+func (s *EmployeeService) SyncExternalEmployee(ctx context.Context, profile models.ExternalProfile) (*models.Employee, error) {
+	id, deleted, err := s.employeeRepo.FindByExternalID(ctx, profile.Source, profile.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	if deleted {
+		return nil, ErrInvalidCredentials
+	}
+
+	if id == 0 {
+		existing, err := s.employeeRepo.GetByEmail(ctx, profile.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if existing.AuthSource != "" {
+				return nil, ErrEmailAlreadyExists
+			}
+			if err := s.employeeRepo.LinkExternal(ctx, existing.ID, profile.Source, profile.ExternalID); err != nil {
+				return nil, err
+			}
+			id = existing.ID
+		}
+	}
+
+	if id == 0 {
+		return s.createExternalEmployee(ctx, profile)
+	}
+
+	employee, err := s.GetEmployeeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]interface{}{}
+	if profile.FullName != "" && profile.FullName != employee.FullName {
+		changes["full_name"] = profile.FullName
+	}
+	if profile.Position != employee.Position {
+		changes["position"] = profile.Position
+	}
+	if profile.Email != "" && profile.Email != employee.Email {
+		changes["email"] = profile.Email
+	}
+	// Роль superadmin каталог не назначает и не снимает
+	if profile.Role != "" && profile.Role != employee.Role && employee.Role != models.RoleSuperAdmin {
+		changes["role"] = profile.Role
+	}
+	if profile.DepartmentID != nil && (employee.DepartmentID == nil || *employee.DepartmentID != *profile.DepartmentID) {
+		changes["department_id"] = *profile.DepartmentID
+	}
+	if len(changes) == 0 {
+		return employee, nil
+	}
+
+	_, err = checkVersion(s.employeeRepo.Patch(ctx, id, employee.Version, changes))
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return nil, ErrEmailAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(ctx, events.EntityEmployee, events.ActionUpdated, id)
+	return s.GetEmployeeByID(ctx, id)
+}
+
+func (s *EmployeeService) createExternalEmployee(ctx context.Context, profile models.ExternalProfile) (*models.Employee, error) {
+	// Локальный пароль не нужен: хеш случайного значения не совпадет ни
+	// с одним введенным паролем
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(secret)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	employee := models.Employee{
+		FullName:     profile.FullName,
+		Position:     profile.Position,
+		Email:        profile.Email,
+		PasswordHash: string(hashedPassword),
+		Role:         profile.Role,
+		DepartmentID: profile.DepartmentID,
+		AuthSource:   profile.Source,
+		ExternalID:   profile.ExternalID,
+	}
+	if employee.FullName == "" {
+		employee.FullName = profile.Email
+	}
+	if employee.Role == "" {
+		employee.Role = models.RoleEmployee
+	}
+
+	id, err := s.employeeRepo.Create(ctx, employee)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(ctx, events.EntityEmployee, events.ActionCreated, id)
+	return s.GetEmployeeByID(ctx, id)
+}
+
+// ChangePassword меняет пароль сотрудника id, проверив текущий пароль и
+// требования к новому
+func (s *EmployeeService) ChangePassword(ctx context.Context, id int, currentPassword, newPassword string) error {
+	employee, err := s.GetEmployeeByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if employee.AuthSource != "" {
+		return ErrExternalAccount
+	}
+
+	hash, err := s.employeeRepo.GetPasswordHash(ctx, id)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(currentPassword)) != nil {
+		return invalidField(ErrWrongPassword, "current_password", "is incorrect")
+	}
+
+	if newPassword == currentPassword {
+		return invalidField(ErrSamePassword, "new_password", "must differ from the current password")
+	}
+	if reason := checkPasswordPolicy(newPassword, *employee); reason != "" {
+		return invalidField(ErrWeakPassword, "new_password", reason)
+	}
+
+	return s.UpdatePassword(ctx, id, newPassword)
+}
+
+// UpdatePassword сохраняет новый пароль сотрудника. Токены входа, выданные
+// до смены пароля, после нее отклоняются, а персональные токены API
+// отзываются в той же транзакции
 func (s *EmployeeService) UpdatePassword(ctx context.Context, id int, newPassword string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	return s.employeeRepo.UpdatePassword(ctx, id, string(hashedPassword))
+	// Время берется из часов приложения: с ним сравнивается iat токенов
+	now := time.Now().UTC()
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.employeeRepo.UpdatePassword(ctx, id, string(hashedPassword), now); err != nil {
+			return err
+		}
+		return s.apiTokenRepo.RevokeForEmployee(ctx, id, now)
+	})
 }
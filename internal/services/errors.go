@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"inventory-system/internal/apperror"
+)
+
+var (
+	ErrSourceLocationMismatch   = apperror.Conflict("source location does not match asset's current location")
+	ErrInvalidDateRange         = apperror.Validation("start date cannot be after end date", nil)
+	ErrEmployeeIsDepartmentHead = apperror.Conflict("cannot delete employee who is a department head")
+)
+
+// invalidReference сообщает, что поле запроса field ссылается на
+// несуществующую запись. Это ошибка проверки входных данных, а не 404,
+// но errors.Is(err, notFound) продолжает работать
+func invalidReference(field string, notFound error) error {
+	return &apperror.Error{
+		Kind:    apperror.KindValidation,
+		Message: notFound.Error(),
+		Fields:  map[string]string{field: notFound.Error()},
+		Err:     notFound,
+	}
+}
+
+// invalidField уточняет ошибку проверки err сообщением об отдельном поле
+func invalidField(err error, field, message string) error {
+	var appErr *apperror.Error
+	kind := apperror.KindValidation
+	if errors.As(err, &appErr) {
+		kind = appErr.Kind
+	}
+
+	return &apperror.Error{
+		Kind:    kind,
+		Message: fmt.Sprintf("%s: field %q %s", err.Error(), field, message),
+		Fields:  map[string]string{field: message},
+		Err:     err,
+	}
+}
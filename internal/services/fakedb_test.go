@@ -0,0 +1,31 @@
+package services
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"inventory-system/internal/repository/repotest"
+)
+
+// fakeResult — ответ fakeDB на запрос: строки для SELECT и RETURNING или
+// число измененных строк для команды
+type fakeResult struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+}
+
+// fakeHandler отвечает на запрос query с аргументами args
+type fakeHandler func(query string, args []driver.Value) (fakeResult, error)
+
+// newFakeDB возвращает *sql.DB, который передает каждый запрос handle.
+// Нужен, чтобы проверять сервисы вместе с настоящими репозиториями без
+// PostgreSQL
+func newFakeDB(t *testing.T, handle fakeHandler) *sql.DB {
+	t.Helper()
+	return repotest.Open(t, func(query string, args []driver.Value) (repotest.Result, error) {
+		result, err := handle(query, args)
+		return repotest.Result{Columns: result.columns, Rows: result.rows, Affected: result.affected}, err
+	})
+}
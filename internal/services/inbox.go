@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"time"
+)
+
+var ErrNotificationNotFound = apperror.NotFound("notification not found")
+
+const (
+	// DefaultInboxLimit — размер страницы входящих по умолчанию
+	DefaultInboxLimit = 20
+	// MaxInboxLimit ограничивает размер страницы входящих
+	MaxInboxLimit = 100
+)
+
+// inboxTitles — заголовки уведомлений по виду и языку. Аргументы
+// подставляются из inboxItem.args, время форматируется по языку
+var inboxTitles = map[string]map[string]string{
+	models.NotificationTransfer: {
+		models.LanguageRussian: "Актив «%s» перемещен: %s → %s",
+		models.LanguageEnglish: "Asset \"%s\" moved: %s → %s",
+	},
+	models.NotificationAssetAssigned: {
+		models.LanguageRussian: "Актив «%s» закреплен за вашим отделом",
+		models.LanguageEnglish: "Asset \"%s\" is now assigned to your department",
+	},
+	models.NotificationDepartmentHead: {
+		models.LanguageRussian: "Вы назначены руководителем отдела «%s»",
+		models.LanguageEnglish: "You have been appointed head of the \"%s\" department",
+	},
+	models.NotificationApprovalRequest: {
+		models.LanguageRussian: "%s просит подтвердить бронь «%s» с %s",
+		models.LanguageEnglish: "%s asks to approve a reservation of \"%s\" from %s",
+	},
+	models.NotificationReservationApproved: {
+		models.LanguageRussian: "Бронь «%s» с %s подтверждена",
+		models.LanguageEnglish: "Your reservation of \"%s\" from %s is approved",
+	},
+	models.NotificationReservationRejected: {
+		models.LanguageRussian: "Бронь «%s» с %s отклонена",
+		models.LanguageEnglish: "Your reservation of \"%s\" from %s is rejected",
+	},
+	models.NotificationWarranty: {
+		models.LanguageRussian: "Гарантия на «%s» заканчивается %s",
+		models.LanguageEnglish: "Warranty for \"%s\" expires on %s",
+	},
+}
+
+// inboxTimeLayouts — формат времени в заголовках по языку
+var inboxTimeLayouts = map[string]string{
+	models.LanguageRussian: "02.01.2006 15:04",
+	models.LanguageEnglish: "2006-01-02 15:04",
+}
+
+// inboxItem описывает уведомление во входящих до выбора языка получателя
+type inboxItem struct {
+	kind     string
+	entity   string
+	entityID int
+	link     string
+	args     []interface{}
+}
+
+// title возвращает заголовок уведомления на языке lang
+func (i inboxItem) title(lang string) string {
+	titles := inboxTitles[i.kind]
+	format, ok := titles[lang]
+	if !ok {
+		lang = models.LanguageRussian
+		format = titles[lang]
+	}
+
+	args := make([]interface{}, len(i.args))
+	for n, arg := range i.args {
+		if t, ok := arg.(time.Time); ok {
+			arg = t.Format(inboxTimeLayouts[lang])
+		}
+		args[n] = arg
+	}
+
+	return truncate(fmt.Sprintf(format, args...), 255)
+}
+
+func (s *NotificationService) addToInbox(ctx context.Context, employeeID int, lang string, item inboxItem) error {
+	entityID := item.entityID
+	_, err := s.notificationRepo.Create(ctx, models.Notification{
+		EmployeeID: employeeID,
+		Kind:       item.kind,
+		Title:      item.title(lang),
+		Link:       item.link,
+		Entity:     item.entity,
+		EntityID:   &entityID,
+	})
+	return err
+}
+
+// GetInbox возвращает страницу входящих сотрудника с числом всех и
+// непрочитанных уведомлений
+func (s *NotificationService) GetInbox(ctx context.Context, employeeID int, filter models.NotificationFilter) (*models.NotificationPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultInboxLimit
+	}
+	if filter.Limit > MaxInboxLimit {
+		filter.Limit = MaxInboxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	items, err := s.notificationRepo.GetPage(ctx, employeeID, filter)
+	if err != nil {
+		return nil, err
+	}
+	total, unread, err := s.notificationRepo.Count(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.UnreadOnly {
+		total = unread
+	}
+
+	return &models.NotificationPage{
+		Items:  items,
+		Total:  total,
+		Unread: unread,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// MarkRead отмечает уведомление сотрудника прочитанным
+func (s *NotificationService) MarkRead(ctx context.Context, employeeID int, id int64) error {
+	found, err := s.notificationRepo.MarkRead(ctx, employeeID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead отмечает прочитанными все уведомления сотрудника и
+// возвращает их число
+func (s *NotificationService) MarkAllRead(ctx context.Context, employeeID int) (int64, error) {
+	return s.notificationRepo.MarkAllRead(ctx, employeeID, time.Now())
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/ldap"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+// AuthSourceLDAP — auth_source сотрудников из LDAP/Active Directory
+const AuthSourceLDAP = "ldap"
+
+var ErrDirectoryNoEmail = apperror.Forbidden("directory account has no email address")
+
+// roleRank упорядочивает роли по правам: из нескольких групп сотрудника
+// выбирается роль с наибольшими правами
+var roleRank = map[string]int{
+	models.RoleEmployee: 1,
+	models.RoleManager:  2,
+	models.RoleAdmin:    3,
+}
+
+// LDAPAttributes — имена атрибутов каталога с данными сотрудника. Пустой
+// ID означает, что идентификатором служит DN записи
+type LDAPAttributes struct {
+	ID         string
+	Email      string
+	FullName   string
+	Position   string
+	Department string
+	Groups     string
+}
+
+// LDAPConfig — настройки провайдера LDAP
+type LDAPConfig struct {
+	Conn ldap.Config
+	// BindDN и BindPassword — служебная учетная запись для поиска
+	// сотрудника; если BindDN пуст, поиск выполняется анонимно
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserFilter — фильтр поиска, {login} заменяется экранированным логином
+	UserFilter string
+	Attributes LDAPAttributes
+	// RoleGroups сопоставляет DN группы роли. Если он пуст, роль
+	// сотрудника каталог не определяет
+	RoleGroups map[string]string
+	// DepartmentMap сопоставляет отдел в каталоге названию отдела в
+	// приложении; отделы без сопоставления ищутся по тому же названию
+	DepartmentMap map[string]string
+}
+
+// LDAPProvider проверяет пароль привязкой (bind) к LDAP-серверу от имени
+// сотрудника. При успешном входе сотрудник создается или обновляется по
+// атрибутам каталога
+type LDAPProvider struct {
+	config         LDAPConfig
+	roleGroups     map[string]string
+	departmentMap  map[string]string
+	employees      *EmployeeService
+	departmentRepo *repository.DepartmentRepository
+}
+
+func NewLDAPProvider(
+	config LDAPConfig,
+	employees *EmployeeService,
+	departmentRepo *repository.DepartmentRepository,
+) *LDAPProvider {
+	roleGroups := make(map[string]string, len(config.RoleGroups))
+	for group, role := range config.RoleGroups {
+		roleGroups[normalizeDN(group)] = role
+	}
+	departmentMap := make(map[string]string, len(config.DepartmentMap))
+	for from, to := range config.DepartmentMap {
+		departmentMap[strings.ToLower(strings.TrimSpace(from))] = to
+	}
+
+	return &LDAPProvider{
+		config:         config,
+		roleGroups:     roleGroups,
+		departmentMap:  departmentMap,
+		employees:      employees,
+		departmentRepo: departmentRepo,
+	}
+}
+
+func (p *LDAPProvider) Name() string {
+	return AuthSourceLDAP
+}
+
+func (p *LDAPProvider) Verify(ctx context.Context, login, password string) (*models.Employee, error) {
+	login = strings.TrimSpace(login)
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := ldap.Dial(ctx, p.config.Conn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if p.config.BindDN != "" {
+		if err := conn.Bind(ctx, p.config.BindDN, p.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service account bind: %w", err)
+		}
+	}
+
+	entries, err := conn.Search(ctx, ldap.SearchRequest{
+		BaseDN:     p.config.BaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     strings.ReplaceAll(p.config.UserFilter, "{login}", ldap.EscapeFilter(login)),
+		Attributes: p.attributes(),
+		SizeLimit:  2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Неоднозначный логин отклоняется так же, как неизвестный
+	if len(entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := entries[0]
+
+	if err := conn.Bind(ctx, entry.DN, password); err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	profile, err := p.profile(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Отдел ищется в организации сотрудника
+	ctx, err = p.employees.ExternalScope(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	profile.DepartmentID, err = p.mapDepartment(ctx, entry.Get(p.config.Attributes.Department))
+	if err != nil {
+		return nil, err
+	}
+	return p.employees.SyncExternalEmployee(ctx, profile)
+}
+
+// profile переводит запись каталога в данные сотрудника
+func (p *LDAPProvider) profile(entry *ldap.Entry) (models.ExternalProfile, error) {
+	attrs := p.config.Attributes
+
+	profile := models.ExternalProfile{
+		Source:     AuthSourceLDAP,
+		ExternalID: entry.DN,
+		Email:      strings.TrimSpace(entry.Get(attrs.Email)),
+		FullName:   strings.TrimSpace(entry.Get(attrs.FullName)),
+		Position:   strings.TrimSpace(entry.Get(attrs.Position)),
+	}
+	if profile.Email == "" {
+		return profile, ErrDirectoryNoEmail
+	}
+	if attrs.ID != "" {
+		// objectGUID в Active Directory — двоичное значение
+		id := entry.Get(attrs.ID)
+		if !utf8.ValidString(id) {
+			id = hex.EncodeToString([]byte(id))
+		}
+		if id != "" {
+			profile.ExternalID = id
+		}
+	}
+
+	if len(p.roleGroups) > 0 {
+		profile.Role = p.mapRole(entry.GetAll(attrs.Groups))
+	}
+	return profile, nil
+}
+
+func (p *LDAPProvider) attributes() []string {
+	attrs := p.config.Attributes
+	var names []string
+	for _, name := range []string{attrs.ID, attrs.Email, attrs.FullName, attrs.Position, attrs.Department, attrs.Groups} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// mapRole возвращает роль с наибольшими правами из групп сотрудника или
+// обычную роль сотрудника, если ни одна группа не сопоставлена
+func (p *LDAPProvider) mapRole(groups []string) string {
+	role := models.RoleEmployee
+	for _, group := range groups {
+		if mapped, ok := p.roleGroups[normalizeDN(group)]; ok && roleRank[mapped] > roleRank[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// mapDepartment возвращает id отдела приложения для отдела из каталога
+// или nil, если такого отдела нет
+func (p *LDAPProvider) mapDepartment(ctx context.Context, name string) (*int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	if mapped, ok := p.departmentMap[strings.ToLower(name)]; ok {
+		name = mapped
+	}
+
+	id, err := p.departmentRepo.FindIDByName(ctx, name)
+	if err != nil || id == 0 {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// normalizeDN приводит DN к виду для сравнения: без учета регистра и
+// пробелов вокруг разделителей
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		name, value, _ := strings.Cut(part, "=")
+		parts[i] = strings.TrimSpace(name) + "=" + strings.TrimSpace(value)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
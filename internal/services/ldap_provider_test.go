@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"inventory-system/internal/ldap"
+	"inventory-system/internal/ldap/ldaptest"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+)
+
+// employeeRow — строка таблицы employees в employeeTable
+type employeeRow struct {
+	id           int
+	fullName     string
+	position     string
+	email        string
+	role         string
+	departmentID *int
+	version      int
+	authSource   string
+	externalID   string
+	organization int
+	deleted      bool
+}
+
+// employeeTable отвечает на запросы EmployeeService, которыми сотрудники
+// из внешних каталогов ищутся, создаются, привязываются и обновляются.
+// Домен example.com закреплен за организацией 1, отдел Engineering
+// имеет id 5
+type employeeTable struct {
+	mu   sync.Mutex
+	rows []*employeeRow
+}
+
+var patchSet = regexp.MustCompile(`(\w+) = \$(\d+)`)
+
+func (t *employeeTable) handle(query string, args []driver.Value) (fakeResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "SELECT organization_id FROM employees WHERE auth_source"):
+		if row := t.external(args[0], args[1]); row != nil {
+			return fakeResult{columns: []string{"organization_id"}, rows: [][]driver.Value{{int64(row.organization)}}}, nil
+		}
+		return fakeResult{columns: []string{"organization_id"}}, nil
+
+	case strings.Contains(query, "SELECT id, deleted_at IS NOT NULL FROM employees"):
+		if row := t.external(args[0], args[1]); row != nil && inScope(row, args[2]) {
+			return fakeResult{columns: []string{"id", "deleted"}, rows: [][]driver.Value{{int64(row.id), row.deleted}}}, nil
+		}
+		return fakeResult{columns: []string{"id", "deleted"}}, nil
+
+	case strings.Contains(query, "WHERE email = $1 AND deleted_at IS NULL"):
+		for _, row := range t.rows {
+			if row.email == args[0] && !row.deleted {
+				return fakeResult{
+					columns: make([]string, 13),
+					rows: [][]driver.Value{{
+						int64(row.id), row.fullName, row.position, row.email, "hash", row.role, department(row),
+						int64(0), nil, false, row.authSource, row.externalID, int64(row.organization),
+					}},
+				}, nil
+			}
+		}
+		return fakeResult{columns: make([]string, 13)}, nil
+
+	case strings.Contains(query, "FROM employees e") && strings.Contains(query, "e.id = $1"):
+		for _, row := range t.rows {
+			if int64(row.id) == args[0] && !row.deleted && inScope(row, args[1]) {
+				return fakeResult{
+					columns: make([]string, 14),
+					rows: [][]driver.Value{{
+						int64(row.id), row.fullName, row.position, row.email, row.role, department(row), nil,
+						int64(row.version), nil, nil, false, row.authSource, row.externalID, int64(row.organization),
+					}},
+				}, nil
+			}
+		}
+		return fakeResult{columns: make([]string, 14)}, nil
+
+	case strings.Contains(query, "INSERT INTO employees"):
+		row := &employeeRow{
+			id:           len(t.rows) + 1,
+			fullName:     args[0].(string),
+			position:     args[1].(string),
+			email:        args[2].(string),
+			role:         args[4].(string),
+			version:      1,
+			authSource:   args[6].(string),
+			externalID:   args[7].(string),
+			organization: int(args[8].(int64)),
+		}
+		if id, ok := args[5].(int64); ok {
+			departmentID := int(id)
+			row.departmentID = &departmentID
+		}
+		t.rows = append(t.rows, row)
+		return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{int64(row.id)}}}, nil
+
+	case strings.Contains(query, "UPDATE employees SET auth_source = $2"):
+		for _, row := range t.rows {
+			if int64(row.id) == args[0] {
+				row.authSource, row.externalID = args[1].(string), args[2].(string)
+				return fakeResult{affected: 1}, nil
+			}
+		}
+		return fakeResult{}, nil
+
+	case strings.HasPrefix(query, "UPDATE employees SET") && strings.Contains(query, "RETURNING version"):
+		sets := patchSet.FindAllStringSubmatch(query[:strings.Index(query, " WHERE ")], -1)
+		id, version := args[len(sets)], args[len(sets)+1]
+		for _, row := range t.rows {
+			if int64(row.id) != id || int64(row.version) != version {
+				continue
+			}
+			for _, set := range sets {
+				n, _ := strconv.Atoi(set[2])
+				switch value := args[n-1]; set[1] {
+				case "full_name":
+					row.fullName = value.(string)
+				case "position":
+					row.position = value.(string)
+				case "email":
+					row.email = value.(string)
+				case "role":
+					row.role = value.(string)
+				case "department_id":
+					departmentID := int(value.(int64))
+					row.departmentID = &departmentID
+				}
+			}
+			row.version++
+			return fakeResult{columns: []string{"version"}, rows: [][]driver.Value{{int64(row.version)}}}, nil
+		}
+		return fakeResult{columns: []string{"version"}}, nil
+
+	case strings.Contains(query, "FROM organizations WHERE $1 = ANY(email_domains)"):
+		if args[0] == "example.com" {
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}}, nil
+		}
+		return fakeResult{columns: []string{"id"}}, nil
+
+	case strings.Contains(query, "FROM departments") && strings.Contains(query, "LOWER(name) = LOWER($1)"):
+		if strings.EqualFold(args[0].(string), "Engineering") {
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{int64(5)}}}, nil
+		}
+		return fakeResult{columns: []string{"id"}}, nil
+	}
+	return fakeResult{}, errors.New("unexpected query: " + query)
+}
+
+func (t *employeeTable) external(source, externalID driver.Value) *employeeRow {
+	for _, row := range t.rows {
+		if row.authSource != "" && row.authSource == source && row.externalID == externalID {
+			return row
+		}
+	}
+	return nil
+}
+
+func inScope(row *employeeRow, scope driver.Value) bool {
+	return scope == int64(0) || scope == int64(row.organization)
+}
+
+func department(row *employeeRow) driver.Value {
+	if row.departmentID == nil {
+		return nil
+	}
+	return int64(*row.departmentID)
+}
+
+// newExternalEmployees возвращает EmployeeService поверх employeeTable
+func newExternalEmployees(t *testing.T, table *employeeTable) (*EmployeeService, *repository.DepartmentRepository) {
+	db := newFakeDB(t, table.handle)
+	departments := repository.NewDepartmentRepository(db)
+	organizations := NewOrganizationService(repository.NewOrganizationRepository(db), "default")
+	return NewEmployeeService(repository.NewEmployeeRepository(db), departments, nil, organizations, nil, nil), departments
+}
+
+const (
+	serviceDN = "cn=reader,dc=example,dc=com"
+	aliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+)
+
+func newTestLDAPProvider(t *testing.T, table *employeeTable, entries ...ldaptest.Entry) (*LDAPProvider, *ldaptest.Server) {
+	server := ldaptest.NewServer(t, append([]ldaptest.Entry{{DN: serviceDN, Password: "reader"}}, entries...)...)
+	employees, departments := newExternalEmployees(t, table)
+	provider := NewLDAPProvider(LDAPConfig{
+		Conn:         ldap.Config{URL: server.URL, Timeout: 5 * time.Second},
+		BindDN:       serviceDN,
+		BindPassword: "reader",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		UserFilter:   "(&(objectClass=person)(uid={login}))",
+		Attributes: LDAPAttributes{
+			ID:         "entryUUID",
+			Email:      "mail",
+			FullName:   "cn",
+			Position:   "title",
+			Department: "ou",
+			Groups:     "memberOf",
+		},
+		RoleGroups: map[string]string{
+			"CN=Admins, OU=Groups, DC=example, DC=com": models.RoleAdmin,
+			"cn=managers,ou=groups,dc=example,dc=com":  models.RoleManager,
+		},
+		DepartmentMap: map[string]string{"R&D": "Engineering"},
+	}, employees, departments)
+	return provider, server
+}
+
+func aliceEntry(groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       aliceDN,
+		Password: "directory-password",
+		Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {"alice"},
+			"entryUUID":   {"2f1c0c7e-0000-4000-8000-000000000001"},
+			"mail":        {"alice@example.com"},
+			"cn":          {"Alice Liddell"},
+			"title":       {"Engineer"},
+			"ou":          {"R&D"},
+			"memberOf":    groups,
+		},
+	}
+}
+
+func TestLDAPProvisioning(t *testing.T) {
+	table := &employeeTable{}
+	provider, server := newTestLDAPProvider(t, table,
+		aliceEntry("cn=managers,ou=groups,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com", "cn=unmapped,dc=example,dc=com"),
+	)
+	ctx := context.Background()
+
+	employee, err := provider.Verify(ctx, " alice ", "directory-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binds := server.Binds(); !reflect.DeepEqual(binds, []string{serviceDN, aliceDN}) {
+		t.Errorf("binds = %v, want the service account and then the employee", binds)
+	}
+
+	// The first login creates the employee from the directory entry
+	if len(table.rows) != 1 {
+		t.Fatalf("%d employees after the first login, want 1", len(table.rows))
+	}
+	row := table.rows[0]
+	if row.authSource != AuthSourceLDAP || row.externalID != "2f1c0c7e-0000-4000-8000-000000000001" || row.organization != 1 {
+		t.Errorf("created employee is linked to %s/%s in organization %d", row.authSource, row.externalID, row.organization)
+	}
+	if employee.FullName != "Alice Liddell" || employee.Position != "Engineer" || employee.Email != "alice@example.com" {
+		t.Errorf("employee = %+v", employee)
+	}
+	// The highest-ranked mapped group wins; DN comparison ignores case and spaces
+	if employee.Role != models.RoleAdmin {
+		t.Errorf("role = %q, want %q", employee.Role, models.RoleAdmin)
+	}
+	if employee.DepartmentID == nil || *employee.DepartmentID != 5 {
+		t.Errorf("department = %v, want the mapped Engineering department", employee.DepartmentID)
+	}
+
+	// The next login updates the same employee from the directory
+	provider.config.Attributes.Groups = "none"
+	provider.config.Attributes.Position = "cn"
+	employee, err = provider.Verify(ctx, "alice", "directory-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.rows) != 1 {
+		t.Fatalf("%d employees after the second login, want 1", len(table.rows))
+	}
+	if employee.ID != row.id || employee.Role != models.RoleEmployee || employee.Position != "Alice Liddell" || employee.Version != 2 {
+		t.Errorf("updated employee = %+v", employee)
+	}
+}
+
+func TestLDAPLinksLocalAccount(t *testing.T) {
+	table := &employeeTable{rows: []*employeeRow{
+		{id: 1, fullName: "Alice", email: "alice@example.com", role: models.RoleEmployee, version: 3, organization: 1},
+	}}
+	provider, _ := newTestLDAPProvider(t, table, aliceEntry("cn=managers,ou=groups,dc=example,dc=com"))
+
+	employee, err := provider.Verify(context.Background(), "alice", "directory-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.rows) != 1 {
+		t.Fatalf("%d employees, want the local account to be linked instead of a new one", len(table.rows))
+	}
+	row := table.rows[0]
+	if row.authSource != AuthSourceLDAP || row.externalID != "2f1c0c7e-0000-4000-8000-000000000001" {
+		t.Errorf("local account linked to %q/%q", row.authSource, row.externalID)
+	}
+	if employee.ID != 1 || employee.Role != models.RoleManager || employee.FullName != "Alice Liddell" {
+		t.Errorf("employee = %+v", employee)
+	}
+}
+
+func TestLDAPRejections(t *testing.T) {
+	bob := ldaptest.Entry{
+		DN:         "uid=bob,ou=people,dc=example,dc=com",
+		Password:   "bob-password",
+		Attributes: map[string][]string{"objectClass": {"person"}, "uid": {"bob"}, "cn": {"Bob"}},
+	}
+	twin := aliceEntry()
+	twin.DN = "uid=alice,ou=contractors,ou=people,dc=example,dc=com"
+
+	tests := []struct {
+		name     string
+		rows     []*employeeRow
+		entries  []ldaptest.Entry
+		login    string
+		password string
+		want     error
+	}{
+		{name: "wrong password", entries: []ldaptest.Entry{aliceEntry()}, login: "alice", password: "wrong", want: ErrInvalidCredentials},
+		{name: "unknown login", entries: []ldaptest.Entry{aliceEntry()}, login: "carol", password: "directory-password", want: ErrInvalidCredentials},
+		{name: "wildcard login", entries: []ldaptest.Entry{aliceEntry()}, login: "*", password: "directory-password", want: ErrInvalidCredentials},
+		{name: "ambiguous login", entries: []ldaptest.Entry{aliceEntry(), twin}, login: "alice", password: "directory-password", want: ErrInvalidCredentials},
+		{name: "empty password", entries: []ldaptest.Entry{aliceEntry()}, login: "alice", password: "", want: ErrInvalidCredentials},
+		{name: "no email", entries: []ldaptest.Entry{bob}, login: "bob", password: "bob-password", want: ErrDirectoryNoEmail},
+		{
+			name: "deleted employee",
+			rows: []*employeeRow{{
+				id: 1, email: "alice@example.com", role: models.RoleEmployee, version: 1, organization: 1, deleted: true,
+				authSource: AuthSourceLDAP, externalID: "2f1c0c7e-0000-4000-8000-000000000001",
+			}},
+			entries: []ldaptest.Entry{aliceEntry()}, login: "alice", password: "directory-password", want: ErrInvalidCredentials,
+		},
+		{
+			name:    "email owned by another provider",
+			rows:    []*employeeRow{{id: 1, email: "alice@example.com", role: models.RoleEmployee, version: 1, organization: 1, authSource: "saml", externalID: "alice"}},
+			entries: []ldaptest.Entry{aliceEntry()}, login: "alice", password: "directory-password", want: ErrEmailAlreadyExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &employeeTable{rows: tt.rows}
+			provider, _ := newTestLDAPProvider(t, table, tt.entries...)
+			_, err := provider.Verify(context.Background(), tt.login, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if len(table.rows) != len(tt.rows) {
+				t.Errorf("a rejected login changed employees: %d rows", len(table.rows))
+			}
+		})
+	}
+}
+
+func TestLDAPMapRole(t *testing.T) {
+	provider := NewLDAPProvider(LDAPConfig{RoleGroups: map[string]string{
+		"cn=staff,dc=example,dc=com":    models.RoleEmployee,
+		"cn=managers,dc=example,dc=com": models.RoleManager,
+		"cn=admins,dc=example,dc=com":   models.RoleAdmin,
+	}}, nil, nil)
+
+	tests := []struct {
+		groups []string
+		want   string
+	}{
+		{nil, models.RoleEmployee},
+		{[]string{"cn=unknown,dc=example,dc=com"}, models.RoleEmployee},
+		{[]string{"CN=Managers, DC=Example, DC=Com"}, models.RoleManager},
+		{[]string{"cn=admins,dc=example,dc=com", "cn=staff,dc=example,dc=com"}, models.RoleAdmin},
+		{[]string{"cn=staff,dc=example,dc=com", "cn=managers,dc=example,dc=com"}, models.RoleManager},
+	}
+	for _, tt := range tests {
+		if got := provider.mapRole(tt.groups); got != tt.want {
+			t.Errorf("mapRole(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
@@ -2,28 +2,31 @@ package services
 
 import (
 	"context"
-	"errors"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/mergepatch"
 	"inventory-system/internal/models"
 	"inventory-system/internal/repository"
 )
 
 var (
-	ErrLocationHasAssets    = errors.New("cannot delete location with assigned assets")
-	ErrLocationHasTransfers = errors.New("cannot delete location with transfer history")
+	ErrLocationHasAssets = apperror.Conflict("cannot delete location with assigned assets")
 )
 
 type LocationService struct {
 	locationRepo *repository.LocationRepository
+	bus          *events.Bus
 }
 
-func NewLocationService(locationRepo *repository.LocationRepository) *LocationService {
+func NewLocationService(locationRepo *repository.LocationRepository, bus *events.Bus) *LocationService {
 	return &LocationService{
 		locationRepo: locationRepo,
+		bus:          bus,
 	}
 }
 
-func (s *LocationService) GetAllLocations(ctx context.Context) ([]models.Location, error) {
-	return s.locationRepo.GetAll(ctx)
+func (s *LocationService) GetAllLocations(ctx context.Context, includeDeleted bool) ([]models.Location, error) {
+	return s.locationRepo.GetAll(ctx, includeDeleted)
 }
 
 func (s *LocationService) GetLocationByID(ctx context.Context, id int) (*models.Location, error) {
@@ -38,14 +41,84 @@ func (s *LocationService) GetLocationByID(ctx context.Context, id int) (*models.
 }
 
 func (s *LocationService) CreateLocation(ctx context.Context, location models.Location) (int, error) {
-	return s.locationRepo.Create(ctx, location)
+	id, err := s.locationRepo.Create(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityLocation, events.ActionCreated, id)
+	return id, nil
+}
+
+// UpdateLocation сохраняет местоположение с версией location.Version и
+// возвращает новую версию. Если его успели изменить, возвращает ErrVersionConflict
+func (s *LocationService) UpdateLocation(ctx context.Context, location models.Location) (int, error) {
+	exists, err := s.locationRepo.Exists(ctx, location.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrLocationNotFound
+	}
+
+	version, err := checkVersion(s.locationRepo.Update(ctx, location))
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityLocation, events.ActionUpdated, location.ID)
+	return version, nil
+}
+
+var locationPatchFields = patchFields{
+	"address": false,
+	"type":    false,
 }
 
-func (s *LocationService) UpdateLocation(ctx context.Context, location models.Location) error {
-	return s.locationRepo.Update(ctx, location)
+// PatchLocation применяет к местоположению JSON Merge Patch и сохраняет
+// только измененные поля
+func (s *LocationService) PatchLocation(ctx context.Context, id, version int, patch mergepatch.Patch) (*models.Location, error) {
+	current, err := s.GetLocationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	var patched models.Location
+	var request models.LocationRequest
+	if err := applyPatch(current, patch, locationPatchFields, &patched, &request); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]interface{})
+
+	if patched.Address != current.Address {
+		changes["address"] = patched.Address
+	}
+	if patched.Type != current.Type {
+		changes["type"] = patched.Type
+	}
+
+	if len(changes) > 0 {
+		if _, err := checkVersion(s.locationRepo.Patch(ctx, id, version, changes)); err != nil {
+			return nil, err
+		}
+		s.bus.Publish(ctx, events.EntityLocation, events.ActionUpdated, id)
+	}
+
+	return s.GetLocationByID(ctx, id)
 }
 
-func (s *LocationService) DeleteLocation(ctx context.Context, id int) error {
+func (s *LocationService) DeleteLocation(ctx context.Context, id int, deletedBy int) error {
+	// Check if location exists
+	exists, err := s.locationRepo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrLocationNotFound
+	}
+
 	// Check if location has assets
 	hasAssets, err := s.locationRepo.HasAssets(ctx, id)
 	if err != nil {
@@ -55,16 +128,23 @@ func (s *LocationService) DeleteLocation(ctx context.Context, id int) error {
 		return ErrLocationHasAssets
 	}
 
-	// Check if location has transfer history
-	hasTransfers, err := s.locationRepo.HasTransfers(ctx, id)
+	if err := s.locationRepo.Delete(ctx, id, deletedBy); err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityLocation, events.ActionDeleted, id)
+	return nil
+}
+
+func (s *LocationService) RestoreLocation(ctx context.Context, id int) error {
+	restored, err := s.locationRepo.Restore(ctx, id)
 	if err != nil {
 		return err
 	}
-	if hasTransfers {
-		return ErrLocationHasTransfers
+	if !restored {
+		return ErrNothingToRestore
 	}
-
-	return s.locationRepo.Delete(ctx, id)
+	s.bus.Publish(ctx, events.EntityLocation, events.ActionRestored, id)
+	return nil
 }
 
 func (s *LocationService) GetLocationsByType(ctx context.Context, locationType string) ([]models.Location, error) {
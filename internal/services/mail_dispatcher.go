@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"inventory-system/internal/mail"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"log"
+	"time"
+)
+
+const (
+	// mailBatchSize ограничивает число писем за один проход
+	mailBatchSize = 50
+	// mailSendTimeout ограничивает время отправки одного письма
+	mailSendTimeout = 30 * time.Second
+	// mailSentRetention — сколько хранятся записи об отправленных письмах
+	mailSentRetention = 24 * time.Hour
+)
+
+// MailDispatcher отправляет письма из очереди через Mailer. Неудачные
+// отправки повторяются с экспоненциально растущей паузой, после
+// maxAttempts попыток письмо помечается как failed
+type MailDispatcher struct {
+	mailQueueRepo *repository.MailQueueRepository
+	mailer        mail.Mailer
+	interval      time.Duration
+	maxAttempts   int
+	retryDelay    time.Duration
+}
+
+func NewMailDispatcher(
+	mailQueueRepo *repository.MailQueueRepository,
+	mailer mail.Mailer,
+	interval time.Duration,
+	maxAttempts int,
+	retryDelay time.Duration,
+) *MailDispatcher {
+	return &MailDispatcher{
+		mailQueueRepo: mailQueueRepo,
+		mailer:        mailer,
+		interval:      interval,
+		maxAttempts:   maxAttempts,
+		retryDelay:    retryDelay,
+	}
+}
+
+// Run выполняет отправку сразу и затем с интервалом interval до отмены ctx
+func (d *MailDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Dispatch(ctx, time.Now()); err != nil {
+			log.Printf("Mail dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch отправляет письма, срок отправки которых наступил к now, и
+// удаляет записи об отправленных раньше now - mailSentRetention
+func (d *MailDispatcher) Dispatch(ctx context.Context, now time.Time) error {
+	// Claimed messages are hidden from other dispatchers while being sent
+	leaseUntil := now.Add(mailBatchSize*mailSendTimeout + time.Minute)
+	messages, err := d.mailQueueRepo.ClaimDue(ctx, now, leaseUntil, mailBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		result := d.attempt(ctx, message, now)
+		if err := d.mailQueueRepo.SaveAttempt(ctx, result); err != nil {
+			return err
+		}
+	}
+
+	_, err = d.mailQueueRepo.PurgeSent(ctx, now.Add(-mailSentRetention))
+	return err
+}
+
+// attempt отправляет одно письмо и возвращает его новое состояние
+func (d *MailDispatcher) attempt(ctx context.Context, message models.MailMessage, now time.Time) models.MailMessage {
+	message.Attempts++
+
+	sendCtx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+	err := d.mailer.Send(sendCtx, mail.Message{
+		To:      message.Recipient,
+		Subject: message.Subject,
+		Text:    message.TextBody,
+		HTML:    message.HTMLBody,
+	})
+	cancel()
+
+	if err == nil {
+		sentAt := time.Now()
+		message.Status = models.MailSent
+		message.LastError = ""
+		message.SentAt = &sentAt
+		return message
+	}
+
+	message.LastError = truncate(err.Error(), maxErrorLength)
+	if message.Attempts >= d.maxAttempts {
+		message.Status = models.MailFailed
+		log.Printf("Mail %d to %s failed after %d attempts: %v",
+			message.ID, message.Recipient, message.Attempts, err)
+		return message
+	}
+
+	message.Status = models.MailPending
+	message.NextAttemptAt = now.Add(retryBackoff(d.retryDelay, message.Attempts))
+	return message
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"inventory-system/internal/mail"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{5, 16 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, maxRetryDelay},
+		{1000, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(time.Minute, tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(1m, %d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// failingMailer отклоняет первые failures писем
+type failingMailer struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	sent     []mail.Message
+}
+
+func (m *failingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("send without a deadline")
+	}
+	if m.failures > 0 {
+		m.failures--
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// mailQueue — очередь mail_queue из одного письма
+type mailQueue struct {
+	mu      sync.Mutex
+	message models.MailMessage
+	purged  []time.Time
+}
+
+func (q *mailQueue) handle(query string, args []driver.Value) (fakeResult, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "UPDATE mail_queue") && strings.Contains(query, "FOR UPDATE SKIP LOCKED"):
+		now := args[0].(time.Time)
+		if q.message.Status != models.MailPending || q.message.NextAttemptAt.After(now) {
+			return fakeResult{columns: make([]string, 11)}, nil
+		}
+		q.message.NextAttemptAt = args[1].(time.Time)
+		m := q.message
+		return fakeResult{
+			columns: make([]string, 11),
+			rows: [][]driver.Value{{
+				m.ID, m.Recipient, m.Subject, m.TextBody, m.HTMLBody, m.Status, int64(m.Attempts),
+				m.NextAttemptAt, m.LastError, m.CreatedAt, nil,
+			}},
+		}, nil
+	case strings.Contains(query, "UPDATE mail_queue") && strings.Contains(query, "SET status = $1"):
+		q.message.Status = args[0].(string)
+		q.message.Attempts = int(args[1].(int64))
+		q.message.NextAttemptAt = args[2].(time.Time)
+		q.message.LastError = args[3].(string)
+		if sentAt, ok := args[4].(time.Time); ok {
+			q.message.SentAt = &sentAt
+		}
+		if q.message.Status != models.MailPending {
+			q.message.TextBody, q.message.HTMLBody = "", ""
+		}
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "DELETE FROM mail_queue WHERE status = 'sent'"):
+		q.purged = append(q.purged, args[0].(time.Time))
+		return fakeResult{}, nil
+	}
+	return fakeResult{}, errors.New("unexpected query: " + query)
+}
+
+func newTestMailDispatcher(t *testing.T, queue *mailQueue, mailer mail.Mailer) *MailDispatcher {
+	db := newFakeDB(t, queue.handle)
+	return NewMailDispatcher(repository.NewMailQueueRepository(db), mailer, time.Minute, 4, time.Minute)
+}
+
+func queuedMail(now time.Time) models.MailMessage {
+	return models.MailMessage{
+		ID:            1,
+		Recipient:     "alice@example.com",
+		Subject:       "Сброс пароля",
+		TextBody:      "https://inventory.example.com/reset?token=secret",
+		HTMLBody:      "<p>reset</p>",
+		Status:        models.MailPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+func TestMailDispatcherRetriesWithBackoff(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	queue := &mailQueue{message: queuedMail(now)}
+	mailer := &failingMailer{failures: 100, err: errors.New("421 4.7.0 try again later")}
+	d := newTestMailDispatcher(t, queue, mailer)
+	ctx := context.Background()
+
+	for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		if err := d.Dispatch(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+		m := queue.message
+		if m.Status != models.MailPending || m.Attempts != attempt+1 || !m.NextAttemptAt.Equal(now.Add(delay)) {
+			t.Fatalf("after attempt %d: status %s, %d attempts, next at %s, want %s",
+				attempt+1, m.Status, m.Attempts, m.NextAttemptAt, now.Add(delay))
+		}
+		if m.LastError != "421 4.7.0 try again later" || m.TextBody == "" {
+			t.Errorf("after attempt %d: last error %q, body %q", attempt+1, m.LastError, m.TextBody)
+		}
+
+		// Nothing is sent before the retry is due
+		if err := d.Dispatch(ctx, m.NextAttemptAt.Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if queue.message.Attempts != attempt+1 {
+			t.Fatal("retried before the backoff elapsed")
+		}
+		now = m.NextAttemptAt
+	}
+
+	// The last allowed attempt fails the message for good and wipes its body
+	if err := d.Dispatch(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if m := queue.message; m.Status != models.MailFailed || m.Attempts != 4 || m.TextBody != "" || m.HTMLBody != "" {
+		t.Errorf("after the last attempt: %+v", m)
+	}
+	if err := d.Dispatch(ctx, now.Add(time.Hour)); err != nil || queue.message.Attempts != 4 {
+		t.Errorf("failed message was retried: %d attempts, %v", queue.message.Attempts, err)
+	}
+}
+
+func TestMailDispatcherSends(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	queue := &mailQueue{message: queuedMail(now)}
+	mailer := &failingMailer{failures: 1, err: errors.New(strings.Repeat("ошибка ", 200))}
+	d := newTestMailDispatcher(t, queue, mailer)
+	ctx := context.Background()
+
+	if err := d.Dispatch(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if n := len([]rune(queue.message.LastError)); n > maxErrorLength {
+		t.Errorf("last error of %d characters is stored", n)
+	}
+
+	now = now.Add(time.Minute)
+	if err := d.Dispatch(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	m := queue.message
+	if m.Status != models.MailSent || m.Attempts != 2 || m.SentAt == nil || m.LastError != "" || m.TextBody != "" {
+		t.Errorf("after sending: %+v", m)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "alice@example.com" || mailer.sent[0].HTML != "<p>reset</p>" {
+		t.Errorf("sent %+v", mailer.sent)
+	}
+
+	// Every pass purges messages sent more than mailSentRetention ago
+	if last := queue.purged[len(queue.purged)-1]; !last.Equal(now.Add(-mailSentRetention)) {
+		t.Errorf("purged messages sent before %s, want %s", last, now.Add(-mailSentRetention))
+	}
+}
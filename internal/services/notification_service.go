@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"inventory-system/internal/events"
+	"inventory-system/internal/mail"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/tenant"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Шаблоны писем из internal/mail/templates
+const (
+	templateTransfer    = "transfer"
+	templateReservation = "reservation"
+	templateWarranty    = "warranty"
+	templatePassword    = "password_reset"
+	templateInvitation  = "invitation"
+)
+
+// notificationData — данные, доступные шаблонам писем
+type notificationData struct {
+	Recipient   models.Employee
+	BaseURL     string
+	Asset       *models.Asset
+	Transfer    *models.AssetTransfer
+	Reservation *models.Reservation
+	DaysLeft    int
+	// Link и ExpiresAt — ссылка сброса пароля или регистрации и срок ее
+	// действия
+	Link      string
+	ExpiresAt time.Time
+	// Inviter — пригласивший администратор; nil, если ссылку регистрации
+	// запросил сам получатель
+	Inviter *models.Employee
+}
+
+// NotificationService готовит письма сотрудникам с учетом их настроек и
+// ставит их в очередь отправки. Письма, поставленные внутри транзакции,
+// отправляются только после ее фиксации
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	mailQueueRepo    *repository.MailQueueRepository
+	employeeRepo     *repository.EmployeeRepository
+	assetRepo        *repository.AssetRepository
+	transferRepo     *repository.TransferRepository
+	transactor       *repository.Transactor
+	templates        *mail.Templates
+	baseURL          string
+	warrantyDays     int
+	interval         time.Duration
+}
+
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	mailQueueRepo *repository.MailQueueRepository,
+	employeeRepo *repository.EmployeeRepository,
+	assetRepo *repository.AssetRepository,
+	transferRepo *repository.TransferRepository,
+	transactor *repository.Transactor,
+	templates *mail.Templates,
+	baseURL string,
+	warrantyDays int,
+	interval time.Duration,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		mailQueueRepo:    mailQueueRepo,
+		employeeRepo:     employeeRepo,
+		assetRepo:        assetRepo,
+		transferRepo:     transferRepo,
+		transactor:       transactor,
+		templates:        templates,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		warrantyDays:     warrantyDays,
+		interval:         interval,
+	}
+}
+
+// GetPreferences возвращает настройки уведомлений сотрудника, а если он
+// их не менял — настройки по умолчанию
+func (s *NotificationService) GetPreferences(ctx context.Context, employeeID int) (*models.NotificationPreferences, error) {
+	prefs, err := s.notificationRepo.GetPreferences(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		defaults := models.DefaultNotificationPreferences(employeeID)
+		return &defaults, nil
+	}
+	return prefs, nil
+}
+
+func (s *NotificationService) UpdatePreferences(ctx context.Context, prefs models.NotificationPreferences) error {
+	return s.notificationRepo.SavePreferences(ctx, prefs)
+}
+
+// NotifyTransfer сообщает сотрудникам отдела, за которым числится актив,
+// о его перемещении. Сотрудник, оформивший перемещение, уведомление не
+// получает
+func (s *NotificationService) NotifyTransfer(ctx context.Context, transferID int) error {
+	transfer, err := s.transferRepo.GetByID(ctx, transferID)
+	if err != nil || transfer == nil {
+		return err
+	}
+	asset, err := s.assetRepo.GetByID(ctx, transfer.AssetID)
+	if err != nil || asset == nil || asset.DepartmentID == nil {
+		return err
+	}
+
+	recipients, err := s.employeeRepo.GetByDepartment(ctx, *asset.DepartmentID)
+	if err != nil {
+		return err
+	}
+
+	item := inboxItem{
+		kind:     models.NotificationTransfer,
+		entity:   events.EntityTransfer,
+		entityID: transfer.ID,
+		link:     "/transfers",
+		args:     []interface{}{transfer.AssetName, transfer.FromLocation, transfer.ToLocation},
+	}
+	letter := mailItem{
+		template: templateTransfer,
+		data:     notificationData{Asset: asset, Transfer: transfer},
+		enabled:  func(p *models.NotificationPreferences) bool { return p.Transfers },
+	}
+	for _, recipient := range recipients {
+		if recipient.ID == transfer.EmployeeID {
+			continue
+		}
+		if err := s.notify(ctx, recipient, item, &letter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyAssetAssigned сообщает сотрудникам отдела, что за ним закреплен
+// актив. Если отдел актива не отличается от previousDepartmentID, ничего
+// не делает
+func (s *NotificationService) NotifyAssetAssigned(ctx context.Context, assetID int, previousDepartmentID *int) error {
+	asset, err := s.assetRepo.GetByID(ctx, assetID)
+	if err != nil || asset == nil || asset.DepartmentID == nil || sameInt(asset.DepartmentID, previousDepartmentID) {
+		return err
+	}
+
+	recipients, err := s.employeeRepo.GetByDepartment(ctx, *asset.DepartmentID)
+	if err != nil {
+		return err
+	}
+
+	item := inboxItem{
+		kind:     models.NotificationAssetAssigned,
+		entity:   events.EntityAsset,
+		entityID: asset.ID,
+		link:     "/assets",
+		args:     []interface{}{asset.Name},
+	}
+	for _, recipient := range recipients {
+		if err := s.notify(ctx, recipient, item, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyDepartmentHead сообщает сотруднику, что он назначен руководителем
+// отдела. Если руководитель не сменился, ничего не делает
+func (s *NotificationService) NotifyDepartmentHead(ctx context.Context, department models.Department, previousHeadID *int) error {
+	if department.HeadID == nil || sameInt(department.HeadID, previousHeadID) {
+		return nil
+	}
+	recipient, err := s.employeeRepo.GetByID(ctx, *department.HeadID)
+	if err != nil || recipient == nil {
+		return err
+	}
+
+	return s.notify(ctx, *recipient, inboxItem{
+		kind:     models.NotificationDepartmentHead,
+		entity:   events.EntityDepartment,
+		entityID: department.ID,
+		link:     "/departments",
+		args:     []interface{}{department.Name},
+	}, nil)
+}
+
+// NotifyApprovalRequest сообщает менеджерам и администраторам, что бронь
+// ждет подтверждения
+func (s *NotificationService) NotifyApprovalRequest(ctx context.Context, reservation models.Reservation) error {
+	recipients, err := s.employeesWithRoles(ctx, models.RoleManager, models.RoleAdmin, models.RoleSuperAdmin)
+	if err != nil {
+		return err
+	}
+
+	item := inboxItem{
+		kind:     models.NotificationApprovalRequest,
+		entity:   events.EntityReservation,
+		entityID: reservation.ID,
+		args:     []interface{}{reservation.EmployeeName, reservation.AssetName, reservation.StartsAt},
+	}
+	for _, recipient := range recipients {
+		if recipient.ID == reservation.EmployeeID {
+			continue
+		}
+		if err := s.notify(ctx, recipient, item, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveApprovalRequest отмечает прочитанными запросы на подтверждение
+// брони, которую уже рассмотрели или отменили
+func (s *NotificationService) ResolveApprovalRequest(ctx context.Context, reservationID int) error {
+	return s.notificationRepo.MarkEntityRead(
+		ctx,
+		models.NotificationApprovalRequest,
+		events.EntityReservation,
+		reservationID,
+		time.Now(),
+	)
+}
+
+// NotifyReservationReviewed сообщает владельцу брони, что ее подтвердили
+// или отклонили
+func (s *NotificationService) NotifyReservationReviewed(ctx context.Context, reservation models.Reservation) error {
+	recipient, err := s.employeeRepo.GetByID(ctx, reservation.EmployeeID)
+	if err != nil || recipient == nil {
+		return err
+	}
+
+	kind := models.NotificationReservationApproved
+	if reservation.Status != models.ReservationApproved {
+		kind = models.NotificationReservationRejected
+	}
+
+	return s.notify(ctx, *recipient, inboxItem{
+		kind:     kind,
+		entity:   events.EntityReservation,
+		entityID: reservation.ID,
+		args:     []interface{}{reservation.AssetName, reservation.StartsAt},
+	}, &mailItem{
+		template: templateReservation,
+		data:     notificationData{Reservation: &reservation},
+		enabled:  func(p *models.NotificationPreferences) bool { return p.Reservations },
+	})
+}
+
+// Run проверяет сроки гарантии сразу и затем с интервалом interval до
+// отмены ctx
+func (s *NotificationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.NotifyExpiringWarranties(ctx, time.Now()); err != nil {
+			log.Printf("Warranty notifications failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NotifyExpiringWarranties напоминает об активах, гарантия которых
+// заканчивается в ближайшие warrantyDays дней. Письма получают сотрудники
+// отдела актива, а если актив не закреплен за отделом — администраторы
+// его организации.
+// О каждой дате окончания гарантии напоминание отправляется один раз
+func (s *NotificationService) NotifyExpiringWarranties(ctx context.Context, now time.Time) error {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	assets, err := s.assetRepo.GetWarrantyExpiring(ctx, today, today.AddDate(0, 0, s.warrantyDays))
+	if err != nil {
+		return err
+	}
+
+	for i := range assets {
+		asset := &assets[i]
+		// Получатели ищутся в организации актива
+		ctx := tenant.WithOrganization(ctx, asset.OrganizationID)
+		err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			recipients, err := s.warrantyRecipients(ctx, asset)
+			if err != nil {
+				return err
+			}
+
+			until, err := time.Parse("2006-01-02", *asset.WarrantyUntil)
+			if err != nil {
+				return err
+			}
+			item := inboxItem{
+				kind:     models.NotificationWarranty,
+				entity:   events.EntityAsset,
+				entityID: asset.ID,
+				link:     "/assets",
+				args:     []interface{}{asset.Name, *asset.WarrantyUntil},
+			}
+			letter := mailItem{
+				template: templateWarranty,
+				data:     notificationData{Asset: asset, DaysLeft: int(until.Sub(today).Hours() / 24)},
+				enabled:  func(p *models.NotificationPreferences) bool { return p.Warranty },
+			}
+
+			for _, recipient := range recipients {
+				if err := s.notify(ctx, recipient, item, &letter); err != nil {
+					return err
+				}
+			}
+			return s.assetRepo.MarkWarrantyNotice(ctx, asset.ID, *asset.WarrantyUntil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NotificationService) warrantyRecipients(ctx context.Context, asset *models.Asset) ([]models.Employee, error) {
+	if asset.DepartmentID != nil {
+		recipients, err := s.employeeRepo.GetByDepartment(ctx, *asset.DepartmentID)
+		if err != nil || len(recipients) > 0 {
+			return recipients, err
+		}
+	}
+	return s.employeesWithRoles(ctx, models.RoleAdmin, models.RoleSuperAdmin)
+}
+
+// employeesWithRoles возвращает сотрудников организации из контекста с
+// любой из ролей roles
+func (s *NotificationService) employeesWithRoles(ctx context.Context, roles ...string) ([]models.Employee, error) {
+	var employees []models.Employee
+	for _, role := range roles {
+		found, err := s.employeeRepo.GetByRole(ctx, role)
+		if err != nil {
+			return nil, err
+		}
+		employees = append(employees, found...)
+	}
+	return employees, nil
+}
+
+// mailItem описывает письмо к уведомлению: шаблон, его данные и
+// настройку, которой получатель может отключить такие письма
+type mailItem struct {
+	template string
+	data     notificationData
+	enabled  func(*models.NotificationPreferences) bool
+}
+
+// notify добавляет уведомление во входящие получателя и, если задано
+// письмо и получатель его не отключил, ставит письмо в очередь. Ошибка
+// шаблона письма не прерывает операцию, вызвавшую уведомление, и только
+// записывается в лог
+func (s *NotificationService) notify(ctx context.Context, recipient models.Employee, item inboxItem, m *mailItem) error {
+	prefs, err := s.GetPreferences(ctx, recipient.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.addToInbox(ctx, recipient.ID, prefs.Language, item); err != nil {
+		return err
+	}
+
+	if m == nil || recipient.Email == "" || !m.enabled(prefs) {
+		return nil
+	}
+
+	_, err = s.enqueueMail(ctx, recipient, prefs.Language, m.template, m.data)
+	return err
+}
+
+// SendPasswordReset отправляет сотруднику ссылку сброса пароля с токеном
+// token. Письмо отправляется независимо от настроек уведомлений и не
+// попадает во входящие
+func (s *NotificationService) SendPasswordReset(ctx context.Context, recipient models.Employee, token string, expiresAt time.Time) error {
+	prefs, err := s.GetPreferences(ctx, recipient.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.enqueueMail(ctx, recipient, prefs.Language, templatePassword, notificationData{
+		Link:      s.baseURL + "/reset-password?token=" + url.QueryEscape(token),
+		ExpiresAt: expiresAt,
+	})
+	return err
+}
+
+// SendInvitation отправляет ссылку регистрации с токеном token на адрес
+// из приглашения. Учетной записи у получателя еще нет, поэтому письмо
+// пишется на языке по умолчанию. Возвращает id письма в очереди или 0,
+// если письмо не удалось составить
+func (s *NotificationService) SendInvitation(ctx context.Context, invitation models.Invitation, inviter *models.Employee, token string) (int64, error) {
+	recipient := models.Employee{Email: invitation.Email}
+	return s.enqueueMail(ctx, recipient, mail.DefaultLanguage, templateInvitation, notificationData{
+		Link:      s.InvitationLink(token),
+		ExpiresAt: invitation.ExpiresAt,
+		Inviter:   inviter,
+	})
+}
+
+// InvitationLink возвращает ссылку на страницу регистрации по приглашению
+func (s *NotificationService) InvitationLink(token string) string {
+	return s.baseURL + "/register?invitation=" + url.QueryEscape(token)
+}
+
+// enqueueMail заполняет шаблон письма на языке lang и ставит письмо
+// получателю в очередь. Возвращает id письма или 0, если шаблон не
+// удалось заполнить
+func (s *NotificationService) enqueueMail(ctx context.Context, recipient models.Employee, lang, template string, data notificationData) (int64, error) {
+	data.Recipient = recipient
+	data.BaseURL = s.baseURL
+	msg, err := s.templates.Render(template, lang, data)
+	if err != nil {
+		log.Printf("Failed to render %s mail for employee %d: %v", template, recipient.ID, err)
+		return 0, nil
+	}
+
+	return s.mailQueueRepo.Enqueue(ctx, models.MailMessage{
+		Recipient: recipient.Email,
+		Subject:   msg.Subject,
+		TextBody:  msg.Text,
+		HTMLBody:  msg.HTML,
+	})
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/oidc"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrSSOFailed           = apperror.Unauthorized("single sign-on failed")
+	ErrSSOEmailNotVerified = apperror.Forbidden("single sign-on email is not verified")
+	ErrSSONoAccount        = apperror.Forbidden("no employee account matches the single sign-on email")
+)
+
+// OIDCFlowExpiry — сколько времени дается на вход на стороне провайдера
+const OIDCFlowExpiry = 10 * time.Minute
+
+// OIDCService выполняет вход через провайдера OpenID Connect (authorization
+// code + PKCE). Сотрудник находится по email из ID-токена; учетные записи
+// при этом не создаются
+type OIDCService struct {
+	provider     *oidc.Provider
+	employeeRepo *repository.EmployeeRepository
+	flowKey      []byte
+	clock        ratelimit.Clock
+}
+
+func NewOIDCService(
+	provider *oidc.Provider,
+	employeeRepo *repository.EmployeeRepository,
+	secretKey string,
+	clock ratelimit.Clock,
+) *OIDCService {
+	// Отдельный ключ для состояния входа: его токен не должен
+	// проверяться ключом токенов доступа
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte("oidc-flow"))
+
+	return &OIDCService{
+		provider:     provider,
+		employeeRepo: employeeRepo,
+		flowKey:      mac.Sum(nil),
+		clock:        clock,
+	}
+}
+
+// Start начинает вход: возвращает адрес страницы входа провайдера и
+// подписанное состояние входа (state, nonce и секрет PKCE), которое
+// хранится в cookie браузера до возврата с провайдера
+func (s *OIDCService) Start(ctx context.Context) (authURL, flow string, err error) {
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := jwt.MapClaims{
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      s.clock.Now().Add(OIDCFlowExpiry).Unix(),
+	}
+	flow, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.flowKey)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, flow, nil
+}
+
+// Finish завершает вход по ответу провайдера: сверяет state с состоянием
+// входа, обменивает код на ID-токен и возвращает сотрудника с email из него
+func (s *OIDCService) Finish(ctx context.Context, flow, state, code string) (*models.Employee, error) {
+	claims, err := s.parseFlow(flow)
+	if err != nil {
+		return nil, ErrSSOFailed
+	}
+	expectedState, _ := claims["state"].(string)
+	if state == "" || !hmac.Equal([]byte(state), []byte(expectedState)) {
+		return nil, ErrSSOFailed
+	}
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+
+	idToken, err := s.provider.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		return nil, ErrSSOFailed
+	}
+
+	if idToken.Email == "" {
+		return nil, ErrSSONoAccount
+	}
+	if idToken.EmailVerified != nil && !*idToken.EmailVerified {
+		return nil, ErrSSOEmailNotVerified
+	}
+
+	employee, err := s.employeeRepo.GetByEmail(ctx, idToken.Email)
+	if err != nil {
+		return nil, err
+	}
+	if employee == nil {
+		log.Printf("OIDC login for %q (sub %s): no matching employee", idToken.Email, idToken.Subject)
+		return nil, ErrSSONoAccount
+	}
+	return employee, nil
+}
+
+func (s *OIDCService) parseFlow(flow string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(flow, func(token *jwt.Token) (interface{}, error) {
+		return s.flowKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.clock.Now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrSSOFailed
+	}
+	return claims, nil
+}
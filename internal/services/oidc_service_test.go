@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"inventory-system/internal/models"
+	"inventory-system/internal/oidc"
+	"inventory-system/internal/oidc/oidctest"
+	"inventory-system/internal/repository"
+)
+
+func newTestOIDCService(t *testing.T, clock *fakeClock) (*OIDCService, *oidctest.Server) {
+	server := oidctest.NewServer(t, "inventory", "client-secret")
+	server.Now = clock.Now
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       server.URL,
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "https://inventory.example.com/auth/sso/callback",
+		Scopes:       []string{"openid", "email"},
+		HTTPClient:   server.Client(),
+		Now:          clock.Now,
+	})
+
+	table := &employeeTable{rows: []*employeeRow{
+		{id: 1, fullName: "Alice", email: "alice@example.com", role: models.RoleEmployee, version: 1, organization: 1},
+	}}
+	employees := repository.NewEmployeeRepository(newFakeDB(t, table.handle))
+	return NewOIDCService(provider, employees, "secret", clock), server
+}
+
+// startSSO начинает вход и проходит страницу входа провайдера. Возвращает
+// состояние входа из cookie, state и code из перенаправления
+func startSSO(t *testing.T, s *OIDCService, server *oidctest.Server) (flow, state, code string) {
+	t.Helper()
+	authURL, flow, err := s.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return flow, location.Query().Get("state"), location.Query().Get("code")
+}
+
+func TestOIDCFinish(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	s, server := newTestOIDCService(t, clock)
+	ctx := context.Background()
+
+	flow, state, code := startSSO(t, s, server)
+	employee, err := s.Finish(ctx, flow, state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if employee.ID != 1 || employee.Email != "alice@example.com" {
+		t.Errorf("employee = %+v", employee)
+	}
+}
+
+func TestOIDCFinishRejectsForeignResponses(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	s, server := newTestOIDCService(t, clock)
+	ctx := context.Background()
+
+	// A callback whose state does not match the browser's flow is refused
+	// before the code is exchanged
+	flow, _, code := startSSO(t, s, server)
+	for _, state := range []string{"", "forged-state"} {
+		if _, err := s.Finish(ctx, flow, state, code); !errors.Is(err, ErrSSOFailed) {
+			t.Errorf("state %q: err = %v, want ErrSSOFailed", state, err)
+		}
+	}
+	if n := server.TokenRequests(); n != 0 {
+		t.Errorf("%d token requests for a mismatched state", n)
+	}
+
+	// The state and code of another browser's login do not fit this flow:
+	// its nonce and PKCE verifier differ
+	_, otherState, otherCode := startSSO(t, s, server)
+	if _, err := s.Finish(ctx, flow, otherState, otherCode); !errors.Is(err, ErrSSOFailed) {
+		t.Errorf("another login's state: err = %v, want ErrSSOFailed", err)
+	}
+
+	// The flow cookie is signed and expires
+	flow, state, code := startSSO(t, s, server)
+	if _, err := s.Finish(ctx, flow+"x", state, code); !errors.Is(err, ErrSSOFailed) {
+		t.Errorf("tampered flow: err = %v, want ErrSSOFailed", err)
+	}
+	clock.Advance(OIDCFlowExpiry + time.Second)
+	if _, err := s.Finish(ctx, flow, state, code); !errors.Is(err, ErrSSOFailed) {
+		t.Errorf("expired flow: err = %v, want ErrSSOFailed", err)
+	}
+}
+
+func TestOIDCFinishChecksEmail(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   error
+	}{
+		{"unverified email", map[string]interface{}{"email_verified": false}, ErrSSOEmailNotVerified},
+		{"no email", map[string]interface{}{"email": nil}, ErrSSONoAccount},
+		{"unknown email", map[string]interface{}{"email": "mallory@example.com"}, ErrSSONoAccount},
+		{"nonce of another login", map[string]interface{}{"nonce": "replayed"}, ErrSSOFailed},
+		{"token for another client", map[string]interface{}{"aud": "another-client"}, ErrSSOFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Now()}
+			s, server := newTestOIDCService(t, clock)
+			server.Claims = tt.claims
+
+			flow, state, code := startSSO(t, s, server)
+			if _, err := s.Finish(context.Background(), flow, state, code); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"strings"
+)
+
+var (
+	ErrOrganizationNotFound = apperror.NotFound("organization not found")
+	ErrEmailDomainTaken     = apperror.Conflict("email domain already belongs to another organization")
+)
+
+// OrganizationService управляет справочником организаций группы и
+// определяет организацию сотрудника по адресу email
+type OrganizationService struct {
+	organizationRepo *repository.OrganizationRepository
+	defaultCode      string
+}
+
+// NewOrganizationService создает сервис. defaultCode — код организации,
+// в которую попадают сотрудники с адресами из доменов, не закрепленных ни
+// за одной организацией
+func NewOrganizationService(organizationRepo *repository.OrganizationRepository, defaultCode string) *OrganizationService {
+	return &OrganizationService{
+		organizationRepo: organizationRepo,
+		defaultCode:      defaultCode,
+	}
+}
+
+func (s *OrganizationService) GetAllOrganizations(ctx context.Context) ([]models.Organization, error) {
+	return s.organizationRepo.GetAll(ctx)
+}
+
+func (s *OrganizationService) GetOrganizationByID(ctx context.Context, id int) (*models.Organization, error) {
+	organization, err := s.organizationRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if organization == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	return organization, nil
+}
+
+func (s *OrganizationService) CreateOrganization(ctx context.Context, organization models.Organization) (int, error) {
+	if err := s.checkDomains(ctx, organization); err != nil {
+		return 0, err
+	}
+	return s.organizationRepo.Create(ctx, organization)
+}
+
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, organization models.Organization) error {
+	if err := s.checkDomains(ctx, organization); err != nil {
+		return err
+	}
+
+	updated, err := s.organizationRepo.Update(ctx, organization)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// checkDomains проверяет, что почтовые домены организации не закреплены за
+// другой организацией: иначе организация сотрудника определялась бы
+// неоднозначно
+func (s *OrganizationService) checkDomains(ctx context.Context, organization models.Organization) error {
+	if len(organization.EmailDomains) == 0 {
+		return nil
+	}
+
+	ownerID, domain, err := s.organizationRepo.DomainOwner(ctx, organization.EmailDomains, organization.ID)
+	if err != nil {
+		return err
+	}
+	if ownerID != 0 {
+		return invalidField(ErrEmailDomainTaken, "email_domains", fmt.Sprintf("domain %s belongs to organization %d", domain, ownerID))
+	}
+	return nil
+}
+
+// ForEmail возвращает организацию, за которой закреплен домен адреса email,
+// а если домен не закреплен — организацию по умолчанию
+func (s *OrganizationService) ForEmail(ctx context.Context, email string) (int, error) {
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		id, err := s.organizationRepo.FindByDomain(ctx, strings.ToLower(email[at+1:]))
+		if err != nil || id != 0 {
+			return id, err
+		}
+	}
+
+	organization, err := s.organizationRepo.GetByCode(ctx, s.defaultCode)
+	if err != nil {
+		return 0, err
+	}
+	if organization == nil {
+		return 0, fmt.Errorf("default organization %q does not exist", s.defaultCode)
+	}
+	return organization.ID, nil
+}
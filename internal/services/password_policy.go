@@ -0,0 +1,69 @@
+package services
+
+import (
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"strings"
+	"unicode"
+)
+
+// Требования к длине пароля. bcrypt учитывает только первые 72 байта
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var ErrWeakPassword = apperror.Validation("password does not meet the password policy", nil)
+
+// commonPasswords — самые распространенные пароли, которые подбирают первыми
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"12345678": true, "123456789": true, "1234567890": true, "87654321": true,
+	"qwerty123": true, "qwertyuiop": true, "1q2w3e4r": true, "1qaz2wsx": true,
+	"abc12345": true, "iloveyou1": true, "welcome1": true, "admin123": true,
+	"letmein1": true, "football1": true, "monkey123": true, "11111111": true,
+	"00000000": true, "qwerty12": true, "zaq12wsx": true, "q1w2e3r4": true,
+}
+
+// checkPasswordPolicy проверяет пароль сотрудника employee. Пароль должен
+// быть длиной от MinPasswordLength до MaxPasswordLength байт, содержать
+// буквы и цифры, не совпадать с распространенными паролями и не содержать
+// имени или email сотрудника. Возвращает описание нарушения или ""
+func checkPasswordPolicy(password string, employee models.Employee) string {
+	if len(password) < MinPasswordLength {
+		return "must be at least 8 characters long"
+	}
+	if len(password) > MaxPasswordLength {
+		return "must be at most 72 bytes long"
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return "must contain both letters and digits"
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return "is too common"
+	}
+
+	personal := strings.Fields(strings.ToLower(employee.FullName))
+	if at := strings.Index(employee.Email, "@"); at > 0 {
+		personal = append(personal, strings.ToLower(employee.Email[:at]))
+	}
+	for _, part := range personal {
+		if len([]rune(part)) >= 3 && strings.Contains(lower, part) {
+			return "must not contain your name or email"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/tenant"
+	"strings"
+	"time"
+)
+
+var ErrInvalidResetToken = apperror.Validation("password reset token is invalid or expired", nil)
+
+// tokenBytes — длина случайной части токенов в ссылках из писем
+const tokenBytes = 32
+
+// PasswordResetService сбрасывает забытые пароли по одноразовым ссылкам,
+// которые отправляются на email сотрудника. В базе хранится только хеш
+// токена, поэтому утечка таблицы не дает сбросить чужой пароль
+type PasswordResetService struct {
+	resetRepo       *repository.PasswordResetRepository
+	employeeRepo    *repository.EmployeeRepository
+	employeeService *EmployeeService
+	transactor      *repository.Transactor
+	notifier        *NotificationService
+	tokenTTL        time.Duration
+}
+
+func NewPasswordResetService(
+	resetRepo *repository.PasswordResetRepository,
+	employeeRepo *repository.EmployeeRepository,
+	employeeService *EmployeeService,
+	transactor *repository.Transactor,
+	notifier *NotificationService,
+	tokenTTL time.Duration,
+) *PasswordResetService {
+	return &PasswordResetService{
+		resetRepo:       resetRepo,
+		employeeRepo:    employeeRepo,
+		employeeService: employeeService,
+		transactor:      transactor,
+		notifier:        notifier,
+		tokenTTL:        tokenTTL,
+	}
+}
+
+// RequestReset отправляет ссылку сброса пароля сотруднику с адресом email
+// и отменяет ссылки, выданные ему раньше. Если такого сотрудника нет,
+// ничего не делает и не сообщает об этом, чтобы по ответу нельзя было
+// проверить, зарегистрирован ли адрес
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string) error {
+	employee, err := s.employeeRepo.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil || employee == nil {
+		return err
+	}
+	// Паролем из внешнего каталога приложение не управляет
+	if employee.AuthSource != "" {
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.tokenTTL)
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.resetRepo.RevokeForEmployee(ctx, employee.ID, now); err != nil {
+			return err
+		}
+		if err := s.resetRepo.Create(ctx, employee.ID, hashToken(token), expiresAt); err != nil {
+			return err
+		}
+		return s.notifier.SendPasswordReset(ctx, *employee, token, expiresAt)
+	})
+}
+
+// ConfirmReset задает новый пароль по токену из ссылки. Токен
+// используется один раз; если новый пароль не проходит проверку, токен
+// остается действующим. После сброса все выданные сотруднику токены входа
+// перестают действовать, а его персональные токены API отзываются
+func (s *PasswordResetService) ConfirmReset(ctx context.Context, token, newPassword string) error {
+	now := time.Now().UTC()
+	// Организация сотрудника становится известна только по токену
+	ctx = tenant.WithAllOrganizations(ctx)
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		employeeID, err := s.resetRepo.Consume(ctx, hashToken(token), now)
+		if err != nil {
+			return err
+		}
+		if employeeID == 0 {
+			return invalidField(ErrInvalidResetToken, "token", "is invalid or expired")
+		}
+
+		employee, err := s.employeeRepo.GetByID(ctx, employeeID)
+		if err != nil {
+			return err
+		}
+		if employee == nil || employee.AuthSource != "" {
+			return invalidField(ErrInvalidResetToken, "token", "is invalid or expired")
+		}
+
+		if reason := checkPasswordPolicy(newPassword, *employee); reason != "" {
+			return invalidField(ErrWeakPassword, "new_password", reason)
+		}
+
+		if err := s.employeeService.UpdatePassword(ctx, employeeID, newPassword); err != nil {
+			return err
+		}
+		// Владелец подтвердил доступ к почте, блокировка входа больше не нужна
+		if _, err := s.employeeRepo.ResetLoginFailures(ctx, employeeID); err != nil {
+			return err
+		}
+		return s.resetRepo.RevokeForEmployee(ctx, employeeID, now)
+	})
+}
+
+// newToken возвращает случайный токен для ссылок из писем
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken возвращает хеш токена, под которым он хранится в базе.
+// Токен случайный и длинный, поэтому медленный хеш не нужен
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+)
+
+// resetToken — строка password_reset_tokens
+type resetToken struct {
+	employeeID int
+	expiresAt  time.Time
+	used       bool
+}
+
+// newResetService возвращает сервис сброса пароля над таблицей tokens,
+// ключом которой служит хеш токена. Сотрудник 1 входит по паролю
+func newResetService(t *testing.T, tokens map[string]*resetToken) *PasswordResetService {
+	db := newFakeDB(t, func(query string, args []driver.Value) (fakeResult, error) {
+		switch {
+		case strings.Contains(query, "UPDATE password_reset_tokens") && strings.Contains(query, "token_hash = $1"):
+			now := args[1].(time.Time)
+			token := tokens[args[0].(string)]
+			if token == nil || token.used || !token.expiresAt.After(now) {
+				return fakeResult{columns: []string{"employee_id"}}, nil
+			}
+			token.used = true
+			return fakeResult{columns: []string{"employee_id"}, rows: [][]driver.Value{{int64(token.employeeID)}}}, nil
+		case strings.Contains(query, "UPDATE password_reset_tokens") && strings.Contains(query, "employee_id = $1"):
+			for _, token := range tokens {
+				if int64(token.employeeID) == args[0] {
+					token.used = true
+				}
+			}
+			return fakeResult{}, nil
+		case strings.Contains(query, "FROM employees e") && strings.Contains(query, "e.id = $1"):
+			return fakeResult{
+				columns: make([]string, 14),
+				rows: [][]driver.Value{{
+					int64(1), "Alice", "Engineer", "alice@example.com", models.RoleEmployee,
+					nil, nil, int64(1), nil, nil, false, "", "", int64(1),
+				}},
+			}, nil
+		case strings.HasPrefix(strings.TrimSpace(query), "UPDATE employees"),
+			strings.Contains(query, "UPDATE api_tokens"):
+			return fakeResult{affected: 1}, nil
+		}
+		t.Errorf("unexpected query %q", query)
+		return fakeResult{}, nil
+	})
+
+	employeeRepo := repository.NewEmployeeRepository(db)
+	transactor := repository.NewTransactor(db)
+	employees := NewEmployeeService(employeeRepo, nil, repository.NewAPITokenRepository(db), nil, transactor, nil)
+	return NewPasswordResetService(repository.NewPasswordResetRepository(db), employeeRepo, employees, transactor, nil, time.Hour)
+}
+
+func TestConfirmResetRejectsReusedToken(t *testing.T) {
+	tokens := map[string]*resetToken{
+		hashToken("reset-token"): {employeeID: 1, expiresAt: time.Now().Add(time.Hour)},
+	}
+	service := newResetService(t, tokens)
+	ctx := context.Background()
+
+	if err := service.ConfirmReset(ctx, "reset-token", "Tr0ub4dor-horse"); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := service.ConfirmReset(ctx, "reset-token", "An0ther-battery"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("second use: err = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestConfirmResetRejectsInvalidToken(t *testing.T) {
+	tokens := map[string]*resetToken{
+		hashToken("expired-token"): {employeeID: 1, expiresAt: time.Now().Add(-time.Minute)},
+	}
+	service := newResetService(t, tokens)
+
+	for _, token := range []string{"expired-token", "unknown-token"} {
+		if err := service.ConfirmReset(context.Background(), token, "Tr0ub4dor-horse"); !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidResetToken", token, err)
+		}
+	}
+}
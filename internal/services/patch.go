@@ -0,0 +1,90 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/mergepatch"
+	"inventory-system/internal/validate"
+	"reflect"
+)
+
+var ErrInvalidPatch = apperror.Validation("invalid patch", nil)
+
+// patchFields перечисляет поля записи, которые можно менять через PATCH.
+// Значение показывает, допустим ли для поля null
+type patchFields map[string]bool
+
+// applyPatch проверяет ключи патча, применяет его к current и записывает
+// результат в patched. Измененные поля проверяются по правилам тела запроса
+// request, которым запись заменяется целиком
+func applyPatch(current interface{}, patch mergepatch.Patch, fields patchFields, patched, request interface{}) error {
+	for key := range patch {
+		nullable, ok := fields[key]
+		if !ok {
+			return invalidField(ErrInvalidPatch, key, "cannot be changed")
+		}
+		if !nullable && patch.IsNull(key) {
+			return invalidField(ErrInvalidPatch, key, "cannot be null")
+		}
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	merged, err := patch.Apply(doc)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	if err := json.Unmarshal(merged, patched); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	if err := json.Unmarshal(merged, request); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return validatePatched(request, patch)
+}
+
+// validatePatched проверяет request и оставляет только ошибки полей,
+// затронутых патчем: ранее сохраненные значения не мешают изменить остальные
+func validatePatched(request interface{}, patch mergepatch.Patch) error {
+	fields := apperror.FieldsOf(validate.Struct(request))
+
+	errs := make(map[string]string)
+	for field, message := range fields {
+		if _, ok := patch[field]; ok {
+			errs[field] = message
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apperror.Validation("request validation failed", errs)
+}
+
+func sameInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameValues сравнивает значения дополнительных полей, считая nil и пустой
+// набор одинаковыми
+func sameValues(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
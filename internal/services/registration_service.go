@@ -0,0 +1,401 @@
+package services
+
+import (
+	"context"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/tenant"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRegistrationDisabled = apperror.Forbidden("registration is disabled")
+	ErrSelfSignupDisabled   = apperror.Forbidden("self-signup is disabled, registration is by invitation only")
+	ErrDomainNotAllowed     = apperror.Validation("email domain is not allowed for self-signup", nil)
+	ErrInvitationNotFound   = apperror.NotFound("invitation not found")
+	ErrInvalidInvitation    = apperror.Validation("invitation is invalid or expired", nil)
+	ErrInvitationClosed     = apperror.Conflict("invitation is already accepted or revoked")
+)
+
+// signupResendInterval — как часто можно запрашивать ссылку регистрации
+// на один адрес
+const signupResendInterval = time.Minute
+
+// RegistrationService управляет регистрацией сотрудников. В зависимости
+// от режима регистрация отключена, возможна только по приглашениям
+// администраторов или также по ссылке, которую сотрудник запрашивает на
+// адрес из разрешенного домена. Ссылка подтверждает, что адрес
+// принадлежит ему
+type RegistrationService struct {
+	invitationRepo  *repository.InvitationRepository
+	employeeRepo    *repository.EmployeeRepository
+	departmentRepo  *repository.DepartmentRepository
+	employeeService *EmployeeService
+	organizations   *OrganizationService
+	transactor      *repository.Transactor
+	notifier        *NotificationService
+	clock           ratelimit.Clock
+	mode            string
+	domains         []string
+	invitationTTL   time.Duration
+}
+
+func NewRegistrationService(
+	invitationRepo *repository.InvitationRepository,
+	employeeRepo *repository.EmployeeRepository,
+	departmentRepo *repository.DepartmentRepository,
+	employeeService *EmployeeService,
+	organizations *OrganizationService,
+	transactor *repository.Transactor,
+	notifier *NotificationService,
+	clock ratelimit.Clock,
+	mode string,
+	domains []string,
+	invitationTTL time.Duration,
+) *RegistrationService {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		normalized = append(normalized, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@")))
+	}
+
+	return &RegistrationService{
+		invitationRepo:  invitationRepo,
+		employeeRepo:    employeeRepo,
+		departmentRepo:  departmentRepo,
+		employeeService: employeeService,
+		organizations:   organizations,
+		transactor:      transactor,
+		notifier:        notifier,
+		clock:           clock,
+		mode:            mode,
+		domains:         normalized,
+		invitationTTL:   invitationTTL,
+	}
+}
+
+// Settings возвращает режим регистрации для страницы регистрации
+func (s *RegistrationService) Settings() models.RegistrationSettings {
+	settings := models.RegistrationSettings{Mode: s.mode}
+	if s.mode == models.RegistrationDomain {
+		settings.Domains = s.domains
+	}
+	return settings
+}
+
+// RequestSignup отправляет ссылку регистрации на адрес из разрешенного
+// домена. Сотрудник регистрируется в организации, за которой закреплен
+// домен адреса, а если домен не закреплен — в организации по умолчанию. Если адрес уже зарегистрирован или ссылка только что
+// отправлялась, ничего не делает и не сообщает об этом
+func (s *RegistrationService) RequestSignup(ctx context.Context, email string) error {
+	if s.mode != models.RegistrationDomain {
+		return s.closedError()
+	}
+
+	email = strings.TrimSpace(email)
+	if !s.domainAllowed(email) {
+		return invalidField(ErrDomainNotAllowed, "email", "must be in one of the domains: "+strings.Join(s.domains, ", "))
+	}
+
+	existing, err := s.employeeRepo.GetByEmail(ctx, email)
+	if err != nil || existing != nil {
+		return err
+	}
+
+	organizationID, err := s.organizations.ForEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	ctx = tenant.WithOrganization(ctx, organizationID)
+
+	now := s.clock.Now().UTC()
+	recent, err := s.invitationRepo.CountCreatedSince(ctx, email, now.Add(-signupResendInterval))
+	if err != nil || recent > 0 {
+		return err
+	}
+
+	_, err = s.invite(ctx, models.Invitation{
+		Email:     email,
+		Role:      models.RoleEmployee,
+		ExpiresAt: now.Add(s.invitationTTL),
+	}, nil)
+	return err
+}
+
+// CreateInvitation приглашает сотрудника с ролью и отделом из запроса и
+// отправляет ему ссылку регистрации. Прежние приглашения на этот адрес
+// отзываются
+func (s *RegistrationService) CreateInvitation(ctx context.Context, inviter *models.Employee, request models.InvitationRequest) (*models.CreatedInvitation, error) {
+	if s.mode == models.RegistrationDisabled {
+		return nil, ErrRegistrationDisabled
+	}
+
+	now := s.clock.Now().UTC()
+	invitation := models.Invitation{
+		Email:        strings.TrimSpace(request.Email),
+		Role:         request.Role,
+		DepartmentID: request.DepartmentID,
+		InvitedBy:    &inviter.ID,
+		ExpiresAt:    now.Add(s.invitationTTL),
+	}
+	if request.ExpiresAt != nil {
+		invitation.ExpiresAt = request.ExpiresAt.UTC()
+		if !invitation.ExpiresAt.After(now) {
+			return nil, invalidField(ErrInvalidInvitation, "expires_at", "must be in the future")
+		}
+	}
+
+	if invitation.DepartmentID != nil {
+		exists, err := s.departmentRepo.Exists(ctx, *invitation.DepartmentID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, invalidReference("department_id", ErrDepartmentNotFound)
+		}
+	}
+
+	existing, err := s.employeeRepo.GetByEmail(ctx, invitation.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrEmailAlreadyExists
+	}
+
+	return s.invite(ctx, invitation, inviter)
+}
+
+// invite сохраняет приглашение, отзывая прежние на тот же адрес вместе с
+// их неотправленными письмами, и отправляет ссылку регистрации
+func (s *RegistrationService) invite(ctx context.Context, invitation models.Invitation, inviter *models.Employee) (*models.CreatedInvitation, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().UTC()
+	invitation.CreatedAt = now
+	invitation.Status = models.InvitationPending
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.invitationRepo.DiscardMailForEmail(ctx, invitation.Email); err != nil {
+			return err
+		}
+		if err := s.invitationRepo.RevokePendingForEmail(ctx, invitation.Email, now); err != nil {
+			return err
+		}
+		invitation.ID, err = s.invitationRepo.Create(ctx, invitation, hashToken(token))
+		if err != nil {
+			return err
+		}
+		return s.sendInvitation(ctx, invitation, inviter, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedInvitation{Invitation: invitation, Link: s.notifier.InvitationLink(token)}, nil
+}
+
+// ResendInvitation выдает непринятому приглашению новую ссылку с новым
+// сроком и отправляет ее. Прежняя ссылка перестает действовать, а еще не
+// отправленное письмо с ней удаляется из очереди
+func (s *RegistrationService) ResendInvitation(ctx context.Context, inviter *models.Employee, id int) (*models.CreatedInvitation, error) {
+	if s.mode == models.RegistrationDisabled {
+		return nil, ErrRegistrationDisabled
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var invitation *models.Invitation
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		invitation, err = s.invitationRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if invitation == nil {
+			return ErrInvitationNotFound
+		}
+
+		if err := s.invitationRepo.DiscardMail(ctx, id); err != nil {
+			return err
+		}
+		invitation.ExpiresAt = s.clock.Now().UTC().Add(s.invitationTTL)
+		renewed, err := s.invitationRepo.Renew(ctx, id, hashToken(token), invitation.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		if !renewed {
+			return ErrInvitationClosed
+		}
+		return s.sendInvitation(ctx, *invitation, inviter, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.Status = invitation.StatusAt(s.clock.Now())
+	return &models.CreatedInvitation{Invitation: *invitation, Link: s.notifier.InvitationLink(token)}, nil
+}
+
+// sendInvitation ставит в очередь письмо со ссылкой регистрации и
+// запоминает его у приглашения
+func (s *RegistrationService) sendInvitation(ctx context.Context, invitation models.Invitation, inviter *models.Employee, token string) error {
+	mailID, err := s.notifier.SendInvitation(ctx, invitation, inviter, token)
+	if err != nil || mailID == 0 {
+		return err
+	}
+	return s.invitationRepo.SetMail(ctx, invitation.ID, mailID)
+}
+
+// GetInvitations возвращает приглашения в состоянии status, начиная с
+// новых. Пустой status — все приглашения
+func (s *RegistrationService) GetInvitations(ctx context.Context, status string) ([]models.Invitation, error) {
+	now := s.clock.Now().UTC()
+	invitations, err := s.invitationRepo.GetAll(ctx, status, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range invitations {
+		invitations[i].Status = invitations[i].StatusAt(now)
+	}
+	return invitations, nil
+}
+
+// GetInvitation возвращает приглашение по id
+func (s *RegistrationService) GetInvitation(ctx context.Context, id int) (*models.Invitation, error) {
+	invitation, err := s.invitationRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil {
+		return nil, ErrInvitationNotFound
+	}
+	invitation.Status = invitation.StatusAt(s.clock.Now().UTC())
+	return invitation, nil
+}
+
+// RevokeInvitation отзывает непринятое приглашение и удаляет из очереди
+// еще не отправленное письмо с ним
+func (s *RegistrationService) RevokeInvitation(ctx context.Context, id int) error {
+	if err := s.invitationRepo.DiscardMail(ctx, id); err != nil {
+		return err
+	}
+
+	revoked, err := s.invitationRepo.Revoke(ctx, id, s.clock.Now().UTC())
+	if err != nil || revoked {
+		return err
+	}
+
+	invitation, err := s.invitationRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if invitation == nil {
+		return ErrInvitationNotFound
+	}
+	if invitation.AcceptedAt != nil {
+		return ErrInvitationClosed
+	}
+	// Уже отозвано
+	return nil
+}
+
+// PreviewInvitation возвращает данные действующего приглашения для
+// страницы регистрации
+func (s *RegistrationService) PreviewInvitation(ctx context.Context, token string) (*models.InvitationPreview, error) {
+	invitation, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InvitationPreview{
+		Email:        invitation.Email,
+		Role:         invitation.Role,
+		DepartmentID: invitation.DepartmentID,
+		ExpiresAt:    invitation.ExpiresAt,
+	}, nil
+}
+
+// Register создает сотрудника по приглашению: email, роль и отдел берутся
+// из приглашения, сотрудник создается в организации приглашения.
+// Приглашение используется один раз
+func (s *RegistrationService) Register(ctx context.Context, request models.RegisterRequest) (int, error) {
+	invitation, err := s.pendingInvitation(ctx, request.InvitationToken)
+	if err != nil {
+		return 0, err
+	}
+	ctx = tenant.WithOrganization(ctx, invitation.OrganizationID)
+
+	var id int
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		id, err = s.employeeService.CreateEmployee(ctx, models.Employee{
+			FullName:     request.FullName,
+			Position:     request.Position,
+			Email:        invitation.Email,
+			PasswordHash: request.Password,
+			Role:         invitation.Role,
+			DepartmentID: invitation.DepartmentID,
+		})
+		if err != nil {
+			return err
+		}
+
+		accepted, err := s.invitationRepo.Accept(ctx, invitation.ID, id, s.clock.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return invalidField(ErrInvalidInvitation, "invitation_token", "is invalid or expired")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// pendingInvitation возвращает действующее приглашение с токеном token
+func (s *RegistrationService) pendingInvitation(ctx context.Context, token string) (*models.Invitation, error) {
+	if s.mode == models.RegistrationDisabled {
+		return nil, ErrRegistrationDisabled
+	}
+
+	invitation, err := s.invitationRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.StatusAt(s.clock.Now().UTC()) != models.InvitationPending {
+		return nil, invalidField(ErrInvalidInvitation, "invitation_token", "is invalid or expired")
+	}
+	return invitation, nil
+}
+
+func (s *RegistrationService) closedError() error {
+	if s.mode == models.RegistrationDisabled {
+		return ErrRegistrationDisabled
+	}
+	return ErrSelfSignupDisabled
+}
+
+// domainAllowed сообщает, входит ли домен адреса email в разрешенные
+func (s *RegistrationService) domainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range s.domains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
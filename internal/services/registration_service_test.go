@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+)
+
+func TestInvitationMustBePending(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := now.Add(-24 * time.Hour)
+
+	// Строки invitations по токену: время принятия и отзыва, срок действия
+	invitations := map[string][]driver.Value{
+		"pending":  {nil, nil, now.Add(time.Hour)},
+		"expired":  {nil, nil, now},
+		"revoked":  {nil, now.Add(-time.Hour), now.Add(time.Hour)},
+		"accepted": {now.Add(-time.Hour), nil, now.Add(time.Hour)},
+	}
+	byHash := make(map[string][]driver.Value, len(invitations))
+	for token, state := range invitations {
+		byHash[hashToken(token)] = state
+	}
+
+	db := newFakeDB(t, func(query string, args []driver.Value) (fakeResult, error) {
+		if !strings.Contains(query, "FROM invitations WHERE token_hash = $1") {
+			t.Errorf("unexpected query %q", query)
+			return fakeResult{}, nil
+		}
+		result := fakeResult{columns: make([]string, 11)}
+		state, ok := byHash[args[0].(string)]
+		if !ok {
+			return result, nil
+		}
+		acceptedAt, revokedAt, expiresAt := state[0], state[1], state[2]
+		var employeeID driver.Value
+		if acceptedAt != nil {
+			employeeID = int64(5)
+		}
+		result.rows = [][]driver.Value{{
+			int64(1), "new@example.com", models.RoleEmployee, nil, int64(1),
+			expiresAt, acceptedAt, employeeID, revokedAt, created, int64(1),
+		}}
+		return result, nil
+	})
+
+	// Приглашение отклоняется раньше, чем создается сотрудник, поэтому
+	// остальные зависимости сервису не нужны
+	service := NewRegistrationService(
+		repository.NewInvitationRepository(db), nil, nil, nil, nil, repository.NewTransactor(db), nil,
+		&fakeClock{now: now}, models.RegistrationInvite, nil, 7*24*time.Hour,
+	)
+	ctx := context.Background()
+
+	if preview, err := service.PreviewInvitation(ctx, "pending"); err != nil || preview.Email != "new@example.com" {
+		t.Errorf("pending invitation: %+v, %v", preview, err)
+	}
+	for _, token := range []string{"expired", "revoked", "accepted", "unknown"} {
+		if _, err := service.PreviewInvitation(ctx, token); !errors.Is(err, ErrInvalidInvitation) {
+			t.Errorf("preview of %s invitation: err = %v, want ErrInvalidInvitation", token, err)
+		}
+		_, err := service.Register(ctx, models.RegisterRequest{
+			InvitationToken: token,
+			FullName:        "Новый Сотрудник",
+			Position:        "Инженер",
+			Password:        "Tr0ub4dor-horse",
+		})
+		if !errors.Is(err, ErrInvalidInvitation) {
+			t.Errorf("registration with %s invitation: err = %v, want ErrInvalidInvitation", token, err)
+		}
+	}
+}
@@ -3,18 +3,28 @@ package services
 import (
 	"context"
 	"inventory-system/internal/models"
+	"inventory-system/internal/tenant"
 	"time"
 )
 
 type ReportService struct {
-	assetService    *AssetService
-	transferService *TransferService
+	assetService        *AssetService
+	transferService     *TransferService
+	categoryService     *CategoryService
+	organizationService *OrganizationService
 }
 
-func NewReportService(assetService *AssetService, transferService *TransferService) *ReportService {
+func NewReportService(
+	assetService *AssetService,
+	transferService *TransferService,
+	categoryService *CategoryService,
+	organizationService *OrganizationService,
+) *ReportService {
 	return &ReportService{
-		assetService:    assetService,
-		transferService: transferService,
+		assetService:        assetService,
+		transferService:     transferService,
+		categoryService:     categoryService,
+		organizationService: organizationService,
 	}
 }
 
@@ -132,3 +142,143 @@ func (s *ReportService) GenerateInventoryReport(ctx context.Context) (*models.In
 
 	return report, nil
 }
+
+// GenerateCategoryReport считает количество и стоимость активов по дереву
+// категорий. Итоги подкатегорий суммируются в итоги родителей
+func (s *ReportService) GenerateCategoryReport(ctx context.Context) ([]models.CategoryReport, error) {
+	tree, err := s.categoryService.GetCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.assetService.GetAllAssets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int)
+	costs := make(map[int]float64)
+	for _, asset := range assets {
+		categoryID := 0
+		if asset.CategoryID != nil {
+			categoryID = *asset.CategoryID
+		}
+		counts[categoryID]++
+		costs[categoryID] += asset.Cost
+	}
+
+	var rollup func(nodes []models.Category) []models.CategoryReport
+	rollup = func(nodes []models.Category) []models.CategoryReport {
+		report := make([]models.CategoryReport, 0, len(nodes))
+		for _, node := range nodes {
+			item := models.CategoryReport{
+				CategoryID:  node.ID,
+				Category:    node.Name,
+				Count:       counts[node.ID],
+				TotalCost:   costs[node.ID],
+				RollupCount: counts[node.ID],
+				RollupCost:  costs[node.ID],
+				Children:    rollup(node.Children),
+			}
+			for _, child := range item.Children {
+				item.RollupCount += child.RollupCount
+				item.RollupCost += child.RollupCost
+			}
+			report = append(report, item)
+		}
+		return report
+	}
+
+	report := rollup(tree)
+	if counts[0] > 0 {
+		report = append(report, models.CategoryReport{
+			Category:    "Без категории",
+			Count:       counts[0],
+			TotalCost:   costs[0],
+			RollupCount: counts[0],
+			RollupCost:  costs[0],
+		})
+	}
+
+	return report, nil
+}
+
+// GenerateGroupInventoryReport строит сводный отчет по активам всех
+// организаций группы: итоги по группе и по каждой организации
+func (s *ReportService) GenerateGroupInventoryReport(ctx context.Context) (*models.GroupInventoryReport, error) {
+	organizations, err := s.organizationService.GetAllOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.GroupInventoryReport{
+		ByStatus:      make(map[string]int),
+		Organizations: make([]models.OrganizationInventoryLine, 0, len(organizations)),
+		GeneratedAt:   time.Now(),
+	}
+	for _, organization := range organizations {
+		assets, err := s.assetService.GetAllAssets(tenant.WithOrganization(ctx, organization.ID))
+		if err != nil {
+			return nil, err
+		}
+
+		line := models.OrganizationInventoryLine{
+			OrganizationID: organization.ID,
+			Organization:   organization.Name,
+			TotalAssets:    len(assets),
+			ByStatus:       make(map[string]int),
+		}
+		for _, asset := range assets {
+			line.TotalValue += asset.Cost
+			line.ByStatus[asset.Status]++
+			report.ByStatus[asset.Status]++
+		}
+
+		report.TotalAssets += line.TotalAssets
+		report.TotalValue += line.TotalValue
+		report.Organizations = append(report.Organizations, line)
+	}
+
+	return report, nil
+}
+
+// GenerateGroupAssetsByStatusReport строит распределение активов по
+// статусам для каждой организации группы и для группы в целом
+func (s *ReportService) GenerateGroupAssetsByStatusReport(ctx context.Context) (*models.GroupAssetsByStatusReport, error) {
+	organizations, err := s.organizationService.GetAllOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.GroupAssetsByStatusReport{
+		Organizations: make([]models.OrganizationAssetsByStatus, 0, len(organizations)),
+	}
+	// Статусы общие для группы: итоги складываются в порядке первого
+	// появления статуса
+	totals := make(map[string]int)
+	for _, organization := range organizations {
+		byStatus, err := s.GenerateAssetsByStatusReport(tenant.WithOrganization(ctx, organization.ID))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range byStatus {
+			i, ok := totals[line.Status]
+			if !ok {
+				i = len(report.Totals)
+				totals[line.Status] = i
+				report.Totals = append(report.Totals, models.AssetsByStatusReport{Status: line.Status})
+			}
+			report.Totals[i].Count += line.Count
+			report.Totals[i].TotalCost += line.TotalCost
+		}
+
+		report.Organizations = append(report.Organizations, models.OrganizationAssetsByStatus{
+			OrganizationID: organization.ID,
+			Organization:   organization.Name,
+			ByStatus:       byStatus,
+		})
+	}
+
+	return report, nil
+}
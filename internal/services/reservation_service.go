@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
+	"inventory-system/internal/ical"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"time"
+)
+
+var (
+	ErrReservationNotFound    = apperror.NotFound("reservation not found")
+	ErrReservationConflict    = apperror.Conflict("asset is already reserved for this time")
+	ErrInvalidReservationTime = apperror.Validation("reservation must end after it starts", nil)
+	ErrReservationInPast      = apperror.Validation("reservation cannot start in the past", nil)
+	ErrReservationNotPending  = apperror.Conflict("reservation is not waiting for approval")
+	ErrReservationClosed      = apperror.Conflict("reservation is already rejected or cancelled")
+	ErrReservationForbidden   = apperror.Forbidden("not allowed to manage this reservation")
+)
+
+type ReservationService struct {
+	reservationRepo *repository.ReservationRepository
+	assetRepo       *repository.AssetRepository
+	employeeRepo    *repository.EmployeeRepository
+	categoryRepo    *repository.CategoryRepository
+	locationRepo    *repository.LocationRepository
+	transactor      *repository.Transactor
+	notifier        *NotificationService
+	bus             *events.Bus
+}
+
+func NewReservationService(
+	reservationRepo *repository.ReservationRepository,
+	assetRepo *repository.AssetRepository,
+	employeeRepo *repository.EmployeeRepository,
+	categoryRepo *repository.CategoryRepository,
+	locationRepo *repository.LocationRepository,
+	transactor *repository.Transactor,
+	notifier *NotificationService,
+	bus *events.Bus,
+) *ReservationService {
+	return &ReservationService{
+		reservationRepo: reservationRepo,
+		assetRepo:       assetRepo,
+		employeeRepo:    employeeRepo,
+		categoryRepo:    categoryRepo,
+		locationRepo:    locationRepo,
+		transactor:      transactor,
+		notifier:        notifier,
+		bus:             bus,
+	}
+}
+
+// canManageReservations сообщает, может ли сотрудник подтверждать и
+// оформлять брони за других
+func canManageReservations(employee *models.Employee) bool {
+	return employee.Role == models.RoleManager || employee.Role == models.RoleAdmin ||
+		employee.Role == models.RoleSuperAdmin
+}
+
+func (s *ReservationService) GetReservations(ctx context.Context, filter models.ReservationFilter) ([]models.Reservation, error) {
+	return s.reservationRepo.GetAll(ctx, filter)
+}
+
+func (s *ReservationService) GetReservationByID(ctx context.Context, id int) (*models.Reservation, error) {
+	reservation, err := s.reservationRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil {
+		return nil, ErrReservationNotFound
+	}
+	return reservation, nil
+}
+
+// CreateReservation бронирует актив. Бронь в категории, требующей
+// подтверждения, создается в статусе pending, иначе сразу approved
+func (s *ReservationService) CreateReservation(ctx context.Context, reservation models.Reservation, actor *models.Employee) (int, error) {
+	// Reserve for yourself unless you are a manager
+	if reservation.EmployeeID == 0 {
+		reservation.EmployeeID = actor.ID
+	}
+	if reservation.EmployeeID != actor.ID && !canManageReservations(actor) {
+		return 0, ErrReservationForbidden
+	}
+
+	// Validate period
+	if !reservation.EndsAt.After(reservation.StartsAt) {
+		return 0, ErrInvalidReservationTime
+	}
+	if reservation.StartsAt.Before(time.Now().Add(-time.Minute)) {
+		return 0, ErrReservationInPast
+	}
+
+	// Validate asset
+	asset, err := s.assetRepo.GetByID(ctx, reservation.AssetID)
+	if err != nil {
+		return 0, err
+	}
+	if asset == nil {
+		return 0, invalidReference("asset_id", ErrAssetNotFound)
+	}
+
+	// Validate employee
+	exists, err := s.employeeRepo.Exists(ctx, reservation.EmployeeID)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, invalidReference("employee_id", ErrEmployeeNotFound)
+	}
+
+	// Check for overlapping reservations
+	reservation.ID = 0
+	overlap, err := s.reservationRepo.HasOverlap(ctx, reservation)
+	if err != nil {
+		return 0, err
+	}
+	if overlap {
+		return 0, ErrReservationConflict
+	}
+
+	reservation.Status = models.ReservationApproved
+	if asset.CategoryID != nil {
+		requiresApproval, err := s.requiresApproval(ctx, *asset.CategoryID)
+		if err != nil {
+			return 0, err
+		}
+		if requiresApproval {
+			reservation.Status = models.ReservationPending
+		}
+	}
+
+	var id int
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		id, err = s.reservationRepo.Create(ctx, reservation)
+		if err != nil || reservation.Status != models.ReservationPending {
+			return err
+		}
+
+		created, err := s.reservationRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if created == nil {
+			return ErrReservationNotFound
+		}
+		return s.notifier.NotifyApprovalRequest(ctx, *created)
+	})
+	if errors.Is(err, repository.ErrReservationOverlap) {
+		return 0, ErrReservationConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.bus.Publish(ctx, events.EntityReservation, events.ActionCreated, id)
+	return id, nil
+}
+
+// requiresApproval учитывает настройку всех родительских категорий
+func (s *ReservationService) requiresApproval(ctx context.Context, categoryID int) (bool, error) {
+	chain, err := s.categoryRepo.GetAncestors(ctx, categoryID)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range chain {
+		if c.RequiresApproval {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *ReservationService) ApproveReservation(ctx context.Context, id int, actor *models.Employee) error {
+	return s.review(ctx, id, actor, models.ReservationApproved)
+}
+
+func (s *ReservationService) RejectReservation(ctx context.Context, id int, actor *models.Employee) error {
+	return s.review(ctx, id, actor, models.ReservationRejected)
+}
+
+func (s *ReservationService) review(ctx context.Context, id int, actor *models.Employee, status string) error {
+	if !canManageReservations(actor) {
+		return ErrReservationForbidden
+	}
+
+	reservation, err := s.GetReservationByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if reservation.Status != models.ReservationPending {
+		return ErrReservationNotPending
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.reservationRepo.UpdateStatus(ctx, id, status, &actor.ID); err != nil {
+			return err
+		}
+		if err := s.notifier.ResolveApprovalRequest(ctx, id); err != nil {
+			return err
+		}
+		reservation.Status = status
+		return s.notifier.NotifyReservationReviewed(ctx, *reservation)
+	})
+	if errors.Is(err, repository.ErrReservationOverlap) {
+		return ErrReservationConflict
+	}
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityReservation, events.ActionUpdated, id)
+	return nil
+}
+
+// CancelReservation отменяет бронь. Отменить может владелец брони или менеджер
+func (s *ReservationService) CancelReservation(ctx context.Context, id int, actor *models.Employee) error {
+	reservation, err := s.GetReservationByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if reservation.EmployeeID != actor.ID && !canManageReservations(actor) {
+		return ErrReservationForbidden
+	}
+	if reservation.Status == models.ReservationRejected || reservation.Status == models.ReservationCancelled {
+		return ErrReservationClosed
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.reservationRepo.UpdateStatus(ctx, id, models.ReservationCancelled, nil); err != nil {
+			return err
+		}
+		return s.notifier.ResolveApprovalRequest(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events.EntityReservation, events.ActionUpdated, id)
+	return nil
+}
+
+// GetAvailableAssets отвечает на вопрос «какие проекторы свободны во вторник
+// с 14:00 до 16:00 в главном офисе»
+func (s *ReservationService) GetAvailableAssets(ctx context.Context, query models.AvailabilityQuery) ([]models.Asset, error) {
+	if !query.To.After(query.From) {
+		return nil, ErrInvalidReservationTime
+	}
+
+	if query.CategoryID != nil {
+		exists, err := s.categoryRepo.Exists(ctx, *query.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, invalidReference("category_id", ErrCategoryNotFound)
+		}
+	}
+
+	if query.LocationID != nil {
+		exists, err := s.locationRepo.Exists(ctx, *query.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, invalidReference("location_id", ErrLocationNotFound)
+		}
+	}
+
+	return s.reservationRepo.GetAvailableAssets(ctx, query)
+}
+
+// GetAssetCalendar возвращает календарь активных броней актива
+func (s *ReservationService) GetAssetCalendar(ctx context.Context, assetID int) (*ical.Calendar, error) {
+	asset, err := s.assetRepo.GetByID(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, ErrAssetNotFound
+	}
+
+	reservations, err := s.reservationRepo.GetAll(ctx, models.ReservationFilter{AssetID: &assetID})
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &ical.Calendar{Name: "Бронирования: " + asset.Name}
+	for _, r := range reservations {
+		if event, ok := reservationEvent(r, r.EmployeeName, asset.Location); ok {
+			calendar.Events = append(calendar.Events, event)
+		}
+	}
+	return calendar, nil
+}
+
+// GetEmployeeCalendar возвращает календарь активных броней сотрудника
+func (s *ReservationService) GetEmployeeCalendar(ctx context.Context, employeeID int) (*ical.Calendar, error) {
+	employee, err := s.employeeRepo.GetByID(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if employee == nil {
+		return nil, ErrEmployeeNotFound
+	}
+
+	reservations, err := s.reservationRepo.GetAll(ctx, models.ReservationFilter{EmployeeID: &employeeID})
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &ical.Calendar{Name: "Бронирования: " + employee.FullName}
+	for _, r := range reservations {
+		if event, ok := reservationEvent(r, r.AssetName, ""); ok {
+			calendar.Events = append(calendar.Events, event)
+		}
+	}
+	return calendar, nil
+}
+
+func reservationEvent(r models.Reservation, summary, location string) (ical.Event, bool) {
+	var status string
+	switch r.Status {
+	case models.ReservationApproved:
+		status = "CONFIRMED"
+	case models.ReservationPending:
+		status = "TENTATIVE"
+	default:
+		return ical.Event{}, false
+	}
+
+	return ical.Event{
+		UID:         fmt.Sprintf("reservation-%d@inventory-system", r.ID),
+		Summary:     summary,
+		Description: r.Purpose,
+		Location:    location,
+		Status:      status,
+		Start:       r.StartsAt,
+		End:         r.EndsAt,
+		Created:     r.CreatedAt,
+	}, true
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/repository"
+	"log"
+	"time"
+)
+
+var (
+	ErrNothingToRestore = apperror.NotFound("no deleted record with this id")
+)
+
+// RetentionService окончательно удаляет записи, которые были помечены
+// удаленными дольше заданного срока
+type RetentionService struct {
+	assetRepo      *repository.AssetRepository
+	employeeRepo   *repository.EmployeeRepository
+	departmentRepo *repository.DepartmentRepository
+	locationRepo   *repository.LocationRepository
+	period         time.Duration
+	interval       time.Duration
+}
+
+func NewRetentionService(
+	assetRepo *repository.AssetRepository,
+	employeeRepo *repository.EmployeeRepository,
+	departmentRepo *repository.DepartmentRepository,
+	locationRepo *repository.LocationRepository,
+	period time.Duration,
+	interval time.Duration,
+) *RetentionService {
+	return &RetentionService{
+		assetRepo:      assetRepo,
+		employeeRepo:   employeeRepo,
+		departmentRepo: departmentRepo,
+		locationRepo:   locationRepo,
+		period:         period,
+		interval:       interval,
+	}
+}
+
+// Run запускает очистку сразу и затем с интервалом interval до отмены ctx
+func (s *RetentionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Purge(ctx, time.Now()); err != nil {
+			log.Printf("Retention purge failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge удаляет записи, помеченные удаленными раньше now - period.
+// Активы удаляются первыми, чтобы освободить ссылки на сотрудников и
+// местоположения
+func (s *RetentionService) Purge(ctx context.Context, now time.Time) error {
+	before := now.Add(-s.period)
+
+	assets, err := s.assetRepo.PurgeDeleted(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	employees, err := s.employeeRepo.PurgeDeleted(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	departments, err := s.departmentRepo.PurgeDeleted(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	locations, err := s.locationRepo.PurgeDeleted(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	if assets+employees+departments+locations > 0 {
+		log.Printf("Retention purge: %d assets, %d employees, %d departments, %d locations",
+			assets, employees, departments, locations)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/signing"
+	"log"
+	"time"
+)
+
+// KeyRotationPolicy — расписание ротации ключей подписи. Каждый ключ
+// подписывает токены RotationInterval; следующий ключ публикуется в JWKS
+// за Prepublish до начала подписи, чтобы другие сервисы и экземпляры
+// приложения успели его загрузить. Ключ, переставший подписывать,
+// публикуется еще TokenLifetime, пока действуют подписанные им токены
+type KeyRotationPolicy struct {
+	Algorithm        string
+	RotationInterval time.Duration
+	Prepublish       time.Duration
+	TokenLifetime    time.Duration
+}
+
+// SigningKeyService ведет ротацию ключей подписи токенов доступа и
+// обновляет набор ключей приложения из базы. Ключи общие для всех
+// экземпляров приложения; ротацию выполняет тот, кто первым заметит, что
+// пора
+type SigningKeyService struct {
+	keyRepo    *repository.SigningKeyRepository
+	transactor *repository.Transactor
+	ring       *signing.KeyRing
+	policy     KeyRotationPolicy
+	clock      ratelimit.Clock
+	interval   time.Duration
+}
+
+func NewSigningKeyService(
+	keyRepo *repository.SigningKeyRepository,
+	transactor *repository.Transactor,
+	ring *signing.KeyRing,
+	policy KeyRotationPolicy,
+	clock ratelimit.Clock,
+	interval time.Duration,
+) *SigningKeyService {
+	return &SigningKeyService{
+		keyRepo:    keyRepo,
+		transactor: transactor,
+		ring:       ring,
+		policy:     policy,
+		clock:      clock,
+		interval:   interval,
+	}
+}
+
+// Run проверяет ротацию и обновляет набор ключей с интервалом interval до
+// отмены ctx. Первую проверку выполняет Rotate при запуске приложения
+func (s *SigningKeyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Rotate(ctx); err != nil {
+			log.Printf("Signing key rotation failed: %v", err)
+		}
+	}
+}
+
+// Rotate создает ключи по расписанию, удаляет снятые с публикации и
+// загружает набор ключей. Без ключей приложение не может выдавать токены,
+// поэтому при первом запуске ключ начинает подписывать сразу
+func (s *SigningKeyService) Rotate(ctx context.Context) error {
+	now := s.clock.Now().UTC()
+
+	var keys []*signing.Key
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.keyRepo.Lock(ctx); err != nil {
+			return err
+		}
+
+		current, err := s.keyRepo.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		activatesAt, retire := s.nextActivation(latestKey(current), now)
+		if !activatesAt.IsZero() {
+			key, err := signing.Generate(s.policy.Algorithm)
+			if err != nil {
+				return err
+			}
+			key.ActivatesAt = activatesAt
+			key.CreatedAt = now
+			if err := s.keyRepo.Create(ctx, key); err != nil {
+				return err
+			}
+			if retire != nil {
+				if err := s.keyRepo.Retire(ctx, retire.ID, activatesAt, activatesAt.Add(s.policy.TokenLifetime)); err != nil {
+					return err
+				}
+			}
+			log.Printf("Signing key %s (%s) created, signs from %s", key.ID, key.Algorithm, activatesAt.Format(time.RFC3339))
+		}
+
+		if _, err := s.keyRepo.DeleteExpired(ctx, now); err != nil {
+			return err
+		}
+
+		keys, err = s.keyRepo.GetAll(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.ring.Replace(keys)
+	return nil
+}
+
+// nextActivation решает, нужен ли новый ключ, и возвращает момент начала
+// его подписи и ключ, который он сменит. Нулевой момент — новый ключ не
+// нужен
+func (s *SigningKeyService) nextActivation(latest *signing.Key, now time.Time) (time.Time, *signing.Key) {
+	// У последнего ключа всегда нет срока окончания подписи: срок
+	// назначается, когда появляется следующий
+	if latest == nil || !latest.RetiresAt.IsZero() {
+		return now, nil
+	}
+
+	// Смена алгоритма в настройках проводится так же, как плановая ротация
+	if latest.Algorithm != s.policy.Algorithm {
+		return laterOf(latest.ActivatesAt, now.Add(s.policy.Prepublish)), latest
+	}
+
+	rotateAt := latest.ActivatesAt.Add(s.policy.RotationInterval)
+	if now.Before(rotateAt.Add(-s.policy.Prepublish)) {
+		return time.Time{}, nil
+	}
+	return laterOf(rotateAt, now.Add(s.policy.Prepublish)), latest
+}
+
+// latestKey возвращает ключ, который начинает подписывать последним
+func latestKey(keys []*signing.Key) *signing.Key {
+	var latest *signing.Key
+	for _, key := range keys {
+		if latest == nil || key.ActivatesAt.After(latest.ActivatesAt) {
+			latest = key
+		}
+	}
+	return latest
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -2,19 +2,23 @@ package services
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/events"
 	"inventory-system/internal/models"
 	"inventory-system/internal/repository"
+	"strings"
 	"time"
 )
 
 var (
-	ErrTransferNotFound         = errors.New("transfer not found")
-	ErrAssetNotFound            = errors.New("asset not found")
-	ErrTransferEmployeeNotFound = errors.New("employee not found")
-	ErrLocationNotFound         = errors.New("location not found")
-	ErrSameLocations            = errors.New("source and destination locations are the same")
-	ErrInvalidTransferDate      = errors.New("transfer date cannot be in the future")
+	ErrTransferNotFound         = apperror.NotFound("transfer not found")
+	ErrAssetNotFound            = apperror.NotFound("asset not found")
+	ErrTransferEmployeeNotFound = apperror.NotFound("employee not found")
+	ErrLocationNotFound         = apperror.NotFound("location not found")
+	ErrSameLocations            = apperror.Validation("source and destination locations are the same", nil)
+	ErrInvalidTransferDate      = apperror.Validation("transfer date cannot be in the future", nil)
+	ErrAssetIsComponent         = apperror.Conflict("asset is a component of a kit; transfer the kit or detach the component first")
 )
 
 type TransferService struct {
@@ -22,6 +26,10 @@ type TransferService struct {
 	assetRepo    *repository.AssetRepository
 	employeeRepo *repository.EmployeeRepository
 	locationRepo *repository.LocationRepository
+	outboxRepo   *repository.OutboxRepository
+	transactor   *repository.Transactor
+	notifier     *NotificationService
+	bus          *events.Bus
 }
 
 func NewTransferService(
@@ -29,12 +37,20 @@ func NewTransferService(
 	assetRepo *repository.AssetRepository,
 	employeeRepo *repository.EmployeeRepository,
 	locationRepo *repository.LocationRepository,
+	outboxRepo *repository.OutboxRepository,
+	transactor *repository.Transactor,
+	notifier *NotificationService,
+	bus *events.Bus,
 ) *TransferService {
 	return &TransferService{
 		transferRepo: transferRepo,
 		assetRepo:    assetRepo,
 		employeeRepo: employeeRepo,
 		locationRepo: locationRepo,
+		outboxRepo:   outboxRepo,
+		transactor:   transactor,
+		notifier:     notifier,
+		bus:          bus,
 	}
 }
 
@@ -53,28 +69,18 @@ func (s *TransferService) GetTransferByID(ctx context.Context, id int) (*models.
 	return transfer, nil
 }
 
+// CreateTransfer перемещает актив вместе с его компонентами. Актив
+// проверяется и компоненты выбираются внутри транзакции под блокировкой
+// строки комплекта, чтобы одновременная привязка компонента или другое
+// перемещение не изменили их между проверкой и записью
 func (s *TransferService) CreateTransfer(ctx context.Context, transfer models.AssetTransfer) (int, error) {
-	// Validate asset
-	asset, err := s.assetRepo.GetByID(ctx, transfer.AssetID)
-	if err != nil {
-		return 0, err
-	}
-	if asset == nil {
-		return 0, ErrAssetNotFound
-	}
-
 	// Validate employee
 	exists, err := s.employeeRepo.Exists(ctx, transfer.EmployeeID)
 	if err != nil {
 		return 0, err
 	}
 	if !exists {
-		return 0, ErrEmployeeNotFound
-	}
-
-	// Validate from location (should match asset's current location)
-	if asset.CurrentLocationID != transfer.FromLocationID {
-		return 0, errors.New("source location does not match asset's current location")
+		return 0, invalidReference("employee_id", ErrEmployeeNotFound)
 	}
 
 	// Validate to location
@@ -83,7 +89,7 @@ func (s *TransferService) CreateTransfer(ctx context.Context, transfer models.As
 		return 0, err
 	}
 	if !exists {
-		return 0, ErrLocationNotFound
+		return 0, invalidReference("to_location_id", ErrLocationNotFound)
 	}
 
 	// Check if locations are different
@@ -96,21 +102,113 @@ func (s *TransferService) CreateTransfer(ctx context.Context, transfer models.As
 		return 0, ErrInvalidTransferDate
 	}
 
-	// Create transfer
-	transferID, err := s.transferRepo.Create(ctx, transfer)
+	var components []models.Asset
+	var transferIDs []int
+	var transferID int
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.assetRepo.LockHierarchy(ctx, transfer.AssetID, 0); err != nil {
+			return err
+		}
+
+		// Validate asset
+		asset, err := s.assetRepo.GetByID(ctx, transfer.AssetID)
+		if err != nil {
+			return err
+		}
+		if asset == nil {
+			return invalidReference("asset_id", ErrAssetNotFound)
+		}
+		if asset.ParentID != nil {
+			return ErrAssetIsComponent
+		}
+
+		// Validate from location (should match asset's current location)
+		if asset.CurrentLocationID != transfer.FromLocationID {
+			return ErrSourceLocationMismatch
+		}
+
+		// Components move together with their kit
+		components, err = s.assetRepo.GetDescendants(ctx, transfer.AssetID)
+		if err != nil {
+			return err
+		}
+
+		// Create transfer
+		transferID, err = s.transferRepo.Create(ctx, transfer)
+		if err != nil {
+			return err
+		}
+
+		// Update asset's current location
+		if err := s.assetRepo.UpdateLocation(ctx, transfer.AssetID, transfer.ToLocationID); err != nil {
+			return err
+		}
+		if err := s.recordTransfer(ctx, transferID); err != nil {
+			return err
+		}
+		if err := s.notifier.NotifyTransfer(ctx, transferID); err != nil {
+			return err
+		}
+		transferIDs = append(transferIDs, transferID)
+
+		for _, component := range components {
+			if component.CurrentLocationID == transfer.ToLocationID {
+				continue
+			}
+
+			componentTransfer := transfer
+			componentTransfer.AssetID = component.ID
+			componentTransfer.FromLocationID = component.CurrentLocationID
+			componentTransfer.Notes = strings.TrimSpace(fmt.Sprintf("%s (в составе комплекта #%d)", transfer.Notes, transfer.AssetID))
+
+			componentTransferID, err := s.transferRepo.Create(ctx, componentTransfer)
+			if err != nil {
+				return err
+			}
+			if err := s.assetRepo.UpdateLocation(ctx, component.ID, transfer.ToLocationID); err != nil {
+				return err
+			}
+			if err := s.recordTransfer(ctx, componentTransferID); err != nil {
+				return err
+			}
+			if err := s.notifier.NotifyTransfer(ctx, componentTransferID); err != nil {
+				return err
+			}
+			transferIDs = append(transferIDs, componentTransferID)
+		}
+
+		return nil
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	// Update asset's current location
-	err = s.assetRepo.UpdateLocation(ctx, transfer.AssetID, transfer.ToLocationID)
-	if err != nil {
-		return 0, err
+	// Moved assets change their location as well
+	s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, transfer.AssetID)
+	for _, component := range components {
+		s.bus.Publish(ctx, events.EntityAsset, events.ActionUpdated, component.ID)
+	}
+	for _, id := range transferIDs {
+		s.bus.Publish(ctx, events.EntityTransfer, events.ActionCreated, id)
 	}
 
 	return transferID, nil
 }
 
+// recordTransfer добавляет в outbox событие asset.transferred
+func (s *TransferService) recordTransfer(ctx context.Context, id int) error {
+	transfer, err := s.transferRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer == nil {
+		return ErrTransferNotFound
+	}
+
+	_, err = s.outboxRepo.Add(ctx, models.EventAssetTransferred, transfer)
+	return err
+}
+
 func (s *TransferService) GetTransfersByAsset(ctx context.Context, assetID int) ([]models.AssetTransfer, error) {
 	// Validate asset
 	exists, err := s.assetRepo.Exists(ctx, assetID)
@@ -152,7 +250,7 @@ func (s *TransferService) GetTransfersByLocation(ctx context.Context, locationID
 
 func (s *TransferService) GetTransfersByDateRange(ctx context.Context, from, to time.Time) ([]models.AssetTransfer, error) {
 	if from.After(to) {
-		return nil, errors.New("start date cannot be after end date")
+		return nil, ErrInvalidDateRange
 	}
 
 	return s.transferRepo.GetByDateRange(ctx, from, to)
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/ratelimit"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/totp"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrTwoFactorEnabled     = apperror.Conflict("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp    = apperror.Conflict("two-factor authentication is not set up")
+	ErrTwoFactorNotEnabled  = apperror.Conflict("two-factor authentication is not enabled")
+	ErrTwoFactorRequired    = apperror.Forbidden("two-factor authentication is required for this role")
+	ErrInvalidTwoFactorCode = apperror.Validation("two-factor code is invalid", nil)
+)
+
+// Резервные коды: recoveryCodeCount кодов по recoveryCodeLength символов
+// base32 (50 бит), для удобства записываются как xxxxx-xxxxx
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// TwoFactorService управляет двухфакторной аутентификацией по кодам TOTP
+// (RFC 6238). Для ролей из requiredRoles она обязательна
+type TwoFactorService struct {
+	twoFactorRepo *repository.TwoFactorRepository
+	employeeRepo  *repository.EmployeeRepository
+	transactor    *repository.Transactor
+	issuer        string
+	requiredRoles map[string]bool
+	clock         ratelimit.Clock
+}
+
+func NewTwoFactorService(
+	twoFactorRepo *repository.TwoFactorRepository,
+	employeeRepo *repository.EmployeeRepository,
+	transactor *repository.Transactor,
+	issuer string,
+	requiredRoles []string,
+	clock ratelimit.Clock,
+) *TwoFactorService {
+	required := make(map[string]bool, len(requiredRoles))
+	for _, role := range requiredRoles {
+		required[role] = true
+	}
+
+	return &TwoFactorService{
+		twoFactorRepo: twoFactorRepo,
+		employeeRepo:  employeeRepo,
+		transactor:    transactor,
+		issuer:        issuer,
+		requiredRoles: required,
+		clock:         clock,
+	}
+}
+
+// Required сообщает, обязательна ли двухфакторная аутентификация для роли
+// сотрудника
+func (s *TwoFactorService) Required(employee *models.Employee) bool {
+	return s.requiredRoles[employee.Role]
+}
+
+// Status возвращает состояние двухфакторной аутентификации сотрудника
+func (s *TwoFactorService) Status(ctx context.Context, employee *models.Employee) (*models.TwoFactorStatus, error) {
+	status := &models.TwoFactorStatus{
+		Enabled:  employee.TwoFactorEnabled,
+		Required: s.Required(employee),
+	}
+	if !employee.TwoFactorEnabled {
+		return status, nil
+	}
+
+	left, err := s.twoFactorRepo.CountRecoveryCodes(ctx, employee.ID)
+	if err != nil {
+		return nil, err
+	}
+	status.RecoveryCodesLeft = left
+	return status, nil
+}
+
+// Setup выпускает новый секрет TOTP. Проверка кода включается только после
+// Enable, поэтому прерванная настройка не мешает входу
+func (s *TwoFactorService) Setup(ctx context.Context, employee *models.Employee) (*models.TwoFactorSetup, error) {
+	if employee.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SavePendingSecret(ctx, employee.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer, employee.Email, secret),
+	}, nil
+}
+
+// Enable включает двухфакторную аутентификацию, если code подтверждает,
+// что секрет из Setup добавлен в приложение. Возвращает резервные коды
+func (s *TwoFactorService) Enable(ctx context.Context, employee *models.Employee, code string) ([]string, error) {
+	var codes []string
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		secret, enabled, err := s.twoFactorRepo.GetSecret(ctx, employee.ID)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			return ErrTwoFactorEnabled
+		}
+		if secret == "" {
+			return ErrTwoFactorNotSetUp
+		}
+
+		step, ok := totp.Validate(secret, code, s.clock.Now())
+		if !ok {
+			return invalidField(ErrInvalidTwoFactorCode, "code", "is invalid")
+		}
+		if _, err := s.twoFactorRepo.UseStep(ctx, employee.ID, step); err != nil {
+			return err
+		}
+		if err := s.twoFactorRepo.Enable(ctx, employee.ID); err != nil {
+			return err
+		}
+
+		codes, err = s.replaceRecoveryCodes(ctx, employee.ID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable отключает двухфакторную аутентификацию после проверки пароля и
+// кода. Сотрудникам, для роли которых она обязательна, отключать ее нельзя
+func (s *TwoFactorService) Disable(ctx context.Context, employee *models.Employee, password, code string) error {
+	if s.Required(employee) {
+		return ErrTwoFactorRequired
+	}
+	if !employee.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	hash, err := s.employeeRepo.GetPasswordHash(ctx, employee.ID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return invalidField(ErrWrongPassword, "password", "is incorrect")
+	}
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		ok, err := s.VerifyCode(ctx, employee.ID, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return invalidField(ErrInvalidTwoFactorCode, "code", "is invalid")
+		}
+		return s.twoFactorRepo.Disable(ctx, employee.ID)
+	})
+}
+
+// RegenerateRecoveryCodes заменяет резервные коды новыми после проверки
+// кода из приложения
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, employee *models.Employee, code string) ([]string, error) {
+	if !employee.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	var codes []string
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		ok, err := s.verifyTOTP(ctx, employee.ID, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return invalidField(ErrInvalidTwoFactorCode, "code", "is invalid")
+		}
+
+		codes, err = s.replaceRecoveryCodes(ctx, employee.ID)
+		return err
+	})
+	return codes, err
+}
+
+// Reset отключает двухфакторную аутентификацию сотрудника без проверки
+// кода — для администратора, когда сотрудник потерял устройство и коды.
+// Если она обязательна для роли, сотрудник настроит ее заново при входе
+func (s *TwoFactorService) Reset(ctx context.Context, employeeID int) error {
+	exists, err := s.employeeRepo.Exists(ctx, employeeID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrEmployeeNotFound
+	}
+	return s.twoFactorRepo.Disable(ctx, employeeID)
+}
+
+// VerifyCode проверяет код из приложения или резервный код. Принятый код
+// повторно не принимается
+func (s *TwoFactorService) VerifyCode(ctx context.Context, employeeID int, code string) (bool, error) {
+	if isTOTPCode(code) {
+		return s.verifyTOTP(ctx, employeeID, code)
+	}
+
+	return s.twoFactorRepo.UseRecoveryCode(ctx, employeeID, hashRecoveryCode(code), s.clock.Now().UTC())
+}
+
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, employeeID int, code string) (bool, error) {
+	secret, enabled, err := s.twoFactorRepo.GetSecret(ctx, employeeID)
+	if err != nil || !enabled {
+		return false, err
+	}
+
+	step, ok := totp.Validate(secret, code, s.clock.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.twoFactorRepo.UseStep(ctx, employeeID, step)
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, employeeID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, employeeID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// isTOTPCode сообщает, похож ли code на код из приложения, а не на
+// резервный код
+func isTOTPCode(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCode возвращает случайный резервный код вида xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength*5/8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:], nil
+}
+
+// hashRecoveryCode возвращает хеш резервного кода без учета регистра,
+// пробелов и дефисов
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"inventory-system/internal/totp"
+)
+
+// twoFactorState — столбцы TOTP сотрудника и его резервные коды
+type twoFactorState struct {
+	mu       sync.Mutex
+	secret   string
+	enabled  bool
+	lastStep *int64
+	// recovery сопоставляет хеш резервного кода признаку использования
+	recovery map[string]bool
+}
+
+func (s *twoFactorState) handle(query string, args []driver.Value) (fakeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "SELECT totp_secret, totp_enabled FROM employees"):
+		var secret driver.Value
+		if s.secret != "" {
+			secret = s.secret
+		}
+		return fakeResult{columns: []string{"totp_secret", "totp_enabled"}, rows: [][]driver.Value{{secret, s.enabled}}}, nil
+	case strings.Contains(query, "SET totp_secret = $2, totp_enabled = FALSE"):
+		s.secret, s.enabled, s.lastStep = args[1].(string), false, nil
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "SET totp_enabled = TRUE"):
+		s.enabled = true
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "SET totp_last_step = $2"):
+		step := args[1].(int64)
+		if s.lastStep != nil && *s.lastStep >= step {
+			return fakeResult{affected: 0}, nil
+		}
+		s.lastStep = &step
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "SET totp_secret = NULL"):
+		s.secret, s.enabled, s.lastStep = "", false, nil
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "DELETE FROM recovery_codes"):
+		s.recovery = make(map[string]bool)
+		return fakeResult{}, nil
+	case strings.Contains(query, "INSERT INTO recovery_codes"):
+		s.recovery[args[1].(string)] = false
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "UPDATE recovery_codes SET used_at"):
+		used, ok := s.recovery[args[1].(string)]
+		if !ok || used {
+			return fakeResult{affected: 0}, nil
+		}
+		s.recovery[args[1].(string)] = true
+		return fakeResult{affected: 1}, nil
+	case strings.Contains(query, "SELECT COUNT(*) FROM recovery_codes"):
+		left := 0
+		for _, used := range s.recovery {
+			if !used {
+				left++
+			}
+		}
+		return fakeResult{columns: []string{"count"}, rows: [][]driver.Value{{int64(left)}}}, nil
+	}
+	return fakeResult{}, errors.New("unexpected query: " + query)
+}
+
+func newTestTwoFactorService(t *testing.T, state *twoFactorState, clock *fakeClock) *TwoFactorService {
+	db := newFakeDB(t, state.handle)
+	return NewTwoFactorService(
+		repository.NewTwoFactorRepository(db),
+		repository.NewEmployeeRepository(db),
+		repository.NewTransactor(db),
+		"Inventory",
+		[]string{models.RoleAdmin},
+		clock,
+	)
+}
+
+// enableTwoFactor настраивает и включает TOTP сотрудника 1 и возвращает
+// секрет и резервные коды
+func enableTwoFactor(t *testing.T, s *TwoFactorService, clock *fakeClock) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	employee := &models.Employee{ID: 1, Email: "alice@example.com", Role: models.RoleEmployee}
+
+	setup, err := s.Setup(ctx, employee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.Code(setup.Secret, totp.Step(clock.Now()))
+	codes, err := s.Enable(ctx, employee, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return setup.Secret, codes
+}
+
+func TestTOTPCodeIsSingleUse(t *testing.T) {
+	state := &twoFactorState{recovery: make(map[string]bool)}
+	clock := &fakeClock{now: time.Unix(1234567890, 0)}
+	s := newTestTwoFactorService(t, state, clock)
+	ctx := context.Background()
+
+	secret, _ := enableTwoFactor(t, s, clock)
+	if !state.enabled {
+		t.Fatal("two-factor authentication is not enabled")
+	}
+
+	// The code that confirmed the setup cannot be replayed at login
+	code, _ := totp.Code(secret, totp.Step(clock.Now()))
+	if ok, err := s.VerifyCode(ctx, 1, code); err != nil || ok {
+		t.Errorf("code of an already used step: ok = %v, err = %v", ok, err)
+	}
+
+	// Nor can a code of an earlier step that is still inside the clock skew window
+	previous, _ := totp.Code(secret, totp.Step(clock.Now())-1)
+	if ok, err := s.VerifyCode(ctx, 1, previous); err != nil || ok {
+		t.Errorf("code of an earlier step: ok = %v, err = %v", ok, err)
+	}
+
+	// The next step's code works once
+	clock.Advance(totp.Period)
+	code, _ = totp.Code(secret, totp.Step(clock.Now()))
+	if ok, err := s.VerifyCode(ctx, 1, code); err != nil || !ok {
+		t.Errorf("code of a new step: ok = %v, err = %v", ok, err)
+	}
+	if ok, err := s.VerifyCode(ctx, 1, code); err != nil || ok {
+		t.Errorf("same code again within its step: ok = %v, err = %v", ok, err)
+	}
+
+	// A used step also blocks regenerating recovery codes with that code
+	employee := &models.Employee{ID: 1, TwoFactorEnabled: true}
+	if _, err := s.RegenerateRecoveryCodes(ctx, employee, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("regenerate with a used code: err = %v", err)
+	}
+}
+
+func TestRecoveryCodeIsSingleUse(t *testing.T) {
+	state := &twoFactorState{recovery: make(map[string]bool)}
+	clock := &fakeClock{now: time.Unix(1234567890, 0)}
+	s := newTestTwoFactorService(t, state, clock)
+	ctx := context.Background()
+
+	_, codes := enableTwoFactor(t, s, clock)
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	for _, code := range codes {
+		if len(code) != recoveryCodeLength+1 || code[recoveryCodeLength/2] != '-' || isTOTPCode(code) {
+			t.Errorf("recovery code %q has an unexpected format", code)
+		}
+	}
+
+	if ok, err := s.VerifyCode(ctx, 1, codes[0]); err != nil || !ok {
+		t.Fatalf("first use: ok = %v, err = %v", ok, err)
+	}
+	if ok, err := s.VerifyCode(ctx, 1, codes[0]); err != nil || ok {
+		t.Errorf("second use: ok = %v, err = %v", ok, err)
+	}
+
+	// Case, spaces and the dash do not matter
+	typed := strings.ToUpper(strings.Replace(codes[1], "-", " ", 1))
+	if ok, err := s.VerifyCode(ctx, 1, typed); err != nil || !ok {
+		t.Errorf("code typed as %q: ok = %v, err = %v", typed, ok, err)
+	}
+	if ok, _ := s.VerifyCode(ctx, 1, codes[1]); ok {
+		t.Error("code accepted again in its canonical form")
+	}
+
+	status, err := s.Status(ctx, &models.Employee{ID: 1, TwoFactorEnabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RecoveryCodesLeft != recoveryCodeCount-2 {
+		t.Errorf("%d recovery codes left, want %d", status.RecoveryCodesLeft, recoveryCodeCount-2)
+	}
+
+	if ok, err := s.VerifyCode(ctx, 1, "aaaaa-bbbbb"); err != nil || ok {
+		t.Errorf("unknown recovery code: ok = %v, err = %v", ok, err)
+	}
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	state := &twoFactorState{recovery: make(map[string]bool)}
+	clock := &fakeClock{now: time.Unix(1234567890, 0)}
+	s := newTestTwoFactorService(t, state, clock)
+	ctx := context.Background()
+	employee := &models.Employee{ID: 1, Email: "alice@example.com", Role: models.RoleEmployee}
+
+	if _, err := s.Enable(ctx, employee, "123456"); !errors.Is(err, ErrTwoFactorNotSetUp) {
+		t.Errorf("enable before setup: err = %v", err)
+	}
+
+	setup, err := s.Setup(ctx, employee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(setup.ProvisioningURI, "otpauth://totp/Inventory:alice@example.com?") {
+		t.Errorf("provisioning uri = %s", setup.ProvisioningURI)
+	}
+
+	wrong, _ := totp.Code(setup.Secret, totp.Step(clock.Now())+5)
+	_, err = s.Enable(ctx, employee, wrong)
+	if !errors.Is(err, ErrInvalidTwoFactorCode) || apperror.FieldsOf(err)["code"] == "" {
+		t.Errorf("wrong code: err = %v", err)
+	}
+	if state.enabled {
+		t.Error("enabled with a wrong code")
+	}
+
+	code, _ := totp.Code(setup.Secret, totp.Step(clock.Now()))
+	if _, err := s.Enable(ctx, employee, code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Enable(ctx, employee, code); !errors.Is(err, ErrTwoFactorEnabled) {
+		t.Errorf("enable twice: err = %v", err)
+	}
+
+	// Administrators cannot switch it off
+	admin := &models.Employee{ID: 1, Role: models.RoleAdmin, TwoFactorEnabled: true}
+	if err := s.Disable(ctx, admin, "password", code); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Errorf("disable for a required role: err = %v", err)
+	}
+}
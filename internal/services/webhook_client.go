@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookTargetBlocked — адрес получателя вебхука находится во
+// внутренней сети: loopback, частные, link-local и другие служебные
+// диапазоны. Запросы туда позволили бы подписке обращаться к внутренним
+// сервисам и метаданным облака
+var ErrWebhookTargetBlocked = errors.New("webhook target address is not allowed")
+
+// blockedNetworks — служебные диапазоны, не покрытые методами net.IP
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved
+	"64:ff9b::/96",  // NAT64
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// webhookIPAllowed сообщает, можно ли отправлять вебхуки на адрес ip
+func webhookIPAllowed(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost разрешает имя host и проверяет, что ни один из его
+// адресов не находится во внутренней сети
+func checkWebhookHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !webhookIPAllowed(addr.IP) {
+			return ErrWebhookTargetBlocked
+		}
+	}
+	return nil
+}
+
+// NewWebhookClient возвращает HTTP-клиент для доставки вебхуков. Адрес
+// проверяется при каждом соединении, уже после разрешения имени, поэтому
+// запрос не уйдет во внутреннюю сеть и при смене DNS-записи после проверки
+// подписки. Переадресации не выполняются: ответ 3xx считается неудачей
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	return newWebhookClient(timeout, webhookIPAllowed)
+}
+
+func newWebhookClient(timeout time.Duration, allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookTargetBlocked, host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// A proxy would make the dialed address meaningless
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Заголовки запроса вебхука. Подпись имеет вид t=<unix-время>,v1=<hex>,
+// где v1 — HMAC-SHA256 секрета подписки от строки "<t>.<тело запроса>"
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	// webhookBatchSize ограничивает число событий и доставок за один проход
+	webhookBatchSize = 100
+	// maxRetryDelay ограничивает паузу между повторными попытками
+	maxRetryDelay = 6 * time.Hour
+	// maxErrorLength ограничивает длину сохраняемого текста ошибки
+	maxErrorLength = 500
+)
+
+// WebhookDispatcher раскладывает события из outbox по подпискам и
+// доставляет их. Неудачные доставки повторяются с экспоненциально растущей
+// паузой, после maxAttempts попыток доставка помечается как dead
+type WebhookDispatcher struct {
+	outboxRepo  *repository.OutboxRepository
+	webhookRepo *repository.WebhookRepository
+	transactor  *repository.Transactor
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+func NewWebhookDispatcher(
+	outboxRepo *repository.OutboxRepository,
+	webhookRepo *repository.WebhookRepository,
+	transactor *repository.Transactor,
+	client *http.Client,
+	interval time.Duration,
+	maxAttempts int,
+	retryDelay time.Duration,
+) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		outboxRepo:  outboxRepo,
+		webhookRepo: webhookRepo,
+		transactor:  transactor,
+		client:      client,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+	}
+}
+
+// Run выполняет рассылку сразу и затем с интервалом interval до отмены ctx
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Dispatch(ctx, time.Now()); err != nil {
+			log.Printf("Webhook dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch выполняет один проход: ставит новые события в очередь доставки
+// и отправляет доставки, срок которых наступил к now
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, now time.Time) error {
+	if err := d.fanOut(ctx, now); err != nil {
+		return err
+	}
+	return d.deliverDue(ctx, now)
+}
+
+// fanOut создает доставки для активных подписок на новые события
+func (d *WebhookDispatcher) fanOut(ctx context.Context, now time.Time) error {
+	return d.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		events, err := d.outboxRepo.Pending(ctx, webhookBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			subscribers, err := d.webhookRepo.SubscribersOf(ctx, event.OrganizationID, event.Type)
+			if err != nil {
+				return err
+			}
+			for _, subscriptionID := range subscribers {
+				if err := d.webhookRepo.CreateDelivery(ctx, subscriptionID, event.ID); err != nil {
+					return err
+				}
+			}
+			if err := d.outboxRepo.MarkDispatched(ctx, event.ID, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deliverDue отправляет до webhookBatchSize доставок, срок которых наступил
+// к now. Доставки отправляются по очереди, поэтому каждая захватывается
+// непосредственно перед отправкой: срок захвата покрывает один запрос, и
+// при остановке диспетчера остальные доставки не ждут, пока истечет
+// захват всей пачки
+func (d *WebhookDispatcher) deliverDue(ctx context.Context, now time.Time) error {
+	started := time.Now()
+	for i := 0; i < webhookBatchSize; i++ {
+		// Claimed deliveries are hidden from other dispatchers while being sent
+		leaseUntil := now.Add(time.Since(started) + d.client.Timeout + time.Minute)
+		deliveries, err := d.webhookRepo.ClaimDue(ctx, now, leaseUntil, 1)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		result := d.attempt(ctx, deliveries[0], now)
+		if err := d.webhookRepo.SaveAttempt(ctx, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt отправляет одну доставку и возвращает ее новое состояние
+func (d *WebhookDispatcher) attempt(ctx context.Context, delivery models.DueDelivery, now time.Time) models.WebhookDelivery {
+	result := delivery.WebhookDelivery
+	result.Attempts++
+
+	status, err := d.send(ctx, delivery, now)
+	if status != 0 {
+		result.ResponseStatus = &status
+	}
+
+	if err == nil {
+		deliveredAt := time.Now()
+		result.Status = models.DeliveryDelivered
+		result.LastError = ""
+		result.DeliveredAt = &deliveredAt
+		return result
+	}
+
+	result.LastError = truncate(err.Error(), maxErrorLength)
+	if result.Attempts >= d.maxAttempts {
+		result.Status = models.DeliveryDead
+		log.Printf("Webhook delivery %d to %s is dead after %d attempts: %v",
+			delivery.ID, delivery.URL, result.Attempts, err)
+		return result
+	}
+
+	result.Status = models.DeliveryPending
+	result.NextAttemptAt = now.Add(retryBackoff(d.retryDelay, result.Attempts))
+	return result
+}
+
+// send выполняет HTTP-запрос и возвращает код ответа. Доставка успешна
+// только при коде 2xx. Тело ответа не сохраняется: получатель может
+// вернуть в нем что угодно
+func (d *WebhookDispatcher) send(ctx context.Context, delivery models.DueDelivery, now time.Time) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "inventory-system-webhooks/1")
+	req.Header.Set(WebhookEventHeader, delivery.Event.Type)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(delivery.Secret, now.Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryBackoff возвращает паузу перед следующей попыткой: delay, затем
+// вдвое больше после каждой неудачи, но не более maxRetryDelay
+func retryBackoff(delay time.Duration, attempts int) time.Duration {
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// SignWebhook вычисляет значение заголовка X-Webhook-Signature для тела
+// body, отправленного в момент timestamp. Получатель проверяет подпись,
+// вычисляя ее тем же способом со своим экземпляром секрета
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncate обрезает s до max байт, не разрывая многобайтовые символы
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
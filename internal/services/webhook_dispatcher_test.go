@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+)
+
+func allowAnyIP(net.IP) bool { return true }
+
+func testDelivery(url string) models.DueDelivery {
+	return models.DueDelivery{
+		WebhookDelivery: models.WebhookDelivery{ID: 7, SubscriptionID: 1, EventID: 42},
+		URL:             url,
+		Secret:          "s3cret",
+		Event: models.Event{
+			ID:      42,
+			Type:    models.EventAssetCreated,
+			Payload: []byte(`{"id":1}`),
+		},
+	}
+}
+
+func testDispatcher(client *http.Client) *WebhookDispatcher {
+	return &WebhookDispatcher{client: client, maxAttempts: 3, retryDelay: time.Minute}
+}
+
+func TestWebhookDispatcherDelivers(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook("s3cret", now.Unix(), body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if got := r.Header.Get(WebhookEventHeader); got != models.EventAssetCreated {
+			t.Errorf("event header = %q", got)
+		}
+		if got := r.Header.Get(WebhookDeliveryHeader); got != "7" {
+			t.Errorf("delivery header = %q", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	d := testDispatcher(newWebhookClient(5*time.Second, allowAnyIP))
+	result := d.attempt(context.Background(), testDelivery(receiver.URL), now)
+
+	if result.Status != models.DeliveryDelivered {
+		t.Fatalf("status = %q, last error %q", result.Status, result.LastError)
+	}
+	if result.ResponseStatus == nil || *result.ResponseStatus != http.StatusNoContent {
+		t.Errorf("response status = %v", result.ResponseStatus)
+	}
+	if received.Load() != 1 {
+		t.Errorf("receiver got %d requests", received.Load())
+	}
+}
+
+func TestWebhookDispatcherDoesNotStoreResponseBody(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal token abc123", http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	now := time.Unix(1700000000, 0)
+	d := testDispatcher(newWebhookClient(5*time.Second, allowAnyIP))
+	result := d.attempt(context.Background(), testDelivery(receiver.URL), now)
+
+	if result.Status != models.DeliveryPending {
+		t.Fatalf("status = %q", result.Status)
+	}
+	if result.LastError != "unexpected status 500" {
+		t.Errorf("last error = %q", result.LastError)
+	}
+	if !result.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("next attempt at %v", result.NextAttemptAt)
+	}
+
+	delivery := testDelivery(receiver.URL)
+	delivery.Attempts = 2
+	if result := d.attempt(context.Background(), delivery, now); result.Status != models.DeliveryDead {
+		t.Errorf("status after last attempt = %q", result.Status)
+	}
+}
+
+func TestWebhookDispatcherDoesNotFollowRedirects(t *testing.T) {
+	var followed atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed.Add(1)
+	}))
+	defer internal.Close()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	d := testDispatcher(newWebhookClient(5*time.Second, allowAnyIP))
+	result := d.attempt(context.Background(), testDelivery(receiver.URL), time.Now())
+
+	if result.Status != models.DeliveryPending || result.LastError != "unexpected status 307" {
+		t.Errorf("status = %q, last error %q", result.Status, result.LastError)
+	}
+	if followed.Load() != 0 {
+		t.Error("redirect was followed")
+	}
+}
+
+func TestWebhookDispatcherLeasesEachDeliveryBeforeSending(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+	record := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		log = append(log, fmt.Sprintf(format, args...))
+	}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("send %s", r.Header.Get(WebhookDeliveryHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	now := time.Unix(1700000000, 0)
+	timeout := 5 * time.Second
+	pending := []int64{1, 2, 3}
+	db := newFakeDB(t, func(query string, args []driver.Value) (fakeResult, error) {
+		switch {
+		case strings.Contains(query, "WITH claimed AS"):
+			if limit := args[2].(int64); limit != 1 {
+				t.Errorf("claimed %d deliveries at once", limit)
+			}
+			if lease := args[1].(time.Time); lease.Before(now.Add(timeout)) {
+				t.Errorf("lease until %v is shorter than the request timeout", lease)
+			}
+			if len(pending) == 0 {
+				return fakeResult{columns: []string{"id"}}, nil
+			}
+			id := pending[0]
+			pending = pending[1:]
+			record("claim %d", id)
+			return fakeResult{
+				columns: make([]string, 15),
+				rows: [][]driver.Value{{
+					id, int64(1), int64(42), models.DeliveryPending, int64(0), now,
+					"", nil, now, nil,
+					receiver.URL, "s3cret", models.EventAssetCreated, []byte(`{"id":1}`), now,
+				}},
+			}, nil
+		case strings.HasPrefix(strings.TrimSpace(query), "UPDATE webhook_deliveries"):
+			record("save %d", args[6].(int64))
+			return fakeResult{affected: 1}, nil
+		}
+		t.Errorf("unexpected query %q", query)
+		return fakeResult{}, nil
+	})
+
+	d := testDispatcher(newWebhookClient(timeout, allowAnyIP))
+	d.webhookRepo = repository.NewWebhookRepository(db)
+	if err := d.deliverDue(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"claim 1", "send 1", "save 1", "claim 2", "send 2", "save 2", "claim 3", "send 3", "save 3"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %q, want %q", log, want)
+	}
+}
+
+func TestWebhookClientBlocksInternalTargets(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	_, err := testDispatcher(NewWebhookClient(5*time.Second)).send(context.Background(), testDelivery(receiver.URL), time.Now())
+	if !errors.Is(err, ErrWebhookTargetBlocked) {
+		t.Fatalf("err = %v, want ErrWebhookTargetBlocked", err)
+	}
+}
+
+func TestWebhookIPAllowed(t *testing.T) {
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := webhookIPAllowed(net.ParseIP(tt.ip)); got != tt.allowed {
+			t.Errorf("webhookIPAllowed(%s) = %v, want %v", tt.ip, got, tt.allowed)
+		}
+	}
+}
+
+func TestValidateSubscriptionRejectsInternalTargets(t *testing.T) {
+	for _, url := range []string{
+		"http://169.254.169.254/latest/meta-data",
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"https://10.0.0.5/hook",
+		"http://localhost/hook",
+	} {
+		err := validateSubscription(context.Background(), models.WebhookSubscription{URL: url})
+		if !errors.Is(err, ErrInvalidWebhookURL) {
+			t.Errorf("%s: err = %v, want ErrInvalidWebhookURL", url, err)
+		}
+	}
+
+	subscription := models.WebhookSubscription{
+		URL:        "https://93.184.216.34/hook",
+		EventTypes: []string{models.EventAssetCreated},
+	}
+	if err := validateSubscription(context.Background(), subscription); err != nil {
+		t.Errorf("public address rejected: %v", err)
+	}
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc"},
+		{"привет", 3, "п"},
+		{"привет", 4, "пр"},
+		{"日本語", 5, "日"},
+		{"日本語", 1, ""},
+	}
+
+	for _, tt := range tests {
+		got := truncate(tt.s, tt.max)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
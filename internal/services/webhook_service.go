@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"inventory-system/internal/apperror"
+	"inventory-system/internal/models"
+	"inventory-system/internal/repository"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound      = apperror.NotFound("webhook subscription not found")
+	ErrInvalidWebhookURL    = apperror.Validation("webhook url must be an absolute http or https url", nil)
+	ErrUnknownEventType     = apperror.Validation("unknown event type", nil)
+	ErrDeliveryNotRetryable = apperror.NotFound("no dead delivery with this id")
+)
+
+// deliveryLogLimit ограничивает число записей журнала доставок в ответе
+const deliveryLogLimit = 100
+
+// WebhookService управляет подписками внешних систем на события
+type WebhookService struct {
+	webhookRepo *repository.WebhookRepository
+}
+
+func NewWebhookService(webhookRepo *repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+	}
+}
+
+// GetAllSubscriptions возвращает подписки без секретов
+func (s *WebhookService) GetAllSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.webhookRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+// GetSubscription возвращает подписку без секрета
+func (s *WebhookService) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	subscription, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrWebhookNotFound
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// CreateSubscription сохраняет подписку и возвращает ее вместе с секретом.
+// Секрет показывается только здесь, поэтому без него он генерируется
+func (s *WebhookService) CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if err := validateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	if subscription.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		subscription.Secret = secret
+	}
+
+	id, err := s.webhookRepo.Create(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateSubscription заменяет подписку. Без секрета сохраняется прежний
+func (s *WebhookService) UpdateSubscription(ctx context.Context, subscription models.WebhookSubscription) error {
+	current, err := s.webhookRepo.GetByID(ctx, subscription.ID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return ErrWebhookNotFound
+	}
+
+	if err := validateSubscription(ctx, subscription); err != nil {
+		return err
+	}
+
+	if subscription.Secret == "" {
+		subscription.Secret = current.Secret
+	}
+
+	return s.webhookRepo.Update(ctx, subscription)
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id int) error {
+	exists, err := s.webhookRepo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrWebhookNotFound
+	}
+
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+// GetDeliveries возвращает последние записи журнала доставок подписки
+func (s *WebhookService) GetDeliveries(ctx context.Context, id int) ([]models.WebhookDelivery, error) {
+	exists, err := s.webhookRepo.Exists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	return s.webhookRepo.GetDeliveries(ctx, id, deliveryLogLimit)
+}
+
+// Redeliver возвращает недоставленное событие в очередь отправки
+func (s *WebhookService) Redeliver(ctx context.Context, id int, deliveryID int64) error {
+	queued, err := s.webhookRepo.Redeliver(ctx, id, deliveryID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !queued {
+		return ErrDeliveryNotRetryable
+	}
+	return nil
+}
+
+func validateSubscription(ctx context.Context, subscription models.WebhookSubscription) error {
+	target, err := url.Parse(subscription.URL)
+	if err != nil || target.Host == "" || (target.Scheme != "http" && target.Scheme != "https") {
+		return invalidField(ErrInvalidWebhookURL, "url", "must be an absolute http or https url")
+	}
+
+	err = checkWebhookHost(ctx, target.Hostname())
+	if errors.Is(err, ErrWebhookTargetBlocked) {
+		return invalidField(ErrInvalidWebhookURL, "url", "must not point to a private, loopback or link-local address")
+	}
+	if err != nil {
+		return invalidField(ErrInvalidWebhookURL, "url", "host cannot be resolved")
+	}
+
+	for _, eventType := range subscription.EventTypes {
+		if !knownEventType(eventType) {
+			return invalidField(ErrUnknownEventType, "event_types", "unknown event type "+eventType)
+		}
+	}
+	return nil
+}
+
+func knownEventType(eventType string) bool {
+	for _, known := range models.EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,159 @@
+// Package signing хранит ключи подписи токенов доступа: создает пары
+// ключей RS256 и EdDSA, выбирает ключ для подписи по времени и публикует
+// открытые ключи в формате JWKS (RFC 7517)
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Поддерживаемые алгоритмы подписи (RFC 7518, RFC 8037)
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits — длина создаваемых ключей RSA
+const rsaKeyBits = 2048
+
+var ErrUnsupportedAlgorithm = errors.New("signing: unsupported algorithm")
+
+// Key — ключ подписи. Ключ подписывает токены с ActivatesAt до RetiresAt и
+// публикуется до ExpiresAt, пока действуют подписанные им токены. Нулевые
+// RetiresAt и ExpiresAt означают, что срок еще не назначен
+type Key struct {
+	ID          string
+	Algorithm   string
+	Private     crypto.Signer
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// Generate создает ключ для алгоритма algorithm. Идентификатор ключа —
+// отпечаток открытого ключа (RFC 7638)
+func Generate(algorithm string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{Algorithm: algorithm, Private: private}
+	key.ID = key.thumbprint()
+	return key, nil
+}
+
+// MarshalPrivateKey кодирует закрытый ключ в PKCS #8 DER для хранения
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(key)
+}
+
+// ParsePrivateKey разбирает закрытый ключ, сохраненный MarshalPrivateKey,
+// и проверяет, что он подходит для алгоритма algorithm
+func ParsePrivateKey(algorithm string, der []byte) (crypto.Signer, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == AlgorithmRS256 {
+			return key, nil
+		}
+	case ed25519.PrivateKey:
+		if algorithm == AlgorithmEdDSA {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q for %T", ErrUnsupportedAlgorithm, algorithm, parsed)
+}
+
+// Public возвращает открытый ключ для проверки подписи
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// signsAt сообщает, подписывает ли ключ токены в момент now
+func (k *Key) signsAt(now time.Time) bool {
+	return !now.Before(k.ActivatesAt) && (k.RetiresAt.IsZero() || now.Before(k.RetiresAt))
+}
+
+// publishedAt сообщает, принимаются ли в момент now токены, подписанные
+// ключом
+func (k *Key) publishedAt(now time.Time) bool {
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
+
+// JWK — открытый ключ в формате JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet — набор открытых ключей, который отдается по
+// /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK возвращает открытый ключ в формате JSON Web Key
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch public := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(public)
+	}
+	return jwk
+}
+
+// thumbprint возвращает отпечаток JWK по RFC 7638: SHA-256 от
+// обязательных полей в лексикографическом порядке
+func (k *Key) thumbprint() string {
+	jwk := k.JWK()
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":%q}`, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return encode(sum[:])
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package signing
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoSigningKey = errors.New("signing: no active signing key")
+	ErrUnknownKey   = errors.New("signing: unknown key")
+)
+
+// KeyRing — набор ключей подписи, общий для запросов. Ключи заменяются
+// целиком при каждой загрузке из хранилища
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+func NewKeyRing() *KeyRing {
+	return &KeyRing{}
+}
+
+// Replace заменяет ключи набора
+func (r *KeyRing) Replace(keys []*Key) {
+	sorted := make([]*Key, len(keys))
+	copy(sorted, keys)
+	// Новые ключи первыми: при перекрытии подписывает последний
+	// активированный
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ActivatesAt.After(sorted[j].ActivatesAt)
+	})
+
+	r.mu.Lock()
+	r.keys = sorted
+	r.mu.Unlock()
+}
+
+// SigningKey возвращает ключ, которым подписываются токены в момент now
+func (r *KeyRing) SigningKey(now time.Time) (*Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.signsAt(now) {
+			return key, nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// Key возвращает ключ kid, если подписанные им токены принимаются в
+// момент now. Опубликованные заранее, еще не активные ключи тоже
+// возвращаются: часы другого экземпляра приложения могут спешить
+func (r *KeyRing) Key(kid string, now time.Time) (*Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.ID == kid && key.publishedAt(now) {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS возвращает открытые ключи, опубликованные в момент now
+func (r *KeyRing) JWKS(now time.Time) JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if key.publishedAt(now) {
+			set.Keys = append(set.Keys, key.JWK())
+		}
+	}
+	return set
+}
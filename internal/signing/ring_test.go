@@ -0,0 +1,192 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// publicKey восстанавливает открытый ключ из JWK так, как это делает
+// сервис, проверяющий токены по /.well-known/jwks.json
+func publicKey(t *testing.T, jwk JWK) crypto.PublicKey {
+	t.Helper()
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(decode(jwk.N)), E: int(new(big.Int).SetBytes(decode(jwk.E)).Int64())}
+	case "OKP":
+		return ed25519.PublicKey(decode(jwk.X))
+	}
+	t.Fatalf("unsupported key type %q", jwk.Kty)
+	return nil
+}
+
+// verifyWithJWKS проверяет подпись токена ключом из опубликованного набора
+func verifyWithJWKS(t *testing.T, token string, set JWKSet) error {
+	t.Helper()
+
+	// Набор проходит через JSON, как при загрузке другим сервисом
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var published JWKSet
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		for _, jwk := range published.Keys {
+			if jwk.Kid == token.Header["kid"] {
+				if token.Method.Alg() != jwk.Alg {
+					return nil, errors.New("algorithm mismatch")
+				}
+				return publicKey(t, jwk), nil
+			}
+		}
+		return nil, ErrUnknownKey
+	}, jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}), jwt.WithoutClaimsValidation())
+	return err
+}
+
+func sign(t *testing.T, key *Key) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), jwt.MapClaims{"sub": 1})
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func generate(t *testing.T, algorithm string) *Key {
+	t.Helper()
+	key, err := Generate(algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func kids(set JWKSet) []string {
+	ids := make([]string, len(set.Keys))
+	for i, jwk := range set.Keys {
+		ids[i] = jwk.Kid
+	}
+	return ids
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	rotation := start.Add(24 * time.Hour)
+	lifetime := time.Hour
+
+	// Ротация со сменой алгоритма: новый ключ опубликован заранее, старый
+	// публикуется еще lifetime после окончания подписи
+	old := generate(t, AlgorithmRS256)
+	old.ActivatesAt = start
+	old.RetiresAt = rotation
+	old.ExpiresAt = rotation.Add(lifetime)
+	next := generate(t, AlgorithmEdDSA)
+	next.ActivatesAt = rotation
+
+	ring := NewKeyRing()
+	ring.Replace([]*Key{old, next})
+
+	before := rotation.Add(-time.Minute)
+	if key, err := ring.SigningKey(before); err != nil || key.ID != old.ID {
+		t.Fatalf("before rotation signs with %v, %v; want the old key", key, err)
+	}
+	if _, err := ring.Key(next.ID, before); err != nil {
+		t.Errorf("prepublished key: %v", err)
+	}
+	oldToken := sign(t, old)
+
+	overlap := rotation.Add(lifetime / 2)
+	key, err := ring.SigningKey(overlap)
+	if err != nil || key.ID != next.ID {
+		t.Fatalf("during overlap signs with %v, %v; want the new key", key, err)
+	}
+	newToken := sign(t, key)
+
+	set := ring.JWKS(overlap)
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS during overlap = %q, want both keys", kids(set))
+	}
+	if err := verifyWithJWKS(t, oldToken, set); err != nil {
+		t.Errorf("token of the old key during overlap: %v", err)
+	}
+	if err := verifyWithJWKS(t, newToken, set); err != nil {
+		t.Errorf("token of the new key during overlap: %v", err)
+	}
+
+	after := old.ExpiresAt
+	if _, err := ring.Key(old.ID, after); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("retired key after expiry: err = %v, want ErrUnknownKey", err)
+	}
+	set = ring.JWKS(after)
+	if ids := kids(set); len(ids) != 1 || ids[0] != next.ID {
+		t.Errorf("JWKS after expiry = %q, want only the new key", ids)
+	}
+	if err := verifyWithJWKS(t, oldToken, set); err == nil {
+		t.Error("token of the retired key verified after expiry")
+	}
+	if err := verifyWithJWKS(t, newToken, set); err != nil {
+		t.Errorf("token of the new key after expiry: %v", err)
+	}
+}
+
+func TestKeyRingWithoutActiveKey(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	key := generate(t, AlgorithmEdDSA)
+	key.ActivatesAt = now.Add(time.Hour)
+
+	ring := NewKeyRing()
+	ring.Replace([]*Key{key})
+	if _, err := ring.SigningKey(now); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("SigningKey before activation: err = %v, want ErrNoSigningKey", err)
+	}
+	if _, err := ring.Key("unknown", now); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key(unknown): err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestKeyIDIsThumbprint(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		key := generate(t, algorithm)
+		der, err := MarshalPrivateKey(key.Private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		private, err := ParsePrivateKey(algorithm, der)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Идентификатор зависит только от ключа, поэтому ключ, загруженный
+		// из базы, получает тот же kid
+		restored := &Key{Algorithm: algorithm, Private: private}
+		if got := restored.thumbprint(); got != key.ID {
+			t.Errorf("%s: kid of the stored key = %q, want %q", algorithm, got, key.ID)
+		}
+	}
+	if _, err := Generate("HS256"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Generate(HS256): err = %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
@@ -0,0 +1,42 @@
+// Package tenant хранит в контексте организацию (юридическое лицо группы),
+// к данным которой обращается запрос. Репозитории ограничивают ею каждый
+// запрос: сотрудники одной организации не видят активы, сотрудников,
+// отделы и местоположения другой
+package tenant
+
+import "context"
+
+type scopeKey struct{}
+
+// allOrganizations — значение области для фоновых задач, которые
+// обслуживают все организации сразу
+const allOrganizations = 0
+
+// WithOrganization возвращает контекст, ограниченный организацией id
+func WithOrganization(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, scopeKey{}, id)
+}
+
+// WithAllOrganizations возвращает контекст, охватывающий все организации.
+// Он нужен фоновым задачам (очистка, рассылки) и поиску учетной записи по
+// глобально уникальному ключу до того, как известна ее организация.
+// Создавать записи в таком контексте нельзя
+func WithAllOrganizations(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, allOrganizations)
+}
+
+// OrganizationID возвращает организацию из контекста. ok = false, если
+// организация не задана или контекст охватывает все организации
+func OrganizationID(ctx context.Context) (id int, ok bool) {
+	id, ok = ctx.Value(scopeKey{}).(int)
+	if !ok || id == allOrganizations {
+		return 0, false
+	}
+	return id, true
+}
+
+// AllOrganizations сообщает, охватывает ли контекст все организации
+func AllOrganizations(ctx context.Context) bool {
+	id, ok := ctx.Value(scopeKey{}).(int)
+	return ok && id == allOrganizations
+}
@@ -0,0 +1,113 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret — ключ "12345678901234567890" из RFC 6238 в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// RFC 6238, appendix B, SHA1. The RFC lists 8-digit codes; a 6-digit
+	// code is the same value modulo 10^6, i.e. its last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		step := Step(time.Unix(tt.unix, 0))
+		got, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tt.want[len(tt.want)-Digits:]; got != want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestCodeSecretFormats(t *testing.T) {
+	want, err := Code(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Authenticator apps show secrets in lower case and sometimes padded
+	for _, secret := range []string{strings.ToLower(rfcSecret), rfcSecret + "===="} {
+		if got, err := Code(secret, 1); err != nil || got != want {
+			t.Errorf("Code(%q) = %s, %v, want %s", secret, got, err, want)
+		}
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	for delta := int64(-Skew); delta <= Skew; delta++ {
+		code, _ := Code(rfcSecret, current+delta)
+		step, ok := Validate(rfcSecret, " "+code+" ", now)
+		if !ok || step != current+delta {
+			t.Errorf("code of step %+d: step = %d, ok = %v, want step %d", delta, step, ok, current+delta)
+		}
+	}
+
+	for _, delta := range []int64{-Skew - 1, Skew + 1} {
+		code, _ := Code(rfcSecret, current+delta)
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("code of step %+d accepted outside the window", delta)
+		}
+	}
+
+	code, _ := Code(rfcSecret, current)
+	for _, bad := range []string{"", code[:Digits-1], code + "0", "abcdef"} {
+		if _, ok := Validate(rfcSecret, bad, now); ok {
+			t.Errorf("Validate(%q) accepted", bad)
+		}
+	}
+	if _, ok := Validate("not base32!", code, now); ok {
+		t.Error("code accepted for an invalid secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateSecret()
+	if a == b || len(a) != 32 || strings.Contains(a, "=") {
+		t.Errorf("secrets %q and %q", a, b)
+	}
+	if _, err := Code(a, 0); err != nil {
+		t.Errorf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	raw := ProvisioningURI("Inventory System", "alice@example.com", rfcSecret)
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Inventory System:alice@example.com" {
+		t.Errorf("uri = %s", raw)
+	}
+	query := u.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Inventory System" ||
+		query.Get("digits") != "6" || query.Get("period") != "30" || query.Get("algorithm") != "SHA1" {
+		t.Errorf("query = %v", query)
+	}
+}
@@ -0,0 +1,280 @@
+// Package validate проверяет структуры запросов по тегам validate и
+// собирает ошибки по всем полям сразу.
+//
+// Правила в теге перечисляются через запятую:
+//
+//	required    значение задано (строка не состоит из одних пробелов)
+//	omitempty   пустое значение не проверяется
+//	min=N       нижняя граница числа или длины строки и среза
+//	max=N       верхняя граница числа или длины строки и среза
+//	email       адрес электронной почты без отображаемого имени
+//	date        дата в формате ГГГГ-ММ-ДД
+//	datetime    дата ГГГГ-ММ-ДД или момент времени в формате RFC 3339
+//	notfuture   дата не позже текущего момента
+//	oneof=a b   одно из перечисленных значений
+//	dive        проверить элементы среза или вложенную структуру
+//
+// Ошибки привязываются к именам полей из тега json
+package validate
+
+import (
+	"fmt"
+	"inventory-system/internal/apperror"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DateLayout — формат дат без времени, принятый в API
+const DateLayout = "2006-01-02"
+
+// Errors — ошибки проверки по полям запроса
+type Errors map[string]string
+
+// Add запоминает первую ошибку поля
+func (e Errors) Add(field, message string) {
+	if _, ok := e[field]; !ok {
+		e[field] = message
+	}
+}
+
+// Checker реализуют запросы с проверками, затрагивающими несколько полей.
+// Check вызывается после проверки тегов и дополняет errs
+type Checker interface {
+	Check(errs Errors)
+}
+
+// Struct проверяет v по тегам validate. Возвращает nil или ошибку вида
+// apperror.KindValidation со списком всех неверных полей
+func Struct(v interface{}) error {
+	errs := make(Errors)
+	walk(reflect.ValueOf(v), "", errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return apperror.Validation("request validation failed", errs)
+}
+
+// ParseDateTime разбирает значение, прошедшее правило datetime
+func ParseDateTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(DateLayout, s, time.Local)
+}
+
+func walk(v reflect.Value, prefix string, errs Errors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		// Embedded request structs share the parent's namespace
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			walk(v.Field(i), prefix, errs)
+			continue
+		}
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		checkField(v.Field(i), field.Tag.Get("validate"), prefix+name, errs)
+	}
+
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	if checker, ok := v.Interface().(Checker); ok {
+		nested := make(Errors)
+		checker.Check(nested)
+		for field, message := range nested {
+			errs.Add(prefix+field, message)
+		}
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func checkField(v reflect.Value, tag, path string, errs Errors) {
+	if tag == "" || tag == "-" {
+		return
+	}
+	rules := strings.Split(tag, ",")
+
+	if has(rules, "omitempty") && isEmpty(v) {
+		return
+	}
+
+	// Pointers are optional unless required
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if has(rules, "required") {
+				errs.Add(path, "is required")
+			}
+			return
+		}
+		v = v.Elem()
+	} else if has(rules, "required") && isEmpty(v) {
+		errs.Add(path, "is required")
+		return
+	}
+
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			dive(v, path, errs)
+			continue
+		}
+		if message := apply(name, arg, v); message != "" {
+			errs.Add(path, message)
+			return
+		}
+	}
+}
+
+func dive(v reflect.Value, path string, errs Errors) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walk(v.Index(i), fmt.Sprintf("%s[%d].", path, i), errs)
+		}
+	case reflect.Struct:
+		walk(v, path+".", errs)
+	}
+}
+
+// apply проверяет одно правило и возвращает текст ошибки
+func apply(name, arg string, v reflect.Value) string {
+	switch name {
+	case "required", "omitempty":
+		return ""
+	case "min", "max":
+		return checkBound(name, arg, v)
+	case "email":
+		s := v.String()
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return "must be a valid email address"
+		}
+	case "date":
+		if _, err := time.Parse(DateLayout, v.String()); err != nil {
+			return "must be a date in YYYY-MM-DD format"
+		}
+	case "datetime":
+		if _, err := ParseDateTime(v.String()); err != nil {
+			return "must be a date in YYYY-MM-DD or RFC 3339 format"
+		}
+	case "notfuture":
+		if inFuture(v) {
+			return "cannot be in the future"
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		s := fmt.Sprint(v.Interface())
+		for _, option := range options {
+			if s == option {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(options, ", ")
+	default:
+		panic("validate: unknown rule " + name)
+	}
+	return ""
+}
+
+func checkBound(name, arg string, v reflect.Value) string {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic("validate: invalid " + name + " argument " + arg)
+	}
+
+	var value float64
+	unit := ""
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = v.Float()
+	case reflect.String:
+		value = float64(utf8.RuneCountInString(v.String()))
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		value = float64(v.Len())
+		unit = " items"
+	default:
+		return ""
+	}
+
+	if name == "min" && value < limit {
+		return "must be at least " + arg + unit
+	}
+	if name == "max" && value > limit {
+		return "must be at most " + arg + unit
+	}
+	return ""
+}
+
+func inFuture(v reflect.Value) bool {
+	now := time.Now()
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.After(now)
+	}
+
+	t, err := ParseDateTime(v.String())
+	if err != nil {
+		return false
+	}
+	// A plain date is compared with today's date
+	if len(v.String()) == len(DateLayout) {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		return t.After(today)
+	}
+	return t.After(now)
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+func has(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"inventory-system/internal/apperror"
+)
+
+type item struct {
+	Name     string `json:"name" validate:"required,max=5"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type Common struct {
+	Comment string `json:"comment" validate:"omitempty,min=3"`
+}
+
+type request struct {
+	Common
+	Email    string     `json:"email" validate:"required,email"`
+	Name     string     `json:"name,omitempty" validate:"required,min=2,max=4"`
+	Role     string     `json:"role" validate:"omitempty,oneof=admin employee"`
+	Level    int        `json:"level" validate:"oneof=1 2 3"`
+	Cost     float64    `json:"cost" validate:"min=0,max=1000.5"`
+	Tags     []string   `json:"tags" validate:"max=2"`
+	Date     string     `json:"date" validate:"omitempty,date,notfuture"`
+	At       string     `json:"at" validate:"omitempty,datetime,notfuture"`
+	Until    *time.Time `json:"until" validate:"omitempty,notfuture"`
+	Manager  *int       `json:"manager" validate:"required"`
+	Items    []item     `json:"items" validate:"dive"`
+	Address  address    `json:"address" validate:"dive"`
+	Internal string     `json:"-" validate:"required"`
+	ignored  string     `validate:"required"`
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+}
+
+// Check требует, чтобы from не был позже to
+func (r *request) Check(errs Errors) {
+	if r.From != "" && r.To != "" && r.From > r.To {
+		errs.Add("to", "must not be before from")
+	}
+}
+
+func valid() request {
+	manager := 1
+	return request{
+		Email:   "ivan@example.com",
+		Name:    "Иван",
+		Level:   2,
+		Cost:    1000.5,
+		Date:    "2020-02-29",
+		At:      "2020-02-29T10:00:00+03:00",
+		Manager: &manager,
+		Items:   []item{{Name: "Стол", Quantity: 1}},
+		Address: address{City: "Москва"},
+	}
+}
+
+func TestStruct(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(DateLayout)
+
+	tests := []struct {
+		name   string
+		change func(r *request)
+		want   Errors
+	}{
+		{"valid", func(r *request) {}, nil},
+		{"blank required string", func(r *request) { r.Name = "  " }, Errors{"name": "is required"}},
+		{"missing required pointer", func(r *request) { r.Manager = nil }, Errors{"manager": "is required"}},
+		{"zero required pointer value", func(r *request) { zero := 0; r.Manager = &zero }, nil},
+		{"email with display name", func(r *request) { r.Email = "Иван <ivan@example.com>" }, Errors{"email": "must be a valid email address"}},
+		{"not an email", func(r *request) { r.Email = "ivan" }, Errors{"email": "must be a valid email address"}},
+		{"min length in runes", func(r *request) { r.Name = "Я" }, Errors{"name": "must be at least 2 characters"}},
+		{"max length in runes", func(r *request) { r.Name = "Иванов" }, Errors{"name": "must be at most 4 characters"}},
+		{"max number", func(r *request) { r.Cost = 1000.51 }, Errors{"cost": "must be at most 1000.5"}},
+		{"min number", func(r *request) { r.Cost = -1 }, Errors{"cost": "must be at least 0"}},
+		{"max items", func(r *request) { r.Tags = []string{"a", "b", "c"} }, Errors{"tags": "must be at most 2 items"}},
+		{"oneof string", func(r *request) { r.Role = "root" }, Errors{"role": "must be one of: admin, employee"}},
+		{"oneof number", func(r *request) { r.Level = 4 }, Errors{"level": "must be one of: 1, 2, 3"}},
+		{"omitempty skips rules", func(r *request) { r.Role = ""; r.Date = ""; r.Comment = "" }, nil},
+		{"invalid date", func(r *request) { r.Date = "2021-02-29" }, Errors{"date": "must be a date in YYYY-MM-DD format"}},
+		{"date with time", func(r *request) { r.Date = "2020-02-29T10:00:00Z" }, Errors{"date": "must be a date in YYYY-MM-DD format"}},
+		{"future date", func(r *request) { r.Date = tomorrow }, Errors{"date": "cannot be in the future"}},
+		{"today", func(r *request) { r.Date = time.Now().Format(DateLayout) }, nil},
+		{"datetime as date", func(r *request) { r.At = "2020-02-29" }, nil},
+		{"invalid datetime", func(r *request) { r.At = "29.02.2020" }, Errors{"at": "must be a date in YYYY-MM-DD or RFC 3339 format"}},
+		{"future datetime", func(r *request) { r.At = future.Format(time.RFC3339) }, Errors{"at": "cannot be in the future"}},
+		{"future time", func(r *request) { r.Until = &future }, Errors{"until": "cannot be in the future"}},
+		{"embedded struct", func(r *request) { r.Comment = "ok" }, Errors{"comment": "must be at least 3 characters"}},
+		{"dive into slice", func(r *request) { r.Items = append(r.Items, item{Name: "Шкафчик"}) }, Errors{
+			"items[1].name":     "must be at most 5 characters",
+			"items[1].quantity": "must be at least 1",
+		}},
+		{"dive into struct", func(r *request) { r.Address.City = "" }, Errors{"address.city": "is required"}},
+		{"checker", func(r *request) { r.From = "2024-02-01"; r.To = "2024-01-01" }, Errors{"to": "must not be before from"}},
+		{"all fields at once", func(r *request) { r.Email = ""; r.Level = 0 }, Errors{
+			"email": "is required",
+			"level": "must be one of: 1, 2, 3",
+		}},
+	}
+
+	for _, tt := range tests {
+		r := valid()
+		tt.change(&r)
+		err := Struct(&r)
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v %v", tt.name, err, apperror.FieldsOf(err))
+			}
+			continue
+		}
+		if apperror.KindOf(err) != apperror.KindValidation {
+			t.Errorf("%s: error kind = %q, want validation", tt.name, apperror.KindOf(err))
+			continue
+		}
+		if got := Errors(apperror.FieldsOf(err)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: errors = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStructReportsFirstErrorOfField(t *testing.T) {
+	r := valid()
+	r.Name = "И"
+	r.From, r.To = "b", "a"
+
+	// Checker runs after the tags and does not replace their errors
+	errs := make(Errors)
+	walk(reflect.ValueOf(&r), "", errs)
+	errs.Add("name", "second error")
+	if errs["name"] != "must be at least 2 characters" || errs["to"] == "" {
+		t.Errorf("errors = %v", errs)
+	}
+}
+
+func TestStructAcceptsValues(t *testing.T) {
+	if err := Struct(valid()); err != nil {
+		t.Errorf("struct value: %v", err)
+	}
+	if err := Struct((*request)(nil)); err != nil {
+		t.Errorf("nil pointer: %v", err)
+	}
+}
+
+func TestUnknownRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("unknown rule did not panic")
+		}
+	}()
+	Struct(struct {
+		Name string `json:"name" validate:"uuid"`
+	}{Name: "x"})
+}
+
+func TestParseDateTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-03-01T10:30:00Z", time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDateTime(tt.value)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDateTime(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := ParseDateTime("01.03.2024"); err == nil {
+		t.Error("ParseDateTime accepted 01.03.2024")
+	}
+}
@@ -0,0 +1,13 @@
+-- Создание таблицы категорий активов со схемой дополнительных полей
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    fields JSONB NOT NULL DEFAULT '[]'
+);
+
+-- Привязка активов к категориям и значения дополнительных полей
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id),
+    ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_assets_category ON assets(category_id);
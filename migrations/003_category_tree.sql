@@ -0,0 +1,28 @@
+-- Иерархия категорий: IT-оборудование → Компьютеры → Ноутбуки
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES categories(id);
+
+-- Название уникально только среди категорий одного уровня
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_categories_parent_name
+    ON categories (COALESCE(parent_id, 0), LOWER(name));
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+
+-- Перенос существующих текстовых категорий в справочник.
+-- Строки, отличающиеся только регистром и пробелами, попадают в одну категорию;
+-- остальные дубликаты («ноутбуки», «Laptop») объединяются через
+-- POST /api/categories/{id}/merge
+INSERT INTO categories (name)
+SELECT DISTINCT ON (LOWER(TRIM(category))) TRIM(category)
+FROM assets
+WHERE category_id IS NULL AND TRIM(category) <> ''
+ORDER BY LOWER(TRIM(category)), TRIM(category)
+ON CONFLICT DO NOTHING;
+
+UPDATE assets a
+SET category_id = c.id,
+    category = c.name
+FROM categories c
+WHERE a.category_id IS NULL
+  AND c.parent_id IS NULL
+  AND LOWER(TRIM(a.category)) = LOWER(c.name);
@@ -0,0 +1,8 @@
+-- Состав комплектов: системный блок, мониторы и док-станция входят в рабочее место
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES assets(id) ON DELETE SET NULL;
+
+ALTER TABLE assets
+    ADD CONSTRAINT chk_assets_parent_not_self CHECK (parent_id IS NULL OR parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_assets_parent ON assets(parent_id);
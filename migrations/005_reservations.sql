@@ -0,0 +1,27 @@
+-- Бронирование общего оборудования (проекторы, ноутбуки переговорных, автомобили)
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Категории, бронирование в которых требует подтверждения менеджера
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS requires_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id SERIAL PRIMARY KEY,
+    asset_id INTEGER NOT NULL REFERENCES assets(id),
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    period TSTZRANGE NOT NULL,
+    purpose TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reviewed_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_reservations_period CHECK (NOT isempty(period) AND NOT lower_inf(period) AND NOT upper_inf(period)),
+    CONSTRAINT chk_reservations_status CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled')),
+    -- Активные брони одного актива не могут пересекаться по времени
+    CONSTRAINT excl_reservations_overlap EXCLUDE USING gist (
+        asset_id WITH =,
+        period WITH &&
+    ) WHERE (status IN ('pending', 'approved'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reservations_employee ON reservations(employee_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_period ON reservations USING gist (period);
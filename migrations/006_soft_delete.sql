@@ -0,0 +1,32 @@
+-- Мягкое удаление активов, сотрудников, отделов и местоположений
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES employees(id) ON DELETE SET NULL;
+
+ALTER TABLE employees
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES employees(id) ON DELETE SET NULL;
+
+ALTER TABLE departments
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES employees(id) ON DELETE SET NULL;
+
+ALTER TABLE locations
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES employees(id) ON DELETE SET NULL;
+
+-- Email должен быть уникален только среди неудаленных сотрудников
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS employees_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_employees_email_active
+    ON employees (email) WHERE deleted_at IS NULL;
+
+-- Ссылка на проверяющего не должна мешать очистке удаленных сотрудников
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_reviewed_by_fkey;
+ALTER TABLE reservations
+    ADD CONSTRAINT reservations_reviewed_by_fkey
+        FOREIGN KEY (reviewed_by) REFERENCES employees(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_assets_deleted_at ON assets(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_employees_deleted_at ON employees(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_departments_deleted_at ON departments(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_locations_deleted_at ON locations(deleted_at) WHERE deleted_at IS NOT NULL;
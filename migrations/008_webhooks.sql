@@ -0,0 +1,44 @@
+-- Исходящие вебхуки: подписки, очередь событий (outbox) и журнал доставок
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          SERIAL PRIMARY KEY,
+    url         VARCHAR(2048) NOT NULL,
+    secret      VARCHAR(255) NOT NULL,
+    event_types TEXT[] NOT NULL,
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- События записываются в одной транзакции с изменением данных и
+-- раскладываются по подпискам фоновым диспетчером
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id            BIGSERIAL PRIMARY KEY,
+    event_type    VARCHAR(50) NOT NULL,
+    payload       JSONB NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending
+    ON outbox_events (id) WHERE dispatched_at IS NULL;
+
+-- status: pending — ожидает отправки, delivered — доставлено,
+-- dead — попытки исчерпаны
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id        BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error      TEXT NOT NULL DEFAULT '',
+    response_status INTEGER,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMP,
+    UNIQUE (subscription_id, event_id),
+    CHECK (status IN ('pending', 'delivered', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription
+    ON webhook_deliveries (subscription_id, created_at DESC);
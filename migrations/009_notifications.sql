@@ -0,0 +1,43 @@
+-- Уведомления по электронной почте: срок гарантии актива, настройки
+-- сотрудников, очередь писем и журнал напоминаний о гарантии
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS warranty_until DATE;
+
+-- Отсутствие строки означает настройки по умолчанию: русский язык,
+-- все уведомления включены
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    employee_id  INTEGER PRIMARY KEY REFERENCES employees(id) ON DELETE CASCADE,
+    language     VARCHAR(2) NOT NULL DEFAULT 'ru',
+    transfers    BOOLEAN NOT NULL DEFAULT TRUE,
+    reservations BOOLEAN NOT NULL DEFAULT TRUE,
+    warranty     BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (language IN ('ru', 'en'))
+);
+
+-- status: pending — ожидает отправки, sent — отправлено,
+-- failed — попытки исчерпаны
+CREATE TABLE IF NOT EXISTS mail_queue (
+    id              BIGSERIAL PRIMARY KEY,
+    recipient       VARCHAR(255) NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    text_body       TEXT NOT NULL,
+    html_body       TEXT NOT NULL DEFAULT '',
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error      TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at         TIMESTAMP,
+    CHECK (status IN ('pending', 'sent', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_queue_due
+    ON mail_queue (next_attempt_at) WHERE status = 'pending';
+
+-- Напоминание отправляется один раз на каждую дату окончания гарантии
+CREATE TABLE IF NOT EXISTS warranty_notices (
+    asset_id       INTEGER NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    warranty_until DATE NOT NULL,
+    sent_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (asset_id, warranty_until)
+);
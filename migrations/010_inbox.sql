@@ -0,0 +1,18 @@
+-- Входящие уведомления сотрудников в приложении
+CREATE TABLE IF NOT EXISTS notifications (
+    id          BIGSERIAL PRIMARY KEY,
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    kind        VARCHAR(30) NOT NULL,
+    title       VARCHAR(255) NOT NULL,
+    link        VARCHAR(255) NOT NULL DEFAULT '',
+    entity      VARCHAR(30) NOT NULL DEFAULT '',
+    entity_id   INTEGER,
+    read_at     TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_employee
+    ON notifications (employee_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_unread
+    ON notifications (employee_id) WHERE read_at IS NULL;
@@ -0,0 +1,15 @@
+-- Сброс пароля по одноразовым ссылкам. Токены хранятся только в виде
+-- SHA-256; password_changed_at отзывает токены входа, выданные раньше
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id          BIGSERIAL PRIMARY KEY,
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    expires_at  TIMESTAMP NOT NULL,
+    used_at     TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_employee
+    ON password_reset_tokens (employee_id) WHERE used_at IS NULL;
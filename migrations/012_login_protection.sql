@@ -0,0 +1,17 @@
+-- Защита входа: счетчик неудачных попыток и блокировка учетной записи,
+-- журнал неудачных входов
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS login_failures (
+    id          BIGSERIAL PRIMARY KEY,
+    email       VARCHAR(255) NOT NULL,
+    employee_id INTEGER REFERENCES employees(id) ON DELETE SET NULL,
+    ip          VARCHAR(64) NOT NULL,
+    user_agent  TEXT NOT NULL DEFAULT '',
+    reason      VARCHAR(32) NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_created ON login_failures (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_failures_email ON login_failures (email, created_at DESC);
@@ -0,0 +1,16 @@
+-- Двухфакторная аутентификация (TOTP). Секрет сохраняется при настройке,
+-- totp_enabled включается после первого верного кода. totp_last_step —
+-- шаг последнего принятого кода, чтобы его нельзя было использовать снова
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+-- Резервные коды хранятся только в виде SHA-256
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    code_hash   CHAR(64) NOT NULL,
+    used_at     TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (employee_id, code_hash)
+);
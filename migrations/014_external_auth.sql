@@ -0,0 +1,10 @@
+-- Сотрудники из внешнего каталога (LDAP/Active Directory). auth_source —
+-- провайдер, который проверяет их пароль; external_id — неизменный
+-- идентификатор записи в каталоге, по которому сотрудник находится при
+-- следующих входах, даже если сменил email
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS auth_source VARCHAR(20);
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_employees_external_id
+    ON employees (auth_source, external_id)
+    WHERE external_id IS NOT NULL;
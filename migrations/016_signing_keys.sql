@@ -0,0 +1,12 @@
+-- Ключи подписи токенов доступа. Ключ подписывает токены с activates_at
+-- до retires_at и публикуется в /.well-known/jwks.json до expires_at,
+-- пока действуют подписанные им токены. Закрытый ключ хранится в PKCS #8
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid          VARCHAR(64) PRIMARY KEY,
+    algorithm    VARCHAR(10) NOT NULL,
+    private_key  BYTEA NOT NULL,
+    activates_at TIMESTAMP NOT NULL,
+    retires_at   TIMESTAMP,
+    expires_at   TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
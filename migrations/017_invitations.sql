@@ -0,0 +1,19 @@
+-- Приглашения к регистрации. Администратор задает роль и отдел, сотрудник
+-- регистрируется по ссылке из письма. Приглашения без invited_by выданы
+-- при самостоятельной регистрации с адресом из разрешенного домена.
+-- Токен хранится только в виде SHA-256
+CREATE TABLE IF NOT EXISTS invitations (
+    id            SERIAL PRIMARY KEY,
+    email         VARCHAR(100) NOT NULL,
+    role          VARCHAR(20) NOT NULL,
+    department_id INTEGER REFERENCES departments(id) ON DELETE SET NULL,
+    invited_by    INTEGER REFERENCES employees(id) ON DELETE SET NULL,
+    token_hash    CHAR(64) NOT NULL UNIQUE,
+    expires_at    TIMESTAMP NOT NULL,
+    accepted_at   TIMESTAMP,
+    employee_id   INTEGER REFERENCES employees(id) ON DELETE SET NULL,
+    revoked_at    TIMESTAMP,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations(LOWER(email));
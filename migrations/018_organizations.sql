@@ -0,0 +1,76 @@
+-- Организации (юридические лица группы). Каждая видит только свои
+-- активы, сотрудников, отделы, местоположения и остальные данные.
+-- email_domains — домены рабочих адресов организации: по ним выбирается
+-- организация сотрудника, который входит через внешний каталог или
+-- регистрируется сам. Существующие данные переходят в организацию default
+CREATE TABLE IF NOT EXISTS organizations (
+    id            SERIAL PRIMARY KEY,
+    code          VARCHAR(50) NOT NULL UNIQUE,
+    name          VARCHAR(255) NOT NULL,
+    email_domains TEXT[] NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO organizations (code, name)
+VALUES ('default', 'Основная организация')
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE asset_transfers ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+-- Неудачные входы с неизвестным адресом не относятся ни к одной организации
+ALTER TABLE login_failures ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id);
+
+UPDATE employees SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE departments SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE locations SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE categories SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE assets SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE asset_transfers SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE reservations SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE webhook_subscriptions SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE outbox_events SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE invitations SET organization_id = (SELECT id FROM organizations WHERE code = 'default') WHERE organization_id IS NULL;
+UPDATE login_failures f SET organization_id = e.organization_id
+FROM employees e
+WHERE f.employee_id = e.id AND f.organization_id IS NULL;
+
+ALTER TABLE employees ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE departments ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE locations ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE categories ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE assets ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE asset_transfers ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE reservations ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE webhook_subscriptions ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE outbox_events ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE invitations ALTER COLUMN organization_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_employees_organization ON employees(organization_id);
+CREATE INDEX IF NOT EXISTS idx_departments_organization ON departments(organization_id);
+CREATE INDEX IF NOT EXISTS idx_locations_organization ON locations(organization_id);
+CREATE INDEX IF NOT EXISTS idx_categories_organization ON categories(organization_id);
+CREATE INDEX IF NOT EXISTS idx_assets_organization ON assets(organization_id);
+CREATE INDEX IF NOT EXISTS idx_asset_transfers_organization ON asset_transfers(organization_id, transfer_date DESC);
+CREATE INDEX IF NOT EXISTS idx_reservations_organization ON reservations(organization_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_organization ON webhook_subscriptions(organization_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_organization ON invitations(organization_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_failures_organization ON login_failures(organization_id, created_at DESC);
+
+-- Названия категорий уникальны в пределах организации. Email сотрудника
+-- по-прежнему уникален во всей группе: по нему определяется организация
+-- при входе
+DROP INDEX IF EXISTS ux_categories_parent_name;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_categories_parent_name
+    ON categories (organization_id, COALESCE(parent_id, 0), LOWER(name));
+
+-- Роль superadmin дает доступ к сводным отчетам по всем организациям и к
+-- управлению организациями. Через API ее нельзя ни назначить, ни снять:
+-- UPDATE employees SET role = 'superadmin' WHERE email = '...';
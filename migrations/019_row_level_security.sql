@@ -0,0 +1,33 @@
+-- Дополнительная защита: политики row-level security отсекают строки
+-- чужих организаций, даже если в запросе приложения забыто условие на
+-- organization_id. Применяется вместе с DB_ROW_LEVEL_SECURITY=true (по
+-- умолчанию): в этом режиме приложение передает организацию запроса в
+-- параметре app.organization_id (0 — фоновые задачи по всем
+-- организациям). Без параметра строки не видны, поэтому с
+-- DB_ROW_LEVEL_SECURITY=false приложение не запускается, если политики
+-- действуют на его роль. FORCE распространяет политики и на владельца
+-- таблиц, от имени которого обычно подключается приложение
+CREATE OR REPLACE FUNCTION current_organization_id() RETURNS INTEGER AS $$
+    SELECT COALESCE(NULLIF(current_setting('app.organization_id', true), ''), '-1')::INTEGER
+$$ LANGUAGE SQL STABLE;
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'employees', 'departments', 'locations', 'categories', 'assets',
+        'asset_transfers', 'reservations', 'webhook_subscriptions',
+        'outbox_events', 'invitations', 'login_failures'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format(
+            'CREATE POLICY tenant_isolation ON %I
+                 USING (current_organization_id() IN (0, organization_id))',
+            t
+        );
+    END LOOP;
+END
+$$;
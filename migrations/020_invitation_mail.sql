@@ -0,0 +1,4 @@
+-- Письмо с приглашением, пока оно ждет отправки, хранит в очереди
+-- действующую ссылку. При отзыве и повторной выдаче приглашения такое
+-- письмо удаляется из очереди
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS mail_id BIGINT REFERENCES mail_queue(id) ON DELETE SET NULL;